@@ -6,11 +6,15 @@ require (
 	github.com/kardianos/service v1.2.0
 	github.com/opentelemetry/opentelemetry-log-collection v0.13.12
 	github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/k8sevent v0.1.0
+	github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/otlp v0.1.0
 	github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/windows v0.1.1
+	github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/cloudwatch v0.1.0
 	github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/elastic v0.1.2
 	github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/googlecloud v0.1.2
+	github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/loki v0.1.0
 	github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/newrelic v0.1.2
 	github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/otlp v0.1.0
+	github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/s3 v0.1.0
 	github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/parser/syslog v0.1.3
 	github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/transformer/k8smetadata v0.1.2
 	github.com/spf13/cobra v1.1.1
@@ -23,6 +27,8 @@ replace github.com/opentelemetry/opentelemetry-log-collection => ../../
 
 replace github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/k8sevent => ../../operator/builtin/input/k8sevent
 
+replace github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/otlp => ../../operator/builtin/input/otlp
+
 replace github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/windows => ../../operator/builtin/input/windows
 
 replace github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/parser/syslog => ../../operator/builtin/parser/syslog
@@ -33,6 +39,12 @@ replace github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/o
 
 replace github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/googlecloud => ../../operator/builtin/output/googlecloud
 
+replace github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/loki => ../../operator/builtin/output/loki
+
 replace github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/newrelic => ../../operator/builtin/output/newrelic
 
 replace github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/otlp => ../../operator/builtin/output/otlp
+
+replace github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/s3 => ../../operator/builtin/output/s3
+
+replace github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/cloudwatch => ../../operator/builtin/output/cloudwatch
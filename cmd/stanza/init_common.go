@@ -16,10 +16,16 @@ package main
 
 import (
 	// Load packages when importing input operators
+	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/exec"
 	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/file"
+	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/fluentforward"
 	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/forward"
+	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/gelf"
 	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/generate"
+	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/http"
+	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/k8scontainer"
 	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/k8sevent"
+	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/otlp"
 	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/stanza"
 	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/stdin"
 	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/tcp"
@@ -31,6 +37,7 @@ import (
 	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/parser/syslog"
 	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/parser/time"
 
+	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/transformer/dedup"
 	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/transformer/filter"
 	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/transformer/hostmetadata"
 	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/transformer/k8smetadata"
@@ -38,15 +45,24 @@ import (
 	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/transformer/noop"
 	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/transformer/ratelimit"
 	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/transformer/recombine"
+	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/transformer/reduce"
 	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/transformer/restructure"
 	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/transformer/router"
 
+	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/cloudwatch"
 	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/drop"
 	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/elastic"
 	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/file"
+	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/fluentforward"
 	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/forward"
+	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/gelf"
 	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/googlecloud"
+	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/http"
+	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/loki"
 	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/newrelic"
 	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/otlp"
+	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/s3"
+	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/splunkhec"
 	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/stdout"
+	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/syslog"
 )
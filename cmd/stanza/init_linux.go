@@ -16,5 +16,8 @@ package main
 
 import (
 	// Load linux only packages when importing input operators
+	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/hoststats"
 	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/journald"
+	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/namedpipe"
+	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/unixsocket"
 )
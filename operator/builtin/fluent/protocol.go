@@ -0,0 +1,66 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fluent
+
+import (
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// EventTimeExtension is the msgpack extension type of a Fluentd EventTime
+const EventTimeExtension = 0
+
+// EventTime is a timestamp with nanosecond precision, encoded as
+// a big endian uint32 of seconds followed by a uint32 of nanoseconds
+type EventTime struct {
+	time.Time
+}
+
+// ExtensionType returns the msgpack extension type
+func (t *EventTime) ExtensionType() int8 { return EventTimeExtension }
+
+// Len returns the encoded length of the extension
+func (t *EventTime) Len() int { return 8 }
+
+// MarshalBinaryTo encodes the timestamp into b
+func (t *EventTime) MarshalBinaryTo(b []byte) error {
+	binary.BigEndian.PutUint32(b, uint32(t.Unix()))
+	binary.BigEndian.PutUint32(b[4:], uint32(t.Nanosecond()))
+	return nil
+}
+
+// UnmarshalBinary decodes the timestamp from b
+func (t *EventTime) UnmarshalBinary(b []byte) error {
+	if len(b) != 8 {
+		return fmt.Errorf("invalid EventTime length %d", len(b))
+	}
+	sec := binary.BigEndian.Uint32(b)
+	nsec := binary.BigEndian.Uint32(b[4:])
+	t.Time = time.Unix(int64(sec), int64(nsec))
+	return nil
+}
+
+// SharedKeyDigest computes the digest used to authenticate each side of the handshake
+func SharedKeyDigest(salt []byte, hostname string, nonce []byte, sharedKey string) string {
+	h := sha512.New()
+	h.Write(salt)
+	h.Write([]byte(hostname))
+	h.Write(nonce)
+	h.Write([]byte(sharedKey))
+	return hex.EncodeToString(h.Sum(nil))
+}
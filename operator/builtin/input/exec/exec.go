@@ -0,0 +1,386 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	osexec "os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	backoff "github.com/cenkalti/backoff/v4"
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/file"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"go.uber.org/zap"
+	"golang.org/x/text/encoding"
+)
+
+func init() {
+	operator.Register("exec_input", func() operator.Builder { return NewExecInputConfig("") })
+}
+
+const (
+	onceMode      = "once"
+	intervalMode  = "interval"
+	superviseMode = "supervise"
+
+	stdoutStream = "stdout"
+	stderrStream = "stderr"
+
+	streamLabel   = "stream"
+	exitCodeLabel = "exit_code"
+
+	defaultMaxLogSize = 1024 * 1024
+)
+
+// NewExecInputConfig creates a new exec input config with default values
+func NewExecInputConfig(operatorID string) *ExecInputConfig {
+	return &ExecInputConfig{
+		InputConfig:     helper.NewInputConfig(operatorID, "exec_input"),
+		Mode:            onceMode,
+		Interval:        helper.Duration{Duration: time.Minute},
+		RestartDelay:    helper.Duration{Duration: time.Second},
+		MaxRestartDelay: helper.Duration{Duration: time.Minute},
+		MaxLogSize:      defaultMaxLogSize,
+		Encoding:        "nop",
+	}
+}
+
+// ExecInputConfig is the configuration of an exec input operator
+type ExecInputConfig struct {
+	helper.InputConfig `yaml:",inline"`
+
+	Command         string                `json:"command"                     yaml:"command"`
+	Args            []string              `json:"args,omitempty"              yaml:"args,omitempty"`
+	WorkingDir      string                `json:"working_dir,omitempty"       yaml:"working_dir,omitempty"`
+	Environment     map[string]string     `json:"environment,omitempty"       yaml:"environment,omitempty"`
+	Mode            string                `json:"mode,omitempty"              yaml:"mode,omitempty"`
+	Interval        helper.Duration       `json:"interval,omitempty"          yaml:"interval,omitempty"`
+	Timeout         helper.Duration       `json:"timeout,omitempty"           yaml:"timeout,omitempty"`
+	RestartDelay    helper.Duration       `json:"restart_delay,omitempty"     yaml:"restart_delay,omitempty"`
+	MaxRestartDelay helper.Duration       `json:"max_restart_delay,omitempty" yaml:"max_restart_delay,omitempty"`
+	Multiline       *file.MultilineConfig `json:"multiline,omitempty"         yaml:"multiline,omitempty"`
+	MaxLogSize      helper.ByteSize       `json:"max_log_size,omitempty"      yaml:"max_log_size,omitempty"`
+	Encoding        string                `json:"encoding,omitempty"          yaml:"encoding,omitempty"`
+}
+
+// Build will build an exec input operator
+func (c ExecInputConfig) Build(context operator.BuildContext) ([]operator.Operator, error) {
+	inputOperator, err := c.InputConfig.Build(context)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Command == "" {
+		return nil, fmt.Errorf("required argument `command` is empty")
+	}
+
+	switch c.Mode {
+	case onceMode, superviseMode:
+	case intervalMode:
+		if c.Interval.Raw() <= 0 {
+			return nil, fmt.Errorf("invalid value for interval '%s'", c.Interval.Raw())
+		}
+	default:
+		return nil, fmt.Errorf("invalid mode '%s'", c.Mode)
+	}
+
+	if c.Timeout.Raw() < 0 {
+		return nil, fmt.Errorf("invalid value for timeout '%s'", c.Timeout.Raw())
+	}
+	if c.Timeout.Raw() > 0 && c.Mode == superviseMode {
+		return nil, fmt.Errorf("`timeout` can not be used with `mode: %s`", superviseMode)
+	}
+
+	if c.RestartDelay.Raw() <= 0 {
+		return nil, fmt.Errorf("invalid value for restart_delay '%s'", c.RestartDelay.Raw())
+	}
+	if c.MaxRestartDelay.Raw() < c.RestartDelay.Raw() {
+		return nil, fmt.Errorf("`max_restart_delay` must not be less than `restart_delay`")
+	}
+
+	if c.MaxLogSize <= 0 {
+		return nil, fmt.Errorf("`max_log_size` must be positive")
+	}
+
+	encoding, err := file.LookupEncoding(c.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	splitFunc, err := file.NewSplitFunc(c.Multiline, encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	env := os.Environ()
+	for key, value := range c.Environment {
+		env = append(env, key+"="+value)
+	}
+
+	execInput := &ExecInput{
+		InputOperator:   inputOperator,
+		command:         c.Command,
+		args:            c.Args,
+		workingDir:      c.WorkingDir,
+		env:             env,
+		mode:            c.Mode,
+		interval:        c.Interval.Raw(),
+		timeout:         c.Timeout.Raw(),
+		restartDelay:    c.RestartDelay.Raw(),
+		maxRestartDelay: c.MaxRestartDelay.Raw(),
+		maxLogSize:      int(c.MaxLogSize),
+		splitFunc:       splitFunc,
+		encoding:        encoding,
+	}
+	return []operator.Operator{execInput}, nil
+}
+
+// ExecInput is an operator that runs a command and emits the lines of its output
+type ExecInput struct {
+	helper.InputOperator
+
+	command         string
+	args            []string
+	workingDir      string
+	env             []string
+	mode            string
+	interval        time.Duration
+	timeout         time.Duration
+	restartDelay    time.Duration
+	maxRestartDelay time.Duration
+	maxLogSize      int
+	splitFunc       bufio.SplitFunc
+	encoding        encoding.Encoding
+
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+}
+
+// Start will start running the command
+func (e *ExecInput) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel = cancel
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+
+		switch e.mode {
+		case onceMode:
+			e.run(ctx)
+		case intervalMode:
+			e.runOnInterval(ctx)
+		case superviseMode:
+			e.supervise(ctx)
+		}
+	}()
+
+	return nil
+}
+
+// Stop will stop running the command, killing it if it is still running
+func (e *ExecInput) Stop() error {
+	e.cancel()
+	e.wg.Wait()
+	return nil
+}
+
+// runOnInterval runs the command immediately and then on each interval. A run
+// that takes longer than the interval delays the next one.
+func (e *ExecInput) runOnInterval(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		e.run(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// supervise keeps the command running, restarting it with an exponential
+// backoff each time it exits. The backoff is reset once the command has run
+// for at least the max restart delay.
+func (e *ExecInput) supervise(ctx context.Context) {
+	b := &backoff.ExponentialBackOff{
+		InitialInterval:     e.restartDelay,
+		RandomizationFactor: backoff.DefaultRandomizationFactor,
+		Multiplier:          backoff.DefaultMultiplier,
+		MaxInterval:         e.maxRestartDelay,
+		Stop:                backoff.Stop,
+		Clock:               backoff.SystemClock,
+	}
+	b.Reset()
+
+	for {
+		started := time.Now()
+		e.run(ctx)
+
+		if time.Since(started) >= e.maxRestartDelay {
+			b.Reset()
+		}
+
+		waitTime := b.NextBackOff()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(waitTime):
+		}
+		e.Infow("Restarting command", "command", e.command, "wait_time", waitTime)
+	}
+}
+
+// run runs the command once and emits the lines of its output. Except when
+// supervised, entries are held until the command exits so that they can be
+// labeled with its exit code.
+func (e *ExecInput) run(ctx context.Context) {
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+	}
+
+	cmd := osexec.CommandContext(ctx, e.command, e.args...)
+	cmd.Dir = e.workingDir
+	cmd.Env = e.env
+
+	stdout, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		e.Errorw("Failed to create stdout pipe", zap.Error(err))
+		return
+	}
+	defer stdout.Close()
+	stderr, stderrWriter, err := os.Pipe()
+	if err != nil {
+		stdoutWriter.Close()
+		e.Errorw("Failed to create stderr pipe", zap.Error(err))
+		return
+	}
+	defer stderr.Close()
+
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = stderrWriter
+	err = cmd.Start()
+	stdoutWriter.Close()
+	stderrWriter.Close()
+	if err != nil {
+		e.Errorw("Failed to start command", zap.Error(err), "command", e.command)
+		return
+	}
+
+	// Background processes started by the command may hold the pipes open
+	// after it is killed, so they are closed to stop reading
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			stdout.Close()
+			stderr.Close()
+		case <-done:
+		}
+	}()
+
+	var pending []*entry.Entry
+	var pendingMux sync.Mutex
+	handle := func(entry *entry.Entry) {
+		if e.mode == superviseMode {
+			e.Write(ctx, entry)
+			return
+		}
+		pendingMux.Lock()
+		pending = append(pending, entry)
+		pendingMux.Unlock()
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		e.read(stdout, stdoutStream, handle)
+	}()
+	go func() {
+		defer wg.Done()
+		e.read(stderr, stderrStream, handle)
+	}()
+	wg.Wait()
+	close(done)
+
+	err = cmd.Wait()
+	exitCode := -1
+	if cmd.ProcessState != nil {
+		exitCode = cmd.ProcessState.ExitCode()
+	}
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		e.Warnw("Command timed out", "command", e.command, "timeout", e.timeout)
+	case err != nil && e.mode == superviseMode:
+		e.Warnw("Command exited", zap.Error(err), "command", e.command, "exit_code", exitCode)
+	case err != nil:
+		e.Debugw("Command exited", zap.Error(err), "command", e.command, "exit_code", exitCode)
+	}
+
+	if e.mode == superviseMode {
+		return
+	}
+
+	// Entries are written even if the operator is stopping, since they were
+	// already read. The exit code is -1 if the command was killed.
+	for _, entry := range pending {
+		entry.AddLabel(exitCodeLabel, strconv.Itoa(exitCode))
+		e.Write(context.Background(), entry)
+	}
+}
+
+// read splits the output of a stream into entries
+func (e *ExecInput) read(r io.Reader, stream string, handle func(*entry.Entry)) {
+	decoder := e.encoding.NewDecoder()
+
+	scanner := file.NewStreamScanner(r, e.splitFunc, e.maxLogSize)
+	for scanner.Scan() {
+		message, err := decoder.Bytes(scanner.Bytes())
+		if err != nil {
+			e.Errorw("Failed to decode output", zap.Error(err), "stream", stream)
+			continue
+		}
+
+		entry, err := e.NewEntry(string(message))
+		if err != nil {
+			e.Errorw("Failed to create entry", zap.Error(err))
+			continue
+		}
+		entry.AddLabel(streamLabel, stream)
+		handle(entry)
+	}
+
+	if err := scanner.Err(); err != nil && !errors.Is(err, os.ErrClosed) {
+		e.Errorw("Failed to read output", zap.Error(err), "stream", stream)
+		// Keep draining so the command does not block writing to the pipe
+		_, _ = io.Copy(ioutil.Discard, r)
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"runtime"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/file"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"github.com/opentelemetry/opentelemetry-log-collection/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// newShellConfig returns a config that runs a shell script
+func newShellConfig(t *testing.T, script string) *ExecInputConfig {
+	if runtime.GOOS == "windows" {
+		t.Skip("Test scripts require a POSIX shell")
+	}
+
+	cfg := NewExecInputConfig("test_id")
+	cfg.OutputIDs = []string{"$.fake"}
+	cfg.Command = "/bin/sh"
+	cfg.Args = []string{"-c", script}
+	return cfg
+}
+
+func startTestInput(t *testing.T, cfg *ExecInputConfig) (operator.Operator, *testutil.FakeOutput) {
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	op := ops[0]
+
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, op.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, op.Start())
+	return op, fake
+}
+
+func receive(t *testing.T, fake *testutil.FakeOutput) *entry.Entry {
+	select {
+	case e := <-fake.Received:
+		return e
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "Timed out waiting for entry")
+	}
+	return nil
+}
+
+func expectNoEntry(t *testing.T, fake *testutil.FakeOutput) {
+	select {
+	case e := <-fake.Received:
+		require.FailNow(t, "Received unexpected entry", e.Record)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestExecInputOnce(t *testing.T) {
+	cfg := newShellConfig(t, "echo out1; echo err1 >&2; echo out2; exit 3")
+
+	op, fake := startTestInput(t, cfg)
+	defer op.Stop()
+
+	lines := []string{}
+	for i := 0; i < 3; i++ {
+		e := receive(t, fake)
+		require.Equal(t, "3", e.Labels[exitCodeLabel])
+		lines = append(lines, e.Labels[streamLabel]+": "+e.Record.(string))
+	}
+	expectNoEntry(t, fake)
+
+	sort.Strings(lines)
+	require.Equal(t, []string{"stderr: err1", "stdout: out1", "stdout: out2"}, lines)
+}
+
+func TestExecInputEnvironment(t *testing.T) {
+	cfg := newShellConfig(t, "echo $TEST_VALUE; pwd")
+	cfg.Environment = map[string]string{"TEST_VALUE": "hello"}
+	cfg.WorkingDir = "/"
+
+	op, fake := startTestInput(t, cfg)
+	defer op.Stop()
+
+	require.Equal(t, "hello", receive(t, fake).Record)
+	require.Equal(t, "/", receive(t, fake).Record)
+	expectNoEntry(t, fake)
+}
+
+func TestExecInputMultiline(t *testing.T) {
+	cfg := newShellConfig(t, "printf 'START 1\\n  detail\\nSTART 2\\n  detail'")
+	cfg.Multiline = &file.MultilineConfig{LineStartPattern: "^START"}
+
+	op, fake := startTestInput(t, cfg)
+	defer op.Stop()
+
+	require.Equal(t, "START 1\n  detail\n", receive(t, fake).Record)
+	require.Equal(t, "START 2\n  detail", receive(t, fake).Record)
+	expectNoEntry(t, fake)
+}
+
+func TestExecInputMaxLogSize(t *testing.T) {
+	cfg := newShellConfig(t, "echo 0123456789abcdefghij; echo short")
+	cfg.MaxLogSize = 16
+
+	op, fake := startTestInput(t, cfg)
+	defer op.Stop()
+
+	require.Equal(t, "0123456789abcdef", receive(t, fake).Record)
+	require.Equal(t, "ghij", receive(t, fake).Record)
+	require.Equal(t, "short", receive(t, fake).Record)
+	expectNoEntry(t, fake)
+}
+
+func TestExecInputInterval(t *testing.T) {
+	cfg := newShellConfig(t, "echo tick")
+	cfg.Mode = intervalMode
+	cfg.Interval = helper.Duration{Duration: 20 * time.Millisecond}
+
+	op, fake := startTestInput(t, cfg)
+	defer op.Stop()
+
+	for i := 0; i < 3; i++ {
+		e := receive(t, fake)
+		require.Equal(t, "tick", e.Record)
+		require.Equal(t, "0", e.Labels[exitCodeLabel])
+	}
+}
+
+func TestExecInputTimeout(t *testing.T) {
+	cfg := newShellConfig(t, "echo before; sleep 10 & wait")
+	cfg.Timeout = helper.Duration{Duration: 100 * time.Millisecond}
+
+	op, fake := startTestInput(t, cfg)
+	defer op.Stop()
+
+	e := receive(t, fake)
+	require.Equal(t, "before", e.Record)
+	require.Equal(t, "-1", e.Labels[exitCodeLabel])
+}
+
+func TestExecInputSupervise(t *testing.T) {
+	cfg := newShellConfig(t, "echo started; exit 1")
+	cfg.Mode = superviseMode
+	cfg.RestartDelay = helper.Duration{Duration: 10 * time.Millisecond}
+	cfg.MaxRestartDelay = helper.Duration{Duration: 20 * time.Millisecond}
+
+	op, fake := startTestInput(t, cfg)
+	defer op.Stop()
+
+	for i := 0; i < 3; i++ {
+		e := receive(t, fake)
+		require.Equal(t, "started", e.Record)
+		require.Equal(t, stdoutStream, e.Labels[streamLabel])
+		require.NotContains(t, e.Labels, exitCodeLabel)
+	}
+}
+
+func TestExecInputSuperviseStreams(t *testing.T) {
+	cfg := newShellConfig(t, "echo up; sleep 10 & wait")
+	cfg.Mode = superviseMode
+
+	op, fake := startTestInput(t, cfg)
+
+	// Lines are emitted while the command is still running
+	require.Equal(t, "up", receive(t, fake).Record)
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		require.NoError(t, op.Stop())
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "Timed out waiting for operator to stop")
+	}
+}
+
+func TestExecInputCommandNotFound(t *testing.T) {
+	cfg := NewExecInputConfig("test_id")
+	cfg.OutputIDs = []string{"$.fake"}
+	cfg.Command = "/does/not/exist"
+
+	op, fake := startTestInput(t, cfg)
+	defer op.Stop()
+
+	expectNoEntry(t, fake)
+}
+
+func TestBuildExecInputErrors(t *testing.T) {
+	cases := []struct {
+		name      string
+		configure func(*ExecInputConfig)
+	}{
+		{"MissingCommand", func(c *ExecInputConfig) { c.Command = "" }},
+		{"UnknownMode", func(c *ExecInputConfig) { c.Mode = "always" }},
+		{"ZeroInterval", func(c *ExecInputConfig) {
+			c.Mode = intervalMode
+			c.Interval = helper.Duration{}
+		}},
+		{"SuperviseTimeout", func(c *ExecInputConfig) {
+			c.Mode = superviseMode
+			c.Timeout = helper.Duration{Duration: time.Second}
+		}},
+		{"ZeroRestartDelay", func(c *ExecInputConfig) { c.RestartDelay = helper.Duration{} }},
+		{"SmallMaxRestartDelay", func(c *ExecInputConfig) { c.MaxRestartDelay = helper.Duration{Duration: time.Millisecond} }},
+		{"ZeroMaxLogSize", func(c *ExecInputConfig) { c.MaxLogSize = 0 }},
+		{"UnknownEncoding", func(c *ExecInputConfig) { c.Encoding = "not-an-encoding" }},
+		{"InvalidMultiline", func(c *ExecInputConfig) { c.Multiline = &file.MultilineConfig{} }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := NewExecInputConfig("test_id")
+			cfg.OutputIDs = []string{"$.fake"}
+			cfg.Command = "echo"
+			tc.configure(cfg)
+
+			_, err := cfg.Build(testutil.NewBuildContext(t))
+			require.Error(t, err)
+		})
+	}
+}
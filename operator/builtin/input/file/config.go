@@ -118,7 +118,7 @@ func (c InputConfig) Build(context operator.BuildContext) ([]operator.Operator,
 		return nil, fmt.Errorf("`fingerprint_size` must be at least %d bytes", minFingerprintSize)
 	}
 
-	encoding, err := lookupEncoding(c.Encoding)
+	encoding, err := LookupEncoding(c.Encoding)
 	if err != nil {
 		return nil, err
 	}
@@ -182,7 +182,9 @@ var encodingOverrides = map[string]encoding.Encoding{
 	"":         encoding.Nop,
 }
 
-func lookupEncoding(enc string) (encoding.Encoding, error) {
+// LookupEncoding returns the encoding with the given name, such as utf-8 or
+// utf-16. An empty name or "nop" leaves the bytes unchanged.
+func LookupEncoding(enc string) (encoding.Encoding, error) {
 	if encoding, ok := encodingOverrides[strings.ToLower(enc)]; ok {
 		return encoding, nil
 	}
@@ -198,11 +200,22 @@ func lookupEncoding(enc string) (encoding.Encoding, error) {
 
 // getSplitFunc will return the split function associated the configured mode.
 func (c InputConfig) getSplitFunc(encoding encoding.Encoding) (bufio.SplitFunc, error) {
-	if c.Multiline == nil {
+	return NewSplitFunc(c.Multiline, encoding)
+}
+
+// NewSplitFunc returns a split function that splits on newlines, or using the
+// multiline configuration if it is not nil
+func NewSplitFunc(multiline *MultilineConfig, encoding encoding.Encoding) (bufio.SplitFunc, error) {
+	if multiline == nil {
 		return NewNewlineSplitFunc(encoding)
 	}
-	endPattern := c.Multiline.LineEndPattern
-	startPattern := c.Multiline.LineStartPattern
+	return multiline.Build()
+}
+
+// Build will build a split function from the multiline configuration.
+func (c MultilineConfig) Build() (bufio.SplitFunc, error) {
+	endPattern := c.LineEndPattern
+	startPattern := c.LineStartPattern
 
 	switch {
 	case endPattern != "" && startPattern != "":
@@ -210,13 +223,13 @@ func (c InputConfig) getSplitFunc(encoding encoding.Encoding) (bufio.SplitFunc,
 	case endPattern == "" && startPattern == "":
 		return nil, fmt.Errorf("one of line_start_pattern or line_end_pattern must be set")
 	case endPattern != "":
-		re, err := regexp.Compile("(?m)" + c.Multiline.LineEndPattern)
+		re, err := regexp.Compile("(?m)" + c.LineEndPattern)
 		if err != nil {
 			return nil, fmt.Errorf("compile line end regex: %s", err)
 		}
 		return NewLineEndSplitFunc(re), nil
 	case startPattern != "":
-		re, err := regexp.Compile("(?m)" + c.Multiline.LineStartPattern)
+		re, err := regexp.Compile("(?m)" + c.LineStartPattern)
 		if err != nil {
 			return nil, fmt.Errorf("compile line start regex: %s", err)
 		}
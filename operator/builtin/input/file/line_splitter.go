@@ -17,6 +17,7 @@ package file
 import (
 	"bufio"
 	"bytes"
+	"io"
 	"regexp"
 
 	"golang.org/x/text/encoding"
@@ -120,3 +121,39 @@ func encodedCarriageReturn(encoding encoding.Encoding) ([]byte, error) {
 	nDst, _, err := encoding.NewEncoder().Transform(out, []byte{'\r'}, true)
 	return out[:nDst], err
 }
+
+// NewFlushingSplitFunc wraps a split function for streams that end, such as
+// pipes and connections. Data left over when the stream ends is returned as a
+// final token, and data that reaches maxLogSize without a token is returned
+// as a token of its own instead of failing the scan.
+func NewFlushingSplitFunc(splitFunc bufio.SplitFunc, maxLogSize int) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		advance, token, err = splitFunc(data, atEOF)
+		if err != nil || advance > 0 || token != nil {
+			return advance, token, err
+		}
+
+		if len(data) >= maxLogSize {
+			return maxLogSize, data[:maxLogSize], nil
+		}
+
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
+
+// NewStreamScanner creates a scanner that splits a stream that ends, such as a
+// pipe or connection, into tokens of at most maxLogSize bytes
+func NewStreamScanner(r io.Reader, splitFunc bufio.SplitFunc, maxLogSize int) *bufio.Scanner {
+	bufferSize := 16 * 1024
+	if maxLogSize < bufferSize {
+		bufferSize = maxLogSize
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufferSize), maxLogSize)
+	scanner.Split(NewFlushingSplitFunc(splitFunc, maxLogSize))
+	return scanner
+}
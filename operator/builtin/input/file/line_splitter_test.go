@@ -402,6 +402,51 @@ func TestNewlineSplitFunc_Encodings(t *testing.T) {
 	}
 }
 
+func TestStreamScanner(t *testing.T) {
+	newlineSplitFunc, err := NewNewlineSplitFunc(unicode.UTF8)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name   string
+		input  []byte
+		tokens []string
+	}{
+		{
+			"TrailingLine",
+			[]byte("log1\nlog2"),
+			[]string{"log1", "log2"},
+		},
+		{
+			"TerminatedLine",
+			[]byte("log1\nlog2\n"),
+			[]string{"log1", "log2"},
+		},
+		{
+			"LongLine",
+			[]byte("0123456789abcdefghij\nlog2\n"),
+			[]string{"0123456789abcdef", "ghij", "log2"},
+		},
+		{
+			"Empty",
+			[]byte(""),
+			[]string{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			scanner := NewStreamScanner(bytes.NewReader(tc.input), newlineSplitFunc, 16)
+
+			tokens := []string{}
+			for scanner.Scan() {
+				tokens = append(tokens, scanner.Text())
+			}
+			require.NoError(t, scanner.Err())
+			require.Equal(t, tc.tokens, tokens)
+		})
+	}
+}
+
 func generatedByteSliceOfLength(length int) []byte {
 	chars := []byte(`abcdefghijklmnopqrstuvwxyz`)
 	newSlice := make([]byte, length)
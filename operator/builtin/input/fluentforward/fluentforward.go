@@ -0,0 +1,375 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fluentforward
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/fluent"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"github.com/tinylib/msgp/msgp"
+	"go.uber.org/zap"
+)
+
+func init() {
+	operator.Register("fluentforward_input", func() operator.Builder { return NewFluentForwardInputConfig("") })
+}
+
+const (
+	// handshakeTimeout is the time allowed for a client to complete the shared key handshake
+	handshakeTimeout = 10 * time.Second
+
+	// defaultMaxMessageSize is the default limit on the size of a single message
+	defaultMaxMessageSize = 16 * 1024 * 1024
+)
+
+// NewFluentForwardInputConfig creates a new fluentforward input config with default values
+func NewFluentForwardInputConfig(operatorID string) *FluentForwardInputConfig {
+	return &FluentForwardInputConfig{
+		InputConfig:    helper.NewInputConfig(operatorID, "fluentforward_input"),
+		TagField:       entry.NewLabelField("fluent.tag"),
+		MaxMessageSize: defaultMaxMessageSize,
+	}
+}
+
+// FluentForwardInputConfig is the configuration of a fluentforward input operator.
+type FluentForwardInputConfig struct {
+	helper.InputConfig `yaml:",inline"`
+
+	ListenAddress  string                  `json:"listen_address"             yaml:"listen_address"`
+	TLS            *helper.TLSServerConfig `json:"tls,omitempty"              yaml:"tls,omitempty"`
+	SharedKey      string                  `json:"shared_key,omitempty"       yaml:"shared_key,omitempty"`
+	SelfHostname   string                  `json:"self_hostname,omitempty"    yaml:"self_hostname,omitempty"`
+	TagField       entry.Field             `json:"tag_field"                  yaml:"tag_field"`
+	MaxMessageSize helper.ByteSize         `json:"max_message_size,omitempty" yaml:"max_message_size,omitempty"`
+}
+
+// Build will build a fluentforward input operator.
+func (c FluentForwardInputConfig) Build(context operator.BuildContext) ([]operator.Operator, error) {
+	inputOperator, err := c.InputConfig.Build(context)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.ListenAddress == "" {
+		return nil, fmt.Errorf("missing required parameter 'listen_address'")
+	}
+
+	address, err := net.ResolveTCPAddr("tcp", c.ListenAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve listen_address: %s", err)
+	}
+
+	if c.MaxMessageSize <= 0 {
+		return nil, fmt.Errorf("`max_message_size` must be positive")
+	}
+
+	var tlsConfig *tls.Config
+	if c.TLS != nil {
+		tlsConfig, err = c.TLS.Build()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hostname := c.SelfHostname
+	if hostname == "" {
+		hostname, err = os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("get hostname: %s", err)
+		}
+	}
+
+	fluentInput := &FluentForwardInput{
+		InputOperator:  inputOperator,
+		address:        address,
+		tlsConfig:      tlsConfig,
+		sharedKey:      c.SharedKey,
+		hostname:       hostname,
+		tagField:       c.TagField,
+		maxMessageSize: int(c.MaxMessageSize),
+	}
+	return []operator.Operator{fluentInput}, nil
+}
+
+// FluentForwardInput is an operator that receives entries using the Fluent Forward protocol.
+type FluentForwardInput struct {
+	helper.InputOperator
+	address        *net.TCPAddr
+	tlsConfig      *tls.Config
+	sharedKey      string
+	hostname       string
+	tagField       entry.Field
+	maxMessageSize int
+
+	listener net.Listener
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// Start will start listening for forwarded entries.
+func (f *FluentForwardInput) Start() error {
+	listener, err := net.ListenTCP("tcp", f.address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on interface: %w", err)
+	}
+
+	f.listener = listener
+	if f.tlsConfig != nil {
+		f.listener = tls.NewListener(listener, f.tlsConfig)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f.cancel = cancel
+	f.goListen(ctx)
+	return nil
+}
+
+// goListen will accept connections from forwarding clients.
+func (f *FluentForwardInput) goListen(ctx context.Context) {
+	f.wg.Add(1)
+
+	go func() {
+		defer f.wg.Done()
+
+		backoff := helper.NewBackoff()
+		for {
+			conn, err := f.listener.Accept()
+			if err != nil {
+				if !backoff.Wait(ctx, f.Debugw, "Listener accept error", err) {
+					return
+				}
+				continue
+			}
+			backoff.Reset()
+
+			f.Debugf("Received connection: %s", conn.RemoteAddr().String())
+			subctx, cancel := context.WithCancel(ctx)
+			f.goHandleClose(subctx, conn)
+			f.goHandleMessages(subctx, conn, cancel)
+		}
+	}()
+}
+
+// goHandleClose will wait for the context to finish before closing a connection.
+func (f *FluentForwardInput) goHandleClose(ctx context.Context, conn net.Conn) {
+	f.wg.Add(1)
+
+	go func() {
+		defer f.wg.Done()
+		<-ctx.Done()
+		f.Debugf("Closing connection: %s", conn.RemoteAddr().String())
+		if err := conn.Close(); err != nil {
+			f.Errorf("Failed to close connection: %s", err)
+		}
+	}()
+}
+
+// goHandleMessages will read messages from a connection until it is closed.
+func (f *FluentForwardInput) goHandleMessages(ctx context.Context, conn net.Conn, cancel context.CancelFunc) {
+	f.wg.Add(1)
+
+	go func() {
+		defer f.wg.Done()
+		defer cancel()
+
+		d := newDecoder(conn, f.maxMessageSize)
+
+		if f.sharedKey != "" {
+			if err := f.handshake(conn, d); err != nil {
+				f.Errorw("Failed handshake", zap.Error(err), "remote_address", conn.RemoteAddr().String())
+				return
+			}
+		}
+
+		for {
+			msg, err := d.decodeMessage()
+			if err != nil {
+				select {
+				case <-ctx.Done():
+				default:
+					if err != io.EOF {
+						f.Errorw("Failed to decode message. Closing connection", zap.Error(err))
+					}
+				}
+				return
+			}
+
+			f.handleMessage(ctx, msg)
+
+			if msg.chunk != "" {
+				if err := writeAck(conn, msg.chunk); err != nil {
+					f.Errorw("Failed to acknowledge chunk", zap.Error(err))
+					return
+				}
+			}
+		}
+	}()
+}
+
+// handleMessage will create and write an entry for each event in a message.
+func (f *FluentForwardInput) handleMessage(ctx context.Context, msg *message) {
+	for _, e := range msg.events {
+		entry, err := f.NewEntry(e.record)
+		if err != nil {
+			f.Errorw("Failed to create entry", zap.Error(err))
+			continue
+		}
+
+		entry.Timestamp = e.time
+		if err := entry.Set(f.tagField, msg.tag); err != nil {
+			f.Errorw("Failed to set tag", zap.Error(err))
+		}
+		f.Write(ctx, entry)
+	}
+}
+
+// handshake will authenticate a client using the shared key.
+//
+// The server sends HELO with a nonce, the client answers with PING containing a
+// digest of the shared key, and the server confirms or rejects it with PONG.
+func (f *FluentForwardInput) handshake(conn net.Conn, d *decoder) error {
+	if err := conn.SetDeadline(time.Now().Add(handshakeTimeout)); err != nil {
+		return err
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("generate nonce: %s", err)
+	}
+
+	if err := writeHelo(conn, nonce); err != nil {
+		return fmt.Errorf("write HELO: %s", err)
+	}
+
+	hostname, salt, digest, err := d.readPing()
+	if err != nil {
+		return fmt.Errorf("read PING: %s", err)
+	}
+
+	expected := fluent.SharedKeyDigest(salt, hostname, nonce, f.sharedKey)
+	if subtle.ConstantTimeCompare([]byte(digest), []byte(expected)) != 1 {
+		if err := writePong(conn, false, "shared_key mismatch", "", ""); err != nil {
+			return fmt.Errorf("write PONG: %s", err)
+		}
+		return fmt.Errorf("shared_key mismatch from '%s'", hostname)
+	}
+
+	serverDigest := fluent.SharedKeyDigest(salt, f.hostname, nonce, f.sharedKey)
+	if err := writePong(conn, true, "", f.hostname, serverDigest); err != nil {
+		return fmt.Errorf("write PONG: %s", err)
+	}
+
+	return conn.SetDeadline(time.Time{})
+}
+
+// writeHelo writes ["HELO", {"nonce": nonce, "auth": "", "keepalive": true}]
+func writeHelo(w io.Writer, nonce []byte) error {
+	b := msgp.AppendArrayHeader(nil, 2)
+	b = msgp.AppendString(b, "HELO")
+	b = msgp.AppendMapHeader(b, 3)
+	b = msgp.AppendString(b, "nonce")
+	b = msgp.AppendBytes(b, nonce)
+	b = msgp.AppendString(b, "auth")
+	b = msgp.AppendBytes(b, []byte{})
+	b = msgp.AppendString(b, "keepalive")
+	b = msgp.AppendBool(b, true)
+	_, err := w.Write(b)
+	return err
+}
+
+// readPing reads ["PING", hostname, salt, digest, username, password]
+func (d *decoder) readPing() (hostname string, salt []byte, digest string, err error) {
+	d.remaining = d.maxSize
+
+	size, err := d.r.ReadArrayHeader()
+	if err != nil {
+		return
+	}
+	if size != 6 {
+		err = fmt.Errorf("invalid PING length %d", size)
+		return
+	}
+
+	msgType, err := d.readString()
+	if err != nil {
+		return
+	}
+	if msgType != "PING" {
+		err = fmt.Errorf("expected PING, got '%s'", msgType)
+		return
+	}
+
+	if hostname, err = d.readString(); err != nil {
+		return
+	}
+	if salt, err = d.readBytes(); err != nil {
+		return
+	}
+	if digest, err = d.readString(); err != nil {
+		return
+	}
+
+	// User authentication is not supported, so username and password are ignored
+	if err = d.r.Skip(); err != nil {
+		return
+	}
+	err = d.r.Skip()
+	return
+}
+
+// writePong writes ["PONG", ok, reason, hostname, digest]
+func writePong(w io.Writer, ok bool, reason, hostname, digest string) error {
+	b := msgp.AppendArrayHeader(nil, 5)
+	b = msgp.AppendString(b, "PONG")
+	b = msgp.AppendBool(b, ok)
+	b = msgp.AppendString(b, reason)
+	b = msgp.AppendString(b, hostname)
+	b = msgp.AppendString(b, digest)
+	_, err := w.Write(b)
+	return err
+}
+
+// writeAck writes {"ack": chunk}
+func writeAck(w io.Writer, chunk string) error {
+	b := msgp.AppendMapHeader(nil, 1)
+	b = msgp.AppendString(b, "ack")
+	b = msgp.AppendString(b, chunk)
+	_, err := w.Write(b)
+	return err
+}
+
+// Stop will stop listening for forwarded entries.
+func (f *FluentForwardInput) Stop() error {
+	f.cancel()
+
+	if err := f.listener.Close(); err != nil {
+		return err
+	}
+
+	f.wg.Wait()
+	return nil
+}
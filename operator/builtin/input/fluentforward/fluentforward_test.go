@@ -0,0 +1,417 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fluentforward
+
+import (
+	"bytes"
+	"compress/gzip"
+	"math"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/fluent"
+	"github.com/opentelemetry/opentelemetry-log-collection/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func newTestFluentForwardInput(t *testing.T, configure func(cfg *FluentForwardInputConfig)) (*FluentForwardInput, *testutil.FakeOutput) {
+	cfg := NewFluentForwardInputConfig("test_input")
+	cfg.ListenAddress = "127.0.0.1:0"
+	cfg.SelfHostname = "server"
+	if configure != nil {
+		configure(cfg)
+	}
+
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	fluentInput := ops[0].(*FluentForwardInput)
+
+	fakeOutput := testutil.NewFakeOutput(t)
+	fluentInput.InputOperator.OutputOperators = []operator.Operator{fakeOutput}
+
+	require.NoError(t, fluentInput.Start())
+	t.Cleanup(func() { require.NoError(t, fluentInput.Stop()) })
+	return fluentInput, fakeOutput
+}
+
+func dial(t *testing.T, f *FluentForwardInput) net.Conn {
+	conn, err := net.Dial("tcp", f.listener.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+	return conn
+}
+
+func expectEntry(t *testing.T, fakeOutput *testutil.FakeOutput, tag string, ts time.Time, record map[string]interface{}) {
+	select {
+	case e := <-fakeOutput.Received:
+		require.True(t, ts.Equal(e.Timestamp), "expected %s, got %s", ts, e.Timestamp)
+		require.Equal(t, map[string]string{"fluent.tag": tag}, e.Labels)
+		require.Equal(t, record, e.Record)
+	case <-time.After(time.Second):
+		require.FailNow(t, "Timed out waiting for entry")
+	}
+}
+
+func expectNoEntry(t *testing.T, fakeOutput *testutil.FakeOutput) {
+	select {
+	case e := <-fakeOutput.Received:
+		require.FailNow(t, "Unexpected entry: %v", e)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func appendEvent(t *testing.T, b []byte, ts time.Time, record map[string]interface{}) []byte {
+	b = msgp.AppendArrayHeader(b, 2)
+	b, err := msgp.AppendExtension(b, &fluent.EventTime{Time: ts})
+	require.NoError(t, err)
+	b, err = msgp.AppendMapStrIntf(b, record)
+	require.NoError(t, err)
+	return b
+}
+
+func appendOptions(t *testing.T, b []byte, options map[string]interface{}) []byte {
+	b, err := msgp.AppendMapStrIntf(b, options)
+	require.NoError(t, err)
+	return b
+}
+
+func readAck(t *testing.T, conn net.Conn) string {
+	response := map[string]interface{}{}
+	require.NoError(t, msgp.NewReader(conn).ReadMapStrIntf(response))
+	return response["ack"].(string)
+}
+
+func TestFluentForwardInput(t *testing.T) {
+	ts := time.Unix(1600000000, 123456789)
+	record := map[string]interface{}{
+		"message": "test message",
+		"count":   int64(1),
+	}
+
+	t.Run("MessageMode", func(t *testing.T) {
+		f, fakeOutput := newTestFluentForwardInput(t, nil)
+		conn := dial(t, f)
+
+		b := msgp.AppendArrayHeader(nil, 3)
+		b = msgp.AppendString(b, "app.logs")
+		b = msgp.AppendInt64(b, ts.Unix())
+		b = appendOptions(t, b, record)
+		_, err := conn.Write(b)
+		require.NoError(t, err)
+
+		expectEntry(t, fakeOutput, "app.logs", time.Unix(ts.Unix(), 0), record)
+	})
+
+	t.Run("MessageModeWithAck", func(t *testing.T) {
+		f, fakeOutput := newTestFluentForwardInput(t, nil)
+		conn := dial(t, f)
+
+		b := msgp.AppendArrayHeader(nil, 4)
+		b = msgp.AppendString(b, "app.logs")
+		b, err := msgp.AppendExtension(b, &fluent.EventTime{Time: ts})
+		require.NoError(t, err)
+		b = appendOptions(t, b, record)
+		b = appendOptions(t, b, map[string]interface{}{"chunk": "abc123"})
+		_, err = conn.Write(b)
+		require.NoError(t, err)
+
+		expectEntry(t, fakeOutput, "app.logs", ts, record)
+		require.Equal(t, "abc123", readAck(t, conn))
+	})
+
+	t.Run("ForwardMode", func(t *testing.T) {
+		f, fakeOutput := newTestFluentForwardInput(t, nil)
+		conn := dial(t, f)
+
+		b := msgp.AppendArrayHeader(nil, 2)
+		b = msgp.AppendString(b, "app.logs")
+		b = msgp.AppendArrayHeader(b, 2)
+		b = appendEvent(t, b, ts, record)
+		b = appendEvent(t, b, ts.Add(time.Second), record)
+		_, err := conn.Write(b)
+		require.NoError(t, err)
+
+		expectEntry(t, fakeOutput, "app.logs", ts, record)
+		expectEntry(t, fakeOutput, "app.logs", ts.Add(time.Second), record)
+	})
+
+	t.Run("PackedForwardMode", func(t *testing.T) {
+		f, fakeOutput := newTestFluentForwardInput(t, nil)
+		conn := dial(t, f)
+
+		packed := appendEvent(t, nil, ts, record)
+		packed = appendEvent(t, packed, ts.Add(time.Second), record)
+
+		b := msgp.AppendArrayHeader(nil, 2)
+		b = msgp.AppendString(b, "app.logs")
+		b = msgp.AppendBytes(b, packed)
+		_, err := conn.Write(b)
+		require.NoError(t, err)
+
+		expectEntry(t, fakeOutput, "app.logs", ts, record)
+		expectEntry(t, fakeOutput, "app.logs", ts.Add(time.Second), record)
+	})
+
+	t.Run("CompressedPackedForwardMode", func(t *testing.T) {
+		f, fakeOutput := newTestFluentForwardInput(t, nil)
+		conn := dial(t, f)
+
+		var compressed bytes.Buffer
+		w := gzip.NewWriter(&compressed)
+		_, err := w.Write(appendEvent(t, nil, ts, record))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		b := msgp.AppendArrayHeader(nil, 3)
+		b = msgp.AppendString(b, "app.logs")
+		b = msgp.AppendBytes(b, compressed.Bytes())
+		b = appendOptions(t, b, map[string]interface{}{"compressed": "gzip", "chunk": "xyz"})
+		_, err = conn.Write(b)
+		require.NoError(t, err)
+
+		expectEntry(t, fakeOutput, "app.logs", ts, record)
+		require.Equal(t, "xyz", readAck(t, conn))
+	})
+
+	t.Run("BinaryStrings", func(t *testing.T) {
+		f, fakeOutput := newTestFluentForwardInput(t, nil)
+		conn := dial(t, f)
+
+		b := msgp.AppendArrayHeader(nil, 3)
+		b = msgp.AppendString(b, "app.logs")
+		b = msgp.AppendInt64(b, ts.Unix())
+		b = appendOptions(t, b, map[string]interface{}{"message": []byte("raw")})
+		_, err := conn.Write(b)
+		require.NoError(t, err)
+
+		expectEntry(t, fakeOutput, "app.logs", time.Unix(ts.Unix(), 0), map[string]interface{}{"message": "raw"})
+	})
+
+	t.Run("InvalidMessage", func(t *testing.T) {
+		f, fakeOutput := newTestFluentForwardInput(t, nil)
+		conn := dial(t, f)
+
+		b := msgp.AppendArrayHeader(nil, 5)
+		b = msgp.AppendString(b, "app.logs")
+		_, err := conn.Write(b)
+		require.NoError(t, err)
+
+		expectNoEntry(t, fakeOutput)
+	})
+
+	t.Run("CustomTagField", func(t *testing.T) {
+		f, fakeOutput := newTestFluentForwardInput(t, func(cfg *FluentForwardInputConfig) {
+			cfg.TagField = entry.NewRecordField("tag")
+		})
+		conn := dial(t, f)
+
+		b := msgp.AppendArrayHeader(nil, 3)
+		b = msgp.AppendString(b, "app.logs")
+		b = msgp.AppendInt64(b, ts.Unix())
+		b = appendOptions(t, b, map[string]interface{}{"message": "test"})
+		_, err := conn.Write(b)
+		require.NoError(t, err)
+
+		select {
+		case e := <-fakeOutput.Received:
+			require.Equal(t, map[string]interface{}{"message": "test", "tag": "app.logs"}, e.Record)
+		case <-time.After(time.Second):
+			require.FailNow(t, "Timed out waiting for entry")
+		}
+	})
+}
+
+func TestDecodeMessageLimits(t *testing.T) {
+	t.Run("UntrustedCount", func(t *testing.T) {
+		b := msgp.AppendArrayHeader(nil, 2)
+		b = msgp.AppendString(b, "app.logs")
+		b = msgp.AppendArrayHeader(b, math.MaxUint32)
+
+		_, err := newDecoder(bytes.NewReader(b), defaultMaxMessageSize).decodeMessage()
+		require.Error(t, err)
+	})
+
+	t.Run("OversizedStringHeader", func(t *testing.T) {
+		b := msgp.AppendArrayHeader(nil, 2)
+		b = append(b, 0xdb, 0xff, 0xff, 0xff, 0xff) // str32 of 4GiB with no body
+
+		_, err := newDecoder(bytes.NewReader(b), defaultMaxMessageSize).decodeMessage()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "exceeds")
+	})
+
+	t.Run("OversizedBinHeader", func(t *testing.T) {
+		b := msgp.AppendArrayHeader(nil, 2)
+		b = msgp.AppendString(b, "app.logs")
+		b = append(b, 0xc6, 0xff, 0xff, 0xff, 0xff) // bin32 of 4GiB with no body
+
+		_, err := newDecoder(bytes.NewReader(b), defaultMaxMessageSize).decodeMessage()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "exceeds")
+	})
+
+	t.Run("OversizedExtensionHeader", func(t *testing.T) {
+		b := msgp.AppendArrayHeader(nil, 3)
+		b = msgp.AppendString(b, "app.logs")
+		b = append(b, 0xc9, 0xff, 0xff, 0xff, 0xff, 0x00) // ext32 of 4GiB with no body
+
+		_, err := newDecoder(bytes.NewReader(b), defaultMaxMessageSize).decodeMessage()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "exceeds")
+	})
+
+	t.Run("RecordTooLarge", func(t *testing.T) {
+		b := msgp.AppendArrayHeader(nil, 3)
+		b = msgp.AppendString(b, "app.logs")
+		b = msgp.AppendInt64(b, 1)
+		b = msgp.AppendMapHeader(b, 1)
+		b = msgp.AppendString(b, "message")
+		b = msgp.AppendString(b, strings.Repeat("a", 100))
+
+		_, err := newDecoder(bytes.NewReader(b), 64).decodeMessage()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "exceeds")
+
+		msg, err := newDecoder(bytes.NewReader(b), 1024).decodeMessage()
+		require.NoError(t, err)
+		require.Len(t, msg.events, 1)
+	})
+
+	t.Run("DecompressedTooLarge", func(t *testing.T) {
+		var compressed bytes.Buffer
+		w := gzip.NewWriter(&compressed)
+		_, err := w.Write(make([]byte, defaultMaxMessageSize+1))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		b := msgp.AppendArrayHeader(nil, 3)
+		b = msgp.AppendString(b, "app.logs")
+		b = msgp.AppendBytes(b, compressed.Bytes())
+		b = appendOptions(t, b, map[string]interface{}{"compressed": "gzip"})
+
+		_, err = newDecoder(bytes.NewReader(b), defaultMaxMessageSize).decodeMessage()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "exceed")
+	})
+}
+
+func TestFluentForwardInputHandshake(t *testing.T) {
+	readHelo := func(t *testing.T, r *msgp.Reader) []byte {
+		size, err := r.ReadArrayHeader()
+		require.NoError(t, err)
+		require.Equal(t, uint32(2), size)
+		msgType, err := r.ReadString()
+		require.NoError(t, err)
+		require.Equal(t, "HELO", msgType)
+		options := map[string]interface{}{}
+		require.NoError(t, r.ReadMapStrIntf(options))
+		return options["nonce"].([]byte)
+	}
+
+	writePing := func(t *testing.T, conn net.Conn, nonce []byte, key string) {
+		salt := []byte("salt")
+		b := msgp.AppendArrayHeader(nil, 6)
+		b = msgp.AppendString(b, "PING")
+		b = msgp.AppendString(b, "client")
+		b = msgp.AppendBytes(b, salt)
+		b = msgp.AppendString(b, fluent.SharedKeyDigest(salt, "client", nonce, key))
+		b = msgp.AppendString(b, "")
+		b = msgp.AppendString(b, "")
+		_, err := conn.Write(b)
+		require.NoError(t, err)
+	}
+
+	readPong := func(t *testing.T, r *msgp.Reader) []interface{} {
+		pong, err := r.ReadIntf()
+		require.NoError(t, err)
+		return pong.([]interface{})
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		f, fakeOutput := newTestFluentForwardInput(t, func(cfg *FluentForwardInputConfig) {
+			cfg.SharedKey = "secret"
+		})
+		conn := dial(t, f)
+		r := msgp.NewReader(conn)
+
+		nonce := readHelo(t, r)
+		writePing(t, conn, nonce, "secret")
+
+		pong := readPong(t, r)
+		require.Equal(t, "PONG", pong[0])
+		require.Equal(t, true, pong[1])
+		require.Equal(t, "server", pong[3])
+		require.Equal(t, fluent.SharedKeyDigest([]byte("salt"), "server", nonce, "secret"), pong[4])
+
+		b := msgp.AppendArrayHeader(nil, 3)
+		b = msgp.AppendString(b, "app.logs")
+		b = msgp.AppendInt64(b, 1600000000)
+		b = appendOptions(t, b, map[string]interface{}{"message": "test"})
+		_, err := conn.Write(b)
+		require.NoError(t, err)
+
+		expectEntry(t, fakeOutput, "app.logs", time.Unix(1600000000, 0), map[string]interface{}{"message": "test"})
+	})
+
+	t.Run("Mismatch", func(t *testing.T) {
+		f, fakeOutput := newTestFluentForwardInput(t, func(cfg *FluentForwardInputConfig) {
+			cfg.SharedKey = "secret"
+		})
+		conn := dial(t, f)
+		r := msgp.NewReader(conn)
+
+		nonce := readHelo(t, r)
+		writePing(t, conn, nonce, "wrong")
+
+		pong := readPong(t, r)
+		require.Equal(t, "PONG", pong[0])
+		require.Equal(t, false, pong[1])
+		require.Equal(t, "shared_key mismatch", pong[2])
+
+		b := msgp.AppendArrayHeader(nil, 3)
+		b = msgp.AppendString(b, "app.logs")
+		b = msgp.AppendInt64(b, 1600000000)
+		b = appendOptions(t, b, map[string]interface{}{"message": "test"})
+		_, _ = conn.Write(b)
+
+		expectNoEntry(t, fakeOutput)
+	})
+}
+
+func TestFluentForwardInputBuild(t *testing.T) {
+	cases := []struct {
+		name      string
+		configure func(cfg *FluentForwardInputConfig)
+	}{
+		{"MissingListenAddress", func(cfg *FluentForwardInputConfig) { cfg.ListenAddress = "" }},
+		{"InvalidListenAddress", func(cfg *FluentForwardInputConfig) { cfg.ListenAddress = "invalid" }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := NewFluentForwardInputConfig("test_input")
+			cfg.ListenAddress = ":0"
+			tc.configure(cfg)
+			_, err := cfg.Build(testutil.NewBuildContext(t))
+			require.Error(t, err)
+		})
+	}
+}
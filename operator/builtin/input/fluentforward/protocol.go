@@ -0,0 +1,427 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fluentforward
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/fluent"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// maxNestingDepth limits how deeply arrays and maps may be nested in a record
+const maxNestingDepth = 64
+
+// event is a single timestamped record
+type event struct {
+	time   time.Time
+	record map[string]interface{}
+}
+
+// message is a decoded Forward protocol message
+type message struct {
+	tag    string
+	events []event
+	chunk  string
+}
+
+// decoder reads Forward protocol messages, rejecting any message whose
+// declared lengths add up to more than maxSize
+type decoder struct {
+	r         *msgp.Reader
+	maxSize   int
+	remaining int
+}
+
+// newDecoder creates a decoder that reads messages from r
+func newDecoder(r io.Reader, maxSize int) *decoder {
+	return &decoder{
+		r:       msgp.NewReader(r),
+		maxSize: maxSize,
+	}
+}
+
+// reserve accounts for n bytes of the current message. Lengths and counts
+// come from the client, so they are checked before anything is allocated.
+// Every array and map element takes at least one byte, so counts are
+// reserved in the same way as lengths.
+func (d *decoder) reserve(n uint32) error {
+	if int64(n) > int64(d.remaining) {
+		return fmt.Errorf("message exceeds %d bytes", d.maxSize)
+	}
+	d.remaining -= int(n)
+	return nil
+}
+
+// decodeMessage reads a single message in any of the Message, Forward,
+// PackedForward or CompressedPackedForward modes
+func (d *decoder) decodeMessage() (*message, error) {
+	d.remaining = d.maxSize
+
+	size, err := d.r.ReadArrayHeader()
+	if err != nil {
+		return nil, err
+	}
+	if size < 2 || size > 4 {
+		return nil, fmt.Errorf("invalid message length %d", size)
+	}
+
+	tag, err := d.readString()
+	if err != nil {
+		return nil, fmt.Errorf("read tag: %s", err)
+	}
+	msg := &message{tag: tag}
+
+	nextType, err := d.r.NextType()
+	if err != nil {
+		return nil, err
+	}
+
+	var packed []byte
+	remaining := size - 2
+	switch nextType {
+	case msgp.ArrayType:
+		// Forward mode: [tag, [[time, record], ...], option]
+		count, err := d.readArrayHeader()
+		if err != nil {
+			return nil, err
+		}
+		msg.events = make([]event, 0)
+		for i := uint32(0); i < count; i++ {
+			e, err := d.decodeEvent()
+			if err != nil {
+				return nil, err
+			}
+			msg.events = append(msg.events, e)
+		}
+	case msgp.BinType, msgp.StrType:
+		// PackedForward mode: [tag, <concatenated events>, option]
+		packed, err = d.readBytes()
+		if err != nil {
+			return nil, fmt.Errorf("read entries: %s", err)
+		}
+	default:
+		// Message mode: [tag, time, record, option]
+		if remaining == 0 {
+			return nil, fmt.Errorf("missing record")
+		}
+		e, err := d.decodeEventFields()
+		if err != nil {
+			return nil, err
+		}
+		msg.events = []event{e}
+		remaining--
+	}
+
+	options := map[string]interface{}{}
+	switch remaining {
+	case 0:
+	case 1:
+		if options, err = d.readMap(0); err != nil {
+			return nil, fmt.Errorf("read options: %s", err)
+		}
+	default:
+		return nil, fmt.Errorf("invalid message length %d", size)
+	}
+
+	if chunk, ok := options["chunk"].(string); ok {
+		msg.chunk = chunk
+	}
+
+	if packed != nil {
+		if compressed, ok := options["compressed"]; ok {
+			if compressed != "gzip" {
+				return nil, fmt.Errorf("unsupported compression '%v'", compressed)
+			}
+			if packed, err = gunzip(packed, d.maxSize); err != nil {
+				return nil, fmt.Errorf("decompress entries: %s", err)
+			}
+		}
+
+		if msg.events, err = decodePackedEvents(packed); err != nil {
+			return nil, err
+		}
+	}
+
+	return msg, nil
+}
+
+// decodePackedEvents reads a stream of concatenated [time, record] events
+func decodePackedEvents(packed []byte) ([]event, error) {
+	// No value can be longer than the data it is read from
+	d := newDecoder(bytes.NewReader(packed), len(packed))
+	d.remaining = len(packed)
+
+	events := make([]event, 0)
+	for {
+		if _, err := d.r.NextType(); err == io.EOF {
+			return events, nil
+		}
+
+		e, err := d.decodeEvent()
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+}
+
+// decodeEvent reads an event of the form [time, record]
+func (d *decoder) decodeEvent() (event, error) {
+	size, err := d.r.ReadArrayHeader()
+	if err != nil {
+		return event{}, fmt.Errorf("read entry: %s", err)
+	}
+	if size != 2 {
+		return event{}, fmt.Errorf("invalid entry length %d", size)
+	}
+	return d.decodeEventFields()
+}
+
+// decodeEventFields reads the time and record of an event
+func (d *decoder) decodeEventFields() (event, error) {
+	t, err := d.decodeTime()
+	if err != nil {
+		return event{}, fmt.Errorf("read time: %s", err)
+	}
+
+	record, err := d.readMap(0)
+	if err != nil {
+		return event{}, fmt.Errorf("read record: %s", err)
+	}
+
+	return event{time: t, record: record}, nil
+}
+
+// decodeTime reads either an EventTime or an integer of seconds since the epoch
+func (d *decoder) decodeTime() (time.Time, error) {
+	nextType, err := d.r.NextType()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	switch nextType {
+	case msgp.ExtensionType:
+		if err := d.reserveExtension(); err != nil {
+			return time.Time{}, err
+		}
+		var t fluent.EventTime
+		if err := d.r.ReadExtension(&t); err != nil {
+			return time.Time{}, err
+		}
+		return t.Time, nil
+	case msgp.IntType, msgp.UintType:
+		sec, err := d.r.ReadInt64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(sec, 0), nil
+	case msgp.Float32Type, msgp.Float64Type:
+		f, err := d.r.ReadFloat64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*1e9)), nil
+	default:
+		return time.Time{}, fmt.Errorf("unsupported time type %s", nextType)
+	}
+}
+
+// readMap reads a map with string keys. Since some clients encode strings
+// as bin, both keys and values that are raw bytes are read as strings.
+func (d *decoder) readMap(depth int) (map[string]interface{}, error) {
+	if depth > maxNestingDepth {
+		return nil, fmt.Errorf("values are nested more than %d levels deep", maxNestingDepth)
+	}
+
+	size, err := d.r.ReadMapHeader()
+	if err != nil {
+		return nil, err
+	}
+	if err := d.reserve(size); err != nil {
+		return nil, err
+	}
+
+	m := map[string]interface{}{}
+	for i := uint32(0); i < size; i++ {
+		key, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		if m[key], err = d.readValue(depth + 1); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// readValue reads a value of any type
+func (d *decoder) readValue(depth int) (interface{}, error) {
+	if depth > maxNestingDepth {
+		return nil, fmt.Errorf("values are nested more than %d levels deep", maxNestingDepth)
+	}
+
+	nextType, err := d.r.NextType()
+	if err != nil {
+		return nil, err
+	}
+
+	switch nextType {
+	case msgp.StrType, msgp.BinType:
+		return d.readString()
+	case msgp.MapType:
+		return d.readMap(depth)
+	case msgp.ArrayType:
+		size, err := d.readArrayHeader()
+		if err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, 0)
+		for i := uint32(0); i < size; i++ {
+			value, err := d.readValue(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	case msgp.ExtensionType:
+		if err := d.reserveExtension(); err != nil {
+			return nil, err
+		}
+		return d.r.ReadIntf()
+	default:
+		// The remaining types have a fixed size
+		return d.r.ReadIntf()
+	}
+}
+
+// readArrayHeader reads the header of an array and reserves its elements
+func (d *decoder) readArrayHeader() (uint32, error) {
+	size, err := d.r.ReadArrayHeader()
+	if err != nil {
+		return 0, err
+	}
+	return size, d.reserve(size)
+}
+
+// readString reads a value encoded as either str or bin as a string
+func (d *decoder) readString() (string, error) {
+	b, err := d.readBytes()
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// readBytes reads a value encoded as either str or bin
+func (d *decoder) readBytes() ([]byte, error) {
+	nextType, err := d.r.NextType()
+	if err != nil {
+		return nil, err
+	}
+
+	var size uint32
+	switch nextType {
+	case msgp.StrType:
+		size, err = d.r.ReadStringHeader()
+	case msgp.BinType:
+		size, err = d.r.ReadBytesHeader()
+	default:
+		return nil, fmt.Errorf("expected str or bin, got %s", nextType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.reserve(size); err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, size)
+	if _, err := d.r.ReadFull(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// reserveExtension peeks at the length of the next extension and reserves it,
+// since reading an extension buffers all of its data
+func (d *decoder) reserveExtension() error {
+	lead, err := d.r.R.Peek(1)
+	if err != nil {
+		return err
+	}
+
+	var size uint32
+	switch lead[0] {
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		// fixext 1, 2, 4, 8 and 16
+		size = 1 << (lead[0] - 0xd4)
+	case 0xc7:
+		// ext 8
+		header, err := d.r.R.Peek(2)
+		if err != nil {
+			return err
+		}
+		size = uint32(header[1])
+	case 0xc8:
+		// ext 16
+		header, err := d.r.R.Peek(3)
+		if err != nil {
+			return err
+		}
+		size = uint32(binary.BigEndian.Uint16(header[1:]))
+	case 0xc9:
+		// ext 32
+		header, err := d.r.R.Peek(5)
+		if err != nil {
+			return err
+		}
+		size = binary.BigEndian.Uint32(header[1:])
+	default:
+		return fmt.Errorf("invalid extension prefix 0x%x", lead[0])
+	}
+
+	return d.reserve(size)
+}
+
+// gunzip will decompress data, rejecting anything larger than maxSize
+func gunzip(data []byte, maxSize int) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	decompressed, err := ioutil.ReadAll(io.LimitReader(reader, int64(maxSize)+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(decompressed) > maxSize {
+		return nil, fmt.Errorf("decompressed entries exceed %d bytes", maxSize)
+	}
+
+	return decompressed, nil
+}
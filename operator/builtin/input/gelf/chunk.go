@@ -0,0 +1,137 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gelf
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// chunkHeaderSize is the size of the magic bytes, message id, sequence number and sequence count
+	chunkHeaderSize = 12
+
+	// maxChunks is the maximum number of chunks a message may be split into
+	maxChunks = 128
+)
+
+// chunkMagic are the bytes that identify a chunked GELF message
+var chunkMagic = []byte{0x1e, 0x0f}
+
+// pendingMessage is a chunked message that has not yet been completely received
+type pendingMessage struct {
+	chunks   [][]byte
+	received int
+	expires  time.Time
+}
+
+// chunkAssembler reassembles chunked GELF messages
+type chunkAssembler struct {
+	timeout    time.Duration
+	maxPending int
+
+	pending map[[8]byte]*pendingMessage
+	mux     sync.Mutex
+}
+
+func newChunkAssembler(timeout time.Duration, maxPending int) *chunkAssembler {
+	return &chunkAssembler{
+		timeout:    timeout,
+		maxPending: maxPending,
+		pending:    make(map[[8]byte]*pendingMessage),
+	}
+}
+
+// Add will add a datagram to the assembler. If the datagram is not chunked, it is returned as is.
+// If the datagram completes a chunked message, the complete message is returned.
+// Otherwise, a nil payload is returned.
+func (a *chunkAssembler) Add(datagram []byte) ([]byte, error) {
+	if !bytes.HasPrefix(datagram, chunkMagic) {
+		return datagram, nil
+	}
+
+	if len(datagram) < chunkHeaderSize {
+		return nil, fmt.Errorf("chunk of %d bytes is smaller than the chunk header", len(datagram))
+	}
+
+	var id [8]byte
+	copy(id[:], datagram[2:10])
+	sequence := int(datagram[10])
+	count := int(datagram[11])
+
+	if count == 0 || count > maxChunks {
+		return nil, fmt.Errorf("invalid chunk count %d", count)
+	}
+
+	if sequence >= count {
+		return nil, fmt.Errorf("chunk sequence number %d exceeds chunk count %d", sequence, count)
+	}
+
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	msg, ok := a.pending[id]
+	if !ok {
+		if len(a.pending) >= a.maxPending {
+			return nil, fmt.Errorf("too many pending chunked messages")
+		}
+		msg = &pendingMessage{
+			chunks:  make([][]byte, count),
+			expires: time.Now().Add(a.timeout),
+		}
+		a.pending[id] = msg
+	}
+
+	if len(msg.chunks) != count {
+		delete(a.pending, id)
+		return nil, fmt.Errorf("chunk count changed from %d to %d", len(msg.chunks), count)
+	}
+
+	// Ignore duplicate chunks
+	if msg.chunks[sequence] != nil {
+		return nil, nil
+	}
+
+	// The datagram buffer is reused by the caller, so the chunk must be copied
+	chunk := make([]byte, len(datagram)-chunkHeaderSize)
+	copy(chunk, datagram[chunkHeaderSize:])
+	msg.chunks[sequence] = chunk
+	msg.received++
+
+	if msg.received < count {
+		return nil, nil
+	}
+
+	delete(a.pending, id)
+	return bytes.Join(msg.chunks, nil), nil
+}
+
+// Expire will discard pending messages that have not been completed before
+// their timeout. It returns the number of messages discarded.
+func (a *chunkAssembler) Expire(now time.Time) int {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	expired := 0
+	for id, msg := range a.pending {
+		if now.After(msg.expires) {
+			delete(a.pending, id)
+			expired++
+		}
+	}
+	return expired
+}
@@ -0,0 +1,94 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gelf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkAssembler(t *testing.T) {
+	t.Run("Unchunked", func(t *testing.T) {
+		a := newChunkAssembler(time.Second, 10)
+		payload, err := a.Add([]byte(`{"short_message":"test"}`))
+		require.NoError(t, err)
+		require.Equal(t, []byte(`{"short_message":"test"}`), payload)
+	})
+
+	t.Run("DuplicateChunk", func(t *testing.T) {
+		a := newChunkAssembler(time.Second, 10)
+		chunks := createChunks([]byte("abcdefgh"), []byte("0123456789"), 2)
+
+		payload, err := a.Add(chunks[0])
+		require.NoError(t, err)
+		require.Nil(t, payload)
+
+		payload, err = a.Add(chunks[0])
+		require.NoError(t, err)
+		require.Nil(t, payload)
+
+		payload, err = a.Add(chunks[1])
+		require.NoError(t, err)
+		require.Equal(t, []byte("0123456789"), payload)
+		require.Len(t, a.pending, 0)
+	})
+
+	t.Run("ShortChunk", func(t *testing.T) {
+		a := newChunkAssembler(time.Second, 10)
+		_, err := a.Add([]byte{0x1e, 0x0f, 0x01})
+		require.Error(t, err)
+	})
+
+	t.Run("TooManyChunks", func(t *testing.T) {
+		a := newChunkAssembler(time.Second, 10)
+		chunk := append([]byte{0x1e, 0x0f}, []byte("abcdefgh")...)
+		chunk = append(chunk, 0, 129)
+		_, err := a.Add(chunk)
+		require.Error(t, err)
+	})
+
+	t.Run("SequenceOutOfRange", func(t *testing.T) {
+		a := newChunkAssembler(time.Second, 10)
+		chunk := append([]byte{0x1e, 0x0f}, []byte("abcdefgh")...)
+		chunk = append(chunk, 2, 2)
+		_, err := a.Add(chunk)
+		require.Error(t, err)
+	})
+
+	t.Run("MaxPending", func(t *testing.T) {
+		a := newChunkAssembler(time.Second, 1)
+		_, err := a.Add(createChunks([]byte("aaaaaaaa"), []byte("0123456789"), 2)[0])
+		require.NoError(t, err)
+		_, err = a.Add(createChunks([]byte("bbbbbbbb"), []byte("0123456789"), 2)[0])
+		require.Error(t, err)
+	})
+
+	t.Run("Expire", func(t *testing.T) {
+		a := newChunkAssembler(time.Second, 10)
+		chunks := createChunks([]byte("abcdefgh"), []byte("0123456789"), 2)
+		_, err := a.Add(chunks[0])
+		require.NoError(t, err)
+
+		require.Equal(t, 0, a.Expire(time.Now()))
+		require.Equal(t, 1, a.Expire(time.Now().Add(2*time.Second)))
+
+		// The remaining chunk starts a new message
+		payload, err := a.Add(chunks[1])
+		require.NoError(t, err)
+		require.Nil(t, payload)
+	})
+}
@@ -0,0 +1,211 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gelf
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"go.uber.org/zap"
+)
+
+func init() {
+	operator.Register("gelf_input", func() operator.Builder { return NewGELFInputConfig("") })
+}
+
+const (
+	// maxDatagramSize is large enough to hold the largest possible udp datagram
+	maxDatagramSize = 64 * 1024
+
+	defaultChunkTimeout       = 5 * time.Second
+	defaultMaxPendingMessages = 1000
+)
+
+// NewGELFInputConfig creates a new GELF input config with default values
+func NewGELFInputConfig(operatorID string) *GELFInputConfig {
+	return &GELFInputConfig{
+		InputConfig:        helper.NewInputConfig(operatorID, "gelf_input"),
+		ChunkTimeout:       helper.Duration{Duration: defaultChunkTimeout},
+		MaxPendingMessages: defaultMaxPendingMessages,
+	}
+}
+
+// GELFInputConfig is the configuration of a GELF input operator.
+type GELFInputConfig struct {
+	helper.InputConfig `yaml:",inline"`
+
+	ListenAddress      string          `json:"listen_address,omitempty"       yaml:"listen_address,omitempty"`
+	ChunkTimeout       helper.Duration `json:"chunk_timeout,omitempty"        yaml:"chunk_timeout,omitempty"`
+	MaxPendingMessages int             `json:"max_pending_messages,omitempty" yaml:"max_pending_messages,omitempty"`
+}
+
+// Build will build a GELF input operator.
+func (c GELFInputConfig) Build(context operator.BuildContext) ([]operator.Operator, error) {
+	inputOperator, err := c.InputConfig.Build(context)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.ListenAddress == "" {
+		return nil, fmt.Errorf("missing required parameter 'listen_address'")
+	}
+
+	address, err := net.ResolveUDPAddr("udp", c.ListenAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve listen_address: %s", err)
+	}
+
+	if c.ChunkTimeout.Raw() <= 0 {
+		return nil, fmt.Errorf("`chunk_timeout` must be positive")
+	}
+
+	if c.MaxPendingMessages <= 0 {
+		return nil, fmt.Errorf("`max_pending_messages` must be positive")
+	}
+
+	gelfInput := &GELFInput{
+		InputOperator: inputOperator,
+		address:       address,
+		chunkTimeout:  c.ChunkTimeout.Raw(),
+		assembler:     newChunkAssembler(c.ChunkTimeout.Raw(), c.MaxPendingMessages),
+	}
+	return []operator.Operator{gelfInput}, nil
+}
+
+// GELFInput is an operator that receives GELF messages over udp.
+type GELFInput struct {
+	helper.InputOperator
+	address      *net.UDPAddr
+	chunkTimeout time.Duration
+	assembler    *chunkAssembler
+
+	connection *net.UDPConn
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+}
+
+// Start will start listening for GELF messages.
+func (g *GELFInput) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	g.cancel = cancel
+
+	conn, err := net.ListenUDP("udp", g.address)
+	if err != nil {
+		return fmt.Errorf("failed to open connection: %s", err)
+	}
+	g.connection = conn
+
+	g.goHandleMessages(ctx)
+	g.goExpireChunks(ctx)
+	return nil
+}
+
+// goHandleMessages will handle datagrams received by the connection.
+func (g *GELFInput) goHandleMessages(ctx context.Context) {
+	g.wg.Add(1)
+
+	go func() {
+		defer g.wg.Done()
+
+		buffer := make([]byte, maxDatagramSize)
+		for {
+			n, _, err := g.connection.ReadFromUDP(buffer)
+			if err != nil {
+				select {
+				case <-ctx.Done():
+					return
+				default:
+					g.Errorw("Failed reading messages", zap.Error(err))
+					continue
+				}
+			}
+
+			payload, err := g.assembler.Add(buffer[:n])
+			if err != nil {
+				g.Warnw("Failed to reassemble chunked message", zap.Error(err))
+				continue
+			}
+
+			// Message is chunked and not yet complete
+			if payload == nil {
+				continue
+			}
+
+			g.handlePayload(ctx, payload)
+		}
+	}()
+}
+
+// goExpireChunks will periodically discard chunked messages that were never completed.
+func (g *GELFInput) goExpireChunks(ctx context.Context) {
+	g.wg.Add(1)
+
+	go func() {
+		defer g.wg.Done()
+
+		ticker := time.NewTicker(g.chunkTimeout / 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if expired := g.assembler.Expire(now); expired > 0 {
+					g.Warnw("Discarded incomplete chunked messages", "count", expired)
+				}
+			}
+		}
+	}()
+}
+
+// handlePayload will decode a complete GELF payload and write the resulting entry.
+func (g *GELFInput) handlePayload(ctx context.Context, payload []byte) {
+	decompressed, err := decompress(payload)
+	if err != nil {
+		g.Warnw("Failed to decompress message", zap.Error(err))
+		return
+	}
+
+	msg, err := parseMessage(decompressed)
+	if err != nil {
+		g.Warnw("Failed to parse message", zap.Error(err))
+		return
+	}
+
+	entry, err := g.NewEntry(msg.Record)
+	if err != nil {
+		g.Errorw("Failed to create entry", zap.Error(err))
+		return
+	}
+	msg.apply(entry)
+
+	g.Write(ctx, entry)
+}
+
+// Stop will stop listening for GELF messages.
+func (g *GELFInput) Stop() error {
+	g.cancel()
+	if g.connection != nil {
+		g.connection.Close()
+	}
+	g.wg.Wait()
+	return nil
+}
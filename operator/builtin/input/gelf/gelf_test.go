@@ -0,0 +1,218 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const testMessage = `{
+  "version": "1.1",
+  "host": "example.org",
+  "short_message": "A short message",
+  "full_message": "Backtrace here\n\nmore stuff",
+  "timestamp": 1385053862.3072,
+  "level": 3,
+  "facility": "app",
+  "_user_id": 9001,
+  "_some_info": "foo"
+}`
+
+func expectedEntry() *entry.Entry {
+	e := entry.New()
+	e.Timestamp = time.Unix(1385053862, 307200000)
+	e.Severity = entry.Error
+	e.Resource = map[string]string{
+		"host.name": "example.org",
+	}
+	e.Labels = map[string]string{
+		"user_id":   "9001",
+		"some_info": "foo",
+	}
+	e.Record = map[string]interface{}{
+		"message":      "A short message",
+		"full_message": "Backtrace here\n\nmore stuff",
+		"facility":     "app",
+	}
+	return e
+}
+
+func newTestGELFInput(t *testing.T) (*GELFInput, *testutil.FakeOutput) {
+	cfg := NewGELFInputConfig("test_input")
+	cfg.ListenAddress = "127.0.0.1:0"
+
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	gelfInput := ops[0].(*GELFInput)
+
+	fakeOutput := testutil.NewFakeOutput(t)
+	gelfInput.InputOperator.OutputOperators = []operator.Operator{fakeOutput}
+
+	require.NoError(t, gelfInput.Start())
+	t.Cleanup(func() { require.NoError(t, gelfInput.Stop()) })
+	return gelfInput, fakeOutput
+}
+
+func sendDatagrams(t *testing.T, address string, datagrams ...[]byte) {
+	conn, err := net.Dial("udp", address)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	for _, datagram := range datagrams {
+		_, err = conn.Write(datagram)
+		require.NoError(t, err)
+	}
+}
+
+func expectEntry(t *testing.T, fakeOutput *testutil.FakeOutput, expected *entry.Entry) {
+	select {
+	case e := <-fakeOutput.Received:
+		require.True(t, expected.Timestamp.Equal(e.Timestamp), "expected %s, got %s", expected.Timestamp, e.Timestamp)
+		e.Timestamp = expected.Timestamp
+		require.Equal(t, expected, e)
+	case <-time.After(time.Second):
+		require.FailNow(t, "Timed out waiting for entry")
+	}
+}
+
+func expectNoEntry(t *testing.T, fakeOutput *testutil.FakeOutput) {
+	select {
+	case e := <-fakeOutput.Received:
+		require.FailNow(t, "Unexpected entry: %v", e)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func gzipPayload(t *testing.T, payload []byte) []byte {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	_, err := w.Write(payload)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return b.Bytes()
+}
+
+func zlibPayload(t *testing.T, payload []byte) []byte {
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	_, err := w.Write(payload)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return b.Bytes()
+}
+
+func createChunks(id []byte, payload []byte, count int) [][]byte {
+	size := (len(payload) + count - 1) / count
+	chunks := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * size
+		if end > len(payload) {
+			end = len(payload)
+		}
+		c := append([]byte{0x1e, 0x0f}, id...)
+		c = append(c, byte(i), byte(count))
+		c = append(c, payload[i*size:end]...)
+		chunks = append(chunks, c)
+	}
+	return chunks
+}
+
+func TestGELFInput(t *testing.T) {
+	gelfInput, fakeOutput := newTestGELFInput(t)
+	address := gelfInput.connection.LocalAddr().String()
+
+	t.Run("Uncompressed", func(t *testing.T) {
+		sendDatagrams(t, address, []byte(testMessage))
+		expectEntry(t, fakeOutput, expectedEntry())
+	})
+
+	t.Run("Gzip", func(t *testing.T) {
+		sendDatagrams(t, address, gzipPayload(t, []byte(testMessage)))
+		expectEntry(t, fakeOutput, expectedEntry())
+	})
+
+	t.Run("Zlib", func(t *testing.T) {
+		sendDatagrams(t, address, zlibPayload(t, []byte(testMessage)))
+		expectEntry(t, fakeOutput, expectedEntry())
+	})
+
+	t.Run("Chunked", func(t *testing.T) {
+		chunks := createChunks([]byte("abcdefgh"), gzipPayload(t, []byte(testMessage)), 3)
+		sendDatagrams(t, address, chunks[2], chunks[0], chunks[1])
+		expectEntry(t, fakeOutput, expectedEntry())
+	})
+
+	t.Run("NestedNumbers", func(t *testing.T) {
+		sendDatagrams(t, address, []byte(`{"short_message": "nested", "timestamp": 1385053862, "details": {"code": 7, "ratios": [0.5, 2]}}`))
+
+		expected := entry.New()
+		expected.Timestamp = time.Unix(1385053862, 0)
+		expected.Record = map[string]interface{}{
+			"message": "nested",
+			"details": map[string]interface{}{
+				"code":   int64(7),
+				"ratios": []interface{}{0.5, int64(2)},
+			},
+		}
+		expectEntry(t, fakeOutput, expected)
+	})
+
+	t.Run("IncompleteChunks", func(t *testing.T) {
+		chunks := createChunks([]byte("12345678"), []byte(testMessage), 3)
+		sendDatagrams(t, address, chunks[0], chunks[2])
+		expectNoEntry(t, fakeOutput)
+	})
+
+	t.Run("InvalidJSON", func(t *testing.T) {
+		sendDatagrams(t, address, []byte(`{"short_message": `))
+		expectNoEntry(t, fakeOutput)
+	})
+
+	t.Run("MissingShortMessage", func(t *testing.T) {
+		sendDatagrams(t, address, []byte(`{"version": "1.1", "host": "example.org"}`))
+		expectNoEntry(t, fakeOutput)
+	})
+}
+
+func TestGELFInputBuild(t *testing.T) {
+	cases := []struct {
+		name      string
+		configure func(cfg *GELFInputConfig)
+	}{
+		{"MissingListenAddress", func(cfg *GELFInputConfig) { cfg.ListenAddress = "" }},
+		{"ZeroChunkTimeout", func(cfg *GELFInputConfig) { cfg.ChunkTimeout.Duration = 0 }},
+		{"ZeroMaxPendingMessages", func(cfg *GELFInputConfig) { cfg.MaxPendingMessages = 0 }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := NewGELFInputConfig("test_input")
+			cfg.ListenAddress = ":0"
+			tc.configure(cfg)
+			_, err := cfg.Build(testutil.NewBuildContext(t))
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+)
+
+const (
+	// maxDecompressedSize protects against decompressing arbitrarily large payloads
+	maxDecompressedSize = 16 * 1024 * 1024
+
+	hostResourceKey = "host.name"
+)
+
+// levelSeverities maps syslog levels used by GELF to entry severities
+var levelSeverities = map[int]entry.Severity{
+	0: entry.Emergency,
+	1: entry.Alert,
+	2: entry.Critical,
+	3: entry.Error,
+	4: entry.Warning,
+	5: entry.Notice,
+	6: entry.Info,
+	7: entry.Debug,
+}
+
+// decompress will decompress a gzip or zlib payload, detected by its magic bytes.
+// Uncompressed payloads are returned unchanged.
+func decompress(payload []byte) ([]byte, error) {
+	var reader io.ReadCloser
+	var err error
+
+	switch {
+	case len(payload) >= 2 && payload[0] == 0x1f && payload[1] == 0x8b:
+		reader, err = gzip.NewReader(bytes.NewReader(payload))
+	case len(payload) >= 2 && payload[0] == 0x78 && (uint16(payload[0])<<8|uint16(payload[1]))%31 == 0:
+		reader, err = zlib.NewReader(bytes.NewReader(payload))
+	default:
+		return payload, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	decompressed, err := ioutil.ReadAll(io.LimitReader(reader, maxDecompressedSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(decompressed) > maxDecompressedSize {
+		return nil, fmt.Errorf("decompressed message exceeds %d bytes", maxDecompressedSize)
+	}
+
+	return decompressed, nil
+}
+
+// message is a GELF message mapped onto the fields of an entry
+type message struct {
+	Record    map[string]interface{}
+	Timestamp time.Time
+	Severity  entry.Severity
+	HasLevel  bool
+	Host      string
+	Labels    map[string]string
+}
+
+// parseMessage will parse a GELF json payload
+func parseMessage(payload []byte) (*message, error) {
+	var raw map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("decode json: %s", err)
+	}
+
+	msg := &message{
+		Record: make(map[string]interface{}),
+		Labels: make(map[string]string),
+	}
+
+	for key, value := range raw {
+		switch {
+		case key == "version":
+			continue
+		case key == "short_message":
+			msg.Record["message"] = value
+		case key == "host":
+			msg.Host = fmt.Sprintf("%v", value)
+		case key == "timestamp":
+			ts, err := parseTimestamp(value)
+			if err != nil {
+				return nil, err
+			}
+			msg.Timestamp = ts
+		case key == "level":
+			severity, err := parseLevel(value)
+			if err != nil {
+				return nil, err
+			}
+			msg.Severity = severity
+			msg.HasLevel = true
+		case key == "_id":
+			// Reserved by the GELF specification
+			continue
+		case strings.HasPrefix(key, "_") && len(key) > 1:
+			msg.Labels[key[1:]] = labelValue(value)
+		default:
+			msg.Record[key] = normalizeValue(value)
+		}
+	}
+
+	if _, ok := msg.Record["message"]; !ok {
+		return nil, fmt.Errorf("message is missing required field 'short_message'")
+	}
+
+	return msg, nil
+}
+
+// apply will set the timestamp, severity, resource and labels of an entry
+func (m *message) apply(e *entry.Entry) {
+	if !m.Timestamp.IsZero() {
+		e.Timestamp = m.Timestamp
+	}
+
+	if m.HasLevel {
+		e.Severity = m.Severity
+	}
+
+	if m.Host != "" {
+		e.AddResourceKey(hostResourceKey, m.Host)
+	}
+
+	for k, v := range m.Labels {
+		e.AddLabel(k, v)
+	}
+}
+
+// parseTimestamp will parse a unix timestamp in seconds with an optional decimal fraction
+func parseTimestamp(value interface{}) (time.Time, error) {
+	number, ok := value.(json.Number)
+	if !ok {
+		return time.Time{}, fmt.Errorf("timestamp of type '%T' is not a number", value)
+	}
+
+	seconds, err := number.Float64()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse timestamp: %s", err)
+	}
+
+	whole, fraction := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(math.Round(fraction*1e6))*1e3), nil
+}
+
+// parseLevel will convert a syslog level to a severity
+func parseLevel(value interface{}) (entry.Severity, error) {
+	number, ok := value.(json.Number)
+	if !ok {
+		return entry.Default, fmt.Errorf("level of type '%T' is not a number", value)
+	}
+
+	level, err := number.Int64()
+	if err != nil {
+		return entry.Default, fmt.Errorf("parse level: %s", err)
+	}
+
+	severity, ok := levelSeverities[int(level)]
+	if !ok {
+		return entry.Default, fmt.Errorf("level %d is not a valid syslog level", level)
+	}
+	return severity, nil
+}
+
+// labelValue will convert an additional field value to a label
+func labelValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// normalizeValue converts json numbers, including those nested in objects
+// and arrays, to int64 or float64
+func normalizeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case map[string]interface{}:
+		for key, nested := range v {
+			v[key] = normalizeValue(nested)
+		}
+		return v
+	case []interface{}:
+		for i, nested := range v {
+			v[i] = normalizeValue(nested)
+		}
+		return v
+	default:
+		return value
+	}
+}
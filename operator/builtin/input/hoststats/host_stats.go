@@ -0,0 +1,411 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build linux
+
+package hoststats
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/errors"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"go.uber.org/zap"
+)
+
+func init() {
+	operator.Register("host_stats_input", func() operator.Builder { return NewHostStatsInputConfig("") })
+}
+
+const (
+	loadType      = "load"
+	memoryType    = "memory"
+	diskType      = "disk"
+	networkType   = "network"
+	processesType = "processes"
+
+	sortByCPU = "cpu"
+	sortByRSS = "rss"
+
+	statsTypeLabel = "stats_type"
+)
+
+var defaultStats = []string{loadType, memoryType, diskType, networkType, processesType}
+
+// NewHostStatsInputConfig creates a new host stats input config with default values
+func NewHostStatsInputConfig(operatorID string) *HostStatsInputConfig {
+	return &HostStatsInputConfig{
+		InputConfig:          helper.NewInputConfig(operatorID, "host_stats_input"),
+		HostIdentifierConfig: helper.NewHostIdentifierConfig(),
+		Interval:             helper.Duration{Duration: time.Minute},
+		ProcPath:             "/proc",
+		Stats:                defaultStats,
+		TopProcesses:         10,
+		SortProcessesBy:      sortByCPU,
+	}
+}
+
+// HostStatsInputConfig is the configuration of a host stats input operator
+type HostStatsInputConfig struct {
+	helper.InputConfig          `yaml:",inline"`
+	helper.HostIdentifierConfig `yaml:",inline"`
+
+	Interval        helper.Duration `json:"interval,omitempty"          yaml:"interval,omitempty"`
+	ProcPath        string          `json:"proc_path,omitempty"         yaml:"proc_path,omitempty"`
+	Stats           []string        `json:"stats,omitempty"             yaml:"stats,omitempty"`
+	TopProcesses    int             `json:"top_processes,omitempty"     yaml:"top_processes,omitempty"`
+	SortProcessesBy string          `json:"sort_processes_by,omitempty" yaml:"sort_processes_by,omitempty"`
+}
+
+// Build will build a host stats input operator
+func (c HostStatsInputConfig) Build(context operator.BuildContext) ([]operator.Operator, error) {
+	inputOperator, err := c.InputConfig.Build(context)
+	if err != nil {
+		return nil, err
+	}
+
+	hostIdentifier, err := c.HostIdentifierConfig.Build()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build host identifier")
+	}
+
+	if c.Interval.Raw() <= 0 {
+		return nil, fmt.Errorf("invalid value for interval '%s'", c.Interval.Raw())
+	}
+
+	stats := make(map[string]bool, len(c.Stats))
+	for _, statsType := range c.Stats {
+		switch statsType {
+		case loadType, memoryType, diskType, networkType, processesType:
+			stats[statsType] = true
+		default:
+			return nil, fmt.Errorf("invalid value for stats '%s'", statsType)
+		}
+	}
+
+	switch c.SortProcessesBy {
+	case sortByCPU, sortByRSS:
+	default:
+		return nil, fmt.Errorf("invalid value for sort_processes_by '%s'", c.SortProcessesBy)
+	}
+
+	if c.TopProcesses < 0 {
+		return nil, fmt.Errorf("invalid value for top_processes '%d'", c.TopProcesses)
+	}
+
+	hostStatsInput := &HostStatsInput{
+		InputOperator:   inputOperator,
+		HostIdentifier:  hostIdentifier,
+		interval:        c.Interval.Raw(),
+		procPath:        c.ProcPath,
+		stats:           stats,
+		topProcesses:    c.TopProcesses,
+		sortProcessesBy: c.SortProcessesBy,
+	}
+	return []operator.Operator{hostStatsInput}, nil
+}
+
+// HostStatsInput is an operator that periodically emits statistics of the host
+type HostStatsInput struct {
+	helper.InputOperator
+	helper.HostIdentifier
+
+	interval        time.Duration
+	procPath        string
+	stats           map[string]bool
+	topProcesses    int
+	sortProcessesBy string
+
+	previous *sample
+	wg       sync.WaitGroup
+	cancel   context.CancelFunc
+}
+
+// sample holds the counters read at a point in time, which are used to
+// calculate rates when the next sample is taken
+type sample struct {
+	time      time.Time
+	disks     map[string]*diskStats
+	network   map[string]*networkStats
+	processes map[processKey]*processStats
+}
+
+// Start will start emitting host stats
+func (h *HostStatsInput) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+
+	// The first sample is only used as the baseline for rates
+	h.previous = h.readCounters(time.Now())
+
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				h.collect(ctx, now)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop will stop emitting host stats
+func (h *HostStatsInput) Stop() error {
+	h.cancel()
+	h.wg.Wait()
+	return nil
+}
+
+// readCounters reads the counters that are needed to calculate rates
+func (h *HostStatsInput) readCounters(now time.Time) *sample {
+	current := &sample{time: now}
+
+	if h.stats[diskType] {
+		disks, err := readDisks(h.procPath)
+		if err != nil {
+			h.Warnw("Failed to read disk stats", zap.Error(err))
+		}
+		current.disks = disks
+	}
+
+	if h.stats[networkType] {
+		network, err := readNetwork(h.procPath)
+		if err != nil {
+			h.Warnw("Failed to read network stats", zap.Error(err))
+		}
+		current.network = network
+	}
+
+	if h.stats[processesType] {
+		processes, err := readProcesses(h.procPath)
+		if err != nil {
+			h.Warnw("Failed to read processes", zap.Error(err))
+		}
+		current.processes = processes
+	}
+
+	return current
+}
+
+// collect emits an entry for each type of stats
+func (h *HostStatsInput) collect(ctx context.Context, now time.Time) {
+	if h.stats[loadType] {
+		if load, err := readLoad(h.procPath); err != nil {
+			h.Warnw("Failed to read load average", zap.Error(err))
+		} else {
+			h.emit(ctx, now, loadType, map[string]interface{}{
+				"load1":             load.load1,
+				"load5":             load.load5,
+				"load15":            load.load15,
+				"running_processes": load.running,
+				"total_processes":   load.total,
+			})
+		}
+	}
+
+	if h.stats[memoryType] {
+		if memory, err := readMemory(h.procPath); err != nil {
+			h.Warnw("Failed to read memory stats", zap.Error(err))
+		} else {
+			h.emit(ctx, now, memoryType, memoryRecord(memory))
+		}
+	}
+
+	current := h.readCounters(now)
+	seconds := now.Sub(h.previous.time).Seconds()
+
+	if current.disks != nil {
+		devices := make(map[string]interface{}, len(current.disks))
+		for name, disk := range current.disks {
+			devices[name] = diskRecord(disk, h.previous.disks[name], seconds)
+		}
+		h.emit(ctx, now, diskType, map[string]interface{}{"devices": devices})
+	}
+
+	if current.network != nil {
+		interfaces := make(map[string]interface{}, len(current.network))
+		for name, stats := range current.network {
+			interfaces[name] = networkRecord(stats, h.previous.network[name], seconds)
+		}
+		h.emit(ctx, now, networkType, map[string]interface{}{"interfaces": interfaces})
+	}
+
+	// Without a previous sample, the CPU usage of every process would be its
+	// total CPU time, so processes are not emitted until two samples are read
+	if current.processes != nil && h.previous.processes != nil {
+		h.emit(ctx, now, processesType, map[string]interface{}{
+			"sort_by":   h.sortProcessesBy,
+			"processes": h.topProcessRecords(current.processes, h.previous.processes, seconds),
+		})
+	}
+
+	h.previous = current
+}
+
+func (h *HostStatsInput) emit(ctx context.Context, now time.Time, statsType string, record map[string]interface{}) {
+	entry, err := h.NewEntry(record)
+	if err != nil {
+		h.Errorw("Failed to create entry", zap.Error(err))
+		return
+	}
+
+	entry.Timestamp = now
+	entry.AddLabel(statsTypeLabel, statsType)
+	h.Identify(entry)
+	h.Write(ctx, entry)
+}
+
+// memoryRecord selects the values of /proc/meminfo that are emitted
+func memoryRecord(memory map[string]uint64) map[string]interface{} {
+	record := map[string]interface{}{}
+	for key, field := range map[string]string{
+		"total_bytes":      "MemTotal",
+		"free_bytes":       "MemFree",
+		"available_bytes":  "MemAvailable",
+		"buffers_bytes":    "Buffers",
+		"cached_bytes":     "Cached",
+		"swap_total_bytes": "SwapTotal",
+		"swap_free_bytes":  "SwapFree",
+	} {
+		if value, ok := memory[field]; ok {
+			record[key] = value
+		}
+	}
+
+	total, available := memory["MemTotal"], memory["MemAvailable"]
+	if total > 0 && available <= total {
+		record["used_bytes"] = total - available
+		record["used_percent"] = float64(total-available) / float64(total) * 100
+	}
+
+	return record
+}
+
+func diskRecord(current, previous *diskStats, seconds float64) map[string]interface{} {
+	record := map[string]interface{}{
+		"reads":         current.reads,
+		"writes":        current.writes,
+		"read_bytes":    current.readBytes,
+		"write_bytes":   current.writeBytes,
+		"read_time_ms":  current.readTimeMs,
+		"write_time_ms": current.writeTimeMs,
+		"io_time_ms":    current.ioTimeMs,
+		"in_progress":   current.inProgress,
+	}
+
+	if previous != nil && seconds > 0 {
+		record["reads_per_second"] = rate(current.reads, previous.reads, seconds)
+		record["writes_per_second"] = rate(current.writes, previous.writes, seconds)
+		record["read_bytes_per_second"] = rate(current.readBytes, previous.readBytes, seconds)
+		record["write_bytes_per_second"] = rate(current.writeBytes, previous.writeBytes, seconds)
+		record["utilization_percent"] = rate(current.ioTimeMs, previous.ioTimeMs, seconds) / 10
+	}
+
+	return record
+}
+
+func networkRecord(current, previous *networkStats, seconds float64) map[string]interface{} {
+	record := map[string]interface{}{
+		"receive_bytes":    current.receiveBytes,
+		"receive_packets":  current.receivePackets,
+		"receive_errors":   current.receiveErrors,
+		"receive_dropped":  current.receiveDropped,
+		"transmit_bytes":   current.transmitBytes,
+		"transmit_packets": current.transmitPackets,
+		"transmit_errors":  current.transmitErrors,
+		"transmit_dropped": current.transmitDrops,
+	}
+
+	if previous != nil && seconds > 0 {
+		record["receive_bytes_per_second"] = rate(current.receiveBytes, previous.receiveBytes, seconds)
+		record["transmit_bytes_per_second"] = rate(current.transmitBytes, previous.transmitBytes, seconds)
+	}
+
+	return record
+}
+
+// topProcessRecords returns the processes using the most CPU or memory. The CPU
+// usage of a process is its CPU time since the previous sample, or since it
+// started if it is new.
+func (h *HostStatsInput) topProcessRecords(current, previous map[processKey]*processStats, seconds float64) []interface{} {
+	type usage struct {
+		*processStats
+		cpuPercent float64
+	}
+
+	usages := make([]usage, 0, len(current))
+	for key, process := range current {
+		ticks := process.cpuTicks
+		if before, ok := previous[key]; ok && before.cpuTicks <= ticks {
+			ticks -= before.cpuTicks
+		}
+
+		u := usage{processStats: process}
+		if seconds > 0 {
+			u.cpuPercent = float64(ticks) / clockTicks / seconds * 100
+		}
+		usages = append(usages, u)
+	}
+
+	sort.Slice(usages, func(i, j int) bool {
+		if h.sortProcessesBy == sortByRSS && usages[i].rssBytes != usages[j].rssBytes {
+			return usages[i].rssBytes > usages[j].rssBytes
+		}
+		if usages[i].cpuPercent != usages[j].cpuPercent {
+			return usages[i].cpuPercent > usages[j].cpuPercent
+		}
+		return usages[i].pid < usages[j].pid
+	})
+
+	if len(usages) > h.topProcesses {
+		usages = usages[:h.topProcesses]
+	}
+
+	records := make([]interface{}, 0, len(usages))
+	for _, u := range usages {
+		records = append(records, map[string]interface{}{
+			"pid":         u.pid,
+			"ppid":        u.ppid,
+			"name":        u.name,
+			"command":     readCommand(h.procPath, u.pid),
+			"state":       u.state,
+			"threads":     u.threads,
+			"cpu_percent": u.cpuPercent,
+			"rss_bytes":   u.rssBytes,
+		})
+	}
+	return records
+}
+
+// rate returns the rate of change of a counter, or zero if it was reset
+func rate(current, previous uint64, seconds float64) float64 {
+	if current < previous {
+		return 0
+	}
+	return float64(current-previous) / seconds
+}
@@ -0,0 +1,251 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build linux
+
+package hoststats
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"github.com/opentelemetry/opentelemetry-log-collection/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestInput(t *testing.T, procPath string, configure func(*HostStatsInputConfig)) (*HostStatsInput, *testutil.FakeOutput) {
+	cfg := NewHostStatsInputConfig("test_id")
+	cfg.OutputIDs = []string{"$.fake"}
+	cfg.ProcPath = procPath
+	cfg.IncludeIP = false
+	if configure != nil {
+		configure(cfg)
+	}
+
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	op := ops[0].(*HostStatsInput)
+
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, op.SetOutputs([]operator.Operator{fake}))
+	return op, fake
+}
+
+func receive(t *testing.T, fake *testutil.FakeOutput) *entry.Entry {
+	select {
+	case e := <-fake.Received:
+		return e
+	case <-time.After(time.Second):
+		require.FailNow(t, "Timed out waiting for entry")
+	}
+	return nil
+}
+
+func TestBuildHostStatsInputErrors(t *testing.T) {
+	cases := []struct {
+		name      string
+		configure func(*HostStatsInputConfig)
+	}{
+		{"ZeroInterval", func(c *HostStatsInputConfig) { c.Interval = helper.Duration{} }},
+		{"UnknownStats", func(c *HostStatsInputConfig) { c.Stats = []string{"load", "cpu"} }},
+		{"UnknownSort", func(c *HostStatsInputConfig) { c.SortProcessesBy = "name" }},
+		{"NegativeTopProcesses", func(c *HostStatsInputConfig) { c.TopProcesses = -1 }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := NewHostStatsInputConfig("test_id")
+			cfg.OutputIDs = []string{"$.fake"}
+			cfg.IncludeIP = false
+			tc.configure(cfg)
+
+			_, err := cfg.Build(testutil.NewBuildContext(t))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestHostStatsInputCollect(t *testing.T) {
+	dir := writeProc(t, map[string]string{
+		"loadavg":    testLoadavg,
+		"meminfo":    testMeminfo,
+		"diskstats":  testDiskstats,
+		"net/dev":    testNetDev,
+		"1/stat":     processStat("1", "systemd", "0", "100", "50", "1", "1000"),
+		"42/stat":    processStat("42", "worker", "1", "7", "3", "500", "10"),
+		"42/cmdline": "worker\x00--busy\x00",
+	})
+
+	op, fake := newTestInput(t, dir, func(c *HostStatsInputConfig) { c.TopProcesses = 2 })
+
+	start := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	op.previous = op.readCounters(start)
+
+	// Ten seconds later the worker has used 5 seconds of CPU, systemd
+	// none, and a new process started and used 1 second
+	updateProc(t, dir, map[string]string{
+		"diskstats": " 259       0 nvme0n1 1100 10 10000 500 2000 20 16000 700 1 1900 1200 0 0 0 0\n",
+		"net/dev":   "  eth0: 1100000    2000    1    2    0     0          0         0   500000    1500    3    4    0     0       0          0\n",
+		"42/stat":   processStat("42", "worker", "1", "407", "103", "500", "10"),
+		"77/stat":   processStat("77", "cron", "1", "100", "0", "9000", "5000"),
+	})
+	now := start.Add(10 * time.Second)
+	op.collect(context.Background(), now)
+
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+
+	entries := map[string]*entry.Entry{}
+	for i := 0; i < 5; i++ {
+		e := receive(t, fake)
+		require.Equal(t, now, e.Timestamp)
+		require.Equal(t, hostname, e.Resource["host.name"])
+		entries[e.Labels[statsTypeLabel]] = e
+	}
+
+	require.Equal(t, map[string]interface{}{
+		"load1":             0.52,
+		"load5":             0.58,
+		"load15":            0.59,
+		"running_processes": uint64(2),
+		"total_processes":   uint64(431),
+	}, entries[loadType].Record)
+
+	memory := entries[memoryType].Record.(map[string]interface{})
+	require.Equal(t, uint64(8000000*1024), memory["used_bytes"])
+	require.Equal(t, 50.0, memory["used_percent"])
+	require.Equal(t, uint64(1000000*1024), memory["swap_total_bytes"])
+
+	devices := entries[diskType].Record.(map[string]interface{})["devices"].(map[string]interface{})
+	require.Len(t, devices, 1)
+	disk := devices["nvme0n1"].(map[string]interface{})
+	require.Equal(t, 10.0, disk["reads_per_second"])
+	require.Equal(t, 0.0, disk["writes_per_second"])
+	require.Equal(t, 2000*sectorSize/10.0, disk["read_bytes_per_second"])
+	require.Equal(t, 10.0, disk["utilization_percent"])
+
+	interfaces := entries[networkType].Record.(map[string]interface{})["interfaces"].(map[string]interface{})
+	require.Len(t, interfaces, 1)
+	eth0 := interfaces["eth0"].(map[string]interface{})
+	require.Equal(t, 10000.0, eth0["receive_bytes_per_second"])
+	require.Equal(t, 0.0, eth0["transmit_bytes_per_second"])
+
+	processes := entries[processesType].Record.(map[string]interface{})["processes"].([]interface{})
+	require.Len(t, processes, 2)
+	worker := processes[0].(map[string]interface{})
+	require.Equal(t, 42, worker["pid"])
+	require.Equal(t, "worker --busy", worker["command"])
+	require.Equal(t, 50.0, worker["cpu_percent"])
+	cron := processes[1].(map[string]interface{})
+	require.Equal(t, 77, cron["pid"])
+	require.Equal(t, 10.0, cron["cpu_percent"])
+}
+
+func TestHostStatsInputSortByRSS(t *testing.T) {
+	dir := writeProc(t, map[string]string{
+		"1/stat":  processStat("1", "systemd", "0", "100", "50", "1", "1000"),
+		"42/stat": processStat("42", "worker", "1", "7", "3", "500", "10"),
+		"77/stat": processStat("77", "cron", "1", "100", "0", "9000", "5000"),
+	})
+
+	op, fake := newTestInput(t, dir, func(c *HostStatsInputConfig) {
+		c.Stats = []string{processesType}
+		c.SortProcessesBy = sortByRSS
+		c.TopProcesses = 2
+	})
+
+	start := time.Now()
+	op.previous = op.readCounters(start)
+	op.collect(context.Background(), start.Add(time.Second))
+
+	e := receive(t, fake)
+	processes := e.Record.(map[string]interface{})["processes"].([]interface{})
+	require.Len(t, processes, 2)
+	require.Equal(t, 77, processes[0].(map[string]interface{})["pid"])
+	require.Equal(t, 1, processes[1].(map[string]interface{})["pid"])
+}
+
+func TestHostStatsInputProcessesReadError(t *testing.T) {
+	dir := writeProc(t, map[string]string{
+		"42/stat": processStat("42", "worker", "1", "7", "3", "500", "10"),
+	})
+
+	op, fake := newTestInput(t, dir, func(c *HostStatsInputConfig) {
+		c.Stats = []string{processesType}
+	})
+
+	// A failed read is not used as the baseline for CPU usage
+	op.procPath = filepath.Join(dir, "missing")
+	start := time.Now()
+	op.previous = op.readCounters(start)
+	op.procPath = dir
+	op.collect(context.Background(), start.Add(time.Second))
+
+	select {
+	case e := <-fake.Received:
+		require.FailNow(t, "Received unexpected entry", e.Record)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	updateProc(t, dir, map[string]string{
+		"42/stat": processStat("42", "worker", "1", "107", "3", "500", "10"),
+	})
+	op.collect(context.Background(), start.Add(2*time.Second))
+
+	e := receive(t, fake)
+	processes := e.Record.(map[string]interface{})["processes"].([]interface{})
+	require.Len(t, processes, 1)
+	require.Equal(t, 100.0, processes[0].(map[string]interface{})["cpu_percent"])
+}
+
+func TestHostStatsInputMissingFiles(t *testing.T) {
+	dir := writeProc(t, map[string]string{"loadavg": testLoadavg})
+
+	op, fake := newTestInput(t, dir, func(c *HostStatsInputConfig) {
+		c.Stats = []string{loadType, memoryType, diskType}
+	})
+
+	start := time.Now()
+	op.previous = op.readCounters(start)
+	op.collect(context.Background(), start.Add(time.Second))
+
+	e := receive(t, fake)
+	require.Equal(t, loadType, e.Labels[statsTypeLabel])
+
+	select {
+	case e := <-fake.Received:
+		require.FailNow(t, "Received unexpected entry", e.Record)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestHostStatsInputStartStop(t *testing.T) {
+	dir := writeProc(t, map[string]string{"loadavg": testLoadavg})
+
+	op, fake := newTestInput(t, dir, func(c *HostStatsInputConfig) {
+		c.Stats = []string{loadType}
+		c.Interval = helper.Duration{Duration: 10 * time.Millisecond}
+	})
+
+	require.NoError(t, op.Start())
+	e := receive(t, fake)
+	require.Equal(t, loadType, e.Labels[statsTypeLabel])
+	require.NoError(t, op.Stop())
+}
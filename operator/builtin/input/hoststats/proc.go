@@ -0,0 +1,305 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build linux
+
+package hoststats
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// clockTicks is the number of clock ticks per second used for process times
+// in /proc. It is 100 on all supported architectures.
+const clockTicks = 100
+
+// sectorSize is the size of the sectors counted in /proc/diskstats
+const sectorSize = 512
+
+type loadStats struct {
+	load1, load5, load15 float64
+	running, total       uint64
+}
+
+// readLoad parses /proc/loadavg
+func readLoad(procPath string) (*loadStats, error) {
+	contents, err := ioutil.ReadFile(filepath.Join(procPath, "loadavg"))
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(string(contents))
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("invalid loadavg '%s'", strings.TrimSpace(string(contents)))
+	}
+
+	stats := &loadStats{}
+	for i, load := range []*float64{&stats.load1, &stats.load5, &stats.load15} {
+		if *load, err = strconv.ParseFloat(fields[i], 64); err != nil {
+			return nil, fmt.Errorf("invalid load average '%s'", fields[i])
+		}
+	}
+
+	processes := strings.SplitN(fields[3], "/", 2)
+	if len(processes) != 2 {
+		return nil, fmt.Errorf("invalid process count '%s'", fields[3])
+	}
+	if stats.running, err = strconv.ParseUint(processes[0], 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid process count '%s'", fields[3])
+	}
+	if stats.total, err = strconv.ParseUint(processes[1], 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid process count '%s'", fields[3])
+	}
+
+	return stats, nil
+}
+
+// readMemory parses /proc/meminfo, returning the values in bytes
+func readMemory(procPath string) (map[string]uint64, error) {
+	file, err := os.Open(filepath.Join(procPath, "meminfo"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	memory := make(map[string]uint64)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// Lines are of the form "MemTotal:       16316412 kB"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		if len(fields) == 3 && fields[2] == "kB" {
+			value *= 1024
+		}
+		memory[strings.TrimSuffix(fields[0], ":")] = value
+	}
+
+	return memory, scanner.Err()
+}
+
+type diskStats struct {
+	reads, writes           uint64
+	readBytes, writeBytes   uint64
+	readTimeMs, writeTimeMs uint64
+	ioTimeMs                uint64
+	inProgress              uint64
+}
+
+// readDisks parses /proc/diskstats, skipping loop and ram devices
+func readDisks(procPath string) (map[string]*diskStats, error) {
+	file, err := os.Open(filepath.Join(procPath, "diskstats"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	disks := make(map[string]*diskStats)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 14 {
+			continue
+		}
+
+		name := fields[2]
+		if strings.HasPrefix(name, "loop") || strings.HasPrefix(name, "ram") {
+			continue
+		}
+
+		values, err := parseUints(fields[3:14])
+		if err != nil {
+			return nil, fmt.Errorf("invalid diskstats for '%s': %s", name, err)
+		}
+
+		disks[name] = &diskStats{
+			reads:       values[0],
+			readBytes:   values[2] * sectorSize,
+			readTimeMs:  values[3],
+			writes:      values[4],
+			writeBytes:  values[6] * sectorSize,
+			writeTimeMs: values[7],
+			inProgress:  values[8],
+			ioTimeMs:    values[9],
+		}
+	}
+
+	return disks, scanner.Err()
+}
+
+type networkStats struct {
+	receiveBytes, receivePackets   uint64
+	receiveErrors, receiveDropped  uint64
+	transmitBytes, transmitPackets uint64
+	transmitErrors, transmitDrops  uint64
+}
+
+// readNetwork parses /proc/net/dev
+func readNetwork(procPath string) (map[string]*networkStats, error) {
+	file, err := os.Open(filepath.Join(procPath, "net", "dev"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	interfaces := make(map[string]*networkStats)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// The two header lines have no colon before the counters
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		name := strings.TrimSpace(parts[0])
+		fields := strings.Fields(parts[1])
+		if len(fields) < 16 {
+			continue
+		}
+
+		values, err := parseUints(fields[:16])
+		if err != nil {
+			return nil, fmt.Errorf("invalid counters for '%s': %s", name, err)
+		}
+
+		interfaces[name] = &networkStats{
+			receiveBytes:    values[0],
+			receivePackets:  values[1],
+			receiveErrors:   values[2],
+			receiveDropped:  values[3],
+			transmitBytes:   values[8],
+			transmitPackets: values[9],
+			transmitErrors:  values[10],
+			transmitDrops:   values[11],
+		}
+	}
+
+	return interfaces, scanner.Err()
+}
+
+// processKey identifies a process, since a pid may be reused by a later process
+type processKey struct {
+	pid       int
+	startTime uint64
+}
+
+type processStats struct {
+	processKey
+	name     string
+	state    string
+	ppid     int
+	threads  uint64
+	cpuTicks uint64
+	rssBytes uint64
+}
+
+// readProcesses reads /proc/<pid>/stat for every process. Processes that exit
+// while they are read are skipped.
+func readProcesses(procPath string) (map[processKey]*processStats, error) {
+	entries, err := ioutil.ReadDir(procPath)
+	if err != nil {
+		return nil, err
+	}
+
+	processes := make(map[processKey]*processStats)
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil || !e.IsDir() {
+			continue
+		}
+
+		process, err := readProcess(filepath.Join(procPath, e.Name()), pid)
+		if err != nil {
+			continue
+		}
+		processes[process.processKey] = process
+	}
+
+	return processes, nil
+}
+
+func readProcess(dir string, pid int) (*processStats, error) {
+	contents, err := ioutil.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return nil, err
+	}
+	stat := string(contents)
+
+	// The name is in parentheses and may itself contain spaces and parentheses
+	nameStart, nameEnd := strings.IndexByte(stat, '('), strings.LastIndexByte(stat, ')')
+	if nameStart < 0 || nameEnd < nameStart {
+		return nil, fmt.Errorf("invalid stat for pid %d", pid)
+	}
+
+	// Fields after the name start with the state, which is field 3 in proc(5)
+	fields := strings.Fields(stat[nameEnd+1:])
+	if len(fields) < 22 {
+		return nil, fmt.Errorf("invalid stat for pid %d", pid)
+	}
+
+	values, err := parseUints([]string{fields[11], fields[12], fields[17], fields[19], fields[21]})
+	if err != nil {
+		return nil, fmt.Errorf("invalid stat for pid %d: %s", pid, err)
+	}
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid stat for pid %d: %s", pid, err)
+	}
+
+	process := &processStats{
+		processKey: processKey{pid: pid, startTime: values[3]},
+		name:       stat[nameStart+1 : nameEnd],
+		state:      fields[0],
+		ppid:       ppid,
+		cpuTicks:   values[0] + values[1],
+		threads:    values[2],
+		rssBytes:   values[4] * uint64(os.Getpagesize()),
+	}
+
+	return process, nil
+}
+
+// readCommand reads the command line of a process. It is only read for the
+// processes that are emitted, and is empty for kernel threads.
+func readCommand(procPath string, pid int) string {
+	cmdline, err := ioutil.ReadFile(filepath.Join(procPath, strconv.Itoa(pid), "cmdline"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " "))
+}
+
+func parseUints(fields []string) ([]uint64, error) {
+	values := make([]uint64, len(fields))
+	for i, field := range fields {
+		value, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
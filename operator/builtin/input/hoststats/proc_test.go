@@ -0,0 +1,180 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+// +build linux
+
+package hoststats
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testLoadavg = "0.52 0.58 0.59 2/431 12345\n"
+
+	testMeminfo = `MemTotal:       16000000 kB
+MemFree:         2000000 kB
+MemAvailable:    8000000 kB
+Buffers:          500000 kB
+Cached:          4000000 kB
+SwapTotal:       1000000 kB
+SwapFree:        1000000 kB
+HugePages_Total:       0
+`
+
+	testDiskstats = `   7       0 loop0 100 0 200 10 0 0 0 0 0 20 10 0 0 0 0
+ 259       0 nvme0n1 1000 10 8000 500 2000 20 16000 700 1 900 1200 0 0 0 0
+ 259       1 nvme0n1p1 900 10 7000 400 1900 20 15000 600 0 800 1000 0 0 0 0
+`
+
+	testNetDev = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:    5000      50    0    0    0     0          0         0     5000      50    0    0    0     0       0          0
+  eth0: 1000000    2000    1    2    0     0          0         0   500000    1500    3    4    0     0       0          0
+`
+)
+
+// writeProc creates a fake proc filesystem containing the given files
+func writeProc(t *testing.T, files map[string]string) string {
+	dir := testutil.NewTempDir(t)
+	updateProc(t, dir, files)
+	return dir
+}
+
+func updateProc(t *testing.T, dir string, files map[string]string) {
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0600))
+	}
+}
+
+func TestReadLoad(t *testing.T) {
+	dir := writeProc(t, map[string]string{"loadavg": testLoadavg})
+
+	load, err := readLoad(dir)
+	require.NoError(t, err)
+	require.Equal(t, &loadStats{load1: 0.52, load5: 0.58, load15: 0.59, running: 2, total: 431}, load)
+}
+
+func TestReadLoadInvalid(t *testing.T) {
+	for _, contents := range []string{"", "0.52 0.58 0.59\n", "a 0.58 0.59 2/431 1\n", "0.52 0.58 0.59 2 1\n"} {
+		dir := writeProc(t, map[string]string{"loadavg": contents})
+		_, err := readLoad(dir)
+		require.Error(t, err, contents)
+	}
+}
+
+func TestReadMemory(t *testing.T) {
+	dir := writeProc(t, map[string]string{"meminfo": testMeminfo})
+
+	memory, err := readMemory(dir)
+	require.NoError(t, err)
+	require.Equal(t, uint64(16000000*1024), memory["MemTotal"])
+	require.Equal(t, uint64(8000000*1024), memory["MemAvailable"])
+	require.Equal(t, uint64(0), memory["HugePages_Total"])
+}
+
+func TestReadDisks(t *testing.T) {
+	dir := writeProc(t, map[string]string{"diskstats": testDiskstats})
+
+	disks, err := readDisks(dir)
+	require.NoError(t, err)
+	require.Len(t, disks, 2)
+	require.Equal(t, &diskStats{
+		reads:       1000,
+		writes:      2000,
+		readBytes:   8000 * sectorSize,
+		writeBytes:  16000 * sectorSize,
+		readTimeMs:  500,
+		writeTimeMs: 700,
+		ioTimeMs:    900,
+		inProgress:  1,
+	}, disks["nvme0n1"])
+}
+
+func TestReadNetwork(t *testing.T) {
+	dir := writeProc(t, map[string]string{"net/dev": testNetDev})
+
+	interfaces, err := readNetwork(dir)
+	require.NoError(t, err)
+	require.Len(t, interfaces, 2)
+	require.Equal(t, &networkStats{
+		receiveBytes:    1000000,
+		receivePackets:  2000,
+		receiveErrors:   1,
+		receiveDropped:  2,
+		transmitBytes:   500000,
+		transmitPackets: 1500,
+		transmitErrors:  3,
+		transmitDrops:   4,
+	}, interfaces["eth0"])
+}
+
+// processStat returns the contents of /proc/<pid>/stat for a process
+func processStat(pid, name, ppid, utime, stime, startTime, rssPages string) string {
+	return pid + " (" + name + ") S " + ppid + " 1 1 0 -1 4194560 100 0 0 0 " + utime + " " + stime +
+		" 0 0 20 0 3 0 " + startTime + " 10000000 " + rssPages + " 18446744073709551615 0 0 0 0 0 0 0 0 0 0 0 0 17 0 0 0 0 0 0\n"
+}
+
+func TestReadProcesses(t *testing.T) {
+	dir := writeProc(t, map[string]string{
+		"1/stat":      processStat("1", "systemd", "0", "100", "50", "1", "1000"),
+		"1/cmdline":   "/sbin/init\x00splash\x00",
+		"42/stat":     processStat("42", "my (odd) name", "1", "7", "3", "500", "10"),
+		"42/cmdline":  "",
+		"99/cmdline":  "exited\x00",
+		"self/stat":   processStat("1", "systemd", "0", "0", "0", "1", "0"),
+		"loadavg":     testLoadavg,
+		"100/stat":    "100 (broken\n",
+		"100/cmdline": "",
+	})
+
+	processes, err := readProcesses(dir)
+	require.NoError(t, err)
+	require.Len(t, processes, 2)
+
+	systemd := processes[processKey{pid: 1, startTime: 1}]
+	require.NotNil(t, systemd)
+	require.Equal(t, "systemd", systemd.name)
+	require.Equal(t, "S", systemd.state)
+	require.Equal(t, 0, systemd.ppid)
+	require.Equal(t, uint64(150), systemd.cpuTicks)
+	require.Equal(t, uint64(3), systemd.threads)
+	require.Equal(t, uint64(1000*os.Getpagesize()), systemd.rssBytes)
+
+	odd := processes[processKey{pid: 42, startTime: 500}]
+	require.NotNil(t, odd)
+	require.Equal(t, "my (odd) name", odd.name)
+	require.Equal(t, 1, odd.ppid)
+	require.Equal(t, uint64(10), odd.cpuTicks)
+}
+
+func TestReadCommand(t *testing.T) {
+	dir := writeProc(t, map[string]string{
+		"1/cmdline":  "/sbin/init\x00splash\x00",
+		"42/cmdline": "",
+	})
+
+	require.Equal(t, "/sbin/init splash", readCommand(dir, 1))
+	require.Equal(t, "", readCommand(dir, 42))
+	require.Equal(t, "", readCommand(dir, 99))
+}
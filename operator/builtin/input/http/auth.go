@@ -0,0 +1,195 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+)
+
+// AuthConfig is the configuration of request authentication
+type AuthConfig struct {
+	BearerTokens []string    `json:"bearer_tokens,omitempty" yaml:"bearer_tokens,omitempty"`
+	Username     string      `json:"username,omitempty"      yaml:"username,omitempty"`
+	Password     string      `json:"password,omitempty"      yaml:"password,omitempty"`
+	HMAC         *HMACConfig `json:"hmac,omitempty"          yaml:"hmac,omitempty"`
+}
+
+// HMACConfig is the configuration of request signature verification
+type HMACConfig struct {
+	Secret    string `json:"secret"              yaml:"secret"`
+	Header    string `json:"header,omitempty"    yaml:"header,omitempty"`
+	Algorithm string `json:"algorithm,omitempty" yaml:"algorithm,omitempty"`
+	Prefix    string `json:"prefix,omitempty"    yaml:"prefix,omitempty"`
+	Encoding  string `json:"encoding,omitempty"  yaml:"encoding,omitempty"`
+}
+
+// authenticator verifies the credentials and signature of requests
+type authenticator struct {
+	bearerTokens [][]byte
+	username     string
+	password     string
+
+	hmacSecret   []byte
+	hmacHeader   string
+	hmacHash     func() hash.Hash
+	hmacPrefix   string
+	hmacEncoding string
+}
+
+// build will build an authenticator from the config
+func (c AuthConfig) build() (*authenticator, error) {
+	if len(c.BearerTokens) > 0 && c.Username != "" {
+		return nil, fmt.Errorf("only one of 'bearer_tokens' and 'username' can be set")
+	}
+
+	if c.Password != "" && c.Username == "" {
+		return nil, fmt.Errorf("'password' requires 'username'")
+	}
+
+	a := &authenticator{
+		username: c.Username,
+		password: c.Password,
+	}
+
+	for _, token := range c.BearerTokens {
+		if token == "" {
+			return nil, fmt.Errorf("'bearer_tokens' must not contain an empty token")
+		}
+		a.bearerTokens = append(a.bearerTokens, []byte(token))
+	}
+
+	if c.HMAC != nil {
+		if err := a.buildHMAC(*c.HMAC); err != nil {
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
+
+func (a *authenticator) buildHMAC(c HMACConfig) error {
+	if c.Secret == "" {
+		return fmt.Errorf("missing required hmac parameter 'secret'")
+	}
+	a.hmacSecret = []byte(c.Secret)
+
+	algorithm := c.Algorithm
+	if algorithm == "" {
+		algorithm = "sha256"
+	}
+
+	// Without a header, signatures are expected in the format used by GitHub,
+	// which names the header after the algorithm
+	var defaultHeader string
+	switch algorithm {
+	case "sha1":
+		a.hmacHash = sha1.New
+		defaultHeader = "X-Hub-Signature"
+	case "sha256":
+		a.hmacHash = sha256.New
+		defaultHeader = "X-Hub-Signature-256"
+	case "sha512":
+		a.hmacHash = sha512.New
+		defaultHeader = "X-Hub-Signature-512"
+	default:
+		return fmt.Errorf("invalid hmac algorithm '%s'", algorithm)
+	}
+
+	a.hmacHeader = c.Header
+	a.hmacPrefix = c.Prefix
+	if a.hmacHeader == "" {
+		a.hmacHeader = defaultHeader
+		if a.hmacPrefix == "" {
+			a.hmacPrefix = algorithm + "="
+		}
+	}
+
+	a.hmacEncoding = c.Encoding
+	switch a.hmacEncoding {
+	case "":
+		a.hmacEncoding = "hex"
+	case "hex", "base64":
+	default:
+		return fmt.Errorf("invalid hmac encoding '%s'", a.hmacEncoding)
+	}
+
+	return nil
+}
+
+// authorize will check the credentials of a request
+func (a *authenticator) authorize(req *http.Request) bool {
+	switch {
+	case len(a.bearerTokens) > 0:
+		auth := req.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") {
+			return false
+		}
+		token := []byte(strings.TrimPrefix(auth, "Bearer "))
+		for _, expected := range a.bearerTokens {
+			if subtle.ConstantTimeCompare(token, expected) == 1 {
+				return true
+			}
+		}
+		return false
+	case a.username != "":
+		username, password, ok := req.BasicAuth()
+		if !ok {
+			return false
+		}
+		usernameMatch := subtle.ConstantTimeCompare([]byte(username), []byte(a.username))
+		passwordMatch := subtle.ConstantTimeCompare([]byte(password), []byte(a.password))
+		return usernameMatch&passwordMatch == 1
+	default:
+		return true
+	}
+}
+
+// verify will check the signature of a request body
+func (a *authenticator) verify(req *http.Request, body []byte) bool {
+	if a.hmacSecret == nil {
+		return true
+	}
+
+	signature := req.Header.Get(a.hmacHeader)
+	if !strings.HasPrefix(signature, a.hmacPrefix) {
+		return false
+	}
+	signature = strings.TrimPrefix(signature, a.hmacPrefix)
+
+	var decoded []byte
+	var err error
+	if a.hmacEncoding == "base64" {
+		decoded, err = base64.StdEncoding.DecodeString(signature)
+	} else {
+		decoded, err = hex.DecodeString(signature)
+	}
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(a.hmacHash, a.hmacSecret)
+	mac.Write(body)
+	return hmac.Equal(decoded, mac.Sum(nil))
+}
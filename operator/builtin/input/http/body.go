@@ -0,0 +1,128 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"strings"
+)
+
+const (
+	formatAuto   = "auto"
+	formatRaw    = "raw"
+	formatNDJSON = "ndjson"
+	formatJSON   = "json"
+	formatForm   = "form"
+)
+
+// detectFormat will choose the format of a request body from its content type
+func detectFormat(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/json":
+		return formatJSON
+	case "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+		return formatNDJSON
+	case "application/x-www-form-urlencoded":
+		return formatForm
+	default:
+		return formatRaw
+	}
+}
+
+// decodeBody will decode a request body into a record for each log
+func decodeBody(format string, body []byte) ([]interface{}, error) {
+	switch format {
+	case formatJSON:
+		return decodeJSON(body)
+	case formatNDJSON:
+		return decodeNDJSON(body)
+	case formatForm:
+		return decodeForm(body)
+	default:
+		return decodeRaw(body), nil
+	}
+}
+
+// decodeRaw returns each non-empty line of the body
+func decodeRaw(body []byte) []interface{} {
+	records := make([]interface{}, 0)
+	for _, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			continue
+		}
+		records = append(records, line)
+	}
+	return records
+}
+
+// decodeJSON returns each element of a json array, or a single json value
+func decodeJSON(body []byte) ([]interface{}, error) {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return nil, fmt.Errorf("decode json: %s", err)
+	}
+
+	if values, ok := value.([]interface{}); ok {
+		return values, nil
+	}
+	return []interface{}{value}, nil
+}
+
+// decodeNDJSON returns each json value of a newline-delimited body
+func decodeNDJSON(body []byte) ([]interface{}, error) {
+	records := make([]interface{}, 0)
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	for {
+		var value interface{}
+		err := decoder.Decode(&value)
+		if err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("decode ndjson: %s", err)
+		}
+		records = append(records, value)
+	}
+}
+
+// decodeForm returns a single map of the form values. Keys with a
+// single value are strings, and keys with several values are lists.
+func decodeForm(body []byte) ([]interface{}, error) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("decode form: %s", err)
+	}
+
+	record := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		if len(v) == 1 {
+			record[k] = v[0]
+			continue
+		}
+
+		list := make([]interface{}, 0, len(v))
+		for _, s := range v {
+			list = append(list, s)
+		}
+		record[k] = list
+	}
+	return []interface{}{record}, nil
+}
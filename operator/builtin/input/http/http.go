@@ -0,0 +1,305 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultMaxBodySize = 10 * 1024 * 1024
+	defaultQueueSize   = 1000
+
+	pathLabel = "http.path"
+)
+
+func init() {
+	operator.Register("http_input", func() operator.Builder { return NewHTTPInputConfig("") })
+}
+
+// NewHTTPInputConfig creates a new http input config with default values
+func NewHTTPInputConfig(operatorID string) *HTTPInputConfig {
+	return &HTTPInputConfig{
+		InputConfig: helper.NewInputConfig(operatorID, "http_input"),
+		MaxBodySize: defaultMaxBodySize,
+		Format:      formatAuto,
+		QueueSize:   defaultQueueSize,
+	}
+}
+
+// HTTPInputConfig is the configuration of an http input operator
+type HTTPInputConfig struct {
+	helper.InputConfig `yaml:",inline"`
+
+	ListenAddress string                  `json:"listen_address"          yaml:"listen_address"`
+	TLS           *helper.TLSServerConfig `json:"tls,omitempty"           yaml:"tls,omitempty"`
+	Auth          AuthConfig              `json:"auth,omitempty"          yaml:"auth,omitempty"`
+	MaxBodySize   helper.ByteSize         `json:"max_body_size,omitempty" yaml:"max_body_size,omitempty"`
+	Format        string                  `json:"format,omitempty"        yaml:"format,omitempty"`
+	Paths         []PathConfig            `json:"paths,omitempty"         yaml:"paths,omitempty"`
+	QueueSize     int                     `json:"queue_size,omitempty"    yaml:"queue_size,omitempty"`
+}
+
+// Build will build an http input operator
+func (c HTTPInputConfig) Build(context operator.BuildContext) ([]operator.Operator, error) {
+	inputOperator, err := c.InputConfig.Build(context)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.ListenAddress == "" {
+		return nil, fmt.Errorf("missing required parameter 'listen_address'")
+	}
+
+	if _, err := net.ResolveTCPAddr("tcp", c.ListenAddress); err != nil {
+		return nil, fmt.Errorf("failed to resolve listen_address: %s", err)
+	}
+
+	if c.MaxBodySize <= 0 {
+		return nil, fmt.Errorf("`max_body_size` must be positive")
+	}
+
+	if c.QueueSize <= 0 {
+		return nil, fmt.Errorf("`queue_size` must be positive")
+	}
+
+	switch c.Format {
+	case formatAuto, formatRaw, formatNDJSON, formatJSON, formatForm:
+	default:
+		return nil, fmt.Errorf("invalid format '%s'", c.Format)
+	}
+
+	auth, err := c.Auth.build()
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]*pathMatcher, 0, len(c.Paths))
+	for _, p := range c.Paths {
+		matcher, err := p.build()
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matcher)
+	}
+
+	var tlsConfig *tls.Config
+	if c.TLS != nil {
+		tlsConfig, err = c.TLS.Build()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	httpInput := &HTTPInput{
+		InputOperator: inputOperator,
+		address:       c.ListenAddress,
+		tlsConfig:     tlsConfig,
+		auth:          auth,
+		maxBodySize:   int64(c.MaxBodySize),
+		format:        c.Format,
+		paths:         paths,
+		queue:         make(chan queuedRecord, c.QueueSize),
+	}
+
+	httpInput.srv = &http.Server{
+		Handler: httpInput,
+	}
+
+	return []operator.Operator{httpInput}, nil
+}
+
+// HTTPInput is an operator that receives logs from http requests
+type HTTPInput struct {
+	helper.InputOperator
+	address     string
+	tlsConfig   *tls.Config
+	auth        *authenticator
+	maxBodySize int64
+	format      string
+	paths       []*pathMatcher
+
+	srv *http.Server
+	ln  net.Listener
+
+	// queue holds the records of accepted requests until they are written.
+	// Requests reserve room for all of their records under queueMux, so
+	// a request is either queued in full or rejected.
+	queue    chan queuedRecord
+	queueMux sync.Mutex
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// queuedRecord is a decoded record waiting to be written
+type queuedRecord struct {
+	record interface{}
+	labels map[string]string
+}
+
+// Start will start listening for http requests
+func (h *HTTPInput) Start() error {
+	ln, err := net.Listen("tcp", h.address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on interface: %w", err)
+	}
+
+	h.ln = ln
+	if h.tlsConfig != nil {
+		h.ln = tls.NewListener(ln, h.tlsConfig)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+	h.goWrite(ctx)
+
+	go func() {
+		err := h.srv.Serve(h.ln)
+		if err != nil && err != http.ErrServerClosed {
+			h.Errorw("Serve error", zap.Error(err))
+		}
+	}()
+
+	return nil
+}
+
+// Stop will stop listening for http requests. Records of requests that
+// were already accepted are written before it returns.
+func (h *HTTPInput) Stop() error {
+	// Shutdown waits for active requests to finish, so nothing
+	// is queued once it returns and the queue can be closed
+	err := h.srv.Shutdown(context.Background())
+	close(h.queue)
+	h.wg.Wait()
+	h.cancel()
+	return err
+}
+
+// goWrite will write queued records until the queue is closed
+func (h *HTTPInput) goWrite(ctx context.Context) {
+	h.wg.Add(1)
+
+	go func() {
+		defer h.wg.Done()
+
+		for q := range h.queue {
+			entry, err := h.NewEntry(q.record)
+			if err != nil {
+				h.Errorw("Failed to create entry", zap.Error(err))
+				continue
+			}
+
+			for k, v := range q.labels {
+				entry.AddLabel(k, v)
+			}
+			h.Write(ctx, entry)
+		}
+	}()
+}
+
+// ServeHTTP will handle a request containing logs
+func (h *HTTPInput) ServeHTTP(wr http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost && req.Method != http.MethodPut {
+		wr.Header().Set("Allow", "POST, PUT")
+		http.Error(wr, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	labels, ok := h.matchPath(req.URL.Path)
+	if !ok {
+		http.Error(wr, "not found", http.StatusNotFound)
+		return
+	}
+
+	if !h.auth.authorize(req) {
+		http.Error(wr, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(wr, req.Body, h.maxBodySize))
+	if err != nil {
+		http.Error(wr, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if !h.auth.verify(req, body) {
+		http.Error(wr, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	format := h.format
+	if format == formatAuto {
+		format = detectFormat(req.Header.Get("Content-Type"))
+	}
+
+	records, err := decodeBody(format, body)
+	if err != nil {
+		http.Error(wr, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(records) > cap(h.queue) {
+		http.Error(wr, "too many logs in request", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if !h.enqueue(records, labels) {
+		http.Error(wr, "too many requests", http.StatusTooManyRequests)
+		return
+	}
+
+	wr.WriteHeader(http.StatusOK)
+}
+
+// matchPath will return the labels of a request path, and whether the path is accepted
+func (h *HTTPInput) matchPath(path string) (map[string]string, bool) {
+	if len(h.paths) == 0 {
+		return map[string]string{pathLabel: path}, true
+	}
+
+	for _, matcher := range h.paths {
+		if labels, ok := matcher.match(path); ok {
+			labels[pathLabel] = path
+			return labels, true
+		}
+	}
+	return nil, false
+}
+
+// enqueue will queue all records of a request, or none if the queue does not have room
+func (h *HTTPInput) enqueue(records []interface{}, labels map[string]string) bool {
+	h.queueMux.Lock()
+	defer h.queueMux.Unlock()
+
+	if cap(h.queue)-len(h.queue) < len(records) {
+		return false
+	}
+
+	for _, record := range records {
+		h.queue <- queuedRecord{record: record, labels: labels}
+	}
+	return true
+}
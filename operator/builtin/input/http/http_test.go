@@ -0,0 +1,490 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"github.com/opentelemetry/opentelemetry-log-collection/testutil"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestHTTPInput(t *testing.T, cfg *HTTPInputConfig) (*HTTPInput, chan *entry.Entry) {
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+
+	mockOutput := testutil.Operator{}
+	httpInput := ops[0].(*HTTPInput)
+	httpInput.InputOperator.OutputOperators = []operator.Operator{&mockOutput}
+
+	entryChan := make(chan *entry.Entry, 10)
+	mockOutput.On("Process", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		entryChan <- args.Get(1).(*entry.Entry)
+	}).Return(nil)
+
+	require.NoError(t, httpInput.Start())
+	t.Cleanup(func() { require.NoError(t, httpInput.Stop()) })
+	return httpInput, entryChan
+}
+
+func newTestConfig() *HTTPInputConfig {
+	cfg := NewHTTPInputConfig("test_id")
+	cfg.ListenAddress = "127.0.0.1:0"
+	return cfg
+}
+
+func post(t *testing.T, url, contentType, body string, headers map[string]string) int {
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	return res.StatusCode
+}
+
+func expectRecords(t *testing.T, entryChan chan *entry.Entry, expected []interface{}) []*entry.Entry {
+	entries := make([]*entry.Entry, 0, len(expected))
+	for _, record := range expected {
+		select {
+		case e := <-entryChan:
+			require.Equal(t, record, e.Record)
+			entries = append(entries, e)
+		case <-time.After(time.Second):
+			require.FailNow(t, "Timed out waiting for entry")
+		}
+	}
+
+	select {
+	case e := <-entryChan:
+		require.FailNow(t, "Unexpected entry", e.Record)
+	case <-time.After(50 * time.Millisecond):
+	}
+	return entries
+}
+
+func TestHTTPInputBuild(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(*HTTPInputConfig)
+	}{
+		{"MissingListenAddress", func(c *HTTPInputConfig) { c.ListenAddress = "" }},
+		{"InvalidListenAddress", func(c *HTTPInputConfig) { c.ListenAddress = "localhost:port" }},
+		{"InvalidFormat", func(c *HTTPInputConfig) { c.Format = "xml" }},
+		{"ZeroMaxBodySize", func(c *HTTPInputConfig) { c.MaxBodySize = 0 }},
+		{"ZeroQueueSize", func(c *HTTPInputConfig) { c.QueueSize = 0 }},
+		{"RelativePath", func(c *HTTPInputConfig) { c.Paths = []PathConfig{{Path: "logs"}} }},
+		{"UnnamedPathParameter", func(c *HTTPInputConfig) { c.Paths = []PathConfig{{Path: "/logs/{}"}} }},
+		{"BearerAndBasic", func(c *HTTPInputConfig) {
+			c.Auth = AuthConfig{BearerTokens: []string{"token"}, Username: "user"}
+		}},
+		{"PasswordWithoutUsername", func(c *HTTPInputConfig) { c.Auth = AuthConfig{Password: "pass"} }},
+		{"EmptyBearerToken", func(c *HTTPInputConfig) { c.Auth = AuthConfig{BearerTokens: []string{""}} }},
+		{"HMACWithoutSecret", func(c *HTTPInputConfig) { c.Auth = AuthConfig{HMAC: &HMACConfig{}} }},
+		{"InvalidHMACAlgorithm", func(c *HTTPInputConfig) {
+			c.Auth = AuthConfig{HMAC: &HMACConfig{Secret: "s", Algorithm: "md5"}}
+		}},
+		{"InvalidHMACEncoding", func(c *HTTPInputConfig) {
+			c.Auth = AuthConfig{HMAC: &HMACConfig{Secret: "s", Encoding: "base32"}}
+		}},
+		{"MissingTLSKey", func(c *HTTPInputConfig) { c.TLS = &helper.TLSServerConfig{CertFile: "cert"} }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			tc.modify(cfg)
+			_, err := cfg.Build(testutil.NewBuildContext(t))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestHTTPInputFormats(t *testing.T) {
+	cases := []struct {
+		name        string
+		format      string
+		contentType string
+		body        string
+		expected    []interface{}
+	}{
+		{
+			"RawLines",
+			formatAuto,
+			"text/plain",
+			"first\r\nsecond\n\nthird",
+			[]interface{}{"first", "second", "third"},
+		},
+		{
+			"NDJSON",
+			formatAuto,
+			"application/x-ndjson",
+			"{\"a\":1}\n{\"b\":\"two\"}\n",
+			[]interface{}{
+				map[string]interface{}{"a": float64(1)},
+				map[string]interface{}{"b": "two"},
+			},
+		},
+		{
+			"JSONArray",
+			formatAuto,
+			"application/json; charset=utf-8",
+			`[{"a":1},"second"]`,
+			[]interface{}{map[string]interface{}{"a": float64(1)}, "second"},
+		},
+		{
+			"JSONObject",
+			formatAuto,
+			"application/json",
+			`{"a":1}`,
+			[]interface{}{map[string]interface{}{"a": float64(1)}},
+		},
+		{
+			"Form",
+			formatAuto,
+			"application/x-www-form-urlencoded",
+			"message=hello&tag=a&tag=b",
+			[]interface{}{map[string]interface{}{"message": "hello", "tag": []interface{}{"a", "b"}}},
+		},
+		{
+			"ConfiguredFormat",
+			formatNDJSON,
+			"text/plain",
+			"{\"a\":1}\n",
+			[]interface{}{map[string]interface{}{"a": float64(1)}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.Format = tc.format
+			httpInput, entryChan := newTestHTTPInput(t, cfg)
+
+			url := "http://" + httpInput.ln.Addr().String() + "/"
+			require.Equal(t, http.StatusOK, post(t, url, tc.contentType, tc.body, nil))
+			expectRecords(t, entryChan, tc.expected)
+		})
+	}
+}
+
+func TestHTTPInputInvalidRequests(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.MaxBodySize = 16
+	httpInput, entryChan := newTestHTTPInput(t, cfg)
+	url := "http://" + httpInput.ln.Addr().String() + "/"
+
+	t.Run("InvalidJSON", func(t *testing.T) {
+		require.Equal(t, http.StatusBadRequest, post(t, url, "application/json", "{", nil))
+	})
+
+	t.Run("BodyTooLarge", func(t *testing.T) {
+		body := strings.Repeat("a", 17)
+		require.Equal(t, http.StatusRequestEntityTooLarge, post(t, url, "text/plain", body, nil))
+	})
+
+	t.Run("MethodNotAllowed", func(t *testing.T) {
+		res, err := http.Get(url)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+	})
+
+	expectRecords(t, entryChan, nil)
+}
+
+func TestHTTPInputPaths(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Paths = []PathConfig{
+		{Path: "/hooks/{source}/{project}", Labels: map[string]string{"kind": "webhook"}},
+		{Path: "/ci"},
+	}
+	httpInput, entryChan := newTestHTTPInput(t, cfg)
+	url := "http://" + httpInput.ln.Addr().String()
+
+	require.Equal(t, http.StatusOK, post(t, url+"/hooks/github/stanza", "text/plain", "pushed", nil))
+	entries := expectRecords(t, entryChan, []interface{}{"pushed"})
+	require.Equal(t, map[string]string{
+		"source":    "github",
+		"project":   "stanza",
+		"kind":      "webhook",
+		"http.path": "/hooks/github/stanza",
+	}, entries[0].Labels)
+
+	require.Equal(t, http.StatusOK, post(t, url+"/ci/", "text/plain", "built", nil))
+	entries = expectRecords(t, entryChan, []interface{}{"built"})
+	require.Equal(t, map[string]string{"http.path": "/ci/"}, entries[0].Labels)
+
+	require.Equal(t, http.StatusNotFound, post(t, url+"/hooks/github", "text/plain", "lost", nil))
+	require.Equal(t, http.StatusNotFound, post(t, url+"/other", "text/plain", "lost", nil))
+	expectRecords(t, entryChan, nil)
+}
+
+func TestHTTPInputAuth(t *testing.T) {
+	t.Run("Bearer", func(t *testing.T) {
+		cfg := newTestConfig()
+		cfg.Auth.BearerTokens = []string{"first", "second"}
+		httpInput, entryChan := newTestHTTPInput(t, cfg)
+		url := "http://" + httpInput.ln.Addr().String() + "/"
+
+		require.Equal(t, http.StatusUnauthorized, post(t, url, "text/plain", "none", nil))
+		require.Equal(t, http.StatusUnauthorized, post(t, url, "text/plain", "wrong", map[string]string{"Authorization": "Bearer third"}))
+		require.Equal(t, http.StatusOK, post(t, url, "text/plain", "valid", map[string]string{"Authorization": "Bearer second"}))
+		expectRecords(t, entryChan, []interface{}{"valid"})
+	})
+
+	t.Run("Basic", func(t *testing.T) {
+		cfg := newTestConfig()
+		cfg.Auth.Username = "user"
+		cfg.Auth.Password = "pass"
+		httpInput, entryChan := newTestHTTPInput(t, cfg)
+		url := "http://" + httpInput.ln.Addr().String() + "/"
+
+		require.Equal(t, http.StatusUnauthorized, post(t, url, "text/plain", "none", nil))
+
+		req, err := http.NewRequest(http.MethodPost, url, strings.NewReader("wrong"))
+		require.NoError(t, err)
+		req.SetBasicAuth("user", "other")
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+		req, err = http.NewRequest(http.MethodPut, url, strings.NewReader("valid"))
+		require.NoError(t, err)
+		req.SetBasicAuth("user", "pass")
+		res, err = http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		expectRecords(t, entryChan, []interface{}{"valid"})
+	})
+
+	t.Run("HMAC", func(t *testing.T) {
+		cfg := newTestConfig()
+		cfg.Auth.HMAC = &HMACConfig{Secret: "secret"}
+		httpInput, entryChan := newTestHTTPInput(t, cfg)
+		url := "http://" + httpInput.ln.Addr().String() + "/"
+
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write([]byte("signed"))
+		signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+		require.Equal(t, http.StatusUnauthorized, post(t, url, "text/plain", "unsigned", nil))
+		require.Equal(t, http.StatusUnauthorized, post(t, url, "text/plain", "tampered", map[string]string{"X-Hub-Signature-256": signature}))
+		require.Equal(t, http.StatusOK, post(t, url, "text/plain", "signed", map[string]string{"X-Hub-Signature-256": signature}))
+		expectRecords(t, entryChan, []interface{}{"signed"})
+	})
+
+	t.Run("HMACDefaultHeaderSHA1", func(t *testing.T) {
+		cfg := newTestConfig()
+		cfg.Auth.HMAC = &HMACConfig{Secret: "secret", Algorithm: "sha1"}
+		httpInput, entryChan := newTestHTTPInput(t, cfg)
+		url := "http://" + httpInput.ln.Addr().String() + "/"
+
+		mac := hmac.New(sha1.New, []byte("secret"))
+		mac.Write([]byte("signed"))
+		signature := "sha1=" + hex.EncodeToString(mac.Sum(nil))
+
+		require.Equal(t, http.StatusUnauthorized, post(t, url, "text/plain", "signed", map[string]string{"X-Hub-Signature-256": signature}))
+		require.Equal(t, http.StatusOK, post(t, url, "text/plain", "signed", map[string]string{"X-Hub-Signature": signature}))
+		expectRecords(t, entryChan, []interface{}{"signed"})
+	})
+}
+
+func TestHTTPInputQueueFull(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.QueueSize = 2
+
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	httpInput := ops[0].(*HTTPInput)
+
+	// The output blocks until released, so records back up in the queue
+	processing := make(chan struct{}, 10)
+	release := make(chan struct{})
+	mockOutput := testutil.Operator{}
+	mockOutput.On("Process", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		processing <- struct{}{}
+		<-release
+	}).Return(nil)
+	httpInput.InputOperator.OutputOperators = []operator.Operator{&mockOutput}
+
+	require.NoError(t, httpInput.Start())
+	defer func() {
+		close(release)
+		require.NoError(t, httpInput.Stop())
+	}()
+	url := "http://" + httpInput.ln.Addr().String() + "/"
+
+	require.Equal(t, http.StatusOK, post(t, url, "text/plain", "blocked", nil))
+	select {
+	case <-processing:
+	case <-time.After(time.Second):
+		require.FailNow(t, "Timed out waiting for entry")
+	}
+
+	require.Equal(t, http.StatusRequestEntityTooLarge, post(t, url, "text/plain", "a\nb\nc", nil))
+	require.Equal(t, http.StatusOK, post(t, url, "text/plain", "a\nb", nil))
+	require.Equal(t, http.StatusTooManyRequests, post(t, url, "text/plain", "c", nil))
+}
+
+func TestHTTPInputStopDrainsQueue(t *testing.T) {
+	cfg := newTestConfig()
+
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	httpInput := ops[0].(*HTTPInput)
+
+	// The output blocks until released, so records back up in the queue
+	processing := make(chan struct{}, 10)
+	release := make(chan struct{})
+	received := make(chan interface{}, 10)
+	mockOutput := testutil.Operator{}
+	mockOutput.On("Process", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		processing <- struct{}{}
+		<-release
+		received <- args.Get(1).(*entry.Entry).Record
+	}).Return(nil)
+	httpInput.InputOperator.OutputOperators = []operator.Operator{&mockOutput}
+
+	require.NoError(t, httpInput.Start())
+	url := "http://" + httpInput.ln.Addr().String() + "/"
+
+	require.Equal(t, http.StatusOK, post(t, url, "text/plain", "first", nil))
+	select {
+	case <-processing:
+	case <-time.After(time.Second):
+		require.FailNow(t, "Timed out waiting for entry")
+	}
+	require.Equal(t, http.StatusOK, post(t, url, "text/plain", "second\nthird", nil))
+
+	stopped := make(chan error)
+	go func() { stopped <- httpInput.Stop() }()
+	close(release)
+
+	select {
+	case err := <-stopped:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		require.FailNow(t, "Timed out waiting for stop")
+	}
+
+	close(received)
+	records := []interface{}{}
+	for record := range received {
+		records = append(records, record)
+	}
+	require.Equal(t, []interface{}{"first", "second", "third"}, records)
+}
+
+func TestHTTPInputTLS(t *testing.T) {
+	tempDir := testutil.NewTempDir(t)
+	certFile, keyFile, pool := createServerCertificate(t, tempDir)
+
+	cfg := newTestConfig()
+	cfg.TLS = &helper.TLSServerConfig{
+		CertFile: certFile,
+		KeyFile:  keyFile,
+	}
+	httpInput, entryChan := newTestHTTPInput(t, cfg)
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		},
+	}
+
+	url := "https://" + httpInput.ln.Addr().String() + "/"
+	res, err := client.Post(url, "text/plain", bytes.NewBufferString("secure"))
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	expectRecords(t, entryChan, []interface{}{"secure"})
+}
+
+func TestPathMatch(t *testing.T) {
+	matcher, err := PathConfig{Path: "/logs/{app}"}.build()
+	require.NoError(t, err)
+
+	labels, ok := matcher.match("/logs/web")
+	require.True(t, ok)
+	require.Equal(t, map[string]string{"app": "web"}, labels)
+
+	_, ok = matcher.match("/logs/")
+	require.False(t, ok)
+
+	_, ok = matcher.match("/logs/web/extra")
+	require.False(t, ok)
+
+	_, ok = matcher.match("/metrics/web")
+	require.False(t, ok)
+}
+
+func createServerCertificate(t *testing.T, dir string) (certFile, keyFile string, pool *x509.CertPool) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(raw)
+	require.NoError(t, err)
+
+	certFile = filepath.Join(dir, "server.crt")
+	keyFile = filepath.Join(dir, "server.key")
+	require.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: raw}), 0600))
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600))
+
+	pool = x509.NewCertPool()
+	pool.AddCert(cert)
+	return certFile, keyFile, pool
+}
@@ -0,0 +1,85 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PathConfig is the configuration of a path that accepts logs
+type PathConfig struct {
+	Path   string            `json:"path"             yaml:"path"`
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+// pathMatcher matches request paths against a pattern such as /hooks/{source}
+type pathMatcher struct {
+	segments []string
+	labels   map[string]string
+}
+
+func (c PathConfig) build() (*pathMatcher, error) {
+	if !strings.HasPrefix(c.Path, "/") {
+		return nil, fmt.Errorf("path '%s' must start with '/'", c.Path)
+	}
+
+	segments := splitPath(c.Path)
+	for _, segment := range segments {
+		if isParam(segment) && len(segment) == 2 {
+			return nil, fmt.Errorf("path '%s' has a parameter without a name", c.Path)
+		}
+	}
+
+	return &pathMatcher{
+		segments: segments,
+		labels:   c.Labels,
+	}, nil
+}
+
+// match will return the labels for a path, and whether the path matched.
+// Each {name} segment of the pattern adds a label with the value of the segment.
+func (m *pathMatcher) match(path string) (map[string]string, bool) {
+	segments := splitPath(path)
+	if len(segments) != len(m.segments) {
+		return nil, false
+	}
+
+	labels := make(map[string]string, len(m.labels)+len(segments))
+	for i, segment := range m.segments {
+		switch {
+		case isParam(segment):
+			if segments[i] == "" {
+				return nil, false
+			}
+			labels[segment[1:len(segment)-1]] = segments[i]
+		case segment != segments[i]:
+			return nil, false
+		}
+	}
+
+	for k, v := range m.labels {
+		labels[k] = v
+	}
+	return labels, true
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+func isParam(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
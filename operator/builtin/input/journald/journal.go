@@ -0,0 +1,514 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build linux
+
+package journald
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4"
+	"github.com/ulikunitz/xz"
+)
+
+// The layout of journal files is described in
+// https://systemd.io/JOURNAL_FILE_FORMAT/. All integers are little endian.
+
+const headerSignature = "LPKSHHRH"
+
+// minHeaderSize covers every header field up to and including
+// tail_entry_monotonic, which all supported journal versions include
+const minHeaderSize = 208
+
+const (
+	headerIncompatibleCompressedXZ   = 1 << 0
+	headerIncompatibleCompressedLZ4  = 1 << 1
+	headerIncompatibleKeyedHash      = 1 << 2
+	headerIncompatibleCompressedZSTD = 1 << 3
+	headerIncompatibleCompact        = 1 << 4
+
+	headerIncompatibleSupported = headerIncompatibleCompressedXZ |
+		headerIncompatibleCompressedLZ4 |
+		headerIncompatibleKeyedHash |
+		headerIncompatibleCompressedZSTD |
+		headerIncompatibleCompact
+)
+
+const (
+	objectData       = 1
+	objectEntry      = 3
+	objectEntryArray = 6
+)
+
+const (
+	objectCompressedXZ   = 1 << 0
+	objectCompressedLZ4  = 1 << 1
+	objectCompressedZSTD = 1 << 2
+)
+
+const (
+	objectHeaderSize     = 16
+	entryObjectItems     = 64
+	entryArrayItems      = 24
+	dataObjectPayload    = 64
+	dataObjectPayloadNew = 72
+)
+
+// maxObjectSize guards against allocating absurd amounts of memory when an
+// object header is corrupted
+const maxObjectSize = 768 << 20
+
+var zstdDecoder, _ = zstd.NewReader(nil)
+
+// errEntryNotReady is returned when the header announces an entry that has
+// not been fully linked into the file yet
+var errEntryNotReady = errors.New("journal entry not yet written")
+
+type journalHeader struct {
+	incompatibleFlags uint32
+	seqnumID          [16]byte
+	headerSize        uint64
+	arenaSize         uint64
+	nEntries          uint64
+	entryArrayOffset  uint64
+}
+
+// entryArray is a link in the chain of entry arrays that indexes every entry
+// of a journal file in order
+type entryArray struct {
+	offset   uint64
+	capacity uint64
+}
+
+// journalFile reads entries from a single journal file
+type journalFile struct {
+	path   string
+	file   *os.File
+	info   os.FileInfo
+	header journalHeader
+	arrays []entryArray
+
+	// position is the index of the next entry to read
+	position uint64
+}
+
+// journalEntry is a single entry read from a journal file
+type journalEntry struct {
+	seqnumID  [16]byte
+	seqnum    uint64
+	realtime  uint64
+	monotonic uint64
+	bootID    [16]byte
+	xorHash   uint64
+	items     []uint64
+	fields    map[string][]string
+}
+
+func openJournalFile(path string) (*journalFile, error) {
+	file, err := os.Open(path) // #nosec - operator must read in files defined by user
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	j := &journalFile{
+		path: path,
+		file: file,
+		info: info,
+	}
+	if err := j.readHeader(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return j, nil
+}
+
+// readHeader reads the file header, which is updated in place as entries are
+// appended to the file
+func (j *journalFile) readHeader() error {
+	buf := make([]byte, minHeaderSize)
+	if _, err := j.file.ReadAt(buf, 0); err != nil {
+		return fmt.Errorf("read header: %s", err)
+	}
+
+	if string(buf[0:8]) != headerSignature {
+		return errors.New("not a journal file")
+	}
+
+	h := journalHeader{
+		incompatibleFlags: binary.LittleEndian.Uint32(buf[12:16]),
+		headerSize:        binary.LittleEndian.Uint64(buf[88:96]),
+		arenaSize:         binary.LittleEndian.Uint64(buf[96:104]),
+		nEntries:          binary.LittleEndian.Uint64(buf[152:160]),
+		entryArrayOffset:  binary.LittleEndian.Uint64(buf[176:184]),
+	}
+	copy(h.seqnumID[:], buf[72:88])
+
+	if unsupported := h.incompatibleFlags &^ headerIncompatibleSupported; unsupported != 0 {
+		return fmt.Errorf("unsupported journal features 0x%x", unsupported)
+	}
+	if h.headerSize < minHeaderSize {
+		return fmt.Errorf("invalid header size %d", h.headerSize)
+	}
+
+	j.header = h
+	return nil
+}
+
+func (j *journalFile) compact() bool {
+	return j.header.incompatibleFlags&headerIncompatibleCompact != 0
+}
+
+// readObject reads a whole object of the given type at offset
+func (j *journalFile) readObject(offset uint64, objectType uint8) (flags uint8, object []byte, err error) {
+	size, err := j.readObjectHeader(offset, objectType)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	object = make([]byte, size)
+	if _, err := j.file.ReadAt(object, int64(offset)); err != nil {
+		return 0, nil, fmt.Errorf("read object at %d: %s", offset, err)
+	}
+	return object[1], object, nil
+}
+
+// readObjectHeader validates the header of the object at offset and returns
+// the size of the object
+func (j *journalFile) readObjectHeader(offset uint64, objectType uint8) (uint64, error) {
+	if offset < j.header.headerSize || offset%8 != 0 {
+		return 0, fmt.Errorf("invalid object offset %d", offset)
+	}
+
+	buf := make([]byte, objectHeaderSize)
+	if _, err := j.file.ReadAt(buf, int64(offset)); err != nil {
+		return 0, fmt.Errorf("read object at %d: %s", offset, err)
+	}
+
+	if buf[0] != objectType {
+		return 0, fmt.Errorf("expected object type %d at %d, found %d", objectType, offset, buf[0])
+	}
+
+	size := binary.LittleEndian.Uint64(buf[8:16])
+	if size < objectHeaderSize || size > maxObjectSize || offset+size > j.header.headerSize+j.header.arenaSize {
+		return 0, fmt.Errorf("invalid object size %d at %d", size, offset)
+	}
+	return size, nil
+}
+
+// entryOffset returns the offset of the entry object with the given index
+func (j *journalFile) entryOffset(index uint64) (uint64, error) {
+	if index >= j.header.nEntries {
+		return 0, errEntryNotReady
+	}
+
+	itemSize := j.arrayItemSize()
+	var start uint64
+	for i := 0; ; i++ {
+		if i == len(j.arrays) {
+			if err := j.extendArrays(); err != nil {
+				return 0, err
+			}
+		}
+
+		array := j.arrays[i]
+		if index >= start+array.capacity {
+			start += array.capacity
+			continue
+		}
+
+		buf := make([]byte, itemSize)
+		itemOffset := array.offset + entryArrayItems + (index-start)*itemSize
+		if _, err := j.file.ReadAt(buf, int64(itemOffset)); err != nil {
+			return 0, fmt.Errorf("read entry array item: %s", err)
+		}
+
+		var offset uint64
+		if itemSize == 4 {
+			offset = uint64(binary.LittleEndian.Uint32(buf))
+		} else {
+			offset = binary.LittleEndian.Uint64(buf)
+		}
+		if offset == 0 {
+			return 0, errEntryNotReady
+		}
+		return offset, nil
+	}
+}
+
+// extendArrays follows the chain of entry arrays by one link
+func (j *journalFile) extendArrays() error {
+	var next uint64
+	if len(j.arrays) == 0 {
+		next = j.header.entryArrayOffset
+	} else {
+		buf := make([]byte, 8)
+		last := j.arrays[len(j.arrays)-1]
+		if _, err := j.file.ReadAt(buf, int64(last.offset+objectHeaderSize)); err != nil {
+			return fmt.Errorf("read entry array: %s", err)
+		}
+		next = binary.LittleEndian.Uint64(buf)
+	}
+
+	if next == 0 {
+		return errEntryNotReady
+	}
+
+	size, err := j.readObjectHeader(next, objectEntryArray)
+	if err != nil {
+		return err
+	}
+	if size <= entryArrayItems {
+		return fmt.Errorf("empty entry array at %d", next)
+	}
+
+	j.arrays = append(j.arrays, entryArray{
+		offset:   next,
+		capacity: (size - entryArrayItems) / j.arrayItemSize(),
+	})
+	return nil
+}
+
+func (j *journalFile) arrayItemSize() uint64 {
+	if j.compact() {
+		return 4
+	}
+	return 8
+}
+
+func (j *journalFile) entryItemSize() uint64 {
+	if j.compact() {
+		return 4
+	}
+	return 16
+}
+
+// readEntry reads the entry object at offset without its data
+func (j *journalFile) readEntry(offset uint64) (*journalEntry, error) {
+	_, object, err := j.readObject(offset, objectEntry)
+	if err != nil {
+		return nil, err
+	}
+	if len(object) < entryObjectItems {
+		return nil, fmt.Errorf("truncated entry at %d", offset)
+	}
+
+	e := &journalEntry{
+		seqnumID:  j.header.seqnumID,
+		seqnum:    binary.LittleEndian.Uint64(object[16:24]),
+		realtime:  binary.LittleEndian.Uint64(object[24:32]),
+		monotonic: binary.LittleEndian.Uint64(object[32:40]),
+		xorHash:   binary.LittleEndian.Uint64(object[56:64]),
+	}
+	copy(e.bootID[:], object[40:56])
+
+	itemSize := j.entryItemSize()
+	items := object[entryObjectItems:]
+	e.items = make([]uint64, 0, uint64(len(items))/itemSize)
+	for i := uint64(0); i+itemSize <= uint64(len(items)); i += itemSize {
+		if itemSize == 4 {
+			e.items = append(e.items, uint64(binary.LittleEndian.Uint32(items[i:])))
+		} else {
+			e.items = append(e.items, binary.LittleEndian.Uint64(items[i:]))
+		}
+	}
+	return e, nil
+}
+
+// readFields reads the data objects referenced by an entry
+func (j *journalFile) readFields(e *journalEntry) error {
+	e.fields = make(map[string][]string, len(e.items))
+	for _, item := range e.items {
+		payload, err := j.readData(item)
+		if err != nil {
+			return err
+		}
+
+		sep := bytes.IndexByte(payload, '=')
+		if sep <= 0 {
+			continue
+		}
+		field := string(payload[:sep])
+		e.fields[field] = append(e.fields[field], string(payload[sep+1:]))
+	}
+	return nil
+}
+
+// readData returns the decompressed payload of the data object at offset
+func (j *journalFile) readData(offset uint64) ([]byte, error) {
+	flags, object, err := j.readObject(offset, objectData)
+	if err != nil {
+		return nil, err
+	}
+
+	start := dataObjectPayload
+	if j.compact() {
+		start = dataObjectPayloadNew
+	}
+	if len(object) < start {
+		return nil, fmt.Errorf("truncated data object at %d", offset)
+	}
+
+	payload, err := decompress(flags, object[start:])
+	if err != nil {
+		return nil, fmt.Errorf("data object at %d: %s", offset, err)
+	}
+	return payload, nil
+}
+
+// decompress decodes a data payload according to its object flags
+func decompress(flags uint8, payload []byte) ([]byte, error) {
+	switch {
+	case flags&objectCompressedXZ != 0:
+		r, err := xz.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("decompress xz: %s", err)
+		}
+		out, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("decompress xz: %s", err)
+		}
+		return out, nil
+	case flags&objectCompressedLZ4 != 0:
+		// LZ4 payloads are a raw block prefixed by the decompressed size
+		if len(payload) < 8 {
+			return nil, errors.New("decompress lz4: truncated payload")
+		}
+		size := binary.LittleEndian.Uint64(payload[:8])
+		if size > maxObjectSize {
+			return nil, fmt.Errorf("decompress lz4: invalid size %d", size)
+		}
+		out := make([]byte, size)
+		n, err := lz4.UncompressBlock(payload[8:], out)
+		if err != nil {
+			return nil, fmt.Errorf("decompress lz4: %s", err)
+		}
+		return out[:n], nil
+	case flags&objectCompressedZSTD != 0:
+		out, err := zstdDecoder.DecodeAll(payload, nil)
+		if err != nil {
+			return nil, fmt.Errorf("decompress zstd: %s", err)
+		}
+		return out, nil
+	default:
+		return payload, nil
+	}
+}
+
+// seek positions the file after the entry identified by the cursor. Entries
+// from the same sequence as the cursor are compared by sequence number, and
+// all others by their realtime timestamp.
+func (j *journalFile) seek(c *cursor) error {
+	sameSeqnum := j.header.seqnumID == c.seqnumID
+
+	var searchErr error
+	j.position = uint64(sort.Search(int(j.header.nEntries), func(i int) bool {
+		if searchErr != nil {
+			return true
+		}
+
+		offset, err := j.entryOffset(uint64(i))
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		e, err := j.readEntry(offset)
+		if err != nil {
+			searchErr = err
+			return true
+		}
+
+		if sameSeqnum {
+			return e.seqnum > c.seqnum
+		}
+		return e.realtime > c.realtime
+	}))
+	return searchErr
+}
+
+// Close closes the underlying file
+func (j *journalFile) Close() error {
+	return j.file.Close()
+}
+
+// cursor identifies a journal entry in the same format used by journalctl
+type cursor struct {
+	seqnumID [16]byte
+	seqnum   uint64
+	realtime uint64
+}
+
+func (e *journalEntry) cursor() string {
+	return fmt.Sprintf("s=%x;i=%x;b=%x;m=%x;t=%x;x=%x",
+		e.seqnumID, e.seqnum, e.bootID, e.monotonic, e.realtime, e.xorHash)
+}
+
+func parseCursor(s string) (*cursor, error) {
+	var c cursor
+	var hasSeqnumID, hasSeqnum, hasRealtime bool
+
+	for _, part := range strings.Split(s, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid cursor '%s'", s)
+		}
+
+		var err error
+		switch kv[0] {
+		case "s":
+			var id []byte
+			id, err = parseID(kv[1])
+			copy(c.seqnumID[:], id)
+			hasSeqnumID = true
+		case "i":
+			c.seqnum, err = strconv.ParseUint(kv[1], 16, 64)
+			hasSeqnum = true
+		case "t":
+			c.realtime, err = strconv.ParseUint(kv[1], 16, 64)
+			hasRealtime = true
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor '%s': %s", s, err)
+		}
+	}
+
+	if !hasSeqnumID || !hasSeqnum || !hasRealtime {
+		return nil, fmt.Errorf("invalid cursor '%s'", s)
+	}
+	return &c, nil
+}
+
+func parseID(s string) ([]byte, error) {
+	id, err := hex.DecodeString(s)
+	if err != nil || len(id) != 16 {
+		return nil, fmt.Errorf("invalid id '%s'", s)
+	}
+	return id, nil
+}
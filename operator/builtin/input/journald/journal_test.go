@@ -0,0 +1,244 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build linux
+
+package journald
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4"
+	"github.com/stretchr/testify/require"
+	"github.com/ulikunitz/xz"
+)
+
+// The fixtures were written by systemd-journald 252, which stores large
+// payloads compressed with zstd. The compact fixture uses the compact layout
+// introduced in that version, and the regular fixture the original one.
+var fixtureCursors = map[string][]string{
+	"compact.journal": {
+		"s=9fe2fa84ff314fc6b1fe45fed7ca1e48;i=1;b=88b03dab11d54bdeb5225e1e3683e87d;m=12a83d1b0;t=65e2551180dc9;x=b1b76c39faa1c2e",
+		"s=9fe2fa84ff314fc6b1fe45fed7ca1e48;i=2;b=88b03dab11d54bdeb5225e1e3683e87d;m=12a83d1d0;t=65e2551180de9;x=43ca177739f19260",
+		"s=9fe2fa84ff314fc6b1fe45fed7ca1e48;i=3;b=88b03dab11d54bdeb5225e1e3683e87d;m=12a83d1fa;t=65e2551180e14;x=8c825a0bbab6ccf4",
+		"s=9fe2fa84ff314fc6b1fe45fed7ca1e48;i=4;b=88b03dab11d54bdeb5225e1e3683e87d;m=12a93098c;t=65e25512745a4;x=dc249bd5e6c98713",
+		"s=9fe2fa84ff314fc6b1fe45fed7ca1e48;i=5;b=88b03dab11d54bdeb5225e1e3683e87d;m=12a9314d5;t=65e25512750ee;x=c8c3d5891e7ddc3c",
+		"s=9fe2fa84ff314fc6b1fe45fed7ca1e48;i=6;b=88b03dab11d54bdeb5225e1e3683e87d;m=12a931e24;t=65e2551275a3d;x=e49d3abcbe78eef5",
+		"s=9fe2fa84ff314fc6b1fe45fed7ca1e48;i=7;b=88b03dab11d54bdeb5225e1e3683e87d;m=12a932d35;t=65e255127694f;x=7e7390bffec7820b",
+		"s=9fe2fa84ff314fc6b1fe45fed7ca1e48;i=8;b=88b03dab11d54bdeb5225e1e3683e87d;m=12a933637;t=65e2551277250;x=67258230a24c4512",
+		"s=9fe2fa84ff314fc6b1fe45fed7ca1e48;i=9;b=88b03dab11d54bdeb5225e1e3683e87d;m=12a94b804;t=65e255128f41c;x=b02497ddb430b5ac",
+		"s=9fe2fa84ff314fc6b1fe45fed7ca1e48;i=a;b=88b03dab11d54bdeb5225e1e3683e87d;m=12aa41c4f;t=65e2551385868;x=6aecdd72ff232182",
+	},
+	"regular.journal": {
+		"s=d9781505439a4c1698381bfbd2fddf99;i=1;b=88b03dab11d54bdeb5225e1e3683e87d;m=12ac4efed;t=65e2551592c06;x=3fbfcd1714b11969",
+		"s=d9781505439a4c1698381bfbd2fddf99;i=2;b=88b03dab11d54bdeb5225e1e3683e87d;m=12ac4f00f;t=65e2551592c28;x=25c3765f4a2ca959",
+		"s=d9781505439a4c1698381bfbd2fddf99;i=3;b=88b03dab11d54bdeb5225e1e3683e87d;m=12ac4f03f;t=65e2551592c59;x=ab34f366c1fbf9d2",
+		"s=d9781505439a4c1698381bfbd2fddf99;i=4;b=88b03dab11d54bdeb5225e1e3683e87d;m=12ad4360d;t=65e2551687226;x=702edf5b47df5f0a",
+		"s=d9781505439a4c1698381bfbd2fddf99;i=5;b=88b03dab11d54bdeb5225e1e3683e87d;m=12ad43d99;t=65e25516879b2;x=6578a9a01600e391",
+		"s=d9781505439a4c1698381bfbd2fddf99;i=6;b=88b03dab11d54bdeb5225e1e3683e87d;m=12ad44402;t=65e255168801b;x=756cff9749e35a80",
+		"s=d9781505439a4c1698381bfbd2fddf99;i=7;b=88b03dab11d54bdeb5225e1e3683e87d;m=12ad44e5b;t=65e2551688a75;x=d86ab477d73a3b4a",
+		"s=d9781505439a4c1698381bfbd2fddf99;i=8;b=88b03dab11d54bdeb5225e1e3683e87d;m=12ad45463;t=65e255168907c;x=ba14b4b6bfb15ebb",
+		"s=d9781505439a4c1698381bfbd2fddf99;i=9;b=88b03dab11d54bdeb5225e1e3683e87d;m=12ad573f9;t=65e255169b011;x=2dadef72de415b3b",
+		"s=d9781505439a4c1698381bfbd2fddf99;i=a;b=88b03dab11d54bdeb5225e1e3683e87d;m=12ae4d111;t=65e2551790d2b;x=ce5bc5a8cfe1abb",
+	},
+}
+
+var fixtureMessages = []string{
+	"",
+	"Journal started",
+	"",
+	"demo message 1",
+	"demo message 2",
+	"demo message 3",
+	"GET /index.html 200",
+	"GET /missing 404",
+	largeMessage(),
+	"Journal stopped",
+}
+
+func readAllEntries(t *testing.T, j *journalFile) []*journalEntry {
+	entries := make([]*journalEntry, 0, j.header.nEntries)
+	for i := uint64(0); i < j.header.nEntries; i++ {
+		offset, err := j.entryOffset(i)
+		require.NoError(t, err)
+		e, err := j.readEntry(offset)
+		require.NoError(t, err)
+		require.NoError(t, j.readFields(e))
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestJournalFile(t *testing.T) {
+	for name, cursors := range fixtureCursors {
+		t.Run(name, func(t *testing.T) {
+			j, err := openJournalFile(filepath.Join("testdata", name))
+			require.NoError(t, err)
+			defer j.Close()
+
+			entries := readAllEntries(t, j)
+			require.Len(t, entries, len(cursors))
+			for i, e := range entries {
+				require.Equal(t, cursors[i], e.cursor())
+				require.Equal(t, []string{"88b03dab11d54bdeb5225e1e3683e87d"}, e.fields["_BOOT_ID"])
+
+				// Some messages logged by journald itself differ between
+				// the fixtures
+				if fixtureMessages[i] != "" {
+					require.Equal(t, []string{fixtureMessages[i]}, e.fields["MESSAGE"])
+				}
+			}
+
+			_, err = j.entryOffset(uint64(len(cursors)))
+			require.Equal(t, errEntryNotReady, err)
+		})
+	}
+}
+
+func TestJournalFileSeek(t *testing.T) {
+	j, err := openJournalFile(filepath.Join("testdata", "compact.journal"))
+	require.NoError(t, err)
+	defer j.Close()
+
+	cases := []struct {
+		name     string
+		cursor   string
+		expected uint64
+	}{
+		{
+			"SameSequence",
+			fixtureCursors["compact.journal"][4],
+			5,
+		},
+		{
+			"SameSequenceLast",
+			fixtureCursors["compact.journal"][9],
+			10,
+		},
+		{
+			"OtherSequenceByTime",
+			"s=00000000000000000000000000000001;i=1;b=88b03dab11d54bdeb5225e1e3683e87d;m=0;t=65e25512750ee;x=0",
+			5,
+		},
+		{
+			"OtherSequenceBefore",
+			"s=00000000000000000000000000000001;i=1;b=88b03dab11d54bdeb5225e1e3683e87d;m=0;t=1;x=0",
+			0,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := parseCursor(tc.cursor)
+			require.NoError(t, err)
+			require.NoError(t, j.seek(c))
+			require.Equal(t, tc.expected, j.position)
+		})
+	}
+}
+
+func TestOpenJournalFileInvalid(t *testing.T) {
+	dir, path := copyFixture(t, "compact.journal")
+
+	_, err := openJournalFile(filepath.Join(dir, "missing.journal"))
+	require.Error(t, err)
+
+	_, err = openJournalFile(filepath.Join("testdata", "..", "journal.go"))
+	require.Error(t, err)
+
+	// Set an incompatible flag that is not supported
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, headerIncompatibleCompact|1<<20)
+	writeAt(t, path, 12, buf)
+
+	_, err = openJournalFile(path)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unsupported journal features")
+}
+
+func TestDecompress(t *testing.T) {
+	expected := []byte(largeMessage())
+
+	t.Run("XZ", func(t *testing.T) {
+		var buf bytes.Buffer
+		w, err := xz.NewWriter(&buf)
+		require.NoError(t, err)
+		_, err = w.Write(expected)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		out, err := decompress(objectCompressedXZ, buf.Bytes())
+		require.NoError(t, err)
+		require.Equal(t, expected, out)
+	})
+
+	t.Run("LZ4", func(t *testing.T) {
+		payload := make([]byte, 8+lz4.CompressBlockBound(len(expected)))
+		binary.LittleEndian.PutUint64(payload, uint64(len(expected)))
+		n, err := lz4.CompressBlock(expected, payload[8:], nil)
+		require.NoError(t, err)
+
+		out, err := decompress(objectCompressedLZ4, payload[:8+n])
+		require.NoError(t, err)
+		require.Equal(t, expected, out)
+	})
+
+	t.Run("ZSTD", func(t *testing.T) {
+		enc, err := zstd.NewWriter(nil)
+		require.NoError(t, err)
+		defer enc.Close()
+
+		out, err := decompress(objectCompressedZSTD, enc.EncodeAll(expected, nil))
+		require.NoError(t, err)
+		require.Equal(t, expected, out)
+	})
+
+	t.Run("Uncompressed", func(t *testing.T) {
+		out, err := decompress(0, expected)
+		require.NoError(t, err)
+		require.Equal(t, expected, out)
+	})
+
+	t.Run("Corrupt", func(t *testing.T) {
+		for _, flag := range []uint8{objectCompressedXZ, objectCompressedLZ4, objectCompressedZSTD} {
+			_, err := decompress(flag, []byte("not compressed at all"))
+			require.Error(t, err)
+		}
+	})
+}
+
+func TestParseCursor(t *testing.T) {
+	c, err := parseCursor(fixtureCursors["compact.journal"][9])
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), c.seqnum)
+	require.Equal(t, uint64(0x65e2551385868), c.realtime)
+	require.Equal(t, "9fe2fa84ff314fc6b1fe45fed7ca1e48", fmt.Sprintf("%x", c.seqnumID))
+
+	invalid := []string{
+		"",
+		"s=9fe2fa84ff314fc6b1fe45fed7ca1e48;i=a",
+		"s=9fe2fa84;i=a;t=1",
+		"s=9fe2fa84ff314fc6b1fe45fed7ca1e48;i=xyz;t=1",
+		"s=9fe2fa84ff314fc6b1fe45fed7ca1e48;i;t=1",
+	}
+	for _, s := range invalid {
+		_, err := parseCursor(s)
+		require.Error(t, err, s)
+	}
+}
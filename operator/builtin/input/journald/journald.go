@@ -17,17 +17,13 @@
 package journald
 
 import (
-	"bufio"
 	"context"
-	"errors"
 	"fmt"
-	"io"
-	"os/exec"
+	"path/filepath"
 	"strconv"
 	"sync"
 	"time"
 
-	jsoniter "github.com/json-iterator/go"
 	"github.com/opentelemetry/opentelemetry-log-collection/entry"
 	"github.com/opentelemetry/opentelemetry-log-collection/operator"
 	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
@@ -38,10 +34,39 @@ func init() {
 	operator.Register("journald_input", func() operator.Builder { return NewJournaldInputConfig("") })
 }
 
+// defaultDirectories are the locations journald stores journal files in
+var defaultDirectories = []string{"/run/log/journal", "/var/log/journal"}
+
+const (
+	messageField  = "MESSAGE"
+	priorityField = "PRIORITY"
+	hostnameField = "_HOSTNAME"
+	unitField     = "_SYSTEMD_UNIT"
+
+	hostResourceKey = "host.name"
+	unitLabel       = "systemd.unit"
+)
+
+// prioritySeverities maps syslog priorities to entry severities
+var prioritySeverities = map[int]entry.Severity{
+	0: entry.Emergency,
+	1: entry.Alert,
+	2: entry.Critical,
+	3: entry.Error,
+	4: entry.Warning,
+	5: entry.Notice,
+	6: entry.Info,
+	7: entry.Debug,
+}
+
+// maxReadEntries limits how many entries are read from each file at once
+const maxReadEntries = 1000
+
 func NewJournaldInputConfig(operatorID string) *JournaldInputConfig {
 	return &JournaldInputConfig{
-		InputConfig: helper.NewInputConfig(operatorID, "journald_input"),
-		StartAt:     "end",
+		InputConfig:  helper.NewInputConfig(operatorID, "journald_input"),
+		StartAt:      "end",
+		PollInterval: helper.Duration{Duration: 200 * time.Millisecond},
 	}
 }
 
@@ -49,9 +74,15 @@ func NewJournaldInputConfig(operatorID string) *JournaldInputConfig {
 type JournaldInputConfig struct {
 	helper.InputConfig `yaml:",inline"`
 
-	Directory *string  `json:"directory,omitempty" yaml:"directory,omitempty"`
-	Files     []string `json:"files,omitempty"     yaml:"files,omitempty"`
-	StartAt   string   `json:"start_at,omitempty"  yaml:"start_at,omitempty"`
+	Directory    *string         `json:"directory,omitempty"     yaml:"directory,omitempty"`
+	Files        []string        `json:"files,omitempty"         yaml:"files,omitempty"`
+	StartAt      string          `json:"start_at,omitempty"      yaml:"start_at,omitempty"`
+	Units        []string        `json:"units,omitempty"         yaml:"units,omitempty"`
+	Priority     string          `json:"priority,omitempty"      yaml:"priority,omitempty"`
+	Identifiers  []string        `json:"identifiers,omitempty"   yaml:"identifiers,omitempty"`
+	Matches      []string        `json:"matches,omitempty"       yaml:"matches,omitempty"`
+	MapFields    bool            `json:"map_fields,omitempty"    yaml:"map_fields,omitempty"`
+	PollInterval helper.Duration `json:"poll_interval,omitempty" yaml:"poll_interval,omitempty"`
 }
 
 // Build will build a journald input operator from the supplied configuration
@@ -61,65 +92,108 @@ func (c JournaldInputConfig) Build(buildContext operator.BuildContext) ([]operat
 		return nil, err
 	}
 
-	args := make([]string, 0, 10)
-
-	// Export logs in UTC time
-	args = append(args, "--utc")
-
-	// Export logs as JSON
-	args = append(args, "--output=json")
-
-	// Continue watching logs until cancelled
-	args = append(args, "--follow")
-
+	var startAtEnd bool
 	switch c.StartAt {
 	case "end":
+		startAtEnd = true
 	case "beginning":
-		args = append(args, "--no-tail")
 	default:
 		return nil, fmt.Errorf("invalid value '%s' for parameter 'start_at'", c.StartAt)
 	}
 
+	if c.PollInterval.Raw() <= 0 {
+		return nil, fmt.Errorf("invalid value '%s' for parameter 'poll_interval'", c.PollInterval.Raw())
+	}
+
+	f, err := c.buildFilter()
+	if err != nil {
+		return nil, err
+	}
+
+	var patterns []string
 	switch {
 	case c.Directory != nil:
-		args = append(args, "--directory", *c.Directory)
+		patterns = directoryPatterns(*c.Directory)
 	case len(c.Files) > 0:
-		for _, file := range c.Files {
-			args = append(args, "--file", file)
+		patterns = c.Files
+	default:
+		for _, dir := range defaultDirectories {
+			patterns = append(patterns, directoryPatterns(dir)...)
 		}
 	}
 
 	journaldInput := &JournaldInput{
 		InputOperator: inputOperator,
 		persist:       helper.NewScopedDBPersister(buildContext.Database, c.ID()),
-		newCmd: func(ctx context.Context, cursor []byte) cmd {
-			if cursor != nil {
-				args = append(args, "--after-cursor", string(cursor))
-			}
-			return exec.CommandContext(ctx, "journalctl", args...)
-		},
-		json: jsoniter.ConfigFastest,
+		reader:        newJournalReader(patterns, f, inputOperator.SugaredLogger),
+		startAtEnd:    startAtEnd,
+		mapFields:     c.MapFields,
+		pollInterval:  c.PollInterval.Raw(),
 	}
 	return []operator.Operator{journaldInput}, nil
 }
 
-// JournaldInput is an operator that process logs using journald
+// buildFilter combines the units, priority, identifiers and matches into a
+// filter that entries must satisfy
+func (c JournaldInputConfig) buildFilter() (filter, error) {
+	var f filter
+
+	if len(c.Units) > 0 {
+		d, err := newUnitsDisjunction(c.Units)
+		if err != nil {
+			return nil, err
+		}
+		f = append(f, d)
+	}
+
+	if c.Priority != "" {
+		d, err := newPriorityDisjunction(c.Priority)
+		if err != nil {
+			return nil, err
+		}
+		f = append(f, d)
+	}
+
+	if len(c.Identifiers) > 0 {
+		f = append(f, disjunction{{"SYSLOG_IDENTIFIER": c.Identifiers}})
+	}
+
+	if len(c.Matches) > 0 {
+		d, err := newMatchesDisjunction(c.Matches)
+		if err != nil {
+			return nil, err
+		}
+		f = append(f, d)
+	}
+
+	return f, nil
+}
+
+// directoryPatterns matches the journal files in a directory and in its
+// per-machine subdirectories, including files journald marked as unclean
+func directoryPatterns(dir string) []string {
+	return []string{
+		filepath.Join(dir, "*.journal"),
+		filepath.Join(dir, "*.journal~"),
+		filepath.Join(dir, "*", "*.journal"),
+		filepath.Join(dir, "*", "*.journal~"),
+	}
+}
+
+// JournaldInput is an operator that reads logs from journal files
 type JournaldInput struct {
 	helper.InputOperator
 
-	newCmd func(ctx context.Context, cursor []byte) cmd
+	reader       *journalReader
+	startAtEnd   bool
+	mapFields    bool
+	pollInterval time.Duration
 
 	persist helper.Persister
-	json    jsoniter.API
 	cancel  context.CancelFunc
 	wg      sync.WaitGroup
 }
 
-type cmd interface {
-	StdoutPipe() (io.ReadCloser, error)
-	Start() error
-}
-
 var lastReadCursorKey = "lastReadCursor"
 
 // Start will start generating log entries.
@@ -133,18 +207,14 @@ func (operator *JournaldInput) Start() error {
 	}
 
 	// Start from a cursor if there is a saved offset
-	cursor := operator.persist.Get(lastReadCursorKey)
-
-	// Start journalctl
-	cmd := operator.newCmd(ctx, cursor)
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("failed to get journalctl stdout: %s", err)
-	}
-	err = cmd.Start()
-	if err != nil {
-		return fmt.Errorf("start journalctl: %s", err)
+	var c *cursor
+	if saved := operator.persist.Get(lastReadCursorKey); saved != nil {
+		c, err = parseCursor(string(saved))
+		if err != nil {
+			operator.Warnw("Ignoring saved cursor", zap.Error(err))
+		}
 	}
+	operator.reader.start(c, operator.startAtEnd)
 
 	// Start a goroutine to periodically flush the offsets
 	operator.wg.Add(1)
@@ -165,19 +235,29 @@ func (operator *JournaldInput) Start() error {
 	go func() {
 		defer operator.wg.Done()
 		defer operator.syncOffsets()
-
-		stdoutBuf := bufio.NewReader(stdout)
+		defer operator.reader.close()
 
 		for {
-			line, err := stdoutBuf.ReadBytes('\n')
-			if err != nil {
-				if err != io.EOF {
-					operator.Errorw("Received error reading from journalctl stdout", zap.Error(err))
-				}
+			operator.readEntries(ctx)
+
+			select {
+			case <-ctx.Done():
 				return
+			case <-time.After(operator.pollInterval):
 			}
+		}
+	}()
 
-			entry, cursor, err := operator.parseJournalEntry(line)
+	return nil
+}
+
+// readEntries writes all entries appended to the journal since the last poll
+func (operator *JournaldInput) readEntries(ctx context.Context) {
+	for {
+		journalEntries, more := operator.reader.read(maxReadEntries)
+		for _, je := range journalEntries {
+			cursor := je.cursor()
+			entry, err := operator.parseJournalEntry(je, cursor)
 			if err != nil {
 				operator.Warnw("Failed to parse journal entry", zap.Error(err))
 				continue
@@ -185,52 +265,79 @@ func (operator *JournaldInput) Start() error {
 			operator.persist.Set(lastReadCursorKey, []byte(cursor))
 			operator.Write(ctx, entry)
 		}
-	}()
 
-	return nil
+		if !more || ctx.Err() != nil {
+			return
+		}
+	}
 }
 
-func (operator *JournaldInput) parseJournalEntry(line []byte) (*entry.Entry, string, error) {
-	var record map[string]interface{}
-	err := operator.json.Unmarshal(line, &record)
-	if err != nil {
-		return nil, "", err
+func (operator *JournaldInput) parseJournalEntry(je *journalEntry, cursor string) (*entry.Entry, error) {
+	if operator.mapFields {
+		return operator.mapJournalEntry(je)
 	}
 
-	timestamp, ok := record["__REALTIME_TIMESTAMP"]
-	if !ok {
-		return nil, "", errors.New("journald record missing __REALTIME_TIMESTAMP field")
+	record := make(map[string]interface{}, len(je.fields)+2)
+	for field, values := range je.fields {
+		if len(values) == 1 {
+			record[field] = values[0]
+			continue
+		}
+
+		// Fields that appear more than once are collected in a list
+		list := make([]interface{}, 0, len(values))
+		for _, v := range values {
+			list = append(list, v)
+		}
+		record[field] = list
 	}
+	record["__CURSOR"] = cursor
+	record["__MONOTONIC_TIMESTAMP"] = strconv.FormatUint(je.monotonic, 10)
 
-	timestampString, ok := timestamp.(string)
-	if !ok {
-		return nil, "", errors.New("journald field for timestamp is not a string")
+	entry, err := operator.NewEntry(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create entry: %s", err)
 	}
 
-	timestampInt, err := strconv.ParseInt(timestampString, 10, 64)
+	entry.Timestamp = time.Unix(0, int64(je.realtime)*1000) // in microseconds
+	return entry, nil
+}
+
+// mapJournalEntry creates an entry with the message as its record, and the
+// priority, hostname and unit of the journal entry promoted to the severity,
+// resource and labels
+func (operator *JournaldInput) mapJournalEntry(je *journalEntry) (*entry.Entry, error) {
+	entry, err := operator.NewEntry(firstValue(je.fields, messageField))
 	if err != nil {
-		return nil, "", fmt.Errorf("parse timestamp: %s", err)
+		return nil, fmt.Errorf("failed to create entry: %s", err)
 	}
 
-	delete(record, "__REALTIME_TIMESTAMP")
+	entry.Timestamp = time.Unix(0, int64(je.realtime)*1000) // in microseconds
 
-	cursor, ok := record["__CURSOR"]
-	if !ok {
-		return nil, "", errors.New("journald record missing __CURSOR field")
+	if priority, err := strconv.Atoi(firstValue(je.fields, priorityField)); err == nil {
+		if severity, ok := prioritySeverities[priority]; ok {
+			entry.Severity = severity
+		}
 	}
 
-	cursorString, ok := cursor.(string)
-	if !ok {
-		return nil, "", errors.New("journald field for cursor is not a string")
+	if hostname := firstValue(je.fields, hostnameField); hostname != "" {
+		entry.AddResourceKey(hostResourceKey, hostname)
 	}
 
-	entry, err := operator.NewEntry(record)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to create entry: %s", err)
+	if unit := firstValue(je.fields, unitField); unit != "" {
+		entry.AddLabel(unitLabel, unit)
 	}
 
-	entry.Timestamp = time.Unix(0, timestampInt*1000) // in microseconds
-	return entry, cursorString, nil
+	return entry, nil
+}
+
+// firstValue returns the first value of a field, or an empty string if the
+// field is not present
+func firstValue(fields map[string][]string, field string) string {
+	if values := fields[field]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
 }
 
 func (operator *JournaldInput) syncOffsets() {
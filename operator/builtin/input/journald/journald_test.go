@@ -17,10 +17,12 @@
 package journald
 
 import (
-	"bytes"
-	"context"
-	"io"
+	"encoding/binary"
+	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -31,29 +33,54 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-type fakeJournaldCmd struct{}
+// copyFixture copies a journal file from testdata into a new directory
+func copyFixture(t *testing.T, name string) (string, string) {
+	dir := testutil.NewTempDir(t)
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
 
-func (f *fakeJournaldCmd) Start() error {
-	return nil
+	path := filepath.Join(dir, "system.journal")
+	require.NoError(t, ioutil.WriteFile(path, data, 0600))
+	return dir, path
 }
 
-func (f *fakeJournaldCmd) StdoutPipe() (io.ReadCloser, error) {
-	response := `{ "_BOOT_ID": "c4fa36de06824d21835c05ff80c54468", "_CAP_EFFECTIVE": "0", "_TRANSPORT": "journal", "_UID": "1000", "_EXE": "/usr/lib/systemd/systemd", "_AUDIT_LOGINUID": "1000", "MESSAGE": "run-docker-netns-4f76d707d45f.mount: Succeeded.", "_PID": "13894", "_CMDLINE": "/lib/systemd/systemd --user", "_MACHINE_ID": "d777d00e7caf45fbadedceba3975520d", "_SELINUX_CONTEXT": "unconfined\n", "CODE_FUNC": "unit_log_success", "SYSLOG_IDENTIFIER": "systemd", "_HOSTNAME": "myhostname", "MESSAGE_ID": "7ad2d189f7e94e70a38c781354912448", "_SYSTEMD_CGROUP": "/user.slice/user-1000.slice/user@1000.service/init.scope", "_SOURCE_REALTIME_TIMESTAMP": "1587047866229317", "USER_UNIT": "run-docker-netns-4f76d707d45f.mount", "SYSLOG_FACILITY": "3", "_SYSTEMD_SLICE": "user-1000.slice", "_AUDIT_SESSION": "286", "CODE_FILE": "../src/core/unit.c", "_SYSTEMD_USER_UNIT": "init.scope", "_COMM": "systemd", "USER_INVOCATION_ID": "88f7ca6bbf244dc8828fa901f9fe9be1", "CODE_LINE": "5487", "_SYSTEMD_INVOCATION_ID": "83f7fc7799064520b26eb6de1630429c", "PRIORITY": "6", "_GID": "1000", "__REALTIME_TIMESTAMP": "1587047866229555", "_SYSTEMD_UNIT": "user@1000.service", "_SYSTEMD_USER_SLICE": "-.slice", "__CURSOR": "s=b1e713b587ae4001a9ca482c4b12c005;i=1eed30;b=c4fa36de06824d21835c05ff80c54468;m=9f9d630205;t=5a369604ee333;x=16c2d4fd4fdb7c36", "__MONOTONIC_TIMESTAMP": "685540311557", "_SYSTEMD_OWNER_UID": "1000" }
-`
-	reader := bytes.NewReader([]byte(response))
-	return ioutil.NopCloser(reader), nil
+// setEntryCount rewrites the entry count in the header of a journal file,
+// which hides or reveals entries as if they were being appended
+func setEntryCount(t *testing.T, path string, n uint64) {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, n)
+	writeAt(t, path, 152, buf)
 }
 
-func TestInputJournald(t *testing.T) {
-	cfg := NewJournaldInputConfig("my_journald_input")
+func writeAt(t *testing.T, path string, offset int64, buf []byte) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	require.NoError(t, err)
+	defer f.Close()
+
+	_, err = f.WriteAt(buf, offset)
+	require.NoError(t, err)
+}
+
+// largeMessage is the message of the fixture entry that is large enough to be
+// stored compressed
+func largeMessage() string {
+	parts := make([]string, 0, 200)
+	for i := 0; i < 200; i++ {
+		parts = append(parts, fmt.Sprintf("payload-%d", i))
+	}
+	return strings.Join(parts, " ")
+}
+
+func startTestInput(t *testing.T, cfg *JournaldInputConfig, buildContext operator.BuildContext) (operator.Operator, chan *entry.Entry) {
 	cfg.OutputIDs = []string{"output"}
+	cfg.PollInterval.Duration = 10 * time.Millisecond
 
-	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	ops, err := cfg.Build(buildContext)
 	require.NoError(t, err)
 	op := ops[0]
 
 	mockOutput := testutil.NewMockOperator("$.output")
-	received := make(chan *entry.Entry)
+	received := make(chan *entry.Entry, 20)
 	mockOutput.On("Process", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
 		received <- args.Get(1).(*entry.Entry)
 	}).Return(nil)
@@ -61,55 +88,285 @@ func TestInputJournald(t *testing.T) {
 	err = op.SetOutputs([]operator.Operator{mockOutput})
 	require.NoError(t, err)
 
-	op.(*JournaldInput).newCmd = func(ctx context.Context, cursor []byte) cmd {
-		return &fakeJournaldCmd{}
+	require.NoError(t, op.Start())
+	return op, received
+}
+
+func expectMessages(t *testing.T, received chan *entry.Entry, messages ...string) {
+	for _, message := range messages {
+		select {
+		case e := <-received:
+			require.Equal(t, message, e.Record.(map[string]interface{})["MESSAGE"])
+		case <-time.After(time.Second):
+			require.FailNow(t, "Timed out waiting for entry", message)
+		}
 	}
 
-	err = op.Start()
-	require.NoError(t, err)
+	select {
+	case e := <-received:
+		require.FailNow(t, "Received unexpected entry", e.Record)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestInputJournald(t *testing.T) {
+	dir, _ := copyFixture(t, "compact.journal")
+
+	cfg := NewJournaldInputConfig("my_journald_input")
+	cfg.Directory = &dir
+	cfg.StartAt = "beginning"
+	cfg.Matches = []string{"_SYSTEMD_UNIT=web.service", "REQUEST_ID=1"}
+
+	op, received := startTestInput(t, cfg, testutil.NewBuildContext(t))
 	defer op.Stop()
 
 	expected := map[string]interface{}{
-		"_BOOT_ID":                   "c4fa36de06824d21835c05ff80c54468",
-		"_CAP_EFFECTIVE":             "0",
-		"_TRANSPORT":                 "journal",
-		"_UID":                       "1000",
-		"_EXE":                       "/usr/lib/systemd/systemd",
-		"_AUDIT_LOGINUID":            "1000",
-		"MESSAGE":                    "run-docker-netns-4f76d707d45f.mount: Succeeded.",
-		"_PID":                       "13894",
-		"_CMDLINE":                   "/lib/systemd/systemd --user",
-		"_MACHINE_ID":                "d777d00e7caf45fbadedceba3975520d",
-		"_SELINUX_CONTEXT":           "unconfined\n",
-		"CODE_FUNC":                  "unit_log_success",
-		"SYSLOG_IDENTIFIER":          "systemd",
-		"_HOSTNAME":                  "myhostname",
-		"MESSAGE_ID":                 "7ad2d189f7e94e70a38c781354912448",
-		"_SYSTEMD_CGROUP":            "/user.slice/user-1000.slice/user@1000.service/init.scope",
-		"_SOURCE_REALTIME_TIMESTAMP": "1587047866229317",
-		"USER_UNIT":                  "run-docker-netns-4f76d707d45f.mount",
-		"SYSLOG_FACILITY":            "3",
-		"_SYSTEMD_SLICE":             "user-1000.slice",
-		"_AUDIT_SESSION":             "286",
-		"CODE_FILE":                  "../src/core/unit.c",
-		"_SYSTEMD_USER_UNIT":         "init.scope",
-		"_COMM":                      "systemd",
-		"USER_INVOCATION_ID":         "88f7ca6bbf244dc8828fa901f9fe9be1",
-		"CODE_LINE":                  "5487",
-		"_SYSTEMD_INVOCATION_ID":     "83f7fc7799064520b26eb6de1630429c",
+		"MESSAGE":                    "GET /index.html 200",
+		"_RUNTIME_SCOPE":             "system",
+		"_CAP_EFFECTIVE":             "1fffeffffff",
 		"PRIORITY":                   "6",
-		"_GID":                       "1000",
-		"_SYSTEMD_UNIT":              "user@1000.service",
-		"_SYSTEMD_USER_SLICE":        "-.slice",
-		"__CURSOR":                   "s=b1e713b587ae4001a9ca482c4b12c005;i=1eed30;b=c4fa36de06824d21835c05ff80c54468;m=9f9d630205;t=5a369604ee333;x=16c2d4fd4fdb7c36",
-		"__MONOTONIC_TIMESTAMP":      "685540311557",
-		"_SYSTEMD_OWNER_UID":         "1000",
+		"_CMDLINE":                   "logger --journald",
+		"_EXE":                       "/usr/bin/logger",
+		"SYSLOG_IDENTIFIER":          "web",
+		"_PID":                       "23387",
+		"_MACHINE_ID":                "fed6b2924c424cf1b9a322f606b4de6d",
+		"_BOOT_ID":                   "88b03dab11d54bdeb5225e1e3683e87d",
+		"_GID":                       "0",
+		"_SYSTEMD_SLICE":             "system.slice",
+		"_UID":                       "0",
+		"_SELINUX_CONTEXT":           "kernel",
+		"_HOSTNAME":                  "vm",
+		"_SYSTEMD_CGROUP":            "/system.slice/web.service",
+		"_SYSTEMD_UNIT":              "web.service",
+		"REQUEST_ID":                 "1",
+		"_TRANSPORT":                 "journal",
+		"_COMM":                      "logger",
+		"_SOURCE_REALTIME_TIMESTAMP": "1792364228602173",
+		"__CURSOR":                   "s=9fe2fa84ff314fc6b1fe45fed7ca1e48;i=7;b=88b03dab11d54bdeb5225e1e3683e87d;m=12a932d35;t=65e255127694f;x=7e7390bffec7820b",
+		"__MONOTONIC_TIMESTAMP":      "5009255733",
 	}
 
 	select {
 	case e := <-received:
 		require.Equal(t, expected, e.Record)
+		require.Equal(t, time.Unix(0, 1792364228602191000), e.Timestamp)
 	case <-time.After(time.Second):
 		require.FailNow(t, "Timed out waiting for entry to be read")
 	}
+
+	expectMessages(t, received)
+}
+
+func TestInputJournaldFilters(t *testing.T) {
+	cases := []struct {
+		name     string
+		modify   func(*JournaldInputConfig)
+		expected []string
+	}{
+		{
+			"UnitsAndPriority",
+			func(cfg *JournaldInputConfig) {
+				cfg.Units = []string{"demo"}
+				cfg.Priority = "warning"
+			},
+			[]string{"demo message 2", "demo message 3"},
+		},
+		{
+			"PriorityRange",
+			func(cfg *JournaldInputConfig) {
+				cfg.Priority = "notice..warning"
+			},
+			[]string{"demo message 2", "GET /missing 404"},
+		},
+		{
+			"IdentifiersAndMatchGroups",
+			func(cfg *JournaldInputConfig) {
+				cfg.Identifiers = []string{"web"}
+				cfg.Matches = []string{"REQUEST_ID=1", "+", "REQUEST_ID=2", "PRIORITY=5"}
+			},
+			[]string{"GET /index.html 200", "GET /missing 404"},
+		},
+		{
+			"UnitsOutsideMatches",
+			func(cfg *JournaldInputConfig) {
+				cfg.Units = []string{"web.service"}
+				cfg.Matches = []string{"SYSLOG_IDENTIFIER=demo"}
+			},
+			nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir, _ := copyFixture(t, "compact.journal")
+
+			cfg := NewJournaldInputConfig("my_journald_input")
+			cfg.Directory = &dir
+			cfg.StartAt = "beginning"
+			tc.modify(cfg)
+
+			op, received := startTestInput(t, cfg, testutil.NewBuildContext(t))
+			defer op.Stop()
+
+			expectMessages(t, received, tc.expected...)
+		})
+	}
+}
+
+func TestInputJournaldMapFields(t *testing.T) {
+	dir, _ := copyFixture(t, "compact.journal")
+
+	cfg := NewJournaldInputConfig("my_journald_input")
+	cfg.Directory = &dir
+	cfg.StartAt = "beginning"
+	cfg.Units = []string{"web.service"}
+	cfg.MapFields = true
+
+	op, received := startTestInput(t, cfg, testutil.NewBuildContext(t))
+	defer op.Stop()
+
+	expected := []struct {
+		record    string
+		severity  entry.Severity
+		timestamp int64
+	}{
+		{"GET /index.html 200", entry.Info, 1792364228602191},
+		{"GET /missing 404", entry.Notice, 1792364228604496},
+		{largeMessage(), entry.Info, 1792364228703260},
+	}
+
+	for _, exp := range expected {
+		select {
+		case e := <-received:
+			require.Equal(t, exp.record, e.Record)
+			require.Equal(t, exp.severity, e.Severity)
+			require.Equal(t, time.Unix(0, exp.timestamp*1000), e.Timestamp)
+			require.Equal(t, map[string]string{"host.name": "vm"}, e.Resource)
+			require.Equal(t, map[string]string{"systemd.unit": "web.service"}, e.Labels)
+		case <-time.After(time.Second):
+			require.FailNow(t, "Timed out waiting for entry to be read")
+		}
+	}
+}
+
+func TestInputJournaldFollow(t *testing.T) {
+	dir, path := copyFixture(t, "compact.journal")
+	setEntryCount(t, path, 4)
+
+	cfg := NewJournaldInputConfig("my_journald_input")
+	cfg.Directory = &dir
+	cfg.StartAt = "beginning"
+	cfg.Matches = []string{"SYSLOG_IDENTIFIER=demo", "SYSLOG_IDENTIFIER=web"}
+
+	op, received := startTestInput(t, cfg, testutil.NewBuildContext(t))
+	defer op.Stop()
+
+	expectMessages(t, received, "demo message 1")
+
+	setEntryCount(t, path, 8)
+	expectMessages(t, received, "demo message 2", "demo message 3", "GET /index.html 200", "GET /missing 404")
+}
+
+func TestInputJournaldHeaderReadError(t *testing.T) {
+	dir, path := copyFixture(t, "compact.journal")
+	setEntryCount(t, path, 4)
+
+	cfg := NewJournaldInputConfig("my_journald_input")
+	cfg.Directory = &dir
+	cfg.StartAt = "beginning"
+	cfg.Matches = []string{"SYSLOG_IDENTIFIER=demo"}
+
+	op, received := startTestInput(t, cfg, testutil.NewBuildContext(t))
+	defer op.Stop()
+
+	expectMessages(t, received, "demo message 1")
+
+	// Entries already read are not read again once the header is valid again
+	writeAt(t, path, 0, []byte("XXXXXXXX"))
+	expectMessages(t, received)
+
+	setEntryCount(t, path, 8)
+	writeAt(t, path, 0, []byte(headerSignature))
+	expectMessages(t, received, "demo message 2", "demo message 3")
+}
+
+func TestInputJournaldStartAtEnd(t *testing.T) {
+	_, path := copyFixture(t, "regular.journal")
+	setEntryCount(t, path, 7)
+
+	cfg := NewJournaldInputConfig("my_journald_input")
+	cfg.Files = []string{path}
+	cfg.Matches = []string{"_SYSTEMD_UNIT=web.service"}
+
+	op, received := startTestInput(t, cfg, testutil.NewBuildContext(t))
+	defer op.Stop()
+
+	expectMessages(t, received)
+
+	setEntryCount(t, path, 10)
+	expectMessages(t, received, "GET /missing 404", largeMessage())
+}
+
+func TestInputJournaldResume(t *testing.T) {
+	dir, path := copyFixture(t, "compact.journal")
+	setEntryCount(t, path, 5)
+
+	buildContext := testutil.NewBuildContext(t)
+	newConfig := func() *JournaldInputConfig {
+		cfg := NewJournaldInputConfig("my_journald_input")
+		cfg.Directory = &dir
+		cfg.StartAt = "beginning"
+		cfg.Matches = []string{"SYSLOG_IDENTIFIER=demo"}
+		return cfg
+	}
+
+	op, received := startTestInput(t, newConfig(), buildContext)
+	expectMessages(t, received, "demo message 1", "demo message 2")
+	require.NoError(t, op.Stop())
+
+	setEntryCount(t, path, 10)
+	op, received = startTestInput(t, newConfig(), buildContext)
+	defer op.Stop()
+	expectMessages(t, received, "demo message 3")
+}
+
+func TestBuildJournaldInvalid(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(*JournaldInputConfig)
+	}{
+		{
+			"StartAt",
+			func(cfg *JournaldInputConfig) { cfg.StartAt = "middle" },
+		},
+		{
+			"PollInterval",
+			func(cfg *JournaldInputConfig) { cfg.PollInterval.Duration = 0 },
+		},
+		{
+			"Match",
+			func(cfg *JournaldInputConfig) { cfg.Matches = []string{"_SYSTEMD_UNIT"} },
+		},
+		{
+			"MatchEmptyGroup",
+			func(cfg *JournaldInputConfig) { cfg.Matches = []string{"PRIORITY=3", "+"} },
+		},
+		{
+			"Unit",
+			func(cfg *JournaldInputConfig) { cfg.Units = []string{""} },
+		},
+		{
+			"Priority",
+			func(cfg *JournaldInputConfig) { cfg.Priority = "loud" },
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := NewJournaldInputConfig("my_journald_input")
+			tc.modify(cfg)
+			_, err := cfg.Build(testutil.NewBuildContext(t))
+			require.Error(t, err)
+		})
+	}
 }
@@ -0,0 +1,219 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build linux
+
+package journald
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// matcher selects journal entries by the values of their fields. As with
+// journalctl, values given for the same field are alternatives, while
+// different fields must all match.
+type matcher map[string][]string
+
+// disjunction is satisfied by entries that satisfy any of its matchers
+type disjunction []matcher
+
+// filter is satisfied by entries that satisfy all of its disjunctions
+type filter []disjunction
+
+// priorityNames are the names of syslog priorities, indexed by their value
+var priorityNames = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// unitSuffixes are the types of systemd units
+var unitSuffixes = []string{
+	".service", ".socket", ".device", ".mount", ".automount", ".swap",
+	".target", ".path", ".timer", ".slice", ".scope",
+}
+
+// newMatcher parses a list of FIELD=value terms
+func newMatcher(terms []string) (matcher, error) {
+	m := make(matcher, len(terms))
+	for _, term := range terms {
+		sep := strings.IndexByte(term, '=')
+		if sep < 0 {
+			return nil, fmt.Errorf("invalid match '%s': expected FIELD=value", term)
+		}
+
+		field := term[:sep]
+		if !validFieldName(field) {
+			return nil, fmt.Errorf("invalid match '%s': invalid field name '%s'", term, field)
+		}
+		m[field] = append(m[field], term[sep+1:])
+	}
+	return m, nil
+}
+
+// newMatchesDisjunction parses a list of FIELD=value terms, in which a '+'
+// term separates groups of alternatives, as with journalctl
+func newMatchesDisjunction(terms []string) (disjunction, error) {
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	groups := [][]string{{}}
+	for _, term := range terms {
+		if term == "+" {
+			groups = append(groups, []string{})
+			continue
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], term)
+	}
+
+	d := make(disjunction, 0, len(groups))
+	for _, group := range groups {
+		if len(group) == 0 {
+			return nil, fmt.Errorf("invalid matches: '+' must separate groups of matches")
+		}
+		m, err := newMatcher(group)
+		if err != nil {
+			return nil, err
+		}
+		d = append(d, m)
+	}
+	return d, nil
+}
+
+// newUnitsDisjunction selects the entries logged by the given units, and
+// those logged about them by systemd. Units without a type are assumed to be
+// services.
+func newUnitsDisjunction(units []string) (disjunction, error) {
+	names := make([]string, 0, len(units))
+	for _, unit := range units {
+		if unit == "" {
+			return nil, fmt.Errorf("invalid unit '%s'", unit)
+		}
+		if !hasUnitSuffix(unit) {
+			unit += ".service"
+		}
+		names = append(names, unit)
+	}
+
+	return disjunction{
+		{"_SYSTEMD_UNIT": names},
+		{"_PID": {"1"}, "UNIT": names},
+	}, nil
+}
+
+func hasUnitSuffix(unit string) bool {
+	for _, suffix := range unitSuffixes {
+		if strings.HasSuffix(unit, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// newPriorityDisjunction parses a priority, which selects it and all more
+// important priorities, or an inclusive FROM..TO range of priorities
+func newPriorityDisjunction(s string) (disjunction, error) {
+	from, to := "0", s
+	if parts := strings.SplitN(s, "..", 2); len(parts) == 2 {
+		from, to = parts[0], parts[1]
+	}
+
+	min, err := parsePriority(from)
+	if err != nil {
+		return nil, err
+	}
+	max, err := parsePriority(to)
+	if err != nil {
+		return nil, err
+	}
+	if min > max {
+		min, max = max, min
+	}
+
+	values := make([]string, 0, max-min+1)
+	for p := min; p <= max; p++ {
+		values = append(values, strconv.Itoa(p))
+	}
+	return disjunction{{"PRIORITY": values}}, nil
+}
+
+// parsePriority parses a syslog priority given as a name or a number
+func parsePriority(s string) (int, error) {
+	for i, name := range priorityNames {
+		if s == name {
+			return i, nil
+		}
+	}
+
+	p, err := strconv.Atoi(s)
+	if err != nil || p < 0 || p >= len(priorityNames) {
+		return 0, fmt.Errorf("invalid priority '%s'", s)
+	}
+	return p, nil
+}
+
+// validFieldName reports whether name is a valid journal field name, which
+// consists of uppercase letters, digits and underscores, and does not start
+// with a digit
+func validFieldName(name string) bool {
+	if name == "" || len(name) > 64 {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r >= 'A' && r <= 'Z', r == '_':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (m matcher) matches(fields map[string][]string) bool {
+	for field, allowed := range m {
+		if !containsAny(fields[field], allowed) {
+			return false
+		}
+	}
+	return true
+}
+
+func (d disjunction) matches(fields map[string][]string) bool {
+	for _, m := range d {
+		if m.matches(fields) {
+			return true
+		}
+	}
+	return len(d) == 0
+}
+
+func (f filter) matches(fields map[string][]string) bool {
+	for _, d := range f {
+		if !d.matches(fields) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsAny(values, allowed []string) bool {
+	for _, v := range values {
+		for _, a := range allowed {
+			if v == a {
+				return true
+			}
+		}
+	}
+	return false
+}
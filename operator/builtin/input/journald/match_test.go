@@ -0,0 +1,142 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build linux
+
+package journald
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatcher(t *testing.T) {
+	fields := map[string][]string{
+		"_SYSTEMD_UNIT":     {"web.service"},
+		"PRIORITY":          {"6"},
+		"SYSLOG_IDENTIFIER": {"web", "nginx"},
+	}
+
+	cases := []struct {
+		name     string
+		terms    []string
+		expected bool
+	}{
+		{"Empty", nil, true},
+		{"Single", []string{"_SYSTEMD_UNIT=web.service"}, true},
+		{"SingleMismatch", []string{"_SYSTEMD_UNIT=demo.service"}, false},
+		{"SameFieldAlternatives", []string{"_SYSTEMD_UNIT=demo.service", "_SYSTEMD_UNIT=web.service"}, true},
+		{"DifferentFields", []string{"_SYSTEMD_UNIT=web.service", "PRIORITY=6"}, true},
+		{"DifferentFieldsMismatch", []string{"_SYSTEMD_UNIT=web.service", "PRIORITY=3"}, false},
+		{"MissingField", []string{"MESSAGE_ID=abc"}, false},
+		{"RepeatedField", []string{"SYSLOG_IDENTIFIER=nginx"}, true},
+		{"EmptyValue", []string{"PRIORITY="}, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := newMatcher(tc.terms)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, m.matches(fields))
+		})
+	}
+}
+
+func TestNewMatcherInvalid(t *testing.T) {
+	invalid := []string{
+		"_SYSTEMD_UNIT",
+		"=value",
+		"systemd_unit=web.service",
+		"1FIELD=value",
+		"FIELD-NAME=value",
+	}
+
+	for _, term := range invalid {
+		t.Run(term, func(t *testing.T) {
+			_, err := newMatcher([]string{term})
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestMatchesDisjunction(t *testing.T) {
+	d, err := newMatchesDisjunction([]string{"_SYSTEMD_UNIT=web.service", "PRIORITY=3", "+", "_TRANSPORT=kernel"})
+	require.NoError(t, err)
+	require.Equal(t, disjunction{
+		{"_SYSTEMD_UNIT": {"web.service"}, "PRIORITY": {"3"}},
+		{"_TRANSPORT": {"kernel"}},
+	}, d)
+
+	require.True(t, d.matches(map[string][]string{"_SYSTEMD_UNIT": {"web.service"}, "PRIORITY": {"3"}}))
+	require.False(t, d.matches(map[string][]string{"_SYSTEMD_UNIT": {"web.service"}, "PRIORITY": {"6"}}))
+	require.True(t, d.matches(map[string][]string{"_TRANSPORT": {"kernel"}, "PRIORITY": {"6"}}))
+
+	for _, terms := range [][]string{{"+"}, {"+", "PRIORITY=3"}, {"PRIORITY=3", "+", "+", "PRIORITY=4"}, {"PRIORITY=3", "+"}} {
+		_, err := newMatchesDisjunction(terms)
+		require.Error(t, err)
+	}
+}
+
+func TestUnitsDisjunction(t *testing.T) {
+	d, err := newUnitsDisjunction([]string{"ssh", "docker.socket"})
+	require.NoError(t, err)
+
+	require.True(t, d.matches(map[string][]string{"_SYSTEMD_UNIT": {"ssh.service"}}))
+	require.True(t, d.matches(map[string][]string{"_SYSTEMD_UNIT": {"docker.socket"}}))
+	require.True(t, d.matches(map[string][]string{"_PID": {"1"}, "UNIT": {"ssh.service"}}))
+	require.False(t, d.matches(map[string][]string{"_PID": {"1234"}, "UNIT": {"ssh.service"}}))
+	require.False(t, d.matches(map[string][]string{"_SYSTEMD_UNIT": {"ssh"}}))
+}
+
+func TestPriorityDisjunction(t *testing.T) {
+	cases := []struct {
+		priority string
+		expected []string
+	}{
+		{"err", []string{"0", "1", "2", "3"}},
+		{"0", []string{"0"}},
+		{"7", []string{"0", "1", "2", "3", "4", "5", "6", "7"}},
+		{"warning..info", []string{"4", "5", "6"}},
+		{"info..warning", []string{"4", "5", "6"}},
+		{"2..crit", []string{"2"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.priority, func(t *testing.T) {
+			d, err := newPriorityDisjunction(tc.priority)
+			require.NoError(t, err)
+			require.Equal(t, disjunction{{"PRIORITY": tc.expected}}, d)
+		})
+	}
+
+	for _, priority := range []string{"", "8", "-1", "error", "..", "err..", "1..2..3"} {
+		_, err := newPriorityDisjunction(priority)
+		require.Error(t, err, priority)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	fields := map[string][]string{"_SYSTEMD_UNIT": {"web.service"}, "PRIORITY": {"3"}}
+
+	require.True(t, filter(nil).matches(fields))
+	require.True(t, filter{
+		{{"_SYSTEMD_UNIT": {"web.service"}}},
+		{{"PRIORITY": {"2", "3"}}},
+	}.matches(fields))
+	require.False(t, filter{
+		{{"_SYSTEMD_UNIT": {"web.service"}}},
+		{{"PRIORITY": {"6"}}},
+	}.matches(fields))
+}
@@ -0,0 +1,201 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build linux
+
+package journald
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+// journalReader follows the journal files matching a set of glob patterns,
+// and returns their new entries ordered by time
+type journalReader struct {
+	patterns []string
+	filter   filter
+	files    []*journalFile
+
+	// failed holds paths that could not be opened or whose header could not
+	// be read, so that the failure is only reported once
+	failed map[string]struct{}
+
+	*zap.SugaredLogger
+}
+
+func newJournalReader(patterns []string, f filter, logger *zap.SugaredLogger) *journalReader {
+	return &journalReader{
+		patterns:      patterns,
+		filter:        f,
+		failed:        make(map[string]struct{}),
+		SugaredLogger: logger,
+	}
+}
+
+// start opens the journal files and positions them after the given cursor,
+// or else at the beginning or end of each file
+func (r *journalReader) start(c *cursor, atEnd bool) {
+	for _, j := range r.scan() {
+		switch {
+		case c != nil:
+			if err := j.seek(c); err != nil {
+				r.Warnw("Failed to seek journal file to cursor, starting at end", "path", j.path, zap.Error(err))
+				j.position = j.header.nEntries
+			}
+		case atEnd:
+			j.position = j.header.nEntries
+		}
+	}
+}
+
+// scan opens journal files that have appeared since the last scan, and closes
+// those that are gone. Files that have been renamed, as happens when journald
+// archives them, are recognized and keep their position.
+func (r *journalReader) scan() []*journalFile {
+	var paths []string
+	for _, pattern := range r.patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			r.Errorw("Failed to glob journal files", "pattern", pattern, zap.Error(err))
+			continue
+		}
+		paths = append(paths, matches...)
+	}
+
+	kept := make([]*journalFile, 0, len(paths))
+	added := make([]*journalFile, 0)
+	seen := make(map[string]struct{}, len(paths))
+	for _, path := range paths {
+		if _, ok := seen[path]; ok {
+			continue
+		}
+		seen[path] = struct{}{}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+
+		if j := r.takeFile(info); j != nil {
+			j.path = path
+			kept = append(kept, j)
+			continue
+		}
+
+		j, err := openJournalFile(path)
+		if err != nil {
+			if _, ok := r.failed[path]; !ok {
+				r.Warnw("Failed to open journal file", "path", path, zap.Error(err))
+				r.failed[path] = struct{}{}
+			}
+			continue
+		}
+		delete(r.failed, path)
+		kept = append(kept, j)
+		added = append(added, j)
+	}
+
+	for _, j := range r.files {
+		j.Close()
+	}
+	r.files = kept
+	return added
+}
+
+// takeFile removes and returns the tracked file that info describes
+func (r *journalReader) takeFile(info os.FileInfo) *journalFile {
+	for i, j := range r.files {
+		if os.SameFile(j.info, info) {
+			r.files = append(r.files[:i], r.files[i+1:]...)
+			return j
+		}
+	}
+	return nil
+}
+
+// read returns up to max new entries from each file that satisfy the
+// filter, ordered by time, and whether more entries are available
+func (r *journalReader) read(max int) ([]*journalEntry, bool) {
+	r.scan()
+
+	var entries []*journalEntry
+	more := false
+	for _, j := range r.files {
+		// The file keeps its position, so that reading resumes where it left
+		// off once the header can be read again
+		if err := j.readHeader(); err != nil {
+			if _, ok := r.failed[j.path]; !ok {
+				r.Warnw("Failed to read journal header", "path", j.path, zap.Error(err))
+				r.failed[j.path] = struct{}{}
+			}
+			continue
+		}
+		delete(r.failed, j.path)
+
+		n := 0
+		for ; n < max && j.position < j.header.nEntries; n++ {
+			e, err := r.readEntry(j)
+			if err == errEntryNotReady {
+				break
+			}
+			j.position++
+			if err != nil {
+				r.Warnw("Failed to read journal entry", "path", j.path, zap.Error(err))
+				continue
+			}
+			if r.filter.matches(e.fields) {
+				entries = append(entries, e)
+			}
+		}
+
+		if n == max && j.position < j.header.nEntries {
+			more = true
+		}
+	}
+
+	sort.SliceStable(entries, func(a, b int) bool {
+		if entries[a].realtime != entries[b].realtime {
+			return entries[a].realtime < entries[b].realtime
+		}
+		return entries[a].seqnum < entries[b].seqnum
+	})
+	return entries, more
+}
+
+func (r *journalReader) readEntry(j *journalFile) (*journalEntry, error) {
+	offset, err := j.entryOffset(j.position)
+	if err != nil {
+		return nil, err
+	}
+	e, err := j.readEntry(offset)
+	if err != nil {
+		return nil, err
+	}
+	if err := j.readFields(e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// close closes all open journal files
+func (r *journalReader) close() {
+	for _, j := range r.files {
+		j.Close()
+	}
+	r.files = nil
+}
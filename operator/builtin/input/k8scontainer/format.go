@@ -0,0 +1,149 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8scontainer
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	formatAuto   = "auto"
+	formatCRI    = "cri"
+	formatDocker = "docker"
+)
+
+// containerLine is a line written by the container runtime
+type containerLine struct {
+	timestamp time.Time
+	stream    string
+	partial   bool
+	message   string
+}
+
+// parseLine parses a line in the given format. In auto mode, lines that look
+// like JSON objects are parsed as Docker json-file lines, and others as CRI.
+func parseLine(format, line string) (*containerLine, error) {
+	switch format {
+	case formatCRI:
+		return parseCRILine(line)
+	case formatDocker:
+		return parseDockerLine(line)
+	default:
+		if strings.HasPrefix(line, "{") {
+			return parseDockerLine(line)
+		}
+		return parseCRILine(line)
+	}
+}
+
+// parseCRILine parses a line in the CRI format, which is
+// `<timestamp> <stream> <tag> <message>`. The tag is a list of flags
+// separated by colons, in which `P` marks a partial line and `F` a full one.
+func parseCRILine(line string) (*containerLine, error) {
+	parts := strings.SplitN(line, " ", 4)
+	if len(parts) < 3 {
+		return nil, fmt.Errorf("invalid cri line")
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("parse cri timestamp: %s", err)
+	}
+
+	l := &containerLine{
+		timestamp: timestamp,
+		stream:    parts[1],
+	}
+	if len(parts) == 4 {
+		l.message = parts[3]
+	}
+
+	for _, flag := range strings.Split(parts[2], ":") {
+		switch flag {
+		case "P":
+			l.partial = true
+		case "F":
+		default:
+			return nil, fmt.Errorf("invalid cri tag '%s'", parts[2])
+		}
+	}
+
+	return l, nil
+}
+
+type dockerLine struct {
+	Log    string `json:"log"`
+	Stream string `json:"stream"`
+	Time   string `json:"time"`
+}
+
+// parseDockerLine parses a line written by the Docker json-file logging
+// driver. Docker splits long lines, and only the last part ends with a
+// newline.
+func parseDockerLine(line string) (*containerLine, error) {
+	var d dockerLine
+	if err := json.Unmarshal([]byte(line), &d); err != nil {
+		return nil, fmt.Errorf("parse docker line: %s", err)
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, d.Time)
+	if err != nil {
+		return nil, fmt.Errorf("parse docker timestamp: %s", err)
+	}
+
+	l := &containerLine{
+		timestamp: timestamp,
+		stream:    d.Stream,
+		message:   d.Log,
+		partial:   true,
+	}
+	if strings.HasSuffix(l.message, "\n") {
+		l.message = strings.TrimSuffix(l.message, "\n")
+		l.partial = false
+	}
+	return l, nil
+}
+
+// podPathPattern matches the paths of container logs written by the kubelet,
+// which are /var/log/pods/<namespace>_<pod>_<uid>/<container>/<restart count>.log
+var podPathPattern = regexp.MustCompile(`(?:^|/)([^_/]+)_([^_/]+)_([^_/]+)/([^/]+)/(\d+)\.log$`)
+
+// podMetadata is the metadata of a container log file
+type podMetadata struct {
+	namespace    string
+	podName      string
+	podUID       string
+	container    string
+	restartCount string
+}
+
+func parsePodPath(path string) (*podMetadata, bool) {
+	match := podPathPattern.FindStringSubmatch(path)
+	if match == nil {
+		return nil, false
+	}
+
+	return &podMetadata{
+		namespace:    match[1],
+		podName:      match[2],
+		podUID:       match[3],
+		container:    match[4],
+		restartCount: match[5],
+	}, true
+}
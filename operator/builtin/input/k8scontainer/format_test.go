@@ -0,0 +1,138 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8scontainer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLine(t *testing.T) {
+	timestamp := time.Date(2020, 10, 16, 8, 15, 40, 123456789, time.UTC)
+
+	cases := []struct {
+		name     string
+		format   string
+		line     string
+		expected *containerLine
+	}{
+		{
+			"CRIFull",
+			formatCRI,
+			"2020-10-16T08:15:40.123456789Z stdout F hello world",
+			&containerLine{timestamp, "stdout", false, "hello world"},
+		},
+		{
+			"CRIPartial",
+			formatCRI,
+			"2020-10-16T08:15:40.123456789Z stderr P hello ",
+			&containerLine{timestamp, "stderr", true, "hello "},
+		},
+		{
+			"CRIEmptyMessage",
+			formatCRI,
+			"2020-10-16T08:15:40.123456789Z stdout F",
+			&containerLine{timestamp, "stdout", false, ""},
+		},
+		{
+			"CRIMessageWithSpaces",
+			formatCRI,
+			"2020-10-16T08:15:40.123456789Z stdout F  indented  text",
+			&containerLine{timestamp, "stdout", false, " indented  text"},
+		},
+		{
+			"DockerFull",
+			formatDocker,
+			`{"log":"hello world\n","stream":"stdout","time":"2020-10-16T08:15:40.123456789Z"}`,
+			&containerLine{timestamp, "stdout", false, "hello world"},
+		},
+		{
+			"DockerPartial",
+			formatDocker,
+			`{"log":"hello ","stream":"stderr","time":"2020-10-16T08:15:40.123456789Z"}`,
+			&containerLine{timestamp, "stderr", true, "hello "},
+		},
+		{
+			"AutoCRI",
+			formatAuto,
+			"2020-10-16T08:15:40.123456789Z stdout F {\"key\":\"value\"}",
+			&containerLine{timestamp, "stdout", false, `{"key":"value"}`},
+		},
+		{
+			"AutoDocker",
+			formatAuto,
+			`{"log":"{\"key\":\"value\"}\n","stream":"stdout","time":"2020-10-16T08:15:40.123456789Z"}`,
+			&containerLine{timestamp, "stdout", false, `{"key":"value"}`},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			line, err := parseLine(tc.format, tc.line)
+			require.NoError(t, err)
+			require.True(t, tc.expected.timestamp.Equal(line.timestamp))
+			line.timestamp = tc.expected.timestamp
+			require.Equal(t, tc.expected, line)
+		})
+	}
+}
+
+func TestParseLineInvalid(t *testing.T) {
+	cases := []struct {
+		name   string
+		format string
+		line   string
+	}{
+		{"CRITooShort", formatCRI, "2020-10-16T08:15:40.123456789Z stdout"},
+		{"CRITimestamp", formatCRI, "yesterday stdout F hello"},
+		{"CRITag", formatCRI, "2020-10-16T08:15:40.123456789Z stdout X hello"},
+		{"DockerJSON", formatDocker, `{"log":"hello`},
+		{"DockerTimestamp", formatDocker, `{"log":"hello\n","stream":"stdout","time":"yesterday"}`},
+		{"DockerAsCRI", formatCRI, `{"log":"hello\n","stream":"stdout","time":"2020-10-16T08:15:40.123456789Z"}`},
+		{"CRIAsDocker", formatDocker, "2020-10-16T08:15:40.123456789Z stdout F hello"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseLine(tc.format, tc.line)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestParsePodPath(t *testing.T) {
+	metadata, ok := parsePodPath("/var/log/pods/kube-system_coredns-f9fd979d6-4bw4x_8dd1ac08-9b2a-4a69-a8b5-1dfd2ae8c4f2/coredns/3.log")
+	require.True(t, ok)
+	require.Equal(t, &podMetadata{
+		namespace:    "kube-system",
+		podName:      "coredns-f9fd979d6-4bw4x",
+		podUID:       "8dd1ac08-9b2a-4a69-a8b5-1dfd2ae8c4f2",
+		container:    "coredns",
+		restartCount: "3",
+	}, metadata)
+
+	invalid := []string{
+		"/var/log/pods/kube-system_coredns/coredns/0.log",
+		"/var/log/pods/kube-system_coredns_uid/coredns/0.log.20201016-081540",
+		"/var/log/pods/kube-system_coredns_uid/coredns/current.log",
+		"/var/log/containers/coredns_kube-system_coredns-0123456789abcdef.log",
+	}
+	for _, path := range invalid {
+		_, ok := parsePodPath(path)
+		require.False(t, ok, path)
+	}
+}
@@ -0,0 +1,293 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8scontainer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/file"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"go.uber.org/zap"
+)
+
+func init() {
+	operator.Register("k8s_container_input", func() operator.Builder { return NewK8sContainerInputConfig("") })
+}
+
+const (
+	namespaceResourceKey    = "k8s.namespace.name"
+	podNameResourceKey      = "k8s.pod.name"
+	podUIDResourceKey       = "k8s.pod.uid"
+	containerResourceKey    = "k8s.container.name"
+	restartCountResourceKey = "k8s.container.restart_count"
+
+	streamLabel   = "stream"
+	filePathLabel = "file_path"
+
+	defaultMaxLogSize         = 1024 * 1024
+	defaultMaxConcurrentFiles = 1024
+)
+
+// NewK8sContainerInputConfig creates a new k8s container input config with default values
+func NewK8sContainerInputConfig(operatorID string) *K8sContainerInputConfig {
+	return &K8sContainerInputConfig{
+		InputConfig:        helper.NewInputConfig(operatorID, "k8s_container_input"),
+		Include:            []string{"/var/log/pods/*/*/*.log"},
+		Format:             formatAuto,
+		StartAt:            "end",
+		PollInterval:       helper.Duration{Duration: 200 * time.Millisecond},
+		ForceFlushPeriod:   helper.Duration{Duration: 5 * time.Second},
+		MaxLogSize:         defaultMaxLogSize,
+		MaxConcurrentFiles: defaultMaxConcurrentFiles,
+	}
+}
+
+// K8sContainerInputConfig is the configuration of a k8s container input operator
+type K8sContainerInputConfig struct {
+	helper.InputConfig `yaml:",inline"`
+
+	Include            []string        `json:"include,omitempty"              yaml:"include,omitempty"`
+	Exclude            []string        `json:"exclude,omitempty"              yaml:"exclude,omitempty"`
+	Format             string          `json:"format,omitempty"               yaml:"format,omitempty"`
+	StartAt            string          `json:"start_at,omitempty"             yaml:"start_at,omitempty"`
+	PollInterval       helper.Duration `json:"poll_interval,omitempty"        yaml:"poll_interval,omitempty"`
+	ForceFlushPeriod   helper.Duration `json:"force_flush_period,omitempty"   yaml:"force_flush_period,omitempty"`
+	MaxLogSize         helper.ByteSize `json:"max_log_size,omitempty"         yaml:"max_log_size,omitempty"`
+	MaxConcurrentFiles int             `json:"max_concurrent_files,omitempty" yaml:"max_concurrent_files,omitempty"`
+}
+
+// Build will build a k8s container input operator from the supplied configuration
+func (c K8sContainerInputConfig) Build(buildContext operator.BuildContext) ([]operator.Operator, error) {
+	inputOperator, err := c.InputConfig.Build(buildContext)
+	if err != nil {
+		return nil, err
+	}
+
+	switch c.Format {
+	case formatAuto, formatCRI, formatDocker:
+	default:
+		return nil, fmt.Errorf("invalid format '%s'", c.Format)
+	}
+
+	if c.ForceFlushPeriod.Raw() <= 0 {
+		return nil, fmt.Errorf("`force_flush_period` must be positive")
+	}
+
+	// Files are discovered, followed and checkpointed by an embedded file
+	// input, which hands each line to this operator
+	fileConfig := file.NewInputConfig(c.ID())
+	fileConfig.Include = c.Include
+	fileConfig.Exclude = c.Exclude
+	fileConfig.StartAt = c.StartAt
+	fileConfig.PollInterval = c.PollInterval
+	fileConfig.MaxLogSize = c.MaxLogSize
+	fileConfig.MaxConcurrentFiles = c.MaxConcurrentFiles
+	fileConfig.IncludeFileName = false
+	fileConfig.IncludeFilePath = true
+
+	fileOperators, err := fileConfig.Build(buildContext)
+	if err != nil {
+		return nil, err
+	}
+
+	op := &K8sContainerInput{
+		InputOperator:    inputOperator,
+		files:            fileOperators[0].(*file.InputOperator),
+		format:           c.Format,
+		forceFlushPeriod: c.ForceFlushPeriod.Raw(),
+		maxLogSize:       int(c.MaxLogSize),
+		partials:         make(map[partialKey]*partialLine),
+	}
+	op.files.OutputOperators = []operator.Operator{lineHandler{op}}
+
+	return []operator.Operator{op}, nil
+}
+
+// K8sContainerInput is an operator that reads the logs of kubernetes containers
+type K8sContainerInput struct {
+	helper.InputOperator
+
+	files            *file.InputOperator
+	format           string
+	forceFlushPeriod time.Duration
+	maxLogSize       int
+
+	partials map[partialKey]*partialLine
+	mux      sync.Mutex
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// partialKey identifies a stream of a container log file. Partial lines of
+// stdout and stderr are interleaved in the same file.
+type partialKey struct {
+	path   string
+	stream string
+}
+
+// partialLine is a line that is being reassembled from its parts
+type partialLine struct {
+	timestamp time.Time
+	message   strings.Builder
+	updated   time.Time
+}
+
+// lineHandler receives the lines read by the embedded file input
+type lineHandler struct {
+	*K8sContainerInput
+}
+
+// Process handles a line read by the embedded file input
+func (h lineHandler) Process(ctx context.Context, e *entry.Entry) error {
+	h.handleLine(ctx, e)
+	return nil
+}
+
+// Start will start reading container logs
+func (k *K8sContainerInput) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	k.cancel = cancel
+
+	k.wg.Add(1)
+	go k.flushStalePartials(ctx)
+
+	return k.files.Start()
+}
+
+// Stop will stop reading container logs, and flush the lines that are still
+// being reassembled
+func (k *K8sContainerInput) Stop() error {
+	err := k.files.Stop()
+	k.cancel()
+	k.wg.Wait()
+
+	k.flushPartials(context.Background(), func(*partialLine) bool { return true })
+	return err
+}
+
+func (k *K8sContainerInput) handleLine(ctx context.Context, e *entry.Entry) {
+	path := e.Labels[filePathLabel]
+
+	raw, ok := e.Record.(string)
+	if !ok {
+		return
+	}
+
+	line, err := parseLine(k.format, raw)
+	if err != nil {
+		k.Warnw("Failed to parse container log line", "path", path, zap.Error(err))
+		return
+	}
+
+	key := partialKey{path: path, stream: line.stream}
+
+	k.mux.Lock()
+	partial, ok := k.partials[key]
+	if !ok && !line.partial {
+		k.mux.Unlock()
+		k.emit(ctx, key, line.timestamp, line.message)
+		return
+	}
+
+	if !ok {
+		partial = &partialLine{timestamp: line.timestamp}
+		k.partials[key] = partial
+	}
+	partial.message.WriteString(line.message)
+	partial.updated = time.Now()
+
+	if line.partial && partial.message.Len() < k.maxLogSize {
+		k.mux.Unlock()
+		return
+	}
+
+	delete(k.partials, key)
+	k.mux.Unlock()
+
+	if line.partial {
+		k.Warnw("Reassembled line exceeds max_log_size, flushing it unfinished", "path", path)
+	}
+	k.emit(ctx, key, partial.timestamp, partial.message.String())
+}
+
+// flushStalePartials periodically flushes lines that have not been
+// completed within the force flush period, such as when a container exits
+// in the middle of writing a line
+func (k *K8sContainerInput) flushStalePartials(ctx context.Context) {
+	defer k.wg.Done()
+
+	ticker := time.NewTicker(k.forceFlushPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			k.flushPartials(ctx, func(partial *partialLine) bool {
+				return now.Sub(partial.updated) >= k.forceFlushPeriod
+			})
+		}
+	}
+}
+
+// flushPartials emits the partial lines selected by shouldFlush as they are
+func (k *K8sContainerInput) flushPartials(ctx context.Context, shouldFlush func(*partialLine) bool) {
+	flushed := make(map[partialKey]*partialLine)
+
+	k.mux.Lock()
+	for key, partial := range k.partials {
+		if shouldFlush(partial) {
+			flushed[key] = partial
+			delete(k.partials, key)
+		}
+	}
+	k.mux.Unlock()
+
+	for key, partial := range flushed {
+		k.emit(ctx, key, partial.timestamp, partial.message.String())
+	}
+}
+
+// emit writes a complete line, with the metadata from the path of its file
+func (k *K8sContainerInput) emit(ctx context.Context, key partialKey, timestamp time.Time, message string) {
+	e, err := k.NewEntry(message)
+	if err != nil {
+		k.Errorw("Failed to create entry", zap.Error(err))
+		return
+	}
+
+	e.Timestamp = timestamp
+	if key.stream != "" {
+		e.AddLabel(streamLabel, key.stream)
+	}
+
+	if metadata, ok := parsePodPath(key.path); ok {
+		e.AddResourceKey(namespaceResourceKey, metadata.namespace)
+		e.AddResourceKey(podNameResourceKey, metadata.podName)
+		e.AddResourceKey(podUIDResourceKey, metadata.podUID)
+		e.AddResourceKey(containerResourceKey, metadata.container)
+		e.AddResourceKey(restartCountResourceKey, metadata.restartCount)
+	}
+
+	k.Write(ctx, e)
+}
@@ -0,0 +1,253 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8scontainer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"github.com/opentelemetry/opentelemetry-log-collection/testutil"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestInput(t *testing.T, modify func(*K8sContainerInputConfig)) (*K8sContainerInput, chan *entry.Entry, string) {
+	dir := testutil.NewTempDir(t)
+
+	cfg := NewK8sContainerInputConfig("test_input")
+	cfg.Include = []string{filepath.Join(dir, "*", "*", "*.log")}
+	cfg.StartAt = "beginning"
+	cfg.PollInterval = helper.Duration{Duration: 10 * time.Millisecond}
+	cfg.OutputIDs = []string{"output"}
+	if modify != nil {
+		modify(cfg)
+	}
+
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	op := ops[0].(*K8sContainerInput)
+
+	received := make(chan *entry.Entry, 100)
+	mockOutput := testutil.NewMockOperator("$.output")
+	mockOutput.On("Process", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		received <- args.Get(1).(*entry.Entry)
+	}).Return(nil)
+	require.NoError(t, op.SetOutputs([]operator.Operator{mockOutput}))
+
+	return op, received, dir
+}
+
+func writeLog(t *testing.T, dir, pod, container string, lines ...string) string {
+	path := filepath.Join(dir, pod, container, "0.log")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600))
+	return path
+}
+
+func waitForEntry(t *testing.T, received chan *entry.Entry) *entry.Entry {
+	select {
+	case e := <-received:
+		return e
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "Timed out waiting for entry")
+		return nil
+	}
+}
+
+func expectNoEntry(t *testing.T, received chan *entry.Entry) {
+	select {
+	case e := <-received:
+		require.FailNow(t, "Received unexpected entry", e.Record)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestK8sContainerInputCRI(t *testing.T) {
+	op, received, dir := newTestInput(t, nil)
+
+	writeLog(t, dir, "default_web-7d8b49557c-6v4xk_2e1f5a2e-8a5b-4a36-9d0c-1b5f1b2f9d11", "nginx",
+		"2020-10-16T08:15:40.000000001Z stdout F first line",
+		"2020-10-16T08:15:40.000000002Z stdout P hel",
+		"2020-10-16T08:15:40.000000003Z stderr F an error",
+		"2020-10-16T08:15:40.000000004Z stdout P lo ",
+		"2020-10-16T08:15:40.000000005Z stdout F world",
+	)
+
+	require.NoError(t, op.Start())
+	defer op.Stop()
+
+	resource := map[string]string{
+		"k8s.namespace.name":          "default",
+		"k8s.pod.name":                "web-7d8b49557c-6v4xk",
+		"k8s.pod.uid":                 "2e1f5a2e-8a5b-4a36-9d0c-1b5f1b2f9d11",
+		"k8s.container.name":          "nginx",
+		"k8s.container.restart_count": "0",
+	}
+
+	expected := []struct {
+		record    string
+		stream    string
+		timestamp time.Time
+	}{
+		{"first line", "stdout", time.Date(2020, 10, 16, 8, 15, 40, 1, time.UTC)},
+		{"an error", "stderr", time.Date(2020, 10, 16, 8, 15, 40, 3, time.UTC)},
+		{"hello world", "stdout", time.Date(2020, 10, 16, 8, 15, 40, 2, time.UTC)},
+	}
+
+	for _, exp := range expected {
+		e := waitForEntry(t, received)
+		require.Equal(t, exp.record, e.Record)
+		require.True(t, exp.timestamp.Equal(e.Timestamp))
+		require.Equal(t, map[string]string{"stream": exp.stream}, e.Labels)
+		require.Equal(t, resource, e.Resource)
+	}
+	expectNoEntry(t, received)
+}
+
+func TestK8sContainerInputDocker(t *testing.T) {
+	op, received, dir := newTestInput(t, func(cfg *K8sContainerInputConfig) {
+		cfg.Format = formatDocker
+	})
+
+	writeLog(t, dir, "kube-system_coredns-f9fd979d6-4bw4x_8dd1ac08-9b2a-4a69-a8b5-1dfd2ae8c4f2", "coredns",
+		`{"log":"[INFO] plugin/reload: Running configuration\n","stream":"stdout","time":"2020-10-16T08:15:40.000000001Z"}`,
+		`{"log":"a very ","stream":"stderr","time":"2020-10-16T08:15:40.000000002Z"}`,
+		`{"log":"long line\n","stream":"stderr","time":"2020-10-16T08:15:40.000000003Z"}`,
+	)
+
+	require.NoError(t, op.Start())
+	defer op.Stop()
+
+	e := waitForEntry(t, received)
+	require.Equal(t, "[INFO] plugin/reload: Running configuration", e.Record)
+	require.Equal(t, "coredns-f9fd979d6-4bw4x", e.Resource["k8s.pod.name"])
+
+	e = waitForEntry(t, received)
+	require.Equal(t, "a very long line", e.Record)
+	require.Equal(t, map[string]string{"stream": "stderr"}, e.Labels)
+	expectNoEntry(t, received)
+}
+
+func TestK8sContainerInputForceFlush(t *testing.T) {
+	op, received, dir := newTestInput(t, func(cfg *K8sContainerInputConfig) {
+		cfg.ForceFlushPeriod = helper.Duration{Duration: 50 * time.Millisecond}
+	})
+
+	writeLog(t, dir, "default_job-x7k2p_6b3cf1c4-0f0e-4f55-8a83-3f5b0c8e1d22", "main",
+		"2020-10-16T08:15:40.000000001Z stdout P unfinished",
+	)
+
+	require.NoError(t, op.Start())
+	defer op.Stop()
+
+	e := waitForEntry(t, received)
+	require.Equal(t, "unfinished", e.Record)
+}
+
+func TestK8sContainerInputFlushOnStop(t *testing.T) {
+	op, received, dir := newTestInput(t, nil)
+
+	writeLog(t, dir, "default_job-x7k2p_6b3cf1c4-0f0e-4f55-8a83-3f5b0c8e1d22", "main",
+		"2020-10-16T08:15:40.000000001Z stdout F done",
+		"2020-10-16T08:15:40.000000002Z stdout P unfinished",
+	)
+
+	require.NoError(t, op.Start())
+	e := waitForEntry(t, received)
+	require.Equal(t, "done", e.Record)
+	expectNoEntry(t, received)
+
+	require.NoError(t, op.Stop())
+	e = waitForEntry(t, received)
+	require.Equal(t, "unfinished", e.Record)
+}
+
+func TestK8sContainerInputMaxLogSize(t *testing.T) {
+	op, received, dir := newTestInput(t, func(cfg *K8sContainerInputConfig) {
+		cfg.MaxLogSize = 64
+	})
+
+	writeLog(t, dir, "default_web-7d8b49557c-6v4xk_2e1f5a2e-8a5b-4a36-9d0c-1b5f1b2f9d11", "nginx",
+		"2020-10-16T08:15:40.000000001Z stdout P "+strings.Repeat("a", 30),
+		"2020-10-16T08:15:40.000000002Z stdout P "+strings.Repeat("b", 30),
+		"2020-10-16T08:15:40.000000003Z stdout P "+strings.Repeat("c", 30),
+		"2020-10-16T08:15:40.000000004Z stdout F end",
+	)
+
+	require.NoError(t, op.Start())
+	defer op.Stop()
+
+	e := waitForEntry(t, received)
+	require.Equal(t, strings.Repeat("a", 30)+strings.Repeat("b", 30)+strings.Repeat("c", 30), e.Record)
+	e = waitForEntry(t, received)
+	require.Equal(t, "end", e.Record)
+}
+
+func TestK8sContainerInputUnknownPath(t *testing.T) {
+	op, received, dir := newTestInput(t, nil)
+
+	writeLog(t, dir, "not-a-pod", "container",
+		"2020-10-16T08:15:40.000000001Z stdout F hello",
+		"not a container line",
+	)
+
+	require.NoError(t, op.Start())
+	defer op.Stop()
+
+	e := waitForEntry(t, received)
+	require.Equal(t, "hello", e.Record)
+	require.Nil(t, e.Resource)
+	expectNoEntry(t, received)
+}
+
+func TestBuildK8sContainerInputInvalid(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(*K8sContainerInputConfig)
+	}{
+		{
+			"Format",
+			func(cfg *K8sContainerInputConfig) { cfg.Format = "syslog" },
+		},
+		{
+			"StartAt",
+			func(cfg *K8sContainerInputConfig) { cfg.StartAt = "middle" },
+		},
+		{
+			"ForceFlushPeriod",
+			func(cfg *K8sContainerInputConfig) { cfg.ForceFlushPeriod = helper.Duration{Duration: 0} },
+		},
+		{
+			"Include",
+			func(cfg *K8sContainerInputConfig) { cfg.Include = []string{"["} },
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := NewK8sContainerInputConfig("test_input")
+			tc.modify(cfg)
+			_, err := cfg.Build(testutil.NewBuildContext(t))
+			require.Error(t, err)
+		})
+	}
+}
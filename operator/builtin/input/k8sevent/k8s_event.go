@@ -16,7 +16,9 @@ package k8sevent
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -26,6 +28,7 @@ import (
 	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
 	"go.uber.org/zap"
 	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	watch "k8s.io/apimachinery/pkg/watch"
@@ -33,6 +36,12 @@ import (
 	"k8s.io/client-go/rest"
 )
 
+const (
+	// Bounds of the delay between retries of a failed watch
+	minWatchDelay = 100 * time.Millisecond
+	maxWatchDelay = 30 * time.Second
+)
+
 func init() {
 	operator.Register("k8s_event_input", func() operator.Builder { return NewK8sEventsConfig("") })
 }
@@ -71,6 +80,8 @@ func (c K8sEventsConfig) Build(context operator.BuildContext) ([]operator.Operat
 		namespaces:         c.Namespaces,
 		discoverNamespaces: c.DiscoverNamespaces,
 		discoveryInterval:  c.DiscoveryInterval,
+		persist:            helper.NewScopedDBPersister(context.Database, c.ID()),
+		states:             make(map[string]*namespaceState),
 	}
 
 	return []operator.Operator{op}, nil
@@ -83,10 +94,24 @@ type K8sEvents struct {
 	discoverNamespaces bool
 	discoveryInterval  helper.Duration
 	namespaces         []string
+	persist            helper.Persister
 
 	cancel       func()
 	wg           sync.WaitGroup
 	namespaceMux sync.Mutex
+
+	states   map[string]*namespaceState
+	stateMux sync.Mutex
+}
+
+// namespaceState is the resource version a namespace is watched from, and
+// the count of each event already emitted from it. It is persisted under the
+// name of the namespace.
+type namespaceState struct {
+	ResourceVersion string           `json:"resource_version"`
+	Counts          map[string]int32 `json:"counts"`
+
+	dirty bool
 }
 
 // Start implements the operator.Operator interface
@@ -94,6 +119,10 @@ func (k *K8sEvents) Start() error {
 	ctx, cancel := context.WithCancel(context.Background())
 	k.cancel = cancel
 
+	if err := k.persist.Load(); err != nil {
+		return errors.Wrap(err, "load resource versions")
+	}
+
 	// Currently, we only support running in the cluster. In contrast to the
 	// k8s_metadata_decorator, it may make sense to relax this restriction
 	// by exposing client config options.
@@ -141,6 +170,20 @@ func (k *K8sEvents) Start() error {
 		k.startFindingNamespaces(ctx, k.client)
 	}
 
+	// Periodically save the position of each namespace
+	k.wg.Add(1)
+	go func() {
+		defer k.wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+				k.syncStates()
+			}
+		}
+	}()
+
 	return nil
 }
 
@@ -148,6 +191,7 @@ func (k *K8sEvents) Start() error {
 func (k *K8sEvents) Stop() error {
 	k.cancel()
 	k.wg.Wait()
+	k.syncStates()
 	return nil
 }
 
@@ -197,11 +241,17 @@ func (k *K8sEvents) startFindingNamespaces(ctx context.Context, client corev1.Co
 }
 
 // startWatchingNamespace creates a goroutine that watches the events for a
-// specific namespace
+// specific namespace. The watch resumes from the last resource version seen,
+// and the events are listed again when there is none or it has expired.
+// Other watch failures are retried with an exponential backoff.
 func (k *K8sEvents) startWatchingNamespace(ctx context.Context, ns string) {
+	state := k.loadState(ns)
+
 	k.wg.Add(1)
 	go func() {
 		defer k.wg.Done()
+
+		backoff := helper.NewBackoffWithLimits(minWatchDelay, maxWatchDelay)
 		for {
 			select {
 			case <-ctx.Done():
@@ -209,18 +259,75 @@ func (k *K8sEvents) startWatchingNamespace(ctx context.Context, ns string) {
 			default:
 			}
 
-			watcher, err := k.client.Events(ns).Watch(ctx, metav1.ListOptions{})
+			if k.resourceVersion(state) == "" {
+				if err := k.listEvents(ctx, ns, state); err != nil {
+					if ctx.Err() == nil {
+						k.Errorw("Failed to list events", zap.String("namespace", ns), zap.Error(err))
+						k.removeNamespace(ns)
+					}
+					return
+				}
+			}
+
+			watcher, err := k.client.Events(ns).Watch(ctx, metav1.ListOptions{
+				ResourceVersion:     k.resourceVersion(state),
+				AllowWatchBookmarks: true,
+			})
 			if err != nil {
-				k.Errorw("Failed to start watcher", zap.Error(err))
-				k.removeNamespace(ns)
-				return
+				if isExpired(err) {
+					k.Debugw("Resource version expired, listing events", zap.String("namespace", ns))
+					k.setResourceVersion(state, "")
+					continue
+				}
+				if !backoff.Wait(ctx, k.Errorw, "Failed to start watcher", err) {
+					return
+				}
+				continue
 			}
 
-			k.consumeWatchEvents(ctx, watcher.ResultChan())
+			err = k.consumeWatchEvents(ctx, state, watcher.ResultChan())
+			watcher.Stop()
+			if err != nil {
+				if !backoff.Wait(ctx, k.Errorw, "Watch failed", err) {
+					return
+				}
+				continue
+			}
+			backoff.Reset()
 		}
 	}()
 }
 
+// listEvents emits the events of a namespace that have not been emitted yet,
+// and sets the resource version to watch from
+func (k *K8sEvents) listEvents(ctx context.Context, ns string, state *namespaceState) error {
+	list, err := k.client.Events(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	// Only the counts of events that still exist are kept
+	k.stateMux.Lock()
+	previous := state.Counts
+	state.Counts = make(map[string]int32, len(list.Items))
+	for _, event := range list.Items {
+		if count, ok := previous[string(event.UID)]; ok {
+			state.Counts[string(event.UID)] = count
+		}
+	}
+	state.dirty = true
+	k.stateMux.Unlock()
+
+	for i := range list.Items {
+		if k.markEmitted(state, &list.Items[i]) {
+			k.emit(ctx, watch.Added, &list.Items[i])
+		}
+	}
+
+	k.setResourceVersion(state, list.ResourceVersion)
+	return nil
+}
+
 // addNamespace will add a namespace.
 func (k *K8sEvents) addNamespace(namespace string) {
 	k.namespaceMux.Lock()
@@ -255,49 +362,203 @@ func (k *K8sEvents) removeNamespace(namespace string) {
 	}
 }
 
-// consumeWatchEvents will read events from the watcher channel until the channel is closed
-// or the context is canceled
-func (k *K8sEvents) consumeWatchEvents(ctx context.Context, events <-chan watch.Event) {
+// consumeWatchEvents will read events from the watcher channel until the channel is closed,
+// the watch fails or the context is canceled. It returns the error of a failed watch,
+// unless the failure is that the resource version has expired.
+func (k *K8sEvents) consumeWatchEvents(ctx context.Context, state *namespaceState, events <-chan watch.Event) error {
 	for {
 		select {
 		case event, ok := <-events:
 			if !ok {
-				k.Error("Watcher channel closed")
-				return
+				k.Debug("Watcher channel closed")
+				return nil
 			}
 
-			typedEvent := event.Object.(*apiv1.Event)
-			record, err := runtime.DefaultUnstructuredConverter.ToUnstructured(event.Object)
-			if err != nil {
-				k.Error("Failed to convert event to map", zap.Error(err))
-				continue
+			if event.Type == watch.Error {
+				err := apierrors.FromObject(event.Object)
+				if isExpired(err) {
+					k.setResourceVersion(state, "")
+					return nil
+				}
+				return err
 			}
 
-			entry, err := k.NewEntry(record)
-			if err != nil {
-				k.Error("Failed to create new entry from record", zap.Error(err))
+			typedEvent, ok := event.Object.(*apiv1.Event)
+			if !ok {
+				k.Errorf("Unexpected object of type %T", event.Object)
 				continue
 			}
 
-			// Prioritize EventTime > LastTimestamp > FirstTimestamp
-			switch {
-			case typedEvent.EventTime.Time != time.Time{}:
-				entry.Timestamp = typedEvent.EventTime.Time
-			case typedEvent.LastTimestamp.Time != time.Time{}:
-				entry.Timestamp = typedEvent.LastTimestamp.Time
-			case typedEvent.FirstTimestamp.Time != time.Time{}:
-				entry.Timestamp = typedEvent.FirstTimestamp.Time
+			switch event.Type {
+			case watch.Added, watch.Modified:
+				if k.markEmitted(state, typedEvent) {
+					k.emit(ctx, event.Type, typedEvent)
+				}
+			case watch.Deleted:
+				k.stateMux.Lock()
+				delete(state.Counts, string(typedEvent.UID))
+				k.stateMux.Unlock()
 			}
 
-			entry.AddLabel("event_type", string(event.Type))
-			k.populateResource(typedEvent, entry)
-			k.Write(ctx, entry)
+			k.setResourceVersion(state, typedEvent.ResourceVersion)
 		case <-ctx.Done():
-			return
+			return nil
 		}
 	}
 }
 
+// emit writes an entry for an event
+func (k *K8sEvents) emit(ctx context.Context, eventType watch.EventType, event *apiv1.Event) {
+	record, err := runtime.DefaultUnstructuredConverter.ToUnstructured(event)
+	if err != nil {
+		k.Error("Failed to convert event to map", zap.Error(err))
+		return
+	}
+
+	entry, err := k.NewEntry(record)
+	if err != nil {
+		k.Error("Failed to create new entry from record", zap.Error(err))
+		return
+	}
+
+	// Prioritize EventTime > LastTimestamp > FirstTimestamp
+	switch {
+	case event.EventTime.Time != time.Time{}:
+		entry.Timestamp = event.EventTime.Time
+	case event.LastTimestamp.Time != time.Time{}:
+		entry.Timestamp = event.LastTimestamp.Time
+	case event.FirstTimestamp.Time != time.Time{}:
+		entry.Timestamp = event.FirstTimestamp.Time
+	}
+
+	entry.Severity = eventSeverity(event)
+	entry.AddLabel("event_type", string(eventType))
+	k.populateResource(event, entry)
+	k.Write(ctx, entry)
+}
+
+// errorReasons are the reasons of warning events that are reported as errors,
+// in addition to those starting with "Failed"
+var errorReasons = map[string]bool{
+	"BackOff":      true,
+	"Evicted":      true,
+	"NodeNotReady": true,
+	"OOMKilling":   true,
+	"SystemOOM":    true,
+}
+
+// eventSeverity maps the type and reason of an event to a severity
+func eventSeverity(event *apiv1.Event) entry.Severity {
+	switch event.Type {
+	case apiv1.EventTypeNormal:
+		return entry.Info
+	case apiv1.EventTypeWarning:
+		if errorReasons[event.Reason] || strings.HasPrefix(event.Reason, "Failed") {
+			return entry.Error
+		}
+		return entry.Warning
+	default:
+		return entry.Default
+	}
+}
+
+// eventCount returns the number of times an event has occurred
+func eventCount(event *apiv1.Event) int32 {
+	count := event.Count
+	if event.Series != nil && event.Series.Count > count {
+		count = event.Series.Count
+	}
+	if count < 1 {
+		count = 1
+	}
+	return count
+}
+
+// markEmitted records the count of an event, and returns false if an event
+// with the same UID and count has already been emitted
+func (k *K8sEvents) markEmitted(state *namespaceState, event *apiv1.Event) bool {
+	k.stateMux.Lock()
+	defer k.stateMux.Unlock()
+
+	uid := string(event.UID)
+	count := eventCount(event)
+	if state.Counts[uid] >= count {
+		return false
+	}
+
+	state.Counts[uid] = count
+	state.dirty = true
+	return true
+}
+
+// loadState returns the state of a namespace, loading it from the persister
+// the first time the namespace is watched
+func (k *K8sEvents) loadState(ns string) *namespaceState {
+	k.stateMux.Lock()
+	defer k.stateMux.Unlock()
+
+	if state, ok := k.states[ns]; ok {
+		return state
+	}
+
+	state := &namespaceState{}
+	if saved := k.persist.Get(ns); saved != nil {
+		if err := json.Unmarshal(saved, state); err != nil {
+			k.Warnw("Ignoring saved resource version", zap.String("namespace", ns), zap.Error(err))
+			state = &namespaceState{}
+		}
+	}
+	if state.Counts == nil {
+		state.Counts = make(map[string]int32)
+	}
+
+	k.states[ns] = state
+	return state
+}
+
+func (k *K8sEvents) resourceVersion(state *namespaceState) string {
+	k.stateMux.Lock()
+	defer k.stateMux.Unlock()
+	return state.ResourceVersion
+}
+
+func (k *K8sEvents) setResourceVersion(state *namespaceState, resourceVersion string) {
+	k.stateMux.Lock()
+	defer k.stateMux.Unlock()
+	if state.ResourceVersion != resourceVersion {
+		state.ResourceVersion = resourceVersion
+		state.dirty = true
+	}
+}
+
+// syncStates saves the state of each namespace that changed since the last sync
+func (k *K8sEvents) syncStates() {
+	k.stateMux.Lock()
+	for ns, state := range k.states {
+		if !state.dirty {
+			continue
+		}
+
+		saved, err := json.Marshal(state)
+		if err != nil {
+			k.Errorw("Failed to encode namespace state", zap.String("namespace", ns), zap.Error(err))
+			continue
+		}
+		k.persist.Set(ns, saved)
+		state.dirty = false
+	}
+	k.stateMux.Unlock()
+
+	if err := k.persist.Sync(); err != nil {
+		k.Errorw("Failed to sync resource versions", zap.Error(err))
+	}
+}
+
+// isExpired returns true if an error means a resource version is too old to watch from
+func isExpired(err error) bool {
+	return apierrors.IsResourceExpired(err) || apierrors.IsGone(err)
+}
+
 // populateResource uses the keys from Event.ObjectMeta to populate the resource of the entry
 func (k *K8sEvents) populateResource(event *apiv1.Event, entry *entry.Entry) {
 	io := event.InvolvedObject
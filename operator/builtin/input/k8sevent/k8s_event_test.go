@@ -16,14 +16,18 @@ package k8sevent
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
 	"github.com/opentelemetry/opentelemetry-log-collection/operator"
 	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
 	"github.com/opentelemetry/opentelemetry-log-collection/testutil"
 	"github.com/stretchr/testify/require"
 	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -64,6 +68,9 @@ func TestWatchNamespace(t *testing.T) {
 	require.NoError(t, err)
 
 	fakeAPI := &fakeTest.Fake{}
+	fakeAPI.AddReactor("list", "events", func(action fakeTest.Action) (bool, runtime.Object, error) {
+		return true, &apiv1.EventList{}, nil
+	})
 	fakeAPI.AddWatchReactor("*", func(action fakeTest.Action) (handled bool, ret watch.Interface, err error) {
 		return true, &fakeWatch{}, nil
 	})
@@ -76,6 +83,8 @@ func TestWatchNamespace(t *testing.T) {
 		},
 		namespaces: []string{"test_namespace"},
 		cancel:     cancel,
+		persist:    helper.NewScopedDBPersister(testutil.NewTestDatabase(t), "test_id"),
+		states:     make(map[string]*namespaceState),
 	}
 
 	fake := testutil.NewFakeOutput(t)
@@ -122,3 +131,292 @@ func TestListNamespaces(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, []string{"test1", "test2"}, namespaces)
 }
+
+// fakeEventsAPI serves a list of events and records the resource version
+// of each watch
+type fakeEventsAPI struct {
+	*fakeTest.Fake
+
+	mux              sync.Mutex
+	events           []apiv1.Event
+	listVersion      string
+	lists            int
+	watchVersions    []string
+	watchErrors      []error
+	watcher          *watch.FakeWatcher
+	watchersReturned chan struct{}
+}
+
+func newFakeEventsAPI(events ...apiv1.Event) *fakeEventsAPI {
+	api := &fakeEventsAPI{
+		Fake:             &fakeTest.Fake{},
+		events:           events,
+		listVersion:      "100",
+		watchersReturned: make(chan struct{}, 10),
+	}
+
+	api.AddReactor("list", "events", func(action fakeTest.Action) (bool, runtime.Object, error) {
+		api.mux.Lock()
+		defer api.mux.Unlock()
+		api.lists++
+		list := &apiv1.EventList{Items: api.events}
+		list.ResourceVersion = api.listVersion
+		return true, list.DeepCopyObject(), nil
+	})
+
+	api.AddWatchReactor("events", func(action fakeTest.Action) (bool, watch.Interface, error) {
+		api.mux.Lock()
+		defer api.mux.Unlock()
+		api.watchVersions = append(api.watchVersions, action.(fakeTest.WatchActionImpl).WatchRestrictions.ResourceVersion)
+		if len(api.watchErrors) > 0 {
+			err := api.watchErrors[0]
+			api.watchErrors = api.watchErrors[1:]
+			return true, nil, err
+		}
+		api.watcher = watch.NewFakeWithChanSize(10, false)
+		api.watchersReturned <- struct{}{}
+		return true, api.watcher, nil
+	})
+
+	return api
+}
+
+func (api *fakeEventsAPI) currentWatcher() *watch.FakeWatcher {
+	api.mux.Lock()
+	defer api.mux.Unlock()
+	return api.watcher
+}
+
+func (api *fakeEventsAPI) counts() (int, []string) {
+	api.mux.Lock()
+	defer api.mux.Unlock()
+	return api.lists, append([]string{}, api.watchVersions...)
+}
+
+func newTestEvent(uid string, count int32, resourceVersion string) apiv1.Event {
+	return apiv1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "testpodname." + uid,
+			Namespace:       "testnamespace",
+			UID:             types.UID(uid),
+			ResourceVersion: resourceVersion,
+		},
+		InvolvedObject: apiv1.ObjectReference{
+			Kind:      "Pod",
+			Name:      "testpodname",
+			Namespace: "testnamespace",
+		},
+		Type:          apiv1.EventTypeWarning,
+		Reason:        "BackOff",
+		Count:         count,
+		LastTimestamp: metav1.Time{Time: fakeTime},
+	}
+}
+
+func newTestOperator(t *testing.T, api *fakeEventsAPI, persist helper.Persister) (*K8sEvents, *testutil.FakeOutput) {
+	inputOp, err := helper.NewInputConfig("test_id", "k8s_event_input").Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	op := &K8sEvents{
+		InputOperator: inputOp,
+		client:        &fakev1.FakeCoreV1{Fake: api.Fake},
+		namespaces:    []string{"testnamespace"},
+		cancel:        cancel,
+		persist:       persist,
+		states:        make(map[string]*namespaceState),
+	}
+
+	fake := testutil.NewFakeOutput(t)
+	op.OutputOperators = []operator.Operator{fake}
+
+	require.NoError(t, persist.Load())
+	op.startWatchingNamespace(ctx, "testnamespace")
+	return op, fake
+}
+
+func expectEvent(t *testing.T, fake *testutil.FakeOutput, uid string, count int32) *entry.Entry {
+	select {
+	case e := <-fake.Received:
+		record := e.Record.(map[string]interface{})
+		require.Equal(t, uid, record["metadata"].(map[string]interface{})["uid"])
+		require.EqualValues(t, count, record["count"])
+		return e
+	case <-time.After(time.Second):
+		require.FailNow(t, "Timed out waiting for entry")
+		return nil
+	}
+}
+
+func expectNoEvent(t *testing.T, fake *testutil.FakeOutput) {
+	select {
+	case e := <-fake.Received:
+		require.FailNow(t, "Received unexpected entry", e.Record)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func waitForWatch(t *testing.T, api *fakeEventsAPI) {
+	select {
+	case <-api.watchersReturned:
+	case <-time.After(time.Second):
+		require.FailNow(t, "Timed out waiting for watch")
+	}
+}
+
+func TestWatchNamespaceDeduplicates(t *testing.T) {
+	api := newFakeEventsAPI(newTestEvent("a", 1, "90"))
+	persist := helper.NewScopedDBPersister(testutil.NewTestDatabase(t), "test_id")
+	op, fake := newTestOperator(t, api, persist)
+	defer op.Stop()
+
+	e := expectEvent(t, fake, "a", 1)
+	require.Equal(t, entry.Error, e.Severity)
+	require.Equal(t, "ADDED", e.Labels["event_type"])
+	waitForWatch(t, api)
+
+	unchanged := newTestEvent("a", 1, "101")
+	api.currentWatcher().Modify(&unchanged)
+	repeated := newTestEvent("a", 2, "102")
+	api.currentWatcher().Modify(&repeated)
+	added := newTestEvent("b", 1, "103")
+	api.currentWatcher().Add(&added)
+
+	e = expectEvent(t, fake, "a", 2)
+	require.Equal(t, "MODIFIED", e.Labels["event_type"])
+	expectEvent(t, fake, "b", 1)
+	expectNoEvent(t, fake)
+
+	lists, versions := api.counts()
+	require.Equal(t, 1, lists)
+	require.Equal(t, []string{"100"}, versions)
+}
+
+func TestWatchNamespaceResumes(t *testing.T) {
+	persist := helper.NewScopedDBPersister(testutil.NewTestDatabase(t), "test_id")
+
+	api := newFakeEventsAPI(newTestEvent("a", 1, "90"))
+	op, fake := newTestOperator(t, api, persist)
+	expectEvent(t, fake, "a", 1)
+	waitForWatch(t, api)
+	added := newTestEvent("b", 1, "150")
+	api.currentWatcher().Add(&added)
+	expectEvent(t, fake, "b", 1)
+	require.NoError(t, op.Stop())
+
+	// The watch resumes from the last resource version without listing
+	api = newFakeEventsAPI(newTestEvent("a", 1, "90"))
+	op, fake = newTestOperator(t, api, persist)
+	waitForWatch(t, api)
+	expectNoEvent(t, fake)
+	require.NoError(t, op.Stop())
+
+	lists, versions := api.counts()
+	require.Equal(t, 0, lists)
+	require.Equal(t, []string{"150"}, versions)
+}
+
+func TestWatchNamespaceRelistsWhenExpired(t *testing.T) {
+	persist := helper.NewScopedDBPersister(testutil.NewTestDatabase(t), "test_id")
+
+	api := newFakeEventsAPI(newTestEvent("a", 1, "90"))
+	op, fake := newTestOperator(t, api, persist)
+	expectEvent(t, fake, "a", 1)
+	waitForWatch(t, api)
+	require.NoError(t, op.Stop())
+
+	// Only events that were not emitted before the restart are emitted
+	api = newFakeEventsAPI(newTestEvent("a", 1, "90"), newTestEvent("a2", 3, "180"))
+	api.listVersion = "200"
+	api.watchErrors = []error{apierrors.NewResourceExpired("too old resource version")}
+	op, fake = newTestOperator(t, api, persist)
+	defer op.Stop()
+
+	expectEvent(t, fake, "a2", 3)
+	waitForWatch(t, api)
+	expectNoEvent(t, fake)
+
+	lists, versions := api.counts()
+	require.Equal(t, 1, lists)
+	require.Equal(t, []string{"100", "200"}, versions)
+}
+
+func TestWatchNamespaceRelistsOnGoneEvent(t *testing.T) {
+	api := newFakeEventsAPI(newTestEvent("a", 1, "90"))
+	persist := helper.NewScopedDBPersister(testutil.NewTestDatabase(t), "test_id")
+	op, fake := newTestOperator(t, api, persist)
+	defer op.Stop()
+
+	expectEvent(t, fake, "a", 1)
+	waitForWatch(t, api)
+
+	api.mux.Lock()
+	api.events = append(api.events, newTestEvent("b", 1, "120"))
+	api.listVersion = "120"
+	api.mux.Unlock()
+
+	gone := apierrors.NewGone("too old resource version").ErrStatus
+	api.currentWatcher().Error(&gone)
+
+	expectEvent(t, fake, "b", 1)
+	waitForWatch(t, api)
+	expectNoEvent(t, fake)
+
+	lists, versions := api.counts()
+	require.Equal(t, 2, lists)
+	require.Equal(t, []string{"100", "120"}, versions)
+}
+
+func TestWatchNamespaceRetriesFailedWatch(t *testing.T) {
+	api := newFakeEventsAPI(newTestEvent("a", 1, "90"))
+	api.watchErrors = []error{
+		apierrors.NewInternalError(fmt.Errorf("unavailable")),
+		apierrors.NewInternalError(fmt.Errorf("unavailable")),
+	}
+	persist := helper.NewScopedDBPersister(testutil.NewTestDatabase(t), "test_id")
+	op, fake := newTestOperator(t, api, persist)
+	defer op.Stop()
+
+	expectEvent(t, fake, "a", 1)
+	waitForWatch(t, api)
+	require.True(t, op.hasNamespace("testnamespace"))
+
+	// A failed watch event is retried from the same resource version
+	failed := apierrors.NewInternalError(fmt.Errorf("watch failed")).ErrStatus
+	api.currentWatcher().Error(&failed)
+
+	waitForWatch(t, api)
+	expectNoEvent(t, fake)
+
+	lists, versions := api.counts()
+	require.Equal(t, 1, lists)
+	require.Equal(t, []string{"100", "100", "100", "100"}, versions)
+}
+
+func TestEventSeverity(t *testing.T) {
+	cases := []struct {
+		eventType string
+		reason    string
+		expected  entry.Severity
+	}{
+		{apiv1.EventTypeNormal, "Scheduled", entry.Info},
+		{apiv1.EventTypeWarning, "Unhealthy", entry.Warning},
+		{apiv1.EventTypeWarning, "FailedMount", entry.Error},
+		{apiv1.EventTypeWarning, "BackOff", entry.Error},
+		{apiv1.EventTypeWarning, "OOMKilling", entry.Error},
+		{"", "Started", entry.Default},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.eventType+tc.reason, func(t *testing.T) {
+			event := &apiv1.Event{Type: tc.eventType, Reason: tc.reason}
+			require.Equal(t, tc.expected, eventSeverity(event))
+		})
+	}
+}
+
+func TestEventCount(t *testing.T) {
+	require.Equal(t, int32(1), eventCount(&apiv1.Event{}))
+	require.Equal(t, int32(4), eventCount(&apiv1.Event{Count: 4}))
+	require.Equal(t, int32(7), eventCount(&apiv1.Event{Count: 1, Series: &apiv1.EventSeries{Count: 7}}))
+}
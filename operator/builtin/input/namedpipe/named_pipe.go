@@ -0,0 +1,285 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build linux
+
+package namedpipe
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/file"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"go.uber.org/zap"
+	"golang.org/x/text/encoding"
+)
+
+func init() {
+	operator.Register("named_pipe_input", func() operator.Builder { return NewNamedPipeInputConfig("") })
+}
+
+const (
+	defaultMaxLogSize = 1024 * 1024
+
+	// retryInterval is the time to wait before opening the pipe again after
+	// it could not be opened
+	retryInterval = time.Second
+)
+
+// NewNamedPipeInputConfig creates a new named pipe input config with default values
+func NewNamedPipeInputConfig(operatorID string) *NamedPipeInputConfig {
+	return &NamedPipeInputConfig{
+		InputConfig: helper.NewInputConfig(operatorID, "named_pipe_input"),
+		Permissions: "0600",
+		MaxLogSize:  defaultMaxLogSize,
+		Encoding:    "nop",
+	}
+}
+
+// NamedPipeInputConfig is the configuration of a named pipe input operator
+type NamedPipeInputConfig struct {
+	helper.InputConfig `yaml:",inline"`
+
+	Path        string                `json:"path"                   yaml:"path"`
+	Permissions string                `json:"permissions,omitempty"  yaml:"permissions,omitempty"`
+	Multiline   *file.MultilineConfig `json:"multiline,omitempty"    yaml:"multiline,omitempty"`
+	MaxLogSize  helper.ByteSize       `json:"max_log_size,omitempty" yaml:"max_log_size,omitempty"`
+	Encoding    string                `json:"encoding,omitempty"     yaml:"encoding,omitempty"`
+}
+
+// Build will build a named pipe input operator
+func (c NamedPipeInputConfig) Build(context operator.BuildContext) ([]operator.Operator, error) {
+	inputOperator, err := c.InputConfig.Build(context)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Path == "" {
+		return nil, fmt.Errorf("missing required parameter 'path'")
+	}
+
+	permissions, err := strconv.ParseUint(c.Permissions, 8, 32)
+	if err != nil || permissions > 0777 {
+		return nil, fmt.Errorf("invalid permissions '%s'", c.Permissions)
+	}
+
+	if c.MaxLogSize <= 0 {
+		return nil, fmt.Errorf("`max_log_size` must be positive")
+	}
+
+	encoding, err := file.LookupEncoding(c.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	splitFunc, err := file.NewSplitFunc(c.Multiline, encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	namedPipeInput := &NamedPipeInput{
+		InputOperator: inputOperator,
+		path:          c.Path,
+		permissions:   os.FileMode(permissions),
+		maxLogSize:    int(c.MaxLogSize),
+		splitFunc:     splitFunc,
+		encoding:      encoding,
+	}
+	return []operator.Operator{namedPipeInput}, nil
+}
+
+// NamedPipeInput is an operator that reads log entries from a named pipe
+type NamedPipeInput struct {
+	helper.InputOperator
+
+	path        string
+	permissions os.FileMode
+	maxLogSize  int
+	splitFunc   bufio.SplitFunc
+	encoding    encoding.Encoding
+
+	pipe    *os.File
+	pipeMux sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// Start will start reading from the pipe, creating it if it does not exist
+func (n *NamedPipeInput) Start() error {
+	if err := n.createPipe(); err != nil {
+		return err
+	}
+
+	n.ctx, n.cancel = context.WithCancel(context.Background())
+
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		n.readPipe(n.ctx)
+	}()
+
+	return nil
+}
+
+// createPipe creates the pipe if it does not exist
+func (n *NamedPipeInput) createPipe() error {
+	info, err := os.Lstat(n.path)
+	switch {
+	case err == nil && info.Mode()&os.ModeNamedPipe == 0:
+		return fmt.Errorf("path '%s' exists and is not a named pipe", n.path)
+	case err == nil:
+		return nil
+	case !os.IsNotExist(err):
+		return fmt.Errorf("stat pipe: %s", err)
+	}
+
+	if err := syscall.Mkfifo(n.path, uint32(n.permissions)); err != nil {
+		return fmt.Errorf("create pipe: %s", err)
+	}
+
+	// The permissions passed to mkfifo are reduced by the umask
+	if err := os.Chmod(n.path, n.permissions); err != nil {
+		return fmt.Errorf("set pipe permissions: %s", err)
+	}
+	return nil
+}
+
+// readPipe reads from the pipe until the context is done. Reading ends each
+// time the last writer closes the pipe, so the pipe is opened again to wait
+// for the next writer.
+func (n *NamedPipeInput) readPipe(ctx context.Context) {
+	for {
+		// Opening the pipe blocks until a writer opens it
+		pipe, err := os.OpenFile(n.path, os.O_RDONLY, 0)
+		if err != nil {
+			n.Errorw("Failed to open pipe", zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retryInterval):
+			}
+			if err := n.createPipe(); err != nil {
+				n.Errorw("Failed to create pipe", zap.Error(err))
+			}
+			continue
+		}
+
+		if !n.setPipe(pipe) {
+			pipe.Close()
+			return
+		}
+
+		n.readMessages(ctx, pipe)
+		n.closePipe()
+
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			n.Debugw("Writers closed the pipe. Reopening", "path", n.path)
+		}
+	}
+}
+
+// setPipe sets the open pipe so that it can be closed by Stop, returning false
+// if the operator is already stopping
+func (n *NamedPipeInput) setPipe(pipe *os.File) bool {
+	n.pipeMux.Lock()
+	defer n.pipeMux.Unlock()
+
+	if n.ctx.Err() != nil {
+		return false
+	}
+	n.pipe = pipe
+	return true
+}
+
+func (n *NamedPipeInput) closePipe() {
+	n.pipeMux.Lock()
+	defer n.pipeMux.Unlock()
+
+	if n.pipe != nil {
+		if err := n.pipe.Close(); err != nil {
+			n.Debugw("Failed to close pipe", zap.Error(err))
+		}
+		n.pipe = nil
+	}
+}
+
+// readMessages splits the data written to the pipe into entries
+func (n *NamedPipeInput) readMessages(ctx context.Context, pipe *os.File) {
+	decoder := n.encoding.NewDecoder()
+
+	scanner := file.NewStreamScanner(pipe, n.splitFunc, n.maxLogSize)
+	for scanner.Scan() {
+		message, err := decoder.Bytes(scanner.Bytes())
+		if err != nil {
+			n.Errorw("Failed to decode message", zap.Error(err))
+			continue
+		}
+
+		entry, err := n.NewEntry(string(message))
+		if err != nil {
+			n.Errorw("Failed to create entry", zap.Error(err))
+			continue
+		}
+		n.Write(ctx, entry)
+	}
+
+	if err := scanner.Err(); err != nil && !errors.Is(err, os.ErrClosed) {
+		n.Errorw("Scanner error", zap.Error(err))
+	}
+}
+
+// Stop will stop reading from the pipe. The pipe is not removed, so writers
+// can keep using it when the operator is started again.
+func (n *NamedPipeInput) Stop() error {
+	if n.cancel == nil {
+		return nil
+	}
+	n.cancel()
+	n.closePipe()
+
+	done := make(chan struct{})
+	go func() {
+		n.wg.Wait()
+		close(done)
+	}()
+
+	// The reader may be blocked opening the pipe, which only returns once a
+	// writer opens it, so the pipe is opened for writing until it has returned
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if writer, err := os.OpenFile(n.path, os.O_WRONLY|syscall.O_NONBLOCK, 0); err == nil {
+			writer.Close()
+		}
+
+		select {
+		case <-done:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
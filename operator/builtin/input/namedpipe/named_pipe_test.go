@@ -0,0 +1,219 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build linux
+
+package namedpipe
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/file"
+	"github.com/opentelemetry/opentelemetry-log-collection/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestConfig(t *testing.T) *NamedPipeInputConfig {
+	cfg := NewNamedPipeInputConfig("test_id")
+	cfg.OutputIDs = []string{"$.fake"}
+	cfg.Path = filepath.Join(testutil.NewTempDir(t), "test.pipe")
+	return cfg
+}
+
+func startTestInput(t *testing.T, cfg *NamedPipeInputConfig) (operator.Operator, *testutil.FakeOutput) {
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	op := ops[0]
+
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, op.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, op.Start())
+	return op, fake
+}
+
+// writePipe opens the pipe, writes to it and closes it
+func writePipe(t *testing.T, path string, data string) {
+	pipe, err := os.OpenFile(path, os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = pipe.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, pipe.Close())
+}
+
+func expectMessages(t *testing.T, fake *testutil.FakeOutput, messages ...string) {
+	for _, message := range messages {
+		select {
+		case e := <-fake.Received:
+			require.Equal(t, message, e.Record)
+		case <-time.After(time.Second):
+			require.FailNow(t, "Timed out waiting for entry", message)
+		}
+	}
+
+	select {
+	case e := <-fake.Received:
+		require.FailNow(t, "Received unexpected entry", e.Record)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func requireStops(t *testing.T, op operator.Operator) {
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		require.NoError(t, op.Stop())
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		require.FailNow(t, "Timed out waiting for operator to stop")
+	}
+}
+
+func TestNamedPipeInputCreatesPipe(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.Permissions = "0660"
+
+	oldMask := syscall.Umask(0077)
+	defer syscall.Umask(oldMask)
+
+	op, _ := startTestInput(t, cfg)
+	defer requireStops(t, op)
+
+	info, err := os.Stat(cfg.Path)
+	require.NoError(t, err)
+	require.NotZero(t, info.Mode()&os.ModeNamedPipe)
+	require.Equal(t, os.FileMode(0660), info.Mode().Perm())
+}
+
+func TestNamedPipeInputExistingPipe(t *testing.T) {
+	cfg := newTestConfig(t)
+	oldMask := syscall.Umask(0)
+	require.NoError(t, syscall.Mkfifo(cfg.Path, 0640))
+	syscall.Umask(oldMask)
+
+	op, fake := startTestInput(t, cfg)
+	defer requireStops(t, op)
+
+	writePipe(t, cfg.Path, "message1\n")
+	expectMessages(t, fake, "message1")
+
+	// The permissions of an existing pipe are not changed
+	info, err := os.Stat(cfg.Path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0640), info.Mode().Perm())
+}
+
+func TestNamedPipeInputReopens(t *testing.T) {
+	cfg := newTestConfig(t)
+	op, fake := startTestInput(t, cfg)
+
+	writePipe(t, cfg.Path, "message1\nmessage2\r\npartial")
+	expectMessages(t, fake, "message1", "message2", "partial")
+
+	writePipe(t, cfg.Path, "message3\n")
+	expectMessages(t, fake, "message3")
+
+	requireStops(t, op)
+
+	// The pipe is left for writers
+	info, err := os.Stat(cfg.Path)
+	require.NoError(t, err)
+	require.NotZero(t, info.Mode()&os.ModeNamedPipe)
+}
+
+func TestNamedPipeInputMultiline(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.Multiline = &file.MultilineConfig{LineStartPattern: "^START"}
+	op, fake := startTestInput(t, cfg)
+	defer requireStops(t, op)
+
+	writePipe(t, cfg.Path, "START 1\n  detail\nSTART 2\n")
+	expectMessages(t, fake, "START 1\n  detail\n", "START 2\n")
+}
+
+func TestNamedPipeInputMaxLogSize(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.MaxLogSize = 16
+	op, fake := startTestInput(t, cfg)
+	defer requireStops(t, op)
+
+	writePipe(t, cfg.Path, "0123456789abcdefghij\nshort\n")
+	expectMessages(t, fake, "0123456789abcdef", "ghij", "short")
+}
+
+func TestNamedPipeInputStopWithOpenWriter(t *testing.T) {
+	cfg := newTestConfig(t)
+	op, fake := startTestInput(t, cfg)
+
+	pipe, err := os.OpenFile(cfg.Path, os.O_WRONLY, 0)
+	require.NoError(t, err)
+	defer pipe.Close()
+
+	_, err = pipe.Write([]byte("message1\n"))
+	require.NoError(t, err)
+	expectMessages(t, fake, "message1")
+
+	requireStops(t, op)
+}
+
+func TestNamedPipeInputStopWithoutWriter(t *testing.T) {
+	cfg := newTestConfig(t)
+	op, _ := startTestInput(t, cfg)
+
+	// Give the reader time to block opening the pipe
+	time.Sleep(50 * time.Millisecond)
+	requireStops(t, op)
+}
+
+func TestNamedPipeInputPathNotPipe(t *testing.T) {
+	cfg := newTestConfig(t)
+	require.NoError(t, ioutil.WriteFile(cfg.Path, []byte("data"), 0600))
+
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	require.Error(t, ops[0].Start())
+	require.NoError(t, ops[0].Stop())
+}
+
+func TestBuildNamedPipeInputErrors(t *testing.T) {
+	cases := []struct {
+		name      string
+		configure func(*NamedPipeInputConfig)
+	}{
+		{"MissingPath", func(c *NamedPipeInputConfig) { c.Path = "" }},
+		{"InvalidPermissions", func(c *NamedPipeInputConfig) { c.Permissions = "rw-------" }},
+		{"LargePermissions", func(c *NamedPipeInputConfig) { c.Permissions = "7777" }},
+		{"ZeroMaxLogSize", func(c *NamedPipeInputConfig) { c.MaxLogSize = 0 }},
+		{"UnknownEncoding", func(c *NamedPipeInputConfig) { c.Encoding = "not-an-encoding" }},
+		{"InvalidMultiline", func(c *NamedPipeInputConfig) { c.Multiline = &file.MultilineConfig{} }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := newTestConfig(t)
+			tc.configure(cfg)
+
+			_, err := cfg.Build(testutil.NewBuildContext(t))
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mitchellh/mapstructure"
+	"go.opentelemetry.io/collector/config/configgrpc"
+	"go.opentelemetry.io/collector/config/confighttp"
+	"go.opentelemetry.io/collector/config/confignet"
+	"go.opentelemetry.io/collector/receiver/otlpreceiver"
+)
+
+const (
+	defaultGRPCEndpoint = "0.0.0.0:55680"
+	defaultHTTPEndpoint = "0.0.0.0:55681"
+)
+
+// ProtocolsConfig makes otlpreceiver.Protocols marshallable with json and yaml
+type ProtocolsConfig struct {
+	otlpreceiver.Protocols
+}
+
+// NewProtocolsConfig creates a new default config with both grpc and http enabled
+func NewProtocolsConfig() ProtocolsConfig {
+	return ProtocolsConfig{
+		otlpreceiver.Protocols{
+			GRPC: newGRPCServerSettings(),
+			HTTP: newHTTPServerSettings(),
+		},
+	}
+}
+
+func newGRPCServerSettings() *configgrpc.GRPCServerSettings {
+	return &configgrpc.GRPCServerSettings{
+		NetAddr: confignet.NetAddr{
+			Endpoint:  defaultGRPCEndpoint,
+			Transport: "tcp",
+		},
+		ReadBufferSize: 512 * 1024,
+	}
+}
+
+func newHTTPServerSettings() *confighttp.HTTPServerSettings {
+	return &confighttp.HTTPServerSettings{
+		Endpoint: defaultHTTPEndpoint,
+	}
+}
+
+// UnmarshalJSON will unmarshal json into a ProtocolsConfig struct
+func (c *ProtocolsConfig) UnmarshalJSON(data []byte) error {
+	raw := make(map[string]interface{})
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	return c.decode(raw)
+}
+
+// UnmarshalYAML will unmarshal yaml into a ProtocolsConfig struct
+func (c *ProtocolsConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	raw := make(map[string]interface{})
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	return c.decode(raw)
+}
+
+// decode will enable each protocol named in raw, using
+// default settings for any that are not specified
+func (c *ProtocolsConfig) decode(raw map[string]interface{}) error {
+	protocols := otlpreceiver.Protocols{}
+	if _, ok := raw["grpc"]; ok {
+		protocols.GRPC = newGRPCServerSettings()
+	}
+	if _, ok := raw["http"]; ok {
+		protocols.HTTP = newHTTPServerSettings()
+	}
+
+	if err := mapstructure.Decode(raw, &protocols); err != nil {
+		return err
+	}
+	c.Protocols = protocols
+	return nil
+}
+
+func (c ProtocolsConfig) validate() error {
+	if c.GRPC == nil && c.HTTP == nil {
+		return fmt.Errorf("at least one of 'grpc' or 'http' must be enabled in 'protocols'")
+	}
+
+	if c.GRPC != nil && c.GRPC.NetAddr.Endpoint == "" {
+		return fmt.Errorf("'protocols.grpc.endpoint' is required")
+	}
+
+	if c.HTTP != nil && c.HTTP.Endpoint == "" {
+		return fmt.Errorf("'protocols.http.endpoint' is required")
+	}
+
+	return nil
+}
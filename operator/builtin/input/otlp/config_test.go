@@ -0,0 +1,65 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestNewProtocolsConfig(t *testing.T) {
+	cfg := NewProtocolsConfig()
+	require.Equal(t, "0.0.0.0:55680", cfg.GRPC.NetAddr.Endpoint)
+	require.Equal(t, "tcp", cfg.GRPC.NetAddr.Transport)
+	require.Equal(t, "0.0.0.0:55681", cfg.HTTP.Endpoint)
+}
+
+func TestProtocolsConfigUnmarshalYAML(t *testing.T) {
+	t.Run("DefaultSettings", func(t *testing.T) {
+		cfg := ProtocolsConfig{}
+		require.NoError(t, yaml.Unmarshal([]byte("grpc:\n"), &cfg))
+		require.Equal(t, "0.0.0.0:55680", cfg.GRPC.NetAddr.Endpoint)
+		require.Nil(t, cfg.HTTP)
+	})
+
+	t.Run("CustomSettings", func(t *testing.T) {
+		raw := `
+grpc:
+  endpoint: localhost:4317
+http:
+  endpoint: localhost:4318
+  tls_settings:
+    cert_file: cert.pem
+    key_file: key.pem
+`
+		cfg := ProtocolsConfig{}
+		require.NoError(t, yaml.Unmarshal([]byte(raw), &cfg))
+		require.Equal(t, "localhost:4317", cfg.GRPC.NetAddr.Endpoint)
+		require.Equal(t, "tcp", cfg.GRPC.NetAddr.Transport)
+		require.Equal(t, "localhost:4318", cfg.HTTP.Endpoint)
+		require.Equal(t, "cert.pem", cfg.HTTP.TLSSetting.CertFile)
+		require.Equal(t, "key.pem", cfg.HTTP.TLSSetting.KeyFile)
+	})
+}
+
+func TestProtocolsConfigUnmarshalJSON(t *testing.T) {
+	cfg := ProtocolsConfig{}
+	require.NoError(t, json.Unmarshal([]byte(`{"http": {"endpoint": "localhost:4318"}}`), &cfg))
+	require.Nil(t, cfg.GRPC)
+	require.Equal(t, "localhost:4318", cfg.HTTP.Endpoint)
+}
@@ -0,0 +1,185 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"go.opentelemetry.io/collector/consumer/pdata"
+)
+
+// Convert converts pdata.Logs to a slice of entries.
+// It is the reverse of the conversion done by otlp_output.
+func Convert(logs pdata.Logs) []*entry.Entry {
+	entries := make([]*entry.Entry, 0, logs.LogRecordCount())
+
+	rls := logs.ResourceLogs()
+	for i := 0; i < rls.Len(); i++ {
+		rl := rls.At(i)
+		if rl.IsNil() {
+			continue
+		}
+
+		var resource map[string]string
+		if !rl.Resource().IsNil() {
+			resource = toStringMap(rl.Resource().Attributes())
+		}
+
+		ills := rl.InstrumentationLibraryLogs()
+		for j := 0; j < ills.Len(); j++ {
+			ill := ills.At(j)
+			if ill.IsNil() {
+				continue
+			}
+
+			logs := ill.Logs()
+			for k := 0; k < logs.Len(); k++ {
+				lr := logs.At(k)
+				if lr.IsNil() {
+					continue
+				}
+				entries = append(entries, convertLogRecord(lr, resource))
+			}
+		}
+	}
+
+	return entries
+}
+
+func convertLogRecord(lr pdata.LogRecord, resource map[string]string) *entry.Entry {
+	e := entry.New()
+
+	if lr.Timestamp() != 0 {
+		e.Timestamp = time.Unix(0, int64(lr.Timestamp()))
+	}
+
+	e.Severity = convertSeverity(lr.SeverityNumber())
+	e.SeverityText = lr.SeverityText()
+
+	if len(resource) > 0 {
+		e.Resource = make(map[string]string, len(resource))
+		for k, v := range resource {
+			e.Resource[k] = v
+		}
+	}
+
+	if lr.Attributes().Len() > 0 {
+		e.Labels = toStringMap(lr.Attributes())
+	}
+
+	if !lr.Body().IsNil() {
+		e.Record = toInterface(lr.Body())
+	}
+
+	return e
+}
+
+// toStringMap converts attributes to a map of strings, since
+// labels and resource values are always strings
+func toStringMap(attributes pdata.AttributeMap) map[string]string {
+	m := make(map[string]string, attributes.Len())
+	attributes.ForEach(func(k string, v pdata.AttributeValue) {
+		m[k] = toString(v)
+	})
+	return m
+}
+
+func toString(value pdata.AttributeValue) string {
+	switch value.Type() {
+	case pdata.AttributeValueSTRING:
+		return value.StringVal()
+	case pdata.AttributeValueINT:
+		return strconv.FormatInt(value.IntVal(), 10)
+	case pdata.AttributeValueDOUBLE:
+		return strconv.FormatFloat(value.DoubleVal(), 'f', -1, 64)
+	case pdata.AttributeValueBOOL:
+		return strconv.FormatBool(value.BoolVal())
+	case pdata.AttributeValueMAP, pdata.AttributeValueARRAY:
+		bytes, err := json.Marshal(toInterface(value))
+		if err != nil {
+			return fmt.Sprintf("%v", toInterface(value))
+		}
+		return string(bytes)
+	default:
+		return ""
+	}
+}
+
+func toInterface(value pdata.AttributeValue) interface{} {
+	switch value.Type() {
+	case pdata.AttributeValueSTRING:
+		return value.StringVal()
+	case pdata.AttributeValueINT:
+		return value.IntVal()
+	case pdata.AttributeValueDOUBLE:
+		return value.DoubleVal()
+	case pdata.AttributeValueBOOL:
+		return value.BoolVal()
+	case pdata.AttributeValueMAP:
+		m := make(map[string]interface{}, value.MapVal().Len())
+		value.MapVal().ForEach(func(k string, v pdata.AttributeValue) {
+			m[k] = toInterface(v)
+		})
+		return m
+	case pdata.AttributeValueARRAY:
+		arr := value.ArrayVal()
+		s := make([]interface{}, 0, arr.Len())
+		for i := 0; i < arr.Len(); i++ {
+			s = append(s, toInterface(arr.At(i)))
+		}
+		return s
+	default:
+		return nil
+	}
+}
+
+var severityLevels = map[pdata.SeverityNumber]entry.Severity{
+	pdata.SeverityNumberUNDEFINED: entry.Default,
+	pdata.SeverityNumberTRACE:     entry.Trace,
+	pdata.SeverityNumberTRACE2:    entry.Trace2,
+	pdata.SeverityNumberTRACE3:    entry.Trace3,
+	pdata.SeverityNumberTRACE4:    entry.Trace4,
+	pdata.SeverityNumberDEBUG:     entry.Debug,
+	pdata.SeverityNumberDEBUG2:    entry.Debug2,
+	pdata.SeverityNumberDEBUG3:    entry.Debug3,
+	pdata.SeverityNumberDEBUG4:    entry.Debug4,
+	pdata.SeverityNumberINFO:      entry.Info,
+	pdata.SeverityNumberINFO2:     entry.Info2,
+	pdata.SeverityNumberINFO3:     entry.Info3,
+	pdata.SeverityNumberINFO4:     entry.Info4,
+	pdata.SeverityNumberWARN:      entry.Warning,
+	pdata.SeverityNumberWARN2:     entry.Warning2,
+	pdata.SeverityNumberWARN3:     entry.Warning3,
+	pdata.SeverityNumberWARN4:     entry.Warning4,
+	pdata.SeverityNumberERROR:     entry.Error,
+	pdata.SeverityNumberERROR2:    entry.Error2,
+	pdata.SeverityNumberERROR3:    entry.Error3,
+	pdata.SeverityNumberERROR4:    entry.Error4,
+	pdata.SeverityNumberFATAL:     entry.Emergency,
+	pdata.SeverityNumberFATAL2:    entry.Emergency2,
+	pdata.SeverityNumberFATAL3:    entry.Emergency3,
+	pdata.SeverityNumberFATAL4:    entry.Emergency4,
+}
+
+func convertSeverity(s pdata.SeverityNumber) entry.Severity {
+	if sev, ok := severityLevels[s]; ok {
+		return sev
+	}
+	return entry.Default
+}
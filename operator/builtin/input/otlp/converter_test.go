@@ -0,0 +1,112 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer/pdata"
+)
+
+func TestConvert(t *testing.T) {
+	entries := Convert(newTestLogs())
+	require.Len(t, entries, 1)
+
+	expected := expectedEntry()
+	require.True(t, expected.Timestamp.Equal(entries[0].Timestamp))
+	entries[0].Timestamp = expected.Timestamp
+	require.Equal(t, expected, entries[0])
+}
+
+func TestConvertBody(t *testing.T) {
+	logs := pdata.NewLogs()
+	logs.ResourceLogs().Resize(1)
+	rl := logs.ResourceLogs().At(0)
+	rl.InstrumentationLibraryLogs().Resize(1)
+
+	nested := pdata.NewAttributeMap()
+	nested.InsertString("key", "value")
+	nestedVal := pdata.NewAttributeValueMap()
+	nestedVal.SetMapVal(nested)
+
+	arr := pdata.NewAnyValueArray()
+	arr.Append(pdata.NewAttributeValueString("a"))
+	arr.Append(pdata.NewAttributeValueInt(1))
+	arrVal := pdata.NewAttributeValueArray()
+	arrVal.SetArrayVal(arr)
+
+	body := pdata.NewAttributeMap()
+	body.InsertString("string", "hello")
+	body.InsertInt("int", 1)
+	body.InsertDouble("double", 1.5)
+	body.InsertBool("bool", true)
+	body.Insert("map", nestedVal)
+	body.Insert("array", arrVal)
+
+	lr := pdata.NewLogRecord()
+	lr.InitEmpty()
+	lr.Body().InitEmpty()
+	lr.Body().SetMapVal(body)
+	lr.Attributes().InsertInt("count", 3)
+	lr.Attributes().InsertBool("ok", true)
+	lr.Attributes().Insert("list", arrVal)
+	rl.InstrumentationLibraryLogs().At(0).Logs().Append(lr)
+
+	entries := Convert(logs)
+	require.Len(t, entries, 1)
+	require.Equal(t, map[string]interface{}{
+		"string": "hello",
+		"int":    int64(1),
+		"double": 1.5,
+		"bool":   true,
+		"map":    map[string]interface{}{"key": "value"},
+		"array":  []interface{}{"a", int64(1)},
+	}, entries[0].Record)
+	require.Equal(t, map[string]string{
+		"count": "3",
+		"ok":    "true",
+		"list":  `["a",1]`,
+	}, entries[0].Labels)
+	require.Nil(t, entries[0].Resource)
+
+	// A missing timestamp defaults to the time of conversion
+	require.WithinDuration(t, time.Now(), entries[0].Timestamp, time.Minute)
+}
+
+func TestConvertSeverity(t *testing.T) {
+	cases := []struct {
+		severity pdata.SeverityNumber
+		expected entry.Severity
+	}{
+		{pdata.SeverityNumberUNDEFINED, entry.Default},
+		{pdata.SeverityNumberTRACE, entry.Trace},
+		{pdata.SeverityNumberDEBUG2, entry.Debug2},
+		{pdata.SeverityNumberINFO, entry.Info},
+		{pdata.SeverityNumberWARN3, entry.Warning3},
+		{pdata.SeverityNumberERROR, entry.Error},
+		{pdata.SeverityNumberERROR4, entry.Error4},
+		{pdata.SeverityNumberFATAL, entry.Emergency},
+		{pdata.SeverityNumberFATAL4, entry.Emergency4},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.expected.String(), func(t *testing.T) {
+			require.Equal(t, tc.expected, convertSeverity(tc.severity))
+		})
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/errors"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configmodels"
+	"go.opentelemetry.io/collector/consumer/pdata"
+	"go.opentelemetry.io/collector/receiver/otlpreceiver"
+	"go.uber.org/zap"
+)
+
+func init() {
+	operator.Register("otlp_input", func() operator.Builder { return NewOTLPInputConfig("") })
+}
+
+// NewOTLPInputConfig creates a new otlp input config with default values
+func NewOTLPInputConfig(operatorID string) *OTLPInputConfig {
+	return &OTLPInputConfig{
+		InputConfig: helper.NewInputConfig(operatorID, "otlp_input"),
+		Protocols:   NewProtocolsConfig(),
+	}
+}
+
+// OTLPInputConfig is the configuration of an otlp input operator
+type OTLPInputConfig struct {
+	helper.InputConfig `yaml:",inline"`
+	Protocols          ProtocolsConfig `json:"protocols" yaml:"protocols"`
+}
+
+// Build will build a new OTLPInput
+func (c OTLPInputConfig) Build(bc operator.BuildContext) ([]operator.Operator, error) {
+	inputOperator, err := c.InputConfig.Build(bc)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.Protocols.validate(); err != nil {
+		return nil, err
+	}
+
+	otlpInput := &OTLPInput{
+		InputOperator: inputOperator,
+	}
+
+	factory := otlpreceiver.NewFactory()
+	receiverConfig := factory.CreateDefaultConfig().(*otlpreceiver.Config)
+	receiverConfig.SetName(c.ID())
+	receiverConfig.Protocols = c.Protocols.Protocols
+
+	params := component.ReceiverCreateParams{Logger: bc.Logger.Desugar()}
+	receiver, err := factory.CreateLogsReceiver(context.Background(), params, receiverConfig, otlpInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "create receiver")
+	}
+	otlpInput.receiver = receiver
+
+	return []operator.Operator{otlpInput}, nil
+}
+
+// OTLPInput is an operator that receives logs from OTLP exporters over grpc and http
+type OTLPInput struct {
+	helper.InputOperator
+	receiver component.LogsReceiver
+}
+
+// Start will start listening for logs
+func (o *OTLPInput) Start() error {
+	return o.receiver.Start(context.Background(), o)
+}
+
+// Stop will stop listening for logs
+func (o *OTLPInput) Stop() error {
+	return o.receiver.Shutdown(context.Background())
+}
+
+// ConsumeLogs will write received logs as entries. It is called by the receiver for each request.
+func (o *OTLPInput) ConsumeLogs(ctx context.Context, logs pdata.Logs) error {
+	for _, e := range Convert(logs) {
+		record := e.Record
+		e.Record = nil
+		if err := e.Set(o.WriteTo, record); err != nil {
+			o.Errorw("Failed to set record", zap.Error(err))
+			continue
+		}
+
+		if err := o.Label(e); err != nil {
+			o.Errorw("Failed to add labels", zap.Error(err))
+			continue
+		}
+
+		if err := o.Identify(e); err != nil {
+			o.Errorw("Failed to add resource keys", zap.Error(err))
+			continue
+		}
+
+		o.Write(ctx, e)
+	}
+	return nil
+}
+
+// ReportFatalError logs errors from the receiver's servers
+func (o *OTLPInput) ReportFatalError(err error) {
+	if err == http.ErrServerClosed {
+		return
+	}
+	o.Errorw("Receiver failed", zap.Error(err))
+}
+
+// GetFactory implements component.Host. Factories are not available to the receiver.
+func (o *OTLPInput) GetFactory(_ component.Kind, _ configmodels.Type) component.Factory {
+	return nil
+}
+
+// GetExtensions implements component.Host. Extensions are not available to the receiver.
+func (o *OTLPInput) GetExtensions() map[configmodels.Extension]component.ServiceExtension {
+	return nil
+}
+
+// GetExporters implements component.Host. Exporters are not available to the receiver.
+func (o *OTLPInput) GetExporters() map[configmodels.DataType]map[configmodels.Exporter]component.Exporter {
+	return nil
+}
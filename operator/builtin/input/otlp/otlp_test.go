@@ -0,0 +1,237 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"github.com/opentelemetry/opentelemetry-log-collection/testutil"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer/pdata"
+	"google.golang.org/grpc"
+)
+
+const testJSON = `{
+  "resourceLogs": [{
+    "resource": {
+      "attributes": [{"key": "host.name", "value": {"stringValue": "test-host"}}]
+    },
+    "instrumentationLibraryLogs": [{
+      "logs": [{
+        "timeUnixNano": "1600000000123456789",
+        "severityNumber": 17,
+        "severityText": "ERROR",
+        "attributes": [{"key": "app", "value": {"stringValue": "web"}}],
+        "body": {"stringValue": "test message"}
+      }]
+    }]
+  }]
+}`
+
+func newTestLogs() pdata.Logs {
+	logs := pdata.NewLogs()
+	logs.ResourceLogs().Resize(1)
+	rl := logs.ResourceLogs().At(0)
+	rl.Resource().InitEmpty()
+	rl.Resource().Attributes().InsertString("host.name", "test-host")
+
+	rl.InstrumentationLibraryLogs().Resize(1)
+	lr := pdata.NewLogRecord()
+	lr.InitEmpty()
+	lr.SetTimestamp(pdata.TimestampUnixNano(1600000000123456789))
+	lr.SetSeverityNumber(pdata.SeverityNumberERROR)
+	lr.SetSeverityText("ERROR")
+	lr.Attributes().InsertString("app", "web")
+	lr.Body().InitEmpty()
+	lr.Body().SetStringVal("test message")
+	rl.InstrumentationLibraryLogs().At(0).Logs().Append(lr)
+	return logs
+}
+
+func expectedEntry() *entry.Entry {
+	e := entry.New()
+	e.Timestamp = time.Unix(0, 1600000000123456789)
+	e.Severity = entry.Error
+	e.SeverityText = "ERROR"
+	e.Resource = map[string]string{"host.name": "test-host"}
+	e.Labels = map[string]string{"app": "web"}
+	e.Record = "test message"
+	return e
+}
+
+// freeAddress returns a local address with an unused port
+func freeAddress(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+func newTestOTLPInput(t *testing.T) (grpcAddress, httpAddress string, fakeOutput *testutil.FakeOutput) {
+	grpcAddress, httpAddress = freeAddress(t), freeAddress(t)
+
+	cfg := NewOTLPInputConfig("test_input")
+	cfg.Protocols.GRPC.NetAddr.Endpoint = grpcAddress
+	cfg.Protocols.HTTP.Endpoint = httpAddress
+
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	otlpInput := ops[0].(*OTLPInput)
+
+	fakeOutput = testutil.NewFakeOutput(t)
+	otlpInput.InputOperator.OutputOperators = []operator.Operator{fakeOutput}
+
+	require.NoError(t, otlpInput.Start())
+	t.Cleanup(func() { require.NoError(t, otlpInput.Stop()) })
+	return grpcAddress, httpAddress, fakeOutput
+}
+
+func expectEntry(t *testing.T, fakeOutput *testutil.FakeOutput, expected *entry.Entry) {
+	select {
+	case e := <-fakeOutput.Received:
+		require.True(t, expected.Timestamp.Equal(e.Timestamp), "expected %s, got %s", expected.Timestamp, e.Timestamp)
+		e.Timestamp = expected.Timestamp
+		require.Equal(t, expected, e)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "Timed out waiting for entry")
+	}
+}
+
+func postLogs(t *testing.T, url, contentType string, body []byte, compress bool) {
+	if compress {
+		var b bytes.Buffer
+		w := gzip.NewWriter(&b)
+		_, err := w.Write(body)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		body = b.Bytes()
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	if compress {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+// rawCodec sends pre-encoded protobuf bytes, since the generated
+// OTLP service clients are internal to the collector
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) { return *(v.(*[]byte)), nil }
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	*(v.(*[]byte)) = data
+	return nil
+}
+
+func (rawCodec) Name() string { return "proto" }
+
+func TestOTLPInput(t *testing.T) {
+	grpcAddress, httpAddress, fakeOutput := newTestOTLPInput(t)
+	url := fmt.Sprintf("http://%s/v1/logs", httpAddress)
+
+	protoBytes, err := newTestLogs().ToOtlpProtoBytes()
+	require.NoError(t, err)
+
+	t.Run("HTTPProtobuf", func(t *testing.T) {
+		postLogs(t, url, "application/x-protobuf", protoBytes, false)
+		expectEntry(t, fakeOutput, expectedEntry())
+	})
+
+	t.Run("HTTPProtobufGzip", func(t *testing.T) {
+		postLogs(t, url, "application/x-protobuf", protoBytes, true)
+		expectEntry(t, fakeOutput, expectedEntry())
+	})
+
+	t.Run("HTTPJSON", func(t *testing.T) {
+		postLogs(t, url, "application/json", []byte(testJSON), false)
+		expectEntry(t, fakeOutput, expectedEntry())
+	})
+
+	t.Run("GRPC", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		conn, err := grpc.DialContext(ctx, grpcAddress, grpc.WithInsecure(), grpc.WithBlock())
+		require.NoError(t, err)
+		defer conn.Close()
+
+		var response []byte
+		err = conn.Invoke(ctx, "/opentelemetry.proto.collector.logs.v1.LogsService/Export", &protoBytes, &response, grpc.ForceCodec(rawCodec{}))
+		require.NoError(t, err)
+		expectEntry(t, fakeOutput, expectedEntry())
+	})
+}
+
+func TestOTLPInputWriteToAndLabels(t *testing.T) {
+	cfg := NewOTLPInputConfig("test_input")
+	cfg.Protocols.HTTP = nil
+	cfg.WriteTo = entry.NewRecordField("body")
+	cfg.Labels = map[string]helper.ExprStringConfig{"source": "otlp"}
+
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	otlpInput := ops[0].(*OTLPInput)
+
+	fakeOutput := testutil.NewFakeOutput(t)
+	otlpInput.InputOperator.OutputOperators = []operator.Operator{fakeOutput}
+
+	require.NoError(t, otlpInput.ConsumeLogs(context.Background(), newTestLogs()))
+
+	expected := expectedEntry()
+	expected.Record = map[string]interface{}{"body": "test message"}
+	expected.Labels["source"] = "otlp"
+	expectEntry(t, fakeOutput, expected)
+}
+
+func TestOTLPInputBuild(t *testing.T) {
+	cases := []struct {
+		name      string
+		configure func(cfg *OTLPInputConfig)
+	}{
+		{"NoProtocols", func(cfg *OTLPInputConfig) {
+			cfg.Protocols.GRPC = nil
+			cfg.Protocols.HTTP = nil
+		}},
+		{"MissingGRPCEndpoint", func(cfg *OTLPInputConfig) { cfg.Protocols.GRPC.NetAddr.Endpoint = "" }},
+		{"MissingHTTPEndpoint", func(cfg *OTLPInputConfig) { cfg.Protocols.HTTP.Endpoint = "" }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := NewOTLPInputConfig("test_input")
+			tc.configure(cfg)
+			_, err := cfg.Build(testutil.NewBuildContext(t))
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcp
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// maxOctetCountDigits is the maximum number of digits allowed in an octet count prefix
+const maxOctetCountDigits = 10
+
+// splitNull is a bufio.SplitFunc that splits a stream on null bytes
+func splitNull(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+
+	// Flush the remaining data when the connection is closed
+	if atEOF {
+		return len(data), data, nil
+	}
+
+	// Request more data.
+	return 0, nil, nil
+}
+
+// splitOctetCount is a bufio.SplitFunc that splits a stream framed
+// with octet counting, as described in RFC 6587 section 3.4.1
+func splitOctetCount(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	// Skip any trailers sent between frames
+	start := 0
+	for start < len(data) && isTrailer(data[start]) {
+		start++
+	}
+
+	space := bytes.IndexByte(data[start:], ' ')
+	if space == -1 {
+		if len(data)-start > maxOctetCountDigits {
+			return 0, nil, fmt.Errorf("octet count prefix exceeds %d digits", maxOctetCountDigits)
+		}
+		if atEOF {
+			// Discard a trailing partial prefix
+			return len(data), nil, nil
+		}
+		return 0, nil, nil
+	}
+
+	prefix := data[start : start+space]
+	length, err := strconv.Atoi(string(prefix))
+	if err != nil || length < 0 || len(prefix) > maxOctetCountDigits {
+		return 0, nil, fmt.Errorf("invalid octet count prefix '%s'", prefix)
+	}
+
+	frameStart := start + space + 1
+	frameEnd := frameStart + length
+	if frameEnd > len(data) {
+		if atEOF {
+			return 0, nil, fmt.Errorf("connection closed with an incomplete frame")
+		}
+		return 0, nil, nil
+	}
+
+	return frameEnd, data[frameStart:frameEnd], nil
+}
+
+func isTrailer(b byte) bool {
+	return b == '\n' || b == '\r' || b == 0
+}
@@ -17,11 +17,15 @@ package tcp
 import (
 	"bufio"
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/file"
 	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
 	"go.uber.org/zap"
 )
@@ -30,10 +34,33 @@ func init() {
 	operator.Register("tcp_input", func() operator.Builder { return NewTCPInputConfig("") })
 }
 
+const (
+	defaultMaxLogSize = 1024 * 1024
+
+	// minBufferSize is the initial size of the buffer used to read from a connection
+	minBufferSize = 16 * 1024
+
+	// handshakeTimeout limits the tls handshake when there is no idle_timeout
+	handshakeTimeout = 10 * time.Second
+
+	// Supported framing modes
+	newlineFraming    = "newline"
+	nullFraming       = "null"
+	octetCountFraming = "octet_count"
+	multilineFraming  = "multiline"
+
+	// Labels added to entries when add_labels is enabled
+	peerIPLabel   = "net.peer.ip"
+	peerPortLabel = "net.peer.port"
+	peerCNLabel   = "tls.peer.common_name"
+)
+
 // NewTCPInputConfig creates a new TCP input config with default values
 func NewTCPInputConfig(operatorID string) *TCPInputConfig {
 	return &TCPInputConfig{
 		InputConfig: helper.NewInputConfig(operatorID, "tcp_input"),
+		MaxLogSize:  defaultMaxLogSize,
+		Framing:     newlineFraming,
 	}
 }
 
@@ -41,7 +68,14 @@ func NewTCPInputConfig(operatorID string) *TCPInputConfig {
 type TCPInputConfig struct {
 	helper.InputConfig `yaml:",inline"`
 
-	ListenAddress string `json:"listen_address,omitempty" yaml:"listen_address,omitempty"`
+	ListenAddress  string                  `json:"listen_address,omitempty"  yaml:"listen_address,omitempty"`
+	TLS            *helper.TLSServerConfig `json:"tls,omitempty"             yaml:"tls,omitempty"`
+	MaxConnections int                     `json:"max_connections,omitempty" yaml:"max_connections,omitempty"`
+	IdleTimeout    helper.Duration         `json:"idle_timeout,omitempty"    yaml:"idle_timeout,omitempty"`
+	MaxLogSize     helper.ByteSize         `json:"max_log_size,omitempty"    yaml:"max_log_size,omitempty"`
+	Framing        string                  `json:"framing,omitempty"         yaml:"framing,omitempty"`
+	Multiline      *file.MultilineConfig   `json:"multiline,omitempty"       yaml:"multiline,omitempty"`
+	AddLabels      bool                    `json:"add_labels,omitempty"      yaml:"add_labels,omitempty"`
 }
 
 // Build will build a tcp input operator.
@@ -60,21 +94,86 @@ func (c TCPInputConfig) Build(context operator.BuildContext) ([]operator.Operato
 		return nil, fmt.Errorf("failed to resolve listen_address: %s", err)
 	}
 
+	if c.MaxLogSize <= 0 {
+		return nil, fmt.Errorf("`max_log_size` must be positive")
+	}
+
+	if c.MaxConnections < 0 {
+		return nil, fmt.Errorf("`max_connections` must not be negative")
+	}
+
+	if c.IdleTimeout.Raw() < 0 {
+		return nil, fmt.Errorf("`idle_timeout` must not be negative")
+	}
+
+	splitFunc, err := c.getSplitFunc()
+	if err != nil {
+		return nil, err
+	}
+
+	var tlsConfig *tls.Config
+	if c.TLS != nil {
+		tlsConfig, err = c.TLS.Build()
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	tcpInput := &TCPInput{
-		InputOperator: inputOperator,
-		address:       address,
+		InputOperator:  inputOperator,
+		address:        address,
+		tlsConfig:      tlsConfig,
+		maxConnections: c.MaxConnections,
+		idleTimeout:    c.IdleTimeout.Raw(),
+		maxLogSize:     int(c.MaxLogSize),
+		splitFunc:      splitFunc,
+		addLabels:      c.AddLabels,
 	}
 	return []operator.Operator{tcpInput}, nil
 }
 
+// getSplitFunc will return the split function associated with the configured framing.
+func (c TCPInputConfig) getSplitFunc() (bufio.SplitFunc, error) {
+	if c.Multiline != nil && c.Framing != multilineFraming {
+		return nil, fmt.Errorf("`multiline` can only be used with `framing: %s`", multilineFraming)
+	}
+
+	switch c.Framing {
+	case newlineFraming, "":
+		return bufio.ScanLines, nil
+	case nullFraming:
+		return splitNull, nil
+	case octetCountFraming:
+		return splitOctetCount, nil
+	case multilineFraming:
+		if c.Multiline == nil {
+			return nil, fmt.Errorf("`multiline` is required when using `framing: %s`", multilineFraming)
+		}
+		splitFunc, err := c.Multiline.Build()
+		if err != nil {
+			return nil, err
+		}
+		return file.NewFlushingSplitFunc(splitFunc, int(c.MaxLogSize)), nil
+	default:
+		return nil, fmt.Errorf("invalid framing '%s'", c.Framing)
+	}
+}
+
 // TCPInput is an operator that listens for log entries over tcp.
 type TCPInput struct {
 	helper.InputOperator
-	address *net.TCPAddr
+	address        *net.TCPAddr
+	tlsConfig      *tls.Config
+	maxConnections int
+	idleTimeout    time.Duration
+	maxLogSize     int
+	splitFunc      bufio.SplitFunc
+	addLabels      bool
 
-	listener *net.TCPListener
-	cancel   context.CancelFunc
-	wg       sync.WaitGroup
+	listener    net.Listener
+	connections chan struct{}
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
 }
 
 // Start will start listening for log entries over tcp.
@@ -85,28 +184,44 @@ func (t *TCPInput) Start() error {
 	}
 
 	t.listener = listener
+	if t.tlsConfig != nil {
+		t.listener = tls.NewListener(listener, t.tlsConfig)
+	}
+
+	if t.maxConnections > 0 {
+		t.connections = make(chan struct{}, t.maxConnections)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	t.cancel = cancel
 	t.goListen(ctx)
 	return nil
 }
 
-// goListenn will listen for tcp connections.
+// goListen will listen for tcp connections.
 func (t *TCPInput) goListen(ctx context.Context) {
 	t.wg.Add(1)
 
 	go func() {
 		defer t.wg.Done()
 
+		backoff := helper.NewBackoff()
 		for {
-			conn, err := t.listener.AcceptTCP()
+			conn, err := t.listener.Accept()
 			if err != nil {
-				select {
-				case <-ctx.Done():
+				if !backoff.Wait(ctx, t.Debugw, "Listener accept error", err) {
 					return
-				default:
-					t.Debugw("Listener accept error", zap.Error(err))
 				}
+				continue
+			}
+			backoff.Reset()
+
+			if !t.acquireConnection() {
+				t.Warnw("Rejecting connection because max_connections has been reached", "remote_address", conn.RemoteAddr().String())
+				if err := conn.Close(); err != nil {
+					t.Errorf("Failed to close connection: %s", err)
+				}
+				continue
 			}
 
 			t.Debugf("Received connection: %s", conn.RemoteAddr().String())
@@ -117,12 +232,34 @@ func (t *TCPInput) goListen(ctx context.Context) {
 	}()
 }
 
+// acquireConnection will reserve a connection slot, returning false if none are available.
+func (t *TCPInput) acquireConnection() bool {
+	if t.connections == nil {
+		return true
+	}
+
+	select {
+	case t.connections <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// releaseConnection will free a connection slot.
+func (t *TCPInput) releaseConnection() {
+	if t.connections != nil {
+		<-t.connections
+	}
+}
+
 // goHandleClose will wait for the context to finish before closing a connection.
 func (t *TCPInput) goHandleClose(ctx context.Context, conn net.Conn) {
 	t.wg.Add(1)
 
 	go func() {
 		defer t.wg.Done()
+		defer t.releaseConnection()
 		<-ctx.Done()
 		t.Debugf("Closing connection: %s", conn.RemoteAddr().String())
 		if err := conn.Close(); err != nil {
@@ -139,21 +276,84 @@ func (t *TCPInput) goHandleMessages(ctx context.Context, conn net.Conn, cancel c
 		defer t.wg.Done()
 		defer cancel()
 
-		scanner := bufio.NewScanner(conn)
+		labels, err := t.connectionLabels(conn)
+		if err != nil {
+			t.Errorw("Failed to establish connection", zap.Error(err), "remote_address", conn.RemoteAddr().String())
+			return
+		}
+
+		scanner := bufio.NewScanner(&idleTimeoutReader{conn: conn, timeout: t.idleTimeout})
+		scanner.Buffer(make([]byte, 0, minInt(minBufferSize, t.maxLogSize)), t.maxLogSize)
+		scanner.Split(t.splitFunc)
 		for scanner.Scan() {
 			entry, err := t.NewEntry(scanner.Text())
 			if err != nil {
 				t.Errorw("Failed to create entry", zap.Error(err))
 				continue
 			}
+			for k, v := range labels {
+				entry.AddLabel(k, v)
+			}
 			t.Write(ctx, entry)
 		}
-		if err := scanner.Err(); err != nil {
-			t.Errorw("Scanner error", zap.Error(err))
+
+		err = scanner.Err()
+		switch {
+		case err == nil:
+		case err == bufio.ErrTooLong:
+			t.Errorw("Log entry too large. Closing connection", zap.Error(err), "max_log_size", t.maxLogSize)
+		case isTimeout(err):
+			t.Debugw("Connection idle timeout exceeded", "remote_address", conn.RemoteAddr().String())
+		default:
+			select {
+			case <-ctx.Done():
+			default:
+				t.Errorw("Scanner error", zap.Error(err))
+			}
 		}
 	}()
 }
 
+// connectionLabels will complete any tls handshake and return the labels
+// that describe the remote end of the connection.
+func (t *TCPInput) connectionLabels(conn net.Conn) (map[string]string, error) {
+	tlsConn, isTLS := conn.(*tls.Conn)
+	if isTLS {
+		timeout := t.idleTimeout
+		if timeout == 0 {
+			timeout = handshakeTimeout
+		}
+		if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+			return nil, err
+		}
+		if err := tlsConn.Handshake(); err != nil {
+			return nil, fmt.Errorf("tls handshake: %s", err)
+		}
+		if err := conn.SetDeadline(time.Time{}); err != nil {
+			return nil, err
+		}
+	}
+
+	if !t.addLabels {
+		return nil, nil
+	}
+
+	labels := make(map[string]string, 3)
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		labels[peerIPLabel] = addr.IP.String()
+		labels[peerPortLabel] = strconv.Itoa(addr.Port)
+	}
+
+	if isTLS {
+		peerCerts := tlsConn.ConnectionState().PeerCertificates
+		if len(peerCerts) > 0 {
+			labels[peerCNLabel] = peerCerts[0].Subject.CommonName
+		}
+	}
+
+	return labels, nil
+}
+
 // Stop will stop listening for log entries over TCP.
 func (t *TCPInput) Stop() error {
 	t.cancel()
@@ -165,3 +365,31 @@ func (t *TCPInput) Stop() error {
 	t.wg.Wait()
 	return nil
 }
+
+// idleTimeoutReader extends the read deadline of a connection before every read
+type idleTimeoutReader struct {
+	conn    net.Conn
+	timeout time.Duration
+}
+
+// Read will read from the underlying connection
+func (r *idleTimeoutReader) Read(p []byte) (int, error) {
+	if r.timeout > 0 {
+		if err := r.conn.SetReadDeadline(time.Now().Add(r.timeout)); err != nil {
+			return 0, err
+		}
+	}
+	return r.conn.Read(p)
+}
+
+func isTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -15,67 +15,374 @@
 package tcp
 
 import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
 	"net"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/opentelemetry/opentelemetry-log-collection/entry"
 	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/file"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
 	"github.com/opentelemetry/opentelemetry-log-collection/testutil"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
-func tcpInputTest(input []byte, expected []string) func(t *testing.T) {
+func newTestTCPInput(t *testing.T, cfg *TCPInputConfig) (*TCPInput, chan *entry.Entry) {
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	op := ops[0]
+
+	mockOutput := testutil.Operator{}
+	tcpInput := op.(*TCPInput)
+	tcpInput.InputOperator.OutputOperators = []operator.Operator{&mockOutput}
+
+	entryChan := make(chan *entry.Entry, 10)
+	mockOutput.On("Process", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		entryChan <- args.Get(1).(*entry.Entry)
+	}).Return(nil)
+
+	return tcpInput, entryChan
+}
+
+func expectEntries(t *testing.T, entryChan chan *entry.Entry, expected []string) {
+	for _, expectedMessage := range expected {
+		select {
+		case entry := <-entryChan:
+			require.Equal(t, expectedMessage, entry.Record)
+		case <-time.After(time.Second):
+			require.FailNow(t, "Timed out waiting for message to be written")
+		}
+	}
+
+	select {
+	case entry := <-entryChan:
+		require.FailNow(t, "Unexpected entry: %s", entry)
+	case <-time.After(100 * time.Millisecond):
+		return
+	}
+}
+
+func tcpInputTest(input []byte, expected []string, configure func(cfg *TCPInputConfig)) func(t *testing.T) {
 	return func(t *testing.T) {
 		cfg := NewTCPInputConfig("test_id")
 		cfg.ListenAddress = ":0"
+		if configure != nil {
+			configure(cfg)
+		}
 
-		ops, err := cfg.Build(testutil.NewBuildContext(t))
-		require.NoError(t, err)
-		op := ops[0]
-
-		mockOutput := testutil.Operator{}
-		tcpInput := op.(*TCPInput)
-		tcpInput.InputOperator.OutputOperators = []operator.Operator{&mockOutput}
-
-		entryChan := make(chan *entry.Entry, 1)
-		mockOutput.On("Process", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
-			entryChan <- args.Get(1).(*entry.Entry)
-		}).Return(nil)
+		tcpInput, entryChan := newTestTCPInput(t, cfg)
 
-		err = tcpInput.Start()
+		err := tcpInput.Start()
 		require.NoError(t, err)
 		defer tcpInput.Stop()
 
 		conn, err := net.Dial("tcp", tcpInput.listener.Addr().String())
 		require.NoError(t, err)
-		defer conn.Close()
 
 		_, err = conn.Write(input)
 		require.NoError(t, err)
+		conn.Close()
 
-		for _, expectedMessage := range expected {
-			select {
-			case entry := <-entryChan:
-				require.Equal(t, expectedMessage, entry.Record)
-			case <-time.After(time.Second):
-				require.FailNow(t, "Timed out waiting for message to be written")
-			}
-		}
+		expectEntries(t, entryChan, expected)
+	}
+}
+
+func TestTcpInput(t *testing.T) {
+	t.Run("Simple", tcpInputTest([]byte("message\n"), []string{"message"}, nil))
+	t.Run("CarriageReturn", tcpInputTest([]byte("message\r\n"), []string{"message"}, nil))
+	t.Run("Null", tcpInputTest([]byte("message1\x00message\n2\x00"), []string{"message1", "message\n2"}, func(cfg *TCPInputConfig) {
+		cfg.Framing = "null"
+	}))
+	t.Run("OctetCount", tcpInputTest([]byte("8 message19 message\n2"), []string{"message1", "message\n2"}, func(cfg *TCPInputConfig) {
+		cfg.Framing = "octet_count"
+	}))
+	t.Run("OctetCountTrailer", tcpInputTest([]byte("8 message1\n9 message\n2\n"), []string{"message1", "message\n2"}, func(cfg *TCPInputConfig) {
+		cfg.Framing = "octet_count"
+	}))
+	t.Run("Multiline", tcpInputTest([]byte("LOGSTART 1\nfoo\nLOGSTART 2\nbar"), []string{"LOGSTART 1\nfoo\n", "LOGSTART 2\nbar"}, func(cfg *TCPInputConfig) {
+		cfg.Framing = "multiline"
+		cfg.Multiline = &file.MultilineConfig{LineStartPattern: "^LOGSTART"}
+	}))
+	t.Run("MaxLogSize", tcpInputTest([]byte("short\nthis message is too long\n"), []string{"short"}, func(cfg *TCPInputConfig) {
+		cfg.MaxLogSize = 10
+	}))
+}
+
+func TestTcpInputBuild(t *testing.T) {
+	cases := []struct {
+		name      string
+		configure func(cfg *TCPInputConfig)
+	}{
+		{
+			"InvalidFraming",
+			func(cfg *TCPInputConfig) { cfg.Framing = "invalid" },
+		},
+		{
+			"MultilineWithoutConfig",
+			func(cfg *TCPInputConfig) { cfg.Framing = "multiline" },
+		},
+		{
+			"MultilineWithWrongFraming",
+			func(cfg *TCPInputConfig) {
+				cfg.Multiline = &file.MultilineConfig{LineStartPattern: "^LOGSTART"}
+			},
+		},
+		{
+			"NegativeMaxConnections",
+			func(cfg *TCPInputConfig) { cfg.MaxConnections = -1 },
+		},
+		{
+			"ZeroMaxLogSize",
+			func(cfg *TCPInputConfig) { cfg.MaxLogSize = 0 },
+		},
+		{
+			"MissingTLSKey",
+			func(cfg *TCPInputConfig) { cfg.TLS = &helper.TLSServerConfig{CertFile: "cert"} },
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := NewTCPInputConfig("test_id")
+			cfg.ListenAddress = ":0"
+			tc.configure(cfg)
+			_, err := cfg.Build(testutil.NewBuildContext(t))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestTcpInputLabels(t *testing.T) {
+	cfg := NewTCPInputConfig("test_id")
+	cfg.ListenAddress = "127.0.0.1:0"
+	cfg.AddLabels = true
+
+	tcpInput, entryChan := newTestTCPInput(t, cfg)
+	require.NoError(t, tcpInput.Start())
+	defer tcpInput.Stop()
+
+	conn, err := net.Dial("tcp", tcpInput.listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("message\n"))
+	require.NoError(t, err)
+
+	select {
+	case e := <-entryChan:
+		localAddr := conn.LocalAddr().(*net.TCPAddr)
+		require.Equal(t, "127.0.0.1", e.Labels["net.peer.ip"])
+		require.Equal(t, strconv.Itoa(localAddr.Port), e.Labels["net.peer.port"])
+	case <-time.After(time.Second):
+		require.FailNow(t, "Timed out waiting for message to be written")
+	}
+}
+
+func TestTcpInputMaxConnections(t *testing.T) {
+	cfg := NewTCPInputConfig("test_id")
+	cfg.ListenAddress = ":0"
+	cfg.MaxConnections = 1
+
+	tcpInput, entryChan := newTestTCPInput(t, cfg)
+	require.NoError(t, tcpInput.Start())
+	defer tcpInput.Stop()
+
+	first, err := net.Dial("tcp", tcpInput.listener.Addr().String())
+	require.NoError(t, err)
+	defer first.Close()
+	_, err = first.Write([]byte("first\n"))
+	require.NoError(t, err)
+	expectEntries(t, entryChan, []string{"first"})
+
+	second, err := net.Dial("tcp", tcpInput.listener.Addr().String())
+	require.NoError(t, err)
+	defer second.Close()
+
+	// The second connection should be closed by the server
+	require.NoError(t, second.SetReadDeadline(time.Now().Add(time.Second)))
+	_, err = second.Read(make([]byte, 1))
+	require.Error(t, err)
+	require.False(t, isTimeout(err), "expected connection to be closed")
+
+	// After the first connection closes, a new connection is accepted
+	first.Close()
+	require.Eventually(t, func() bool {
+		return len(tcpInput.connections) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	third, err := net.Dial("tcp", tcpInput.listener.Addr().String())
+	require.NoError(t, err)
+	defer third.Close()
+	_, err = third.Write([]byte("third\n"))
+	require.NoError(t, err)
+	expectEntries(t, entryChan, []string{"third"})
+}
+
+func TestTcpInputIdleTimeout(t *testing.T) {
+	cfg := NewTCPInputConfig("test_id")
+	cfg.ListenAddress = ":0"
+	cfg.IdleTimeout = helper.Duration{Duration: 100 * time.Millisecond}
+
+	tcpInput, _ := newTestTCPInput(t, cfg)
+	require.NoError(t, tcpInput.Start())
+	defer tcpInput.Stop()
+
+	conn, err := net.Dial("tcp", tcpInput.listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	_, err = conn.Read(make([]byte, 1))
+	require.Error(t, err)
+	require.False(t, isTimeout(err), "expected connection to be closed by the server")
+}
+
+func TestTcpInputAcceptBackoff(t *testing.T) {
+	cfg := NewTCPInputConfig("test_id")
+	cfg.ListenAddress = ":0"
+
+	tcpInput, _ := newTestTCPInput(t, cfg)
+	listener := &failingListener{}
+	tcpInput.listener = listener
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tcpInput.cancel = cancel
+	tcpInput.goListen(ctx)
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, tcpInput.Stop())
+
+	require.Less(t, atomic.LoadInt32(&listener.accepts), int32(10))
+}
+
+// failingListener is a listener whose Accept always fails
+type failingListener struct {
+	accepts int32
+}
+
+func (l *failingListener) Accept() (net.Conn, error) {
+	atomic.AddInt32(&l.accepts, 1)
+	return nil, fmt.Errorf("too many open files")
+}
+
+func (l *failingListener) Close() error   { return nil }
+func (l *failingListener) Addr() net.Addr { return &net.TCPAddr{} }
+
+func TestTcpInputTLS(t *testing.T) {
+	tempDir := testutil.NewTempDir(t)
+	ca, caKey := createCertificate(t, "ca", nil, nil)
+	serverCert, serverKey := createCertificate(t, "server", ca, caKey)
+	clientCert, clientKey := createCertificate(t, "client", ca, caKey)
+
+	caFile := writePEM(t, filepath.Join(tempDir, "ca.crt"), "CERTIFICATE", ca.Raw)
+	certFile := writePEM(t, filepath.Join(tempDir, "server.crt"), "CERTIFICATE", serverCert.Raw)
+	keyFile := writePEM(t, filepath.Join(tempDir, "server.key"), "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(serverKey))
+
+	cfg := NewTCPInputConfig("test_id")
+	cfg.ListenAddress = "127.0.0.1:0"
+	cfg.AddLabels = true
+	cfg.TLS = &helper.TLSServerConfig{
+		CertFile: certFile,
+		KeyFile:  keyFile,
+		CAFile:   caFile,
+	}
+
+	tcpInput, entryChan := newTestTCPInput(t, cfg)
+	require.NoError(t, tcpInput.Start())
+	defer tcpInput.Stop()
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(ca)
+
+	t.Run("ClientCertificate", func(t *testing.T) {
+		conn, err := tls.Dial("tcp", tcpInput.listener.Addr().String(), &tls.Config{
+			RootCAs:    rootCAs,
+			ServerName: "server",
+			Certificates: []tls.Certificate{{
+				Certificate: [][]byte{clientCert.Raw},
+				PrivateKey:  clientKey,
+			}},
+		})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		_, err = conn.Write([]byte("message\n"))
+		require.NoError(t, err)
 
 		select {
-		case entry := <-entryChan:
-			require.FailNow(t, "Unexpected entry: %s", entry)
-		case <-time.After(100 * time.Millisecond):
-			return
+		case e := <-entryChan:
+			require.Equal(t, "message", e.Record)
+			require.Equal(t, "client", e.Labels["tls.peer.common_name"])
+		case <-time.After(time.Second):
+			require.FailNow(t, "Timed out waiting for message to be written")
+		}
+	})
+
+	t.Run("NoClientCertificate", func(t *testing.T) {
+		conn, err := tls.Dial("tcp", tcpInput.listener.Addr().String(), &tls.Config{
+			RootCAs:    rootCAs,
+			ServerName: "server",
+		})
+		if err == nil {
+			defer conn.Close()
+			// With TLS 1.3, the client learns of the rejection on its first read
+			_, _ = conn.Write([]byte("message\n"))
+			_, err = bufio.NewReader(conn).ReadByte()
 		}
+		require.Error(t, err)
+		expectEntries(t, entryChan, []string{})
+	})
+}
+
+func createCertificate(t *testing.T, commonName string, parent *x509.Certificate, parentKey *rsa.PrivateKey) (*x509.Certificate, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
 	}
+
+	// A nil parent creates a self signed certificate authority
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+		parent = template
+		parentKey = key
+	}
+
+	raw, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(raw)
+	require.NoError(t, err)
+	return cert, key
 }
 
-func TestTcpInput(t *testing.T) {
-	t.Run("Simple", tcpInputTest([]byte("message\n"), []string{"message"}))
-	t.Run("CarriageReturn", tcpInputTest([]byte("message\r\n"), []string{"message"}))
+func writePEM(t *testing.T, path, blockType string, raw []byte) string {
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: raw})
+	require.NoError(t, ioutil.WriteFile(path, data, 0600))
+	return path
 }
 
 func BenchmarkTcpInput(b *testing.B) {
@@ -15,9 +15,11 @@
 package udp
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"net"
+	"strconv"
 	"sync"
 
 	"github.com/opentelemetry/opentelemetry-log-collection/operator"
@@ -29,10 +31,21 @@ func init() {
 	operator.Register("udp_input", func() operator.Builder { return NewUDPInputConfig("") })
 }
 
+const (
+	// defaultReadBufferSize is large enough to hold the largest possible udp datagram
+	defaultReadBufferSize = 64 * 1024
+
+	// Labels added to entries when add_labels is enabled
+	peerIPLabel   = "net.peer.ip"
+	peerPortLabel = "net.peer.port"
+)
+
 // NewUDPInputConfig creates a new UDP input config with default values
 func NewUDPInputConfig(operatorID string) *UDPInputConfig {
 	return &UDPInputConfig{
-		InputConfig: helper.NewInputConfig(operatorID, "udp_input"),
+		InputConfig:    helper.NewInputConfig(operatorID, "udp_input"),
+		ReadBufferSize: defaultReadBufferSize,
+		Workers:        1,
 	}
 }
 
@@ -40,7 +53,12 @@ func NewUDPInputConfig(operatorID string) *UDPInputConfig {
 type UDPInputConfig struct {
 	helper.InputConfig `yaml:",inline"`
 
-	ListenAddress string `json:"listen_address,omitempty" yaml:"listen_address,omitempty"`
+	ListenAddress    string          `json:"listen_address,omitempty"     yaml:"listen_address,omitempty"`
+	ReadBufferSize   helper.ByteSize `json:"read_buffer_size,omitempty"   yaml:"read_buffer_size,omitempty"`
+	SocketBufferSize helper.ByteSize `json:"socket_buffer_size,omitempty" yaml:"socket_buffer_size,omitempty"`
+	Workers          int             `json:"workers,omitempty"            yaml:"workers,omitempty"`
+	SplitLines       bool            `json:"split_lines,omitempty"        yaml:"split_lines,omitempty"`
+	AddLabels        bool            `json:"add_labels,omitempty"         yaml:"add_labels,omitempty"`
 }
 
 // Build will build a udp input operator.
@@ -59,23 +77,47 @@ func (c UDPInputConfig) Build(context operator.BuildContext) ([]operator.Operato
 		return nil, fmt.Errorf("failed to resolve listen_address: %s", err)
 	}
 
+	if c.ReadBufferSize <= 0 {
+		return nil, fmt.Errorf("`read_buffer_size` must be positive")
+	}
+
+	if c.SocketBufferSize < 0 {
+		return nil, fmt.Errorf("`socket_buffer_size` must not be negative")
+	}
+
+	if c.Workers < 1 {
+		return nil, fmt.Errorf("`workers` must be at least 1")
+	}
+
+	if c.Workers > 1 && !reusePortSupported {
+		return nil, fmt.Errorf("`workers` greater than 1 is not supported on this platform")
+	}
+
 	udpInput := &UDPInput{
-		InputOperator: inputOperator,
-		address:       address,
-		buffer:        make([]byte, 8192),
+		InputOperator:    inputOperator,
+		address:          address,
+		readBufferSize:   int(c.ReadBufferSize),
+		socketBufferSize: int(c.SocketBufferSize),
+		workers:          c.Workers,
+		splitLines:       c.SplitLines,
+		addLabels:        c.AddLabels,
 	}
 	return []operator.Operator{udpInput}, nil
 }
 
 // UDPInput is an operator that listens to a socket for log entries.
 type UDPInput struct {
-	buffer []byte
 	helper.InputOperator
-	address *net.UDPAddr
+	address          *net.UDPAddr
+	readBufferSize   int
+	socketBufferSize int
+	workers          int
+	splitLines       bool
+	addLabels        bool
 
-	connection net.PacketConn
-	cancel     context.CancelFunc
-	wg         sync.WaitGroup
+	connections []*net.UDPConn
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
 }
 
 // Start will start listening for messages on a socket.
@@ -83,25 +125,59 @@ func (u *UDPInput) Start() error {
 	ctx, cancel := context.WithCancel(context.Background())
 	u.cancel = cancel
 
-	conn, err := net.ListenUDP("udp", u.address)
-	if err != nil {
-		return fmt.Errorf("failed to open connection: %s", err)
+	address := u.address
+	for i := 0; i < u.workers; i++ {
+		conn, err := u.listen(ctx, address)
+		if err != nil {
+			u.closeConnections()
+			return fmt.Errorf("failed to open connection: %s", err)
+		}
+		u.connections = append(u.connections, conn)
+
+		// Bind the remaining workers to the port chosen for the first
+		// in case the listen address uses a dynamic port
+		address = conn.LocalAddr().(*net.UDPAddr)
 	}
-	u.connection = conn
 
-	u.goHandleMessages(ctx)
+	for _, conn := range u.connections {
+		u.goHandleMessages(ctx, conn)
+	}
 	return nil
 }
 
+// listen will open a udp socket on the address
+func (u *UDPInput) listen(ctx context.Context, address *net.UDPAddr) (*net.UDPConn, error) {
+	lc := net.ListenConfig{}
+	if u.workers > 1 {
+		lc.Control = reusePort
+	}
+
+	packetConn, err := lc.ListenPacket(ctx, "udp", address.String())
+	if err != nil {
+		return nil, err
+	}
+	conn := packetConn.(*net.UDPConn)
+
+	if u.socketBufferSize > 0 {
+		if err := conn.SetReadBuffer(u.socketBufferSize); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("set socket buffer size: %s", err)
+		}
+	}
+
+	return conn, nil
+}
+
 // goHandleMessages will handle messages from a udp connection.
-func (u *UDPInput) goHandleMessages(ctx context.Context) {
+func (u *UDPInput) goHandleMessages(ctx context.Context, conn *net.UDPConn) {
 	u.wg.Add(1)
 
 	go func() {
 		defer u.wg.Done()
 
+		buffer := make([]byte, u.readBufferSize)
 		for {
-			message, err := u.readMessage()
+			message, remoteAddr, err := u.readMessage(conn, buffer)
 			if err != nil {
 				select {
 				case <-ctx.Done():
@@ -112,35 +188,70 @@ func (u *UDPInput) goHandleMessages(ctx context.Context) {
 				break
 			}
 
-			entry, err := u.NewEntry(message)
-			if err != nil {
-				u.Errorw("Failed to create entry", zap.Error(err))
+			if !u.splitLines {
+				u.handleMessage(ctx, message, remoteAddr)
 				continue
 			}
 
-			u.Write(ctx, entry)
+			for _, line := range bytes.Split(message, []byte{'\n'}) {
+				line = bytes.TrimSuffix(line, []byte{'\r'})
+				if len(line) == 0 {
+					continue
+				}
+				u.handleMessage(ctx, line, remoteAddr)
+			}
 		}
 	}()
 }
 
-// readMessage will read log messages from the connection.
-func (u *UDPInput) readMessage() (string, error) {
-	n, _, err := u.connection.ReadFrom(u.buffer)
+// handleMessage will create and write an entry from a message.
+func (u *UDPInput) handleMessage(ctx context.Context, message []byte, remoteAddr *net.UDPAddr) {
+	entry, err := u.NewEntry(string(message))
 	if err != nil {
-		return "", err
+		u.Errorw("Failed to create entry", zap.Error(err))
+		return
+	}
+
+	if u.addLabels && remoteAddr != nil {
+		entry.AddLabel(peerIPLabel, remoteAddr.IP.String())
+		entry.AddLabel(peerPortLabel, strconv.Itoa(remoteAddr.Port))
+	}
+
+	u.Write(ctx, entry)
+}
+
+// readMessage will read a datagram from the connection.
+func (u *UDPInput) readMessage(conn *net.UDPConn, buffer []byte) ([]byte, *net.UDPAddr, error) {
+	n, remoteAddr, err := conn.ReadFromUDP(buffer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if n == len(buffer) {
+		u.Warnw("Datagram filled the read buffer and may have been truncated. Consider increasing read_buffer_size", "read_buffer_size", len(buffer))
 	}
 
 	// Remove trailing characters and NULs
-	for ; (n > 0) && (u.buffer[n-1] < 32); n-- {
+	for ; (n > 0) && (buffer[n-1] < 32); n-- {
 	}
 
-	return string(u.buffer[:n]), nil
+	return buffer[:n], remoteAddr, nil
 }
 
 // Stop will stop listening for udp messages.
 func (u *UDPInput) Stop() error {
 	u.cancel()
-	u.connection.Close()
+	u.closeConnections()
 	u.wg.Wait()
 	return nil
 }
+
+// closeConnections will close all open connections.
+func (u *UDPInput) closeConnections() {
+	for _, conn := range u.connections {
+		if err := conn.Close(); err != nil {
+			u.Debugw("Failed to close connection", zap.Error(err))
+		}
+	}
+	u.connections = nil
+}
@@ -15,7 +15,10 @@
 package udp
 
 import (
+	"bytes"
 	"net"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -26,10 +29,13 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-func udpInputTest(input []byte, expected []string) func(t *testing.T) {
+func udpInputTest(input []byte, expected []string, configure func(cfg *UDPInputConfig)) func(t *testing.T) {
 	return func(t *testing.T) {
 		cfg := NewUDPInputConfig("test_input")
 		cfg.ListenAddress = ":0"
+		if configure != nil {
+			configure(cfg)
+		}
 
 		ops, err := cfg.Build(testutil.NewBuildContext(t))
 		require.NoError(t, err)
@@ -41,7 +47,7 @@ func udpInputTest(input []byte, expected []string) func(t *testing.T) {
 
 		udpInput.InputOperator.OutputOperators = []operator.Operator{&mockOutput}
 
-		entryChan := make(chan *entry.Entry, 1)
+		entryChan := make(chan *entry.Entry, 10)
 		mockOutput.On("Process", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
 			entryChan <- args.Get(1).(*entry.Entry)
 		}).Return(nil)
@@ -50,7 +56,7 @@ func udpInputTest(input []byte, expected []string) func(t *testing.T) {
 		require.NoError(t, err)
 		defer udpInput.Stop()
 
-		conn, err := net.Dial("udp", udpInput.connection.LocalAddr().String())
+		conn, err := net.Dial("udp", udpInput.connections[0].LocalAddr().String())
 		require.NoError(t, err)
 		defer conn.Close()
 
@@ -76,10 +82,83 @@ func udpInputTest(input []byte, expected []string) func(t *testing.T) {
 }
 
 func TestUDPInput(t *testing.T) {
-	t.Run("Simple", udpInputTest([]byte("message1"), []string{"message1"}))
-	t.Run("TrailingNewlines", udpInputTest([]byte("message1\n"), []string{"message1"}))
-	t.Run("TrailingCRNewlines", udpInputTest([]byte("message1\r\n"), []string{"message1"}))
-	t.Run("NewlineInMessage", udpInputTest([]byte("message1\nmessage2\n"), []string{"message1\nmessage2"}))
+	t.Run("Simple", udpInputTest([]byte("message1"), []string{"message1"}, nil))
+	t.Run("TrailingNewlines", udpInputTest([]byte("message1\n"), []string{"message1"}, nil))
+	t.Run("TrailingCRNewlines", udpInputTest([]byte("message1\r\n"), []string{"message1"}, nil))
+	t.Run("NewlineInMessage", udpInputTest([]byte("message1\nmessage2\n"), []string{"message1\nmessage2"}, nil))
+	t.Run("SplitLines", udpInputTest([]byte("message1\r\n\nmessage2\n"), []string{"message1", "message2"}, func(cfg *UDPInputConfig) {
+		cfg.SplitLines = true
+	}))
+	t.Run("LargeDatagram", udpInputTest(bytes.Repeat([]byte("a"), 20000), []string{strings.Repeat("a", 20000)}, nil))
+	t.Run("SocketBufferSize", udpInputTest([]byte("message1"), []string{"message1"}, func(cfg *UDPInputConfig) {
+		cfg.SocketBufferSize = 1024 * 1024
+	}))
+}
+
+func TestUDPInputBuild(t *testing.T) {
+	cases := []struct {
+		name      string
+		configure func(cfg *UDPInputConfig)
+	}{
+		{"MissingListenAddress", func(cfg *UDPInputConfig) { cfg.ListenAddress = "" }},
+		{"ZeroReadBufferSize", func(cfg *UDPInputConfig) { cfg.ReadBufferSize = 0 }},
+		{"NegativeSocketBufferSize", func(cfg *UDPInputConfig) { cfg.SocketBufferSize = -1 }},
+		{"ZeroWorkers", func(cfg *UDPInputConfig) { cfg.Workers = 0 }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := NewUDPInputConfig("test_input")
+			cfg.ListenAddress = ":0"
+			tc.configure(cfg)
+			_, err := cfg.Build(testutil.NewBuildContext(t))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestUDPInputWorkers(t *testing.T) {
+	if !reusePortSupported {
+		t.Skip("SO_REUSEPORT is not supported on this platform")
+	}
+
+	cfg := NewUDPInputConfig("test_input")
+	cfg.ListenAddress = "127.0.0.1:0"
+	cfg.Workers = 4
+	cfg.AddLabels = true
+
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	udpInput := ops[0].(*UDPInput)
+
+	fakeOutput := testutil.NewFakeOutput(t)
+	udpInput.InputOperator.OutputOperators = []operator.Operator{fakeOutput}
+
+	require.NoError(t, udpInput.Start())
+	defer udpInput.Stop()
+	require.Len(t, udpInput.connections, 4)
+
+	address := udpInput.connections[0].LocalAddr().String()
+	for _, conn := range udpInput.connections {
+		require.Equal(t, address, conn.LocalAddr().String())
+	}
+
+	conn, err := net.Dial("udp", address)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("message1"))
+	require.NoError(t, err)
+
+	select {
+	case e := <-fakeOutput.Received:
+		localAddr := conn.LocalAddr().(*net.UDPAddr)
+		require.Equal(t, "message1", e.Record)
+		require.Equal(t, "127.0.0.1", e.Labels["net.peer.ip"])
+		require.Equal(t, strconv.Itoa(localAddr.Port), e.Labels["net.peer.port"])
+	case <-time.After(time.Second):
+		require.FailNow(t, "Timed out waiting for message to be written")
+	}
 }
 
 func BenchmarkUdpInput(b *testing.B) {
@@ -99,7 +178,7 @@ func BenchmarkUdpInput(b *testing.B) {
 
 	done := make(chan struct{})
 	go func() {
-		conn, err := net.Dial("udp", udpInput.connection.LocalAddr().String())
+		conn, err := net.Dial("udp", udpInput.connections[0].LocalAddr().String())
 		require.NoError(b, err)
 		defer udpInput.Stop()
 		defer conn.Close()
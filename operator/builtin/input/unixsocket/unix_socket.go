@@ -0,0 +1,391 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build linux
+
+package unixsocket
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/file"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"go.uber.org/zap"
+	"golang.org/x/text/encoding"
+)
+
+func init() {
+	operator.Register("unix_socket_input", func() operator.Builder { return NewUnixSocketInputConfig("") })
+}
+
+const (
+	streamSocket   = "stream"
+	datagramSocket = "datagram"
+
+	defaultMaxLogSize = 1024 * 1024
+
+	minAcceptDelay = 5 * time.Millisecond
+	maxAcceptDelay = time.Second
+)
+
+// NewUnixSocketInputConfig creates a new unix socket input config with default values
+func NewUnixSocketInputConfig(operatorID string) *UnixSocketInputConfig {
+	return &UnixSocketInputConfig{
+		InputConfig: helper.NewInputConfig(operatorID, "unix_socket_input"),
+		SocketType:  streamSocket,
+		MaxLogSize:  defaultMaxLogSize,
+		Encoding:    "nop",
+	}
+}
+
+// UnixSocketInputConfig is the configuration of a unix socket input operator
+type UnixSocketInputConfig struct {
+	helper.InputConfig `yaml:",inline"`
+
+	Path        string                `json:"path"                   yaml:"path"`
+	SocketType  string                `json:"socket_type,omitempty"  yaml:"socket_type,omitempty"`
+	Permissions string                `json:"permissions,omitempty"  yaml:"permissions,omitempty"`
+	Owner       string                `json:"owner,omitempty"        yaml:"owner,omitempty"`
+	Group       string                `json:"group,omitempty"        yaml:"group,omitempty"`
+	Multiline   *file.MultilineConfig `json:"multiline,omitempty"    yaml:"multiline,omitempty"`
+	MaxLogSize  helper.ByteSize       `json:"max_log_size,omitempty" yaml:"max_log_size,omitempty"`
+	Encoding    string                `json:"encoding,omitempty"     yaml:"encoding,omitempty"`
+}
+
+// Build will build a unix socket input operator
+func (c UnixSocketInputConfig) Build(context operator.BuildContext) ([]operator.Operator, error) {
+	inputOperator, err := c.InputConfig.Build(context)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Path == "" {
+		return nil, fmt.Errorf("missing required parameter 'path'")
+	}
+
+	switch c.SocketType {
+	case streamSocket, datagramSocket:
+	default:
+		return nil, fmt.Errorf("invalid socket_type '%s'", c.SocketType)
+	}
+
+	var permissions os.FileMode
+	if c.Permissions != "" {
+		mode, err := strconv.ParseUint(c.Permissions, 8, 32)
+		if err != nil || mode > 0777 {
+			return nil, fmt.Errorf("invalid permissions '%s'", c.Permissions)
+		}
+		permissions = os.FileMode(mode)
+	}
+
+	uid, err := lookupUID(c.Owner)
+	if err != nil {
+		return nil, err
+	}
+
+	gid, err := lookupGID(c.Group)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.MaxLogSize <= 0 {
+		return nil, fmt.Errorf("`max_log_size` must be positive")
+	}
+
+	encoding, err := file.LookupEncoding(c.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	splitFunc, err := file.NewSplitFunc(c.Multiline, encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	unixSocketInput := &UnixSocketInput{
+		InputOperator: inputOperator,
+		path:          c.Path,
+		socketType:    c.SocketType,
+		permissions:   permissions,
+		uid:           uid,
+		gid:           gid,
+		maxLogSize:    int(c.MaxLogSize),
+		splitFunc:     splitFunc,
+		encoding:      encoding,
+	}
+	return []operator.Operator{unixSocketInput}, nil
+}
+
+// lookupUID returns the id of a user given its name or id, or -1 if owner is empty
+func lookupUID(owner string) (int, error) {
+	if owner == "" {
+		return -1, nil
+	}
+	if uid, err := strconv.Atoi(owner); err == nil {
+		return uid, nil
+	}
+
+	u, err := user.Lookup(owner)
+	if err != nil {
+		return 0, fmt.Errorf("invalid owner '%s': %s", owner, err)
+	}
+	return strconv.Atoi(u.Uid)
+}
+
+// lookupGID returns the id of a group given its name or id, or -1 if group is empty
+func lookupGID(group string) (int, error) {
+	if group == "" {
+		return -1, nil
+	}
+	if gid, err := strconv.Atoi(group); err == nil {
+		return gid, nil
+	}
+
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		return 0, fmt.Errorf("invalid group '%s': %s", group, err)
+	}
+	return strconv.Atoi(g.Gid)
+}
+
+// UnixSocketInput is an operator that reads log entries from a unix socket
+type UnixSocketInput struct {
+	helper.InputOperator
+
+	path        string
+	socketType  string
+	permissions os.FileMode
+	uid, gid    int
+	maxLogSize  int
+	splitFunc   bufio.SplitFunc
+	encoding    encoding.Encoding
+
+	listener *net.UnixListener
+	conn     *net.UnixConn
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// Start will start listening on the socket
+func (u *UnixSocketInput) Start() error {
+	if err := removeStaleSocket(u.path); err != nil {
+		return err
+	}
+
+	address := &net.UnixAddr{Name: u.path}
+	var err error
+	if u.socketType == streamSocket {
+		u.listener, err = net.ListenUnix("unix", address)
+	} else {
+		address.Net = "unixgram"
+		u.conn, err = net.ListenUnixgram("unixgram", address)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to listen on socket: %s", err)
+	}
+
+	if err := u.setOwnership(); err != nil {
+		u.close()
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	u.cancel = cancel
+
+	if u.listener != nil {
+		u.goListen(ctx)
+	} else {
+		u.goHandleDatagrams(ctx)
+	}
+	return nil
+}
+
+// removeStaleSocket removes a socket file left behind by a previous run, which
+// would otherwise prevent listening on the path
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	switch {
+	case os.IsNotExist(err):
+		return nil
+	case err != nil:
+		return fmt.Errorf("stat socket: %s", err)
+	case info.Mode()&os.ModeSocket == 0:
+		return fmt.Errorf("path '%s' exists and is not a socket", path)
+	}
+	return os.Remove(path)
+}
+
+// setOwnership sets the configured permissions and ownership of the socket file
+func (u *UnixSocketInput) setOwnership() error {
+	if u.permissions != 0 {
+		if err := os.Chmod(u.path, u.permissions); err != nil {
+			return fmt.Errorf("set socket permissions: %s", err)
+		}
+	}
+
+	if u.uid != -1 || u.gid != -1 {
+		if err := os.Chown(u.path, u.uid, u.gid); err != nil {
+			return fmt.Errorf("set socket ownership: %s", err)
+		}
+	}
+	return nil
+}
+
+// goListen will accept connections to a stream socket
+func (u *UnixSocketInput) goListen(ctx context.Context) {
+	u.wg.Add(1)
+
+	go func() {
+		defer u.wg.Done()
+
+		backoff := helper.NewBackoff()
+		for {
+			conn, err := u.listener.Accept()
+			if err != nil {
+				if !backoff.Wait(ctx, u.Debugw, "Listener accept error", err) {
+					return
+				}
+				continue
+			}
+			backoff.Reset()
+
+			subctx, cancel := context.WithCancel(ctx)
+			u.goHandleClose(subctx, conn)
+			u.goHandleMessages(subctx, conn, cancel)
+		}
+	}()
+}
+
+// goHandleClose will wait for the context to finish before closing a connection
+func (u *UnixSocketInput) goHandleClose(ctx context.Context, conn net.Conn) {
+	u.wg.Add(1)
+
+	go func() {
+		defer u.wg.Done()
+		<-ctx.Done()
+		if err := conn.Close(); err != nil {
+			u.Errorf("Failed to close connection: %s", err)
+		}
+	}()
+}
+
+// goHandleMessages will handle messages from a stream connection
+func (u *UnixSocketInput) goHandleMessages(ctx context.Context, conn net.Conn, cancel context.CancelFunc) {
+	u.wg.Add(1)
+
+	go func() {
+		defer u.wg.Done()
+		defer cancel()
+
+		if err := u.readMessages(ctx, conn, u.encoding.NewDecoder()); err != nil {
+			select {
+			case <-ctx.Done():
+			default:
+				u.Errorw("Scanner error", zap.Error(err))
+			}
+		}
+	}()
+}
+
+// goHandleDatagrams will handle messages from a datagram socket. Each
+// datagram is split into entries on its own.
+func (u *UnixSocketInput) goHandleDatagrams(ctx context.Context) {
+	u.wg.Add(1)
+
+	go func() {
+		defer u.wg.Done()
+
+		decoder := u.encoding.NewDecoder()
+		buffer := make([]byte, u.maxLogSize)
+		backoff := helper.NewBackoff()
+		for {
+			n, _, err := u.conn.ReadFrom(buffer)
+			if err != nil {
+				if !backoff.Wait(ctx, u.Errorw, "Failed reading messages", err) {
+					return
+				}
+				continue
+			}
+			backoff.Reset()
+
+			if n == len(buffer) {
+				u.Warnw("Datagram filled the read buffer and may have been truncated. Consider increasing max_log_size", "max_log_size", u.maxLogSize)
+			}
+
+			if err := u.readMessages(ctx, bytes.NewReader(buffer[:n]), decoder); err != nil {
+				u.Errorw("Scanner error", zap.Error(err))
+			}
+		}
+	}()
+}
+
+// readMessages splits a stream into entries
+func (u *UnixSocketInput) readMessages(ctx context.Context, r io.Reader, decoder *encoding.Decoder) error {
+	scanner := file.NewStreamScanner(r, u.splitFunc, u.maxLogSize)
+	for scanner.Scan() {
+		message, err := decoder.Bytes(scanner.Bytes())
+		if err != nil {
+			u.Errorw("Failed to decode message", zap.Error(err))
+			continue
+		}
+
+		entry, err := u.NewEntry(string(message))
+		if err != nil {
+			u.Errorw("Failed to create entry", zap.Error(err))
+			continue
+		}
+		u.Write(ctx, entry)
+	}
+	return scanner.Err()
+}
+
+// Stop will stop listening on the socket and remove the socket file
+func (u *UnixSocketInput) Stop() error {
+	if u.cancel != nil {
+		u.cancel()
+	}
+	u.close()
+	u.wg.Wait()
+	return nil
+}
+
+// close closes the socket. Closing a stream listener removes the socket file,
+// which is done here for datagram sockets.
+func (u *UnixSocketInput) close() {
+	if u.listener != nil {
+		if err := u.listener.Close(); err != nil {
+			u.Debugw("Failed to close listener", zap.Error(err))
+		}
+	}
+
+	if u.conn != nil {
+		if err := u.conn.Close(); err != nil {
+			u.Debugw("Failed to close socket", zap.Error(err))
+		}
+		if err := os.Remove(u.path); err != nil && !os.IsNotExist(err) {
+			u.Debugw("Failed to remove socket", zap.Error(err))
+		}
+	}
+}
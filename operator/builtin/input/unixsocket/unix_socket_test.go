@@ -0,0 +1,234 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build linux
+
+package unixsocket
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/file"
+	"github.com/opentelemetry/opentelemetry-log-collection/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestConfig(t *testing.T) *UnixSocketInputConfig {
+	cfg := NewUnixSocketInputConfig("test_id")
+	cfg.OutputIDs = []string{"$.fake"}
+	cfg.Path = filepath.Join(testutil.NewTempDir(t), "test.sock")
+	return cfg
+}
+
+func startTestInput(t *testing.T, cfg *UnixSocketInputConfig) (operator.Operator, *testutil.FakeOutput) {
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	op := ops[0]
+
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, op.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, op.Start())
+	return op, fake
+}
+
+func expectMessages(t *testing.T, fake *testutil.FakeOutput, messages ...string) {
+	for _, message := range messages {
+		select {
+		case e := <-fake.Received:
+			require.Equal(t, message, e.Record)
+		case <-time.After(time.Second):
+			require.FailNow(t, "Timed out waiting for entry", message)
+		}
+	}
+
+	select {
+	case e := <-fake.Received:
+		require.FailNow(t, "Received unexpected entry", e.Record)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestUnixSocketInputStream(t *testing.T) {
+	cfg := newTestConfig(t)
+	op, fake := startTestInput(t, cfg)
+
+	conn, err := net.Dial("unix", cfg.Path)
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("message1\nmessage2\r\nmessage3"))
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	expectMessages(t, fake, "message1", "message2", "message3")
+
+	require.NoError(t, op.Stop())
+	_, err = os.Stat(cfg.Path)
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestUnixSocketInputDatagram(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.SocketType = datagramSocket
+	op, fake := startTestInput(t, cfg)
+
+	conn, err := net.Dial("unixgram", cfg.Path)
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("<13>message1"))
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("message2\nmessage3\n"))
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	expectMessages(t, fake, "<13>message1", "message2", "message3")
+
+	require.NoError(t, op.Stop())
+	_, err = os.Stat(cfg.Path)
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestUnixSocketInputMultiline(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.Multiline = &file.MultilineConfig{LineStartPattern: "^START"}
+	op, fake := startTestInput(t, cfg)
+	defer op.Stop()
+
+	conn, err := net.Dial("unix", cfg.Path)
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("START 1\n  detail\nSTART 2\n"))
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	expectMessages(t, fake, "START 1\n  detail\n", "START 2\n")
+}
+
+func TestUnixSocketInputMaxLogSize(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.MaxLogSize = 16
+	op, fake := startTestInput(t, cfg)
+	defer op.Stop()
+
+	conn, err := net.Dial("unix", cfg.Path)
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("0123456789abcdefghij\nshort\n"))
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	expectMessages(t, fake, "0123456789abcdef", "ghij", "short")
+}
+
+func TestUnixSocketInputStopWithOpenConnection(t *testing.T) {
+	cfg := newTestConfig(t)
+	op, fake := startTestInput(t, cfg)
+
+	conn, err := net.Dial("unix", cfg.Path)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("message1\n"))
+	require.NoError(t, err)
+	expectMessages(t, fake, "message1")
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		require.NoError(t, op.Stop())
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		require.FailNow(t, "Timed out waiting for operator to stop")
+	}
+}
+
+func TestUnixSocketInputOwnership(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.Permissions = "0620"
+	cfg.Owner = strconv.Itoa(os.Getuid())
+	cfg.Group = strconv.Itoa(os.Getgid())
+	op, _ := startTestInput(t, cfg)
+	defer op.Stop()
+
+	info, err := os.Stat(cfg.Path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0620), info.Mode().Perm())
+	require.NotZero(t, info.Mode()&os.ModeSocket)
+}
+
+func TestUnixSocketInputStaleSocket(t *testing.T) {
+	cfg := newTestConfig(t)
+
+	// A listener that is not closed cleanly leaves its socket file behind
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: cfg.Path, Net: "unix"})
+	require.NoError(t, err)
+	stale.SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	op, fake := startTestInput(t, cfg)
+	defer op.Stop()
+
+	conn, err := net.Dial("unix", cfg.Path)
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("message1\n"))
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	expectMessages(t, fake, "message1")
+}
+
+func TestUnixSocketInputPathNotSocket(t *testing.T) {
+	cfg := newTestConfig(t)
+	require.NoError(t, ioutil.WriteFile(cfg.Path, []byte("data"), 0600))
+
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	require.Error(t, ops[0].Start())
+
+	// The file is left untouched
+	contents, err := ioutil.ReadFile(cfg.Path)
+	require.NoError(t, err)
+	require.Equal(t, "data", string(contents))
+}
+
+func TestBuildUnixSocketInputErrors(t *testing.T) {
+	cases := []struct {
+		name      string
+		configure func(*UnixSocketInputConfig)
+	}{
+		{"MissingPath", func(c *UnixSocketInputConfig) { c.Path = "" }},
+		{"UnknownSocketType", func(c *UnixSocketInputConfig) { c.SocketType = "seqpacket" }},
+		{"InvalidPermissions", func(c *UnixSocketInputConfig) { c.Permissions = "rw-rw-rw-" }},
+		{"LargePermissions", func(c *UnixSocketInputConfig) { c.Permissions = "7777" }},
+		{"UnknownOwner", func(c *UnixSocketInputConfig) { c.Owner = "no-such-user-for-test" }},
+		{"UnknownGroup", func(c *UnixSocketInputConfig) { c.Group = "no-such-group-for-test" }},
+		{"ZeroMaxLogSize", func(c *UnixSocketInputConfig) { c.MaxLogSize = 0 }},
+		{"UnknownEncoding", func(c *UnixSocketInputConfig) { c.Encoding = "not-an-encoding" }},
+		{"InvalidMultiline", func(c *UnixSocketInputConfig) { c.Multiline = &file.MultilineConfig{} }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := newTestConfig(t)
+			tc.configure(cfg)
+
+			_, err := cfg.Build(testutil.NewBuildContext(t))
+			require.Error(t, err)
+		})
+	}
+}
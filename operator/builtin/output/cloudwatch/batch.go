@@ -0,0 +1,121 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudwatch
+
+import (
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"go.uber.org/zap"
+)
+
+// Limits of a PutLogEvents request
+const (
+	maxBatchEvents = 10000
+	maxBatchBytes  = 1048576
+	maxBatchSpan   = 24 * time.Hour
+
+	// eventOverhead is the number of bytes added to the size of each message
+	eventOverhead = 26
+
+	// maxEventBytes is the largest message that can be sent in an event
+	maxEventBytes = 262144 - eventOverhead
+)
+
+// streamID identifies a log stream
+type streamID struct {
+	group  string
+	stream string
+}
+
+// batch is a group of events sent to a log stream in a single request
+type batch struct {
+	streamID
+	events []*cloudwatchlogs.InputLogEvent
+}
+
+// createBatches will group entries by log stream, sort the entries of each stream
+// by timestamp, and split them into batches that fit the limits of a request
+func (c *CloudWatchOutput) createBatches(entries []*entry.Entry) []*batch {
+	ids := make([]streamID, 0)
+	eventsByID := make(map[streamID][]*cloudwatchlogs.InputLogEvent)
+
+	for _, e := range entries {
+		id, err := c.findStream(e)
+		if err != nil {
+			c.Errorw("Failed to find log stream", zap.Error(err))
+			continue
+		}
+
+		message, err := helper.FormatRecord(e.Record)
+		if err != nil {
+			c.Errorw("Failed to format message", zap.Error(err))
+			continue
+		}
+
+		if message == "" {
+			continue
+		}
+
+		if len(message) > maxEventBytes {
+			c.Errorw("Dropping entry with a message larger than the max event size", zap.Int("size", len(message)))
+			continue
+		}
+
+		if _, ok := eventsByID[id]; !ok {
+			ids = append(ids, id)
+		}
+		eventsByID[id] = append(eventsByID[id], &cloudwatchlogs.InputLogEvent{
+			Message:   aws.String(message),
+			Timestamp: aws.Int64(e.Timestamp.UnixNano() / int64(time.Millisecond)),
+		})
+	}
+
+	batches := make([]*batch, 0, len(ids))
+	for _, id := range ids {
+		batches = append(batches, splitEvents(id, eventsByID[id])...)
+	}
+	return batches
+}
+
+// splitEvents will sort the events of a stream and split them into batches
+func splitEvents(id streamID, events []*cloudwatchlogs.InputLogEvent) []*batch {
+	sort.SliceStable(events, func(i, j int) bool {
+		return *events[i].Timestamp < *events[j].Timestamp
+	})
+
+	batches := make([]*batch, 0, 1)
+	current := &batch{streamID: id}
+	size := 0
+	for _, event := range events {
+		eventSize := len(*event.Message) + eventOverhead
+		if len(current.events) > 0 {
+			span := time.Duration(*event.Timestamp-*current.events[0].Timestamp) * time.Millisecond
+			if len(current.events) == maxBatchEvents || size+eventSize > maxBatchBytes || span >= maxBatchSpan {
+				batches = append(batches, current)
+				current = &batch{streamID: id}
+				size = 0
+			}
+		}
+
+		current.events = append(current.events, event)
+		size += eventSize
+	}
+	return append(batches, current)
+}
@@ -0,0 +1,105 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudwatch
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/stretchr/testify/require"
+)
+
+func newEvents(count, size int, start time.Time, step time.Duration) []*cloudwatchlogs.InputLogEvent {
+	events := make([]*cloudwatchlogs.InputLogEvent, 0, count)
+	for i := 0; i < count; i++ {
+		ts := start.Add(time.Duration(i) * step)
+		events = append(events, &cloudwatchlogs.InputLogEvent{
+			Message:   aws.String(strings.Repeat("a", size)),
+			Timestamp: aws.Int64(ts.UnixNano() / int64(time.Millisecond)),
+		})
+	}
+	return events
+}
+
+func batchSizes(batches []*batch) []int {
+	sizes := make([]int, 0, len(batches))
+	for _, b := range batches {
+		sizes = append(sizes, len(b.events))
+	}
+	return sizes
+}
+
+func TestSplitEvents(t *testing.T) {
+	id := streamID{group: "group", stream: "stream"}
+	start := time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC)
+
+	cases := []struct {
+		name     string
+		events   []*cloudwatchlogs.InputLogEvent
+		expected []int
+	}{
+		{
+			"Single",
+			newEvents(3, 10, start, time.Second),
+			[]int{3},
+		},
+		{
+			"MaxEvents",
+			newEvents(maxBatchEvents+1, 1, start, time.Millisecond),
+			[]int{maxBatchEvents, 1},
+		},
+		{
+			"MaxBytes",
+			newEvents(5, maxBatchBytes/4, start, time.Second),
+			[]int{3, 2},
+		},
+		{
+			"MaxSpan",
+			newEvents(3, 10, start, 12*time.Hour),
+			[]int{2, 1},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			batches := splitEvents(id, tc.events)
+			require.Equal(t, tc.expected, batchSizes(batches))
+			for _, b := range batches {
+				require.Equal(t, id, b.streamID)
+			}
+		})
+	}
+}
+
+func TestSplitEventsSortsByTimestamp(t *testing.T) {
+	events := []*cloudwatchlogs.InputLogEvent{
+		{Message: aws.String("c"), Timestamp: aws.Int64(3)},
+		{Message: aws.String("a"), Timestamp: aws.Int64(1)},
+		{Message: aws.String("b1"), Timestamp: aws.Int64(2)},
+		{Message: aws.String("b2"), Timestamp: aws.Int64(2)},
+	}
+
+	batches := splitEvents(streamID{}, events)
+	require.Len(t, batches, 1)
+
+	messages := make([]string, 0, 4)
+	for _, event := range batches[0].events {
+		messages = append(messages, *event.Message)
+	}
+	require.Equal(t, []string{"a", "b1", "b2", "c"}, messages)
+}
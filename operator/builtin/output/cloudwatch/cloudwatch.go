@@ -0,0 +1,264 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudwatch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/errors"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/buffer"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/flusher"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"go.uber.org/zap"
+)
+
+func init() {
+	operator.Register("cloudwatch_output", func() operator.Builder { return NewCloudWatchOutputConfig("") })
+}
+
+// NewCloudWatchOutputConfig creates a new cloudwatch output config with default values
+func NewCloudWatchOutputConfig(operatorID string) *CloudWatchOutputConfig {
+	return &CloudWatchOutputConfig{
+		OutputConfig:  helper.NewOutputConfig(operatorID, "cloudwatch_output"),
+		BufferConfig:  buffer.NewConfig(),
+		FlusherConfig: flusher.NewConfig(),
+		AutoCreate:    true,
+		Timeout:       helper.NewDuration(30 * time.Second),
+	}
+}
+
+// CloudWatchOutputConfig is the configuration of a cloudwatch output operator
+type CloudWatchOutputConfig struct {
+	helper.OutputConfig `yaml:",inline"`
+	BufferConfig        buffer.Config  `json:"buffer"  yaml:"buffer"`
+	FlusherConfig       flusher.Config `json:"flusher" yaml:"flusher"`
+
+	Region         string          `json:"region,omitempty"           yaml:"region,omitempty"`
+	Profile        string          `json:"profile,omitempty"          yaml:"profile,omitempty"`
+	Endpoint       string          `json:"endpoint,omitempty"         yaml:"endpoint,omitempty"`
+	LogGroup       string          `json:"log_group,omitempty"        yaml:"log_group,omitempty"`
+	LogGroupField  *entry.Field    `json:"log_group_field,omitempty"  yaml:"log_group_field,omitempty"`
+	LogStream      string          `json:"log_stream,omitempty"       yaml:"log_stream,omitempty"`
+	LogStreamField *entry.Field    `json:"log_stream_field,omitempty" yaml:"log_stream_field,omitempty"`
+	AutoCreate     bool            `json:"auto_create"                yaml:"auto_create"`
+	Timeout        helper.Duration `json:"timeout,omitempty"          yaml:"timeout,omitempty"`
+}
+
+// Build will build a cloudwatch output operator
+func (c CloudWatchOutputConfig) Build(bc operator.BuildContext) ([]operator.Operator, error) {
+	outputOperator, err := c.OutputConfig.Build(bc)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.LogGroup == "" && c.LogGroupField == nil {
+		return nil, errors.NewError("missing required parameter 'log_group'", "Set 'log_group', 'log_group_field' or both")
+	}
+
+	if c.LogStream == "" && c.LogStreamField == nil {
+		return nil, errors.NewError("missing required parameter 'log_stream'", "Set 'log_stream', 'log_stream_field' or both")
+	}
+
+	// Credentials and region are loaded from the environment and shared config files
+	awsConfig := aws.NewConfig().WithHTTPClient(&http.Client{Timeout: c.Timeout.Raw()})
+	if c.Region != "" {
+		awsConfig = awsConfig.WithRegion(c.Region)
+	}
+	if c.Endpoint != "" {
+		awsConfig = awsConfig.WithEndpoint(c.Endpoint)
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *awsConfig,
+		Profile:           c.Profile,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "create aws session")
+	}
+
+	buffer, err := c.BufferConfig.Build(bc, c.ID())
+	if err != nil {
+		return nil, err
+	}
+
+	flusher := c.FlusherConfig.Build(bc.Logger.SugaredLogger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cloudWatchOutput := &CloudWatchOutput{
+		OutputOperator: outputOperator,
+		buffer:         buffer,
+		flusher:        flusher,
+		client:         cloudwatchlogs.New(sess),
+		logGroup:       c.LogGroup,
+		logGroupField:  c.LogGroupField,
+		logStream:      c.LogStream,
+		logStreamField: c.LogStreamField,
+		autoCreate:     c.AutoCreate,
+		streams:        make(map[streamID]*streamState),
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+
+	return []operator.Operator{cloudWatchOutput}, nil
+}
+
+// client is the part of the cloudwatch logs api used by the operator
+type client interface {
+	PutLogEventsWithContext(aws.Context, *cloudwatchlogs.PutLogEventsInput, ...request.Option) (*cloudwatchlogs.PutLogEventsOutput, error)
+	CreateLogGroupWithContext(aws.Context, *cloudwatchlogs.CreateLogGroupInput, ...request.Option) (*cloudwatchlogs.CreateLogGroupOutput, error)
+	CreateLogStreamWithContext(aws.Context, *cloudwatchlogs.CreateLogStreamInput, ...request.Option) (*cloudwatchlogs.CreateLogStreamOutput, error)
+}
+
+// CloudWatchOutput is an operator that sends entries to cloudwatch logs
+type CloudWatchOutput struct {
+	helper.OutputOperator
+	buffer  buffer.Buffer
+	flusher *flusher.Flusher
+	client  client
+
+	logGroup       string
+	logGroupField  *entry.Field
+	logStream      string
+	logStreamField *entry.Field
+	autoCreate     bool
+
+	streams    map[streamID]*streamState
+	streamsMux sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// streamState is the state of a log stream that requests to it share
+type streamState struct {
+	// mux allows one request at a time to a stream, since each request
+	// uses the sequence token returned by the previous one
+	mux           sync.Mutex
+	sequenceToken *string
+}
+
+// Start signals to the CloudWatchOutput to begin flushing
+func (c *CloudWatchOutput) Start() error {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.feedFlusher(c.ctx)
+	}()
+
+	return nil
+}
+
+// Stop tells the CloudWatchOutput to stop gracefully
+func (c *CloudWatchOutput) Stop() error {
+	c.cancel()
+	c.wg.Wait()
+	c.flusher.Stop()
+	return c.buffer.Close()
+}
+
+// Process adds an entry to the output's buffer
+func (c *CloudWatchOutput) Process(ctx context.Context, entry *entry.Entry) error {
+	return c.buffer.Add(ctx, entry)
+}
+
+func (c *CloudWatchOutput) feedFlusher(ctx context.Context) {
+	for {
+		entries, clearer, err := c.buffer.ReadChunk(ctx)
+		if err != nil && err == context.Canceled {
+			return
+		} else if err != nil {
+			c.Errorw("Failed to read chunk", zap.Error(err))
+			continue
+		}
+
+		batches := c.createBatches(entries)
+
+		// Batches that have been sent are skipped when the flush is retried
+		next := 0
+		c.flusher.Do(func(ctx context.Context) error {
+			for ; next < len(batches); next++ {
+				retry, err := c.send(ctx, batches[next])
+				if err != nil && retry {
+					return err
+				} else if err != nil {
+					c.Errorw("Dropping batch after a non-retryable error", zap.Error(err))
+				}
+			}
+
+			if err := clearer.MarkAllAsFlushed(); err != nil {
+				c.Errorw("Failed to mark entries as flushed", zap.Error(err))
+			}
+			return nil
+		})
+	}
+}
+
+// findStream will find the log group and log stream of an entry
+func (c *CloudWatchOutput) findStream(e *entry.Entry) (streamID, error) {
+	group, err := readField(e, c.logGroupField, c.logGroup)
+	if err != nil {
+		return streamID{}, errors.Wrap(err, "find log group")
+	}
+
+	stream, err := readField(e, c.logStreamField, c.logStream)
+	if err != nil {
+		return streamID{}, errors.Wrap(err, "find log stream")
+	}
+
+	return streamID{group: group, stream: stream}, nil
+}
+
+// readField will read a string from a field of an entry, or return the
+// default value if the entry does not have the field
+func readField(e *entry.Entry, field *entry.Field, defaultValue string) (string, error) {
+	if field == nil {
+		return defaultValue, nil
+	}
+
+	var value string
+	if err := e.Read(*field, &value); err != nil || value == "" {
+		if defaultValue == "" {
+			return "", fmt.Errorf("entry does not have field '%s'", field.String())
+		}
+		return defaultValue, nil
+	}
+	return value, nil
+}
+
+// streamState returns the state of a log stream
+func (c *CloudWatchOutput) streamState(id streamID) *streamState {
+	c.streamsMux.Lock()
+	defer c.streamsMux.Unlock()
+
+	state, ok := c.streams[id]
+	if !ok {
+		state = &streamState{}
+		c.streams[id] = state
+	}
+	return state
+}
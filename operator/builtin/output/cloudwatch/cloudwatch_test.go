@@ -0,0 +1,323 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudwatch
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/buffer"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"github.com/opentelemetry/opentelemetry-log-collection/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type putRequest struct {
+	group    string
+	stream   string
+	messages []string
+}
+
+// testClient is a fake cloudwatch logs client
+type testClient struct {
+	sync.Mutex
+	groups  map[string]bool
+	streams map[streamID]int
+	puts    chan putRequest
+
+	// errs are returned by the next PutLogEvents requests
+	errs []error
+}
+
+func newTestClient() *testClient {
+	return &testClient{
+		groups:  make(map[string]bool),
+		streams: make(map[streamID]int),
+		puts:    make(chan putRequest, 10),
+	}
+}
+
+func (c *testClient) createStream(group, stream string) {
+	c.groups[group] = true
+	c.streams[streamID{group: group, stream: stream}] = 0
+}
+
+func (c *testClient) PutLogEventsWithContext(_ aws.Context, input *cloudwatchlogs.PutLogEventsInput, _ ...request.Option) (*cloudwatchlogs.PutLogEventsOutput, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		return nil, err
+	}
+
+	id := streamID{group: *input.LogGroupName, stream: *input.LogStreamName}
+	seq, ok := c.streams[id]
+	if !ok {
+		return nil, &cloudwatchlogs.ResourceNotFoundException{Message_: aws.String("The specified log stream does not exist.")}
+	}
+
+	expected := aws.String(fmt.Sprint(seq))
+	if seq == 0 {
+		expected = nil
+	}
+	if aws.StringValue(input.SequenceToken) != aws.StringValue(expected) {
+		return nil, &cloudwatchlogs.InvalidSequenceTokenException{ExpectedSequenceToken: expected}
+	}
+	c.streams[id] = seq + 1
+
+	put := putRequest{group: id.group, stream: id.stream}
+	for _, event := range input.LogEvents {
+		put.messages = append(put.messages, *event.Message)
+	}
+	c.puts <- put
+
+	return &cloudwatchlogs.PutLogEventsOutput{NextSequenceToken: aws.String(fmt.Sprint(seq + 1))}, nil
+}
+
+func (c *testClient) CreateLogGroupWithContext(_ aws.Context, input *cloudwatchlogs.CreateLogGroupInput, _ ...request.Option) (*cloudwatchlogs.CreateLogGroupOutput, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.groups[*input.LogGroupName] {
+		return nil, awserr.New(cloudwatchlogs.ErrCodeResourceAlreadyExistsException, "The specified log group already exists", nil)
+	}
+	c.groups[*input.LogGroupName] = true
+	return &cloudwatchlogs.CreateLogGroupOutput{}, nil
+}
+
+func (c *testClient) CreateLogStreamWithContext(_ aws.Context, input *cloudwatchlogs.CreateLogStreamInput, _ ...request.Option) (*cloudwatchlogs.CreateLogStreamOutput, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	id := streamID{group: *input.LogGroupName, stream: *input.LogStreamName}
+	if !c.groups[id.group] {
+		return nil, &cloudwatchlogs.ResourceNotFoundException{Message_: aws.String("The specified log group does not exist.")}
+	}
+	if _, ok := c.streams[id]; ok {
+		return nil, awserr.New(cloudwatchlogs.ErrCodeResourceAlreadyExistsException, "The specified log stream already exists", nil)
+	}
+	c.streams[id] = 0
+	return &cloudwatchlogs.CreateLogStreamOutput{}, nil
+}
+
+func (c *testClient) expectPut(t *testing.T) putRequest {
+	select {
+	case put := <-c.puts:
+		return put
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "Timed out waiting for request")
+	}
+	return putRequest{}
+}
+
+func (c *testClient) expectNoPut(t *testing.T) {
+	select {
+	case put := <-c.puts:
+		require.FailNow(t, "Unexpected request", put)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func newTestConfig() *CloudWatchOutputConfig {
+	cfg := NewCloudWatchOutputConfig("test")
+	cfg.Region = "us-east-1"
+	cfg.LogGroup = "group"
+	cfg.LogStream = "stream"
+	cfg.BufferConfig.Builder.(*buffer.MemoryBufferConfig).MaxChunkDelay = helper.NewDuration(50 * time.Millisecond)
+	return cfg
+}
+
+func startOutput(t *testing.T, cfg *CloudWatchOutputConfig, client *testClient, entries ...*entry.Entry) *CloudWatchOutput {
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	op := ops[0].(*CloudWatchOutput)
+	op.client = client
+	require.NoError(t, op.Start())
+	t.Cleanup(func() { require.NoError(t, op.Stop()) })
+
+	for _, e := range entries {
+		require.NoError(t, op.Process(context.Background(), e))
+	}
+	return op
+}
+
+func newEntry(record interface{}, labels map[string]string, offset time.Duration) *entry.Entry {
+	e := entry.New()
+	e.Timestamp = time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC).Add(offset)
+	e.Record = record
+	e.Labels = labels
+	return e
+}
+
+func TestCloudWatchOutputBuild(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(*CloudWatchOutputConfig)
+	}{
+		{"MissingLogGroup", func(c *CloudWatchOutputConfig) { c.LogGroup = "" }},
+		{"MissingLogStream", func(c *CloudWatchOutputConfig) { c.LogStream = "" }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			tc.modify(cfg)
+			_, err := cfg.Build(testutil.NewBuildContext(t))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestCloudWatchOutput(t *testing.T) {
+	client := newTestClient()
+	client.createStream("group", "stream")
+
+	startOutput(t, newTestConfig(), client,
+		newEntry("second", nil, time.Second),
+		newEntry(map[string]interface{}{"key": "value"}, nil, 2*time.Second),
+		newEntry("first", nil, 0),
+		newEntry("", nil, 0),
+	)
+
+	put := client.expectPut(t)
+	require.Equal(t, "group", put.group)
+	require.Equal(t, "stream", put.stream)
+	require.Equal(t, []string{"first", "second", `{"key":"value"}`}, put.messages)
+	client.expectNoPut(t)
+}
+
+func TestCloudWatchOutputStreamFields(t *testing.T) {
+	client := newTestClient()
+	client.createStream("default", "web")
+	client.createStream("default", "db")
+	client.createStream("audit", "db")
+
+	cfg := newTestConfig()
+	cfg.LogGroup = "default"
+	groupField := entry.NewLabelField("group")
+	cfg.LogGroupField = &groupField
+	cfg.LogStream = ""
+	streamField := entry.NewLabelField("app")
+	cfg.LogStreamField = &streamField
+
+	startOutput(t, cfg, client,
+		newEntry("one", map[string]string{"app": "web"}, 0),
+		newEntry("two", map[string]string{"app": "db"}, 0),
+		newEntry("three", map[string]string{"app": "db", "group": "audit"}, 0),
+		newEntry("four", map[string]string{"app": "web"}, 0),
+		newEntry("missing stream", nil, 0),
+	)
+
+	puts := make(map[streamID][]string)
+	for i := 0; i < 3; i++ {
+		put := client.expectPut(t)
+		puts[streamID{group: put.group, stream: put.stream}] = put.messages
+	}
+	client.expectNoPut(t)
+
+	expected := map[streamID][]string{
+		{group: "default", stream: "web"}: {"one", "four"},
+		{group: "default", stream: "db"}:  {"two"},
+		{group: "audit", stream: "db"}:    {"three"},
+	}
+	require.Equal(t, expected, puts)
+}
+
+func TestCloudWatchOutputAutoCreate(t *testing.T) {
+	client := newTestClient()
+
+	startOutput(t, newTestConfig(), client, newEntry("message", nil, 0))
+
+	put := client.expectPut(t)
+	require.Equal(t, []string{"message"}, put.messages)
+	require.True(t, client.groups["group"])
+}
+
+func TestCloudWatchOutputAutoCreateDisabled(t *testing.T) {
+	client := newTestClient()
+
+	cfg := newTestConfig()
+	cfg.AutoCreate = false
+	startOutput(t, cfg, client, newEntry("message", nil, 0))
+
+	client.expectNoPut(t)
+	require.False(t, client.groups["group"])
+}
+
+func TestCloudWatchOutputSequenceToken(t *testing.T) {
+	client := newTestClient()
+	client.createStream("group", "stream")
+	// Another writer has used the stream
+	client.streams[streamID{group: "group", stream: "stream"}] = 5
+
+	op := startOutput(t, newTestConfig(), client, newEntry("first", nil, 0))
+	require.Equal(t, []string{"first"}, client.expectPut(t).messages)
+
+	require.NoError(t, op.Process(context.Background(), newEntry("second", nil, 0)))
+	require.Equal(t, []string{"second"}, client.expectPut(t).messages)
+}
+
+func TestCloudWatchOutputThrottling(t *testing.T) {
+	client := newTestClient()
+	client.createStream("group", "stream")
+	client.errs = []error{
+		awserr.New("ThrottlingException", "Rate exceeded", nil),
+		&cloudwatchlogs.ServiceUnavailableException{Message_: aws.String("Service unavailable")},
+	}
+
+	startOutput(t, newTestConfig(), client, newEntry("message", nil, 0))
+
+	require.Equal(t, []string{"message"}, client.expectPut(t).messages)
+	client.expectNoPut(t)
+}
+
+func TestCloudWatchOutputDataAlreadyAccepted(t *testing.T) {
+	client := newTestClient()
+	client.createStream("group", "stream")
+	client.streams[streamID{group: "group", stream: "stream"}] = 1
+	client.errs = []error{
+		&cloudwatchlogs.DataAlreadyAcceptedException{ExpectedSequenceToken: aws.String("1")},
+	}
+
+	op := startOutput(t, newTestConfig(), client, newEntry("first", nil, 0))
+	client.expectNoPut(t)
+
+	require.NoError(t, op.Process(context.Background(), newEntry("second", nil, 0)))
+	require.Equal(t, []string{"second"}, client.expectPut(t).messages)
+}
+
+func TestCloudWatchOutputInvalidParameter(t *testing.T) {
+	client := newTestClient()
+	client.createStream("group", "stream")
+	client.errs = []error{
+		&cloudwatchlogs.InvalidParameterException{Message_: aws.String("Invalid parameter")},
+	}
+
+	op := startOutput(t, newTestConfig(), client, newEntry("dropped", nil, 0))
+	client.expectNoPut(t)
+
+	require.NoError(t, op.Process(context.Background(), newEntry("sent", nil, 0)))
+	require.Equal(t, []string{"sent"}, client.expectPut(t).messages)
+}
@@ -0,0 +1,12 @@
+module github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/cloudwatch
+
+go 1.14
+
+require (
+	github.com/aws/aws-sdk-go v1.34.9
+	github.com/opentelemetry/opentelemetry-log-collection v0.13.12
+	github.com/stretchr/testify v1.6.1
+	go.uber.org/zap v1.16.0
+)
+
+replace github.com/opentelemetry/opentelemetry-log-collection => ../../../../
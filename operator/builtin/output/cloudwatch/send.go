@@ -0,0 +1,126 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudwatch
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/opentelemetry/opentelemetry-log-collection/errors"
+	"go.uber.org/zap"
+)
+
+// maxSendAttempts is the number of times a batch is sent within a single
+// flush when the request fails for a reason that can be fixed right away,
+// such as an expired sequence token or a missing log stream
+const maxSendAttempts = 3
+
+// send will send a batch to its log stream. If the batch could not be sent,
+// retry reports whether the flush should be retried later.
+func (c *CloudWatchOutput) send(ctx context.Context, b *batch) (retry bool, err error) {
+	state := c.streamState(b.streamID)
+	state.mux.Lock()
+	defer state.mux.Unlock()
+
+	for attempt := 0; attempt < maxSendAttempts; attempt++ {
+		err = c.putLogEvents(ctx, b, state)
+		if err == nil {
+			return false, nil
+		}
+
+		switch e := err.(type) {
+		case *cloudwatchlogs.InvalidSequenceTokenException:
+			// Another writer has used the stream, so send again with the expected token
+			state.sequenceToken = e.ExpectedSequenceToken
+			continue
+		case *cloudwatchlogs.DataAlreadyAcceptedException:
+			// The batch was accepted by a previous request whose response was lost
+			state.sequenceToken = e.ExpectedSequenceToken
+			return false, nil
+		case *cloudwatchlogs.ResourceNotFoundException:
+			if !c.autoCreate {
+				return false, errors.Wrap(err, "put log events")
+			}
+			if err := c.createStream(ctx, b.streamID); err != nil {
+				return true, err
+			}
+			state.sequenceToken = nil
+			continue
+		case *cloudwatchlogs.InvalidParameterException:
+			return false, errors.Wrap(err, "put log events")
+		}
+
+		return true, errors.Wrap(err, "put log events")
+	}
+
+	return true, errors.Wrap(err, "put log events")
+}
+
+// putLogEvents will make a single PutLogEvents request for a batch
+func (c *CloudWatchOutput) putLogEvents(ctx context.Context, b *batch, state *streamState) error {
+	output, err := c.client.PutLogEventsWithContext(ctx, &cloudwatchlogs.PutLogEventsInput{
+		LogGroupName:  aws.String(b.group),
+		LogStreamName: aws.String(b.stream),
+		LogEvents:     b.events,
+		SequenceToken: state.sequenceToken,
+	})
+	if err != nil {
+		return err
+	}
+
+	state.sequenceToken = output.NextSequenceToken
+	if info := output.RejectedLogEventsInfo; info != nil {
+		c.Warnw("Log events were rejected",
+			zap.String("log_group", b.group),
+			zap.String("log_stream", b.stream),
+			zap.Int64p("too_old_end_index", info.TooOldLogEventEndIndex),
+			zap.Int64p("too_new_start_index", info.TooNewLogEventStartIndex),
+			zap.Int64p("expired_end_index", info.ExpiredLogEventEndIndex),
+		)
+	}
+	return nil
+}
+
+// createStream will create a log stream and its log group
+func (c *CloudWatchOutput) createStream(ctx context.Context, id streamID) error {
+	c.Debugw("Creating log stream", zap.String("log_group", id.group), zap.String("log_stream", id.stream))
+
+	_, err := c.client.CreateLogGroupWithContext(ctx, &cloudwatchlogs.CreateLogGroupInput{
+		LogGroupName: aws.String(id.group),
+	})
+	if err != nil && !isAlreadyExists(err) {
+		return fmt.Errorf("create log group '%s': %s", id.group, err)
+	}
+
+	_, err = c.client.CreateLogStreamWithContext(ctx, &cloudwatchlogs.CreateLogStreamInput{
+		LogGroupName:  aws.String(id.group),
+		LogStreamName: aws.String(id.stream),
+	})
+	if err != nil && !isAlreadyExists(err) {
+		return fmt.Errorf("create log stream '%s': %s", id.stream, err)
+	}
+
+	return nil
+}
+
+func isAlreadyExists(err error) bool {
+	if awsErr, ok := err.(awserr.Error); ok {
+		return awsErr.Code() == cloudwatchlogs.ErrCodeResourceAlreadyExistsException
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elastic
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+)
+
+const (
+	opIndex  = "index"
+	opCreate = "create"
+)
+
+// bulkItem is a single operation of a bulk request
+type bulkItem struct {
+	entry    *entry.Entry
+	action   []byte
+	document []byte
+}
+
+// bulkDirective is the action and metadata line that precedes a document in a bulk request
+type bulkDirective struct {
+	Index    string `json:"_index"`
+	ID       string `json:"_id,omitempty"`
+	Pipeline string `json:"pipeline,omitempty"`
+}
+
+// dataStreamDocument adds the @timestamp field required by data streams to an entry
+type dataStreamDocument struct {
+	Timestamp time.Time `json:"@timestamp"`
+	*entry.Entry
+}
+
+// bulkBody will create the body of a bulk request from items. The bulk API expects
+// newline-delimited json strings, with an operation directive immediately followed by the document.
+// https://www.elastic.co/guide/en/elasticsearch/reference/master/docs-bulk.html
+func bulkBody(items []*bulkItem) []byte {
+	var buffer bytes.Buffer
+	for _, item := range items {
+		buffer.Write(item.action)
+		buffer.WriteByte('\n')
+		buffer.Write(item.document)
+		buffer.WriteByte('\n')
+	}
+	return buffer.Bytes()
+}
+
+// bulkResponse is the response of a bulk request
+type bulkResponse struct {
+	Errors bool                          `json:"errors"`
+	Items  []map[string]bulkItemResponse `json:"items"`
+}
+
+// bulkItemResponse is the result of a single operation of a bulk request
+type bulkItemResponse struct {
+	Index  string          `json:"_index"`
+	ID     string          `json:"_id"`
+	Status int             `json:"status"`
+	Error  json.RawMessage `json:"error,omitempty"`
+}
+
+// itemResult classifies the result of a bulk operation
+type itemResult int
+
+const (
+	itemSucceeded itemResult = iota
+	itemRetryable
+	itemFailed
+)
+
+// classify will decide whether a bulk operation succeeded, can be retried, or failed permanently
+func (r bulkItemResponse) classify(op string) itemResult {
+	switch {
+	case r.Status >= 200 && r.Status < 300:
+		return itemSucceeded
+	case r.Status == http.StatusConflict && op == opCreate:
+		// The document was created by an earlier attempt
+		return itemSucceeded
+	case r.Status == http.StatusTooManyRequests || r.Status >= 500:
+		return itemRetryable
+	default:
+		return itemFailed
+	}
+}
+
+// parseBulkResponse will return the result of each operation, in the order of the request
+func parseBulkResponse(body []byte, count int) ([]bulkItemResponse, error) {
+	var response bulkResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("decode bulk response: %s", err)
+	}
+
+	results := make([]bulkItemResponse, 0, count)
+	for _, item := range response.Items {
+		for _, result := range item {
+			results = append(results, result)
+		}
+	}
+
+	if len(results) != count {
+		return nil, fmt.Errorf("bulk response has %d items, expected %d", len(results), count)
+	}
+	return results, nil
+}
@@ -0,0 +1,69 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elastic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkItemResponseClassify(t *testing.T) {
+	cases := []struct {
+		name     string
+		status   int
+		op       string
+		expected itemResult
+	}{
+		{"Created", 201, opIndex, itemSucceeded},
+		{"Updated", 200, opIndex, itemSucceeded},
+		{"ConflictOnCreate", 409, opCreate, itemSucceeded},
+		{"ConflictOnIndex", 409, opIndex, itemFailed},
+		{"MapperError", 400, opIndex, itemFailed},
+		{"TooManyRequests", 429, opIndex, itemRetryable},
+		{"Unavailable", 503, opCreate, itemRetryable},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, bulkItemResponse{Status: tc.status}.classify(tc.op))
+		})
+	}
+}
+
+func TestParseBulkResponse(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		body := `{"took": 3, "errors": true, "items": [
+			{"index": {"_index": "a", "_id": "1", "status": 201}},
+			{"create": {"_index": "b", "_id": "2", "status": 400, "error": {"type": "mapper_parsing_exception"}}}
+		]}`
+		results, err := parseBulkResponse([]byte(body), 2)
+		require.NoError(t, err)
+		require.Equal(t, []bulkItemResponse{
+			{Index: "a", ID: "1", Status: 201},
+			{Index: "b", ID: "2", Status: 400, Error: []byte(`{"type": "mapper_parsing_exception"}`)},
+		}, results)
+	})
+
+	t.Run("WrongCount", func(t *testing.T) {
+		_, err := parseBulkResponse([]byte(`{"items": []}`), 1)
+		require.Error(t, err)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := parseBulkResponse([]byte(`not json`), 1)
+		require.Error(t, err)
+	})
+}
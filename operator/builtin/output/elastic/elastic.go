@@ -18,8 +18,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
 	"strconv"
 	"sync"
+	"time"
 
 	elasticsearch "github.com/elastic/go-elasticsearch/v7"
 	"github.com/elastic/go-elasticsearch/v7/esapi"
@@ -52,13 +57,17 @@ type ElasticOutputConfig struct {
 	BufferConfig        buffer.Config  `json:"buffer" yaml:"buffer"`
 	FlusherConfig       flusher.Config `json:"flusher" yaml:"flusher"`
 
-	Addresses  []string     `json:"addresses"             yaml:"addresses,flow"`
-	Username   string       `json:"username"              yaml:"username"`
-	Password   string       `json:"password"              yaml:"password"`
-	CloudID    string       `json:"cloud_id"              yaml:"cloud_id"`
-	APIKey     string       `json:"api_key"               yaml:"api_key"`
-	IndexField *entry.Field `json:"index_field,omitempty" yaml:"index_field,omitempty"`
-	IDField    *entry.Field `json:"id_field,omitempty"    yaml:"id_field,omitempty"`
+	Addresses      []string                `json:"addresses"                  yaml:"addresses,flow"`
+	Username       string                  `json:"username"                   yaml:"username"`
+	Password       string                  `json:"password"                   yaml:"password"`
+	CloudID        string                  `json:"cloud_id"                   yaml:"cloud_id"`
+	APIKey         string                  `json:"api_key"                    yaml:"api_key"`
+	Index          helper.ExprStringConfig `json:"index,omitempty"            yaml:"index,omitempty"`
+	IndexField     *entry.Field            `json:"index_field,omitempty"      yaml:"index_field,omitempty"`
+	IDField        *entry.Field            `json:"id_field,omitempty"         yaml:"id_field,omitempty"`
+	Pipeline       helper.ExprStringConfig `json:"pipeline,omitempty"         yaml:"pipeline,omitempty"`
+	DataStream     bool                    `json:"data_stream,omitempty"      yaml:"data_stream,omitempty"`
+	DeadLetterPath string                  `json:"dead_letter_path,omitempty" yaml:"dead_letter_path,omitempty"`
 }
 
 // Build will build an elasticsearch output operator.
@@ -68,6 +77,32 @@ func (c ElasticOutputConfig) Build(bc operator.BuildContext) ([]operator.Operato
 		return nil, err
 	}
 
+	if c.Index != "" && c.IndexField != nil {
+		return nil, fmt.Errorf("only one of 'index' and 'index_field' can be set")
+	}
+
+	var index *helper.ExprString
+	if c.Index != "" {
+		index, err = c.Index.Build()
+		if err != nil {
+			return nil, errors.Wrap(err, "build index")
+		}
+
+		for _, s := range index.SubStrings {
+			if _, err := formatIndex(s, time.Time{}); err != nil {
+				return nil, errors.Wrap(err, "invalid index")
+			}
+		}
+	}
+
+	var pipeline *helper.ExprString
+	if c.Pipeline != "" {
+		pipeline, err = c.Pipeline.Build()
+		if err != nil {
+			return nil, errors.Wrap(err, "build pipeline")
+		}
+	}
+
 	cfg := elasticsearch.Config{
 		Addresses: c.Addresses,
 		Username:  c.Username,
@@ -94,12 +129,23 @@ func (c ElasticOutputConfig) Build(bc operator.BuildContext) ([]operator.Operato
 
 	ctx, cancel := context.WithCancel(context.Background())
 
+	opType := opIndex
+	if c.DataStream {
+		// Data streams only accept the create operation
+		opType = opCreate
+	}
+
 	elasticOutput := &ElasticOutput{
 		OutputOperator: outputOperator,
 		buffer:         buffer,
 		client:         client,
+		index:          index,
 		indexField:     c.IndexField,
 		idField:        c.IDField,
+		pipeline:       pipeline,
+		opType:         opType,
+		dataStream:     c.DataStream,
+		deadLetterPath: c.DeadLetterPath,
 		flusher:        flusher,
 		ctx:            ctx,
 		cancel:         cancel,
@@ -115,8 +161,16 @@ type ElasticOutput struct {
 	flusher *flusher.Flusher
 
 	client     *elasticsearch.Client
+	index      *helper.ExprString
 	indexField *entry.Field
 	idField    *entry.Field
+	pipeline   *helper.ExprString
+	opType     string
+	dataStream bool
+
+	deadLetterPath string
+	deadLetter     *os.File
+	deadLetterMux  sync.Mutex
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -125,6 +179,14 @@ type ElasticOutput struct {
 
 // Start signals to the ElasticOutput to begin flushing
 func (e *ElasticOutput) Start() error {
+	if e.deadLetterPath != "" {
+		file, err := os.OpenFile(e.deadLetterPath, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0660)
+		if err != nil {
+			return errors.Wrap(err, "open dead letter file")
+		}
+		e.deadLetter = file
+	}
+
 	e.wg.Add(1)
 	go func() {
 		defer e.wg.Done()
@@ -139,6 +201,11 @@ func (e *ElasticOutput) Stop() error {
 	e.cancel()
 	e.wg.Wait()
 	e.flusher.Stop()
+	if e.deadLetter != nil {
+		if err := e.deadLetter.Close(); err != nil {
+			e.Errorw("Failed to close dead letter file", zap.Error(err))
+		}
+	}
 	return e.buffer.Close()
 }
 
@@ -147,57 +214,58 @@ func (e *ElasticOutput) Process(ctx context.Context, entry *entry.Entry) error {
 	return e.buffer.Add(ctx, entry)
 }
 
-// ProcessMulti will send entries to elasticsearch.
-func (e *ElasticOutput) createRequest(entries []*entry.Entry) *esapi.BulkRequest {
-	type indexDirective struct {
-		Index struct {
-			Index string `json:"_index"`
-			ID    string `json:"_id"`
-		} `json:"index"`
-	}
-
-	// The bulk API expects newline-delimited json strings, with an operation directive
-	// immediately followed by the document.
-	// https://www.elastic.co/guide/en/elasticsearch/reference/master/docs-bulk.html
-	var buffer bytes.Buffer
-	var err error
+// createItems will create a bulk operation for each entry
+func (e *ElasticOutput) createItems(entries []*entry.Entry) []*bulkItem {
+	items := make([]*bulkItem, 0, len(entries))
 	for _, entry := range entries {
-		directive := indexDirective{}
-		directive.Index.Index, err = e.FindIndex(entry)
+		item, err := e.createItem(entry)
 		if err != nil {
-			e.Warnw("Failed to find index", zap.Any("error", err))
+			e.Warnw("Failed to create bulk operation", zap.Any("error", err))
 			continue
 		}
+		items = append(items, item)
+	}
+	return items
+}
 
-		directive.Index.ID, err = e.FindID(entry)
-		if err != nil {
-			e.Warnw("Failed to find id", zap.Any("error", err))
-			continue
-		}
+func (e *ElasticOutput) createItem(entry *entry.Entry) (*bulkItem, error) {
+	var err error
+	directive := bulkDirective{}
+	directive.Index, err = e.FindIndex(entry)
+	if err != nil {
+		return nil, errors.Wrap(err, "find index")
+	}
 
-		directiveJSON, err := json.Marshal(directive)
-		if err != nil {
-			e.Warnw("Failed to marshal directive JSON", zap.Any("error", err))
-			continue
-		}
+	directive.ID, err = e.FindID(entry)
+	if err != nil {
+		return nil, errors.Wrap(err, "find id")
+	}
 
-		entryJSON, err := json.Marshal(entry)
-		if err != nil {
-			e.Warnw("Failed to marshal entry JSON", zap.Any("error", err))
-			continue
-		}
+	directive.Pipeline, err = e.FindPipeline(entry)
+	if err != nil {
+		return nil, errors.Wrap(err, "find pipeline")
+	}
 
-		buffer.Write(directiveJSON)
-		buffer.Write([]byte("\n"))
-		buffer.Write(entryJSON)
-		buffer.Write([]byte("\n"))
+	action, err := json.Marshal(map[string]bulkDirective{e.opType: directive})
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal directive JSON")
 	}
 
-	request := &esapi.BulkRequest{
-		Body: bytes.NewReader(buffer.Bytes()),
+	var document []byte
+	if e.dataStream {
+		document, err = json.Marshal(dataStreamDocument{entry.Timestamp, entry})
+	} else {
+		document, err = json.Marshal(entry)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal entry JSON")
 	}
 
-	return request
+	return &bulkItem{
+		entry:    entry,
+		action:   action,
+		document: document,
+	}, nil
 }
 
 func (e *ElasticOutput) feedFlusher(ctx context.Context) {
@@ -206,28 +274,27 @@ func (e *ElasticOutput) feedFlusher(ctx context.Context) {
 		if err != nil && err == context.Canceled {
 			return
 		} else if err != nil {
-			e.Errorf("Failed to read chunk", zap.Error(err))
+			e.Errorw("Failed to read chunk", zap.Error(err))
 			continue
 		}
 
+		// Only the items that failed with a retryable error are sent again when the flush is retried
+		items := e.createItems(entries)
 		e.flusher.Do(func(ctx context.Context) error {
-			req := e.createRequest(entries)
-			res, err := req.Do(ctx, e.client)
-			if err != nil {
-				return errors.NewError(
-					"Client failed to submit request to elasticsearch.",
-					"Review the underlying error message to troubleshoot the issue",
-					"underlying_error", err.Error(),
-				)
-			}
-
-			if res.IsError() {
-				return errors.NewError(
-					"Request to elasticsearch returned a failure code.",
-					"Review status and status code for further details.",
-					"status_code", strconv.Itoa(res.StatusCode),
-					"status", res.Status(),
-				)
+			if len(items) > 0 {
+				retry, err := e.send(ctx, items)
+				if err != nil {
+					return err
+				}
+
+				if len(retry) > 0 {
+					items = retry
+					return errors.NewError(
+						"Elasticsearch rejected documents with a retryable error.",
+						"The rejected documents will be retried.",
+						"count", strconv.Itoa(len(retry)),
+					)
+				}
 			}
 
 			if err = clearer.MarkAllAsFlushed(); err != nil {
@@ -238,8 +305,126 @@ func (e *ElasticOutput) feedFlusher(ctx context.Context) {
 	}
 }
 
+// send will submit a bulk request and return the items that should be retried
+func (e *ElasticOutput) send(ctx context.Context, items []*bulkItem) ([]*bulkItem, error) {
+	req := &esapi.BulkRequest{
+		Body: bytes.NewReader(bulkBody(items)),
+	}
+
+	res, err := req.Do(ctx, e.client)
+	if err != nil {
+		return nil, errors.NewError(
+			"Client failed to submit request to elasticsearch.",
+			"Review the underlying error message to troubleshoot the issue",
+			"underlying_error", err.Error(),
+		)
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "read bulk response")
+	}
+
+	// A failure of the whole request, such as a 401 from bad credentials or a 404
+	// from a wrong address, says nothing about the documents themselves, so they
+	// are retried rather than sent to the dead letter file
+	if res.IsError() {
+		if res.StatusCode != http.StatusTooManyRequests && res.StatusCode < 500 {
+			e.Errorw("Elasticsearch rejected the bulk request. Check the configuration of the output",
+				"status_code", res.StatusCode,
+				"error", string(body),
+			)
+		}
+		return nil, errors.NewError(
+			"Request to elasticsearch returned a failure code.",
+			"Review status and status code for further details.",
+			"status_code", strconv.Itoa(res.StatusCode),
+			"status", res.Status(),
+		)
+	}
+
+	results, err := parseBulkResponse(body, len(items))
+	if err != nil {
+		return nil, errors.Wrap(err, "parse bulk response")
+	}
+
+	retry := make([]*bulkItem, 0)
+	for i, result := range results {
+		switch result.classify(e.opType) {
+		case itemRetryable:
+			retry = append(retry, items[i])
+		case itemFailed:
+			e.sendToDeadLetter(items[i], result)
+		}
+	}
+	return retry, nil
+}
+
+// deadLetterRecord is written to the dead letter file for each document that elasticsearch rejected
+type deadLetterRecord struct {
+	Index  string          `json:"index"`
+	Status int             `json:"status"`
+	Error  json.RawMessage `json:"error,omitempty"`
+	Entry  *entry.Entry    `json:"entry"`
+}
+
+// sendToDeadLetter will write a rejected document to the dead letter file, or log it if there is none
+func (e *ElasticOutput) sendToDeadLetter(item *bulkItem, result bulkItemResponse) {
+	if e.deadLetter == nil {
+		e.Errorw("Dropping document rejected by elasticsearch",
+			"index", result.Index,
+			"status", result.Status,
+			"error", string(result.Error),
+		)
+		return
+	}
+
+	record, err := json.Marshal(deadLetterRecord{
+		Index:  result.Index,
+		Status: result.Status,
+		Error:  result.Error,
+		Entry:  item.entry,
+	})
+	if err != nil {
+		e.Errorw("Failed to marshal dead letter record", zap.Error(err))
+		return
+	}
+
+	e.deadLetterMux.Lock()
+	defer e.deadLetterMux.Unlock()
+	if _, err := e.deadLetter.Write(append(record, '\n')); err != nil {
+		e.Errorw("Failed to write to dead letter file", zap.Error(err))
+	}
+}
+
 // FindIndex will find an index that will represent an entry in elasticsearch.
 func (e *ElasticOutput) FindIndex(entry *entry.Entry) (string, error) {
+	if e.index != nil {
+		// Time directives are expanded in the literal parts of the index only,
+		// so that a '%' in a substituted field value is kept as is
+		index := &helper.ExprString{
+			SubStrings: make([]string, len(e.index.SubStrings)),
+			SubExprs:   e.index.SubExprs,
+		}
+		for i, s := range e.index.SubStrings {
+			formatted, err := formatIndex(s, entry.Timestamp)
+			if err != nil {
+				return "", err
+			}
+			index.SubStrings[i] = formatted
+		}
+
+		env := helper.GetExprEnv(entry)
+		defer helper.PutExprEnv(env)
+
+		rendered, err := index.Render(env)
+		if err != nil {
+			return "", errors.Wrap(err, "render index")
+		}
+		return rendered, nil
+	}
+
 	if e.indexField == nil {
 		return "default", nil
 	}
@@ -267,3 +452,19 @@ func (e *ElasticOutput) FindID(entry *entry.Entry) (string, error) {
 
 	return value, nil
 }
+
+// FindPipeline will find the ingest pipeline that should process an entry in elasticsearch.
+func (e *ElasticOutput) FindPipeline(entry *entry.Entry) (string, error) {
+	if e.pipeline == nil {
+		return "", nil
+	}
+
+	env := helper.GetExprEnv(entry)
+	defer helper.PutExprEnv(env)
+
+	pipeline, err := e.pipeline.Render(env)
+	if err != nil {
+		return "", errors.Wrap(err, "render pipeline")
+	}
+	return pipeline, nil
+}
@@ -21,10 +21,12 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
 	"time"
 
 	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
 	"github.com/opentelemetry/opentelemetry-log-collection/testutil"
 	"github.com/stretchr/testify/require"
 )
@@ -57,6 +59,32 @@ func TestFindIndex(t *testing.T) {
 		require.Error(t, err)
 	})
 
+	t.Run("Template", func(t *testing.T) {
+		index, err := helper.ExprStringConfig(`logs-EXPR($record.service)-%Y.%m.%d`).Build()
+		require.NoError(t, err)
+		output := &ElasticOutput{index: index}
+
+		entry := entry.New()
+		entry.Timestamp = time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC)
+		entry.Record = map[string]interface{}{"service": "web"}
+		idx, err := output.FindIndex(entry)
+		require.NoError(t, err)
+		require.Equal(t, "logs-web-2021.02.03", idx)
+	})
+
+	t.Run("TemplatePercentInValue", func(t *testing.T) {
+		index, err := helper.ExprStringConfig(`logs-EXPR($labels.app)-%Y`).Build()
+		require.NoError(t, err)
+		output := &ElasticOutput{index: index}
+
+		entry := entry.New()
+		entry.Timestamp = time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC)
+		entry.Labels = map[string]string{"app": "web%d"}
+		idx, err := output.FindIndex(entry)
+		require.NoError(t, err)
+		require.Equal(t, "logs-web%d-2021", idx)
+	})
+
 	t.Run("IndexFieldUnset", func(t *testing.T) {
 		entry := entry.New()
 		output := &ElasticOutput{}
@@ -111,7 +139,9 @@ func TestElastic(t *testing.T) {
 			panic(err)
 		}
 		received <- body
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(200)
+		w.Write([]byte(`{"errors": false, "items": [{"index": {"status": 201}}]}`))
 	}))
 	defer ts.Close()
 
@@ -125,6 +155,7 @@ func TestElastic(t *testing.T) {
 	e.Record = "test"
 
 	require.NoError(t, op.Start())
+	defer op.Stop()
 	op.Process(context.Background(), e)
 	select {
 	case <-time.After(5 * time.Second):
@@ -145,3 +176,248 @@ func TestElastic(t *testing.T) {
 		require.Equal(t, "test", entry["record"])
 	}
 }
+
+func TestFindPipeline(t *testing.T) {
+	t.Run("Unset", func(t *testing.T) {
+		output := &ElasticOutput{}
+		pipeline, err := output.FindPipeline(entry.New())
+		require.NoError(t, err)
+		require.Equal(t, "", pipeline)
+	})
+
+	t.Run("Expression", func(t *testing.T) {
+		pipeline, err := helper.ExprStringConfig(`EXPR($labels.format)-pipeline`).Build()
+		require.NoError(t, err)
+		output := &ElasticOutput{pipeline: pipeline}
+
+		entry := entry.New()
+		entry.AddLabel("format", "nginx")
+		p, err := output.FindPipeline(entry)
+		require.NoError(t, err)
+		require.Equal(t, "nginx-pipeline", p)
+	})
+}
+
+func TestElasticBuild(t *testing.T) {
+	cases := []struct {
+		name      string
+		configure func(cfg *ElasticOutputConfig)
+	}{
+		{"IndexAndIndexField", func(cfg *ElasticOutputConfig) {
+			field := entry.NewRecordField("index")
+			cfg.Index = "logs"
+			cfg.IndexField = &field
+		}},
+		{"InvalidIndexDirective", func(cfg *ElasticOutputConfig) { cfg.Index = "logs-%Q" }},
+		{"InvalidIndexExpression", func(cfg *ElasticOutputConfig) { cfg.Index = "logs-EXPR($record.)" }},
+		{"InvalidPipelineExpression", func(cfg *ElasticOutputConfig) { cfg.Pipeline = "EXPR(+)" }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := NewElasticOutputConfig("test")
+			tc.configure(cfg)
+			_, err := cfg.Build(testutil.NewBuildContext(t))
+			require.Error(t, err)
+		})
+	}
+}
+
+type bulkLine struct {
+	action   map[string]map[string]interface{}
+	document map[string]interface{}
+}
+
+func decodeBulk(t *testing.T, body []byte) []bulkLine {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	lines := []bulkLine{}
+	for dec.More() {
+		line := bulkLine{}
+		require.NoError(t, dec.Decode(&line.action))
+		require.NoError(t, dec.Decode(&line.document))
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestElasticBulkItemErrors(t *testing.T) {
+	responses := []string{
+		`{"errors": true, "items": [
+			{"index": {"_index": "logs", "status": 201}},
+			{"index": {"_index": "logs", "status": 429, "error": {"type": "es_rejected_execution_exception"}}},
+			{"index": {"_index": "logs", "status": 400, "error": {"type": "mapper_parsing_exception"}}}
+		]}`,
+		`{"errors": false, "items": [{"index": {"_index": "logs", "status": 201}}]}`,
+	}
+
+	received := make(chan []byte, 2)
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			panic(err)
+		}
+		received <- body
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write([]byte(responses[requests]))
+		requests++
+	}))
+	defer ts.Close()
+
+	deadLetter, err := ioutil.TempFile("", "dead_letter")
+	require.NoError(t, err)
+	deadLetter.Close()
+	defer os.Remove(deadLetter.Name())
+
+	cfg := NewElasticOutputConfig("test")
+	cfg.Addresses = []string{ts.URL}
+	cfg.Index = "logs"
+	cfg.DeadLetterPath = deadLetter.Name()
+
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	op := ops[0]
+	require.NoError(t, op.Start())
+	defer op.Stop()
+
+	for _, record := range []string{"ok", "retry", "invalid"} {
+		e := entry.New()
+		e.Record = record
+		require.NoError(t, op.Process(context.Background(), e))
+	}
+
+	expectRecords := func(expected ...string) {
+		select {
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "Timed out waiting for request")
+		case body := <-received:
+			records := []string{}
+			for _, line := range decodeBulk(t, body) {
+				records = append(records, line.document["record"].(string))
+			}
+			require.Equal(t, expected, records)
+		}
+	}
+
+	expectRecords("ok", "retry", "invalid")
+	expectRecords("retry")
+
+	require.Eventually(t, func() bool {
+		contents, err := ioutil.ReadFile(deadLetter.Name())
+		require.NoError(t, err)
+		if len(contents) == 0 {
+			return false
+		}
+
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal(contents, &record))
+		require.Equal(t, "logs", record["index"])
+		require.Equal(t, float64(400), record["status"])
+		require.Equal(t, map[string]interface{}{"type": "mapper_parsing_exception"}, record["error"])
+		require.Equal(t, "invalid", record["entry"].(map[string]interface{})["record"])
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestElasticRequestError(t *testing.T) {
+	received := make(chan []byte, 2)
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			panic(err)
+		}
+		received <- body
+		w.Header().Set("Content-Type", "application/json")
+		if requests == 0 {
+			w.WriteHeader(401)
+			w.Write([]byte(`{"error": {"type": "security_exception"}, "status": 401}`))
+		} else {
+			w.WriteHeader(200)
+			w.Write([]byte(`{"errors": false, "items": [{"index": {"_index": "logs", "status": 201}}]}`))
+		}
+		requests++
+	}))
+	defer ts.Close()
+
+	deadLetter, err := ioutil.TempFile("", "dead_letter")
+	require.NoError(t, err)
+	deadLetter.Close()
+	defer os.Remove(deadLetter.Name())
+
+	cfg := NewElasticOutputConfig("test")
+	cfg.Addresses = []string{ts.URL}
+	cfg.Index = "logs"
+	cfg.DeadLetterPath = deadLetter.Name()
+
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	op := ops[0]
+	require.NoError(t, op.Start())
+
+	e := entry.New()
+	e.Record = "unauthorized"
+	require.NoError(t, op.Process(context.Background(), e))
+
+	// The whole request is retried after it is rejected
+	for i := 0; i < 2; i++ {
+		select {
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "Timed out waiting for request")
+		case body := <-received:
+			lines := decodeBulk(t, body)
+			require.Len(t, lines, 1)
+			require.Equal(t, "unauthorized", lines[0].document["record"])
+		}
+	}
+	require.NoError(t, op.Stop())
+
+	contents, err := ioutil.ReadFile(deadLetter.Name())
+	require.NoError(t, err)
+	require.Empty(t, contents)
+}
+
+func TestElasticDataStream(t *testing.T) {
+	received := make(chan []byte, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			panic(err)
+		}
+		received <- body
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write([]byte(`{"errors": false, "items": [{"create": {"status": 201}}]}`))
+	}))
+	defer ts.Close()
+
+	cfg := NewElasticOutputConfig("test")
+	cfg.Addresses = []string{ts.URL}
+	cfg.Index = "logs-app-default"
+	cfg.DataStream = true
+	cfg.Pipeline = "app-pipeline"
+
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	op := ops[0]
+	require.NoError(t, op.Start())
+	defer op.Stop()
+
+	e := entry.New()
+	e.Timestamp = time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC)
+	e.Record = "test"
+	require.NoError(t, op.Process(context.Background(), e))
+
+	select {
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "Timed out waiting for request")
+	case body := <-received:
+		lines := decodeBulk(t, body)
+		require.Len(t, lines, 1)
+		require.Equal(t, "logs-app-default", lines[0].action["create"]["_index"])
+		require.Equal(t, "app-pipeline", lines[0].action["create"]["pipeline"])
+		require.Equal(t, "2021-02-03T04:05:06Z", lines[0].document["@timestamp"])
+		require.Equal(t, "test", lines[0].document["record"])
+	}
+}
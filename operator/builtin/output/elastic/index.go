@@ -0,0 +1,52 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elastic
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	strptime "github.com/observiq/ctimefmt"
+)
+
+// formatIndex replaces the strftime directives in an index name, such as %Y, %m or %d,
+// with the time of the entry in UTC. Other characters are kept as they are.
+func formatIndex(index string, t time.Time) (string, error) {
+	if !strings.Contains(index, "%") {
+		return index, nil
+	}
+
+	t = t.UTC()
+	var b strings.Builder
+	for i := 0; i < len(index); i++ {
+		if index[i] != '%' {
+			b.WriteByte(index[i])
+			continue
+		}
+
+		if i+1 == len(index) {
+			return "", fmt.Errorf("index '%s' ends with an incomplete directive", index)
+		}
+
+		formatted, err := strptime.Format(index[i:i+2], t)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(formatted)
+		i++
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,55 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elastic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatIndex(t *testing.T) {
+	ts := time.Date(2021, 2, 3, 23, 30, 0, 0, time.FixedZone("EST", -5*60*60))
+
+	cases := []struct {
+		index    string
+		expected string
+	}{
+		{"logs", "logs"},
+		{"logs-%Y.%m.%d", "logs-2021.02.04"},
+		{"logs-%F-%H", "logs-2021-02-04-04"},
+		{"logs-2-%y", "logs-2-21"},
+		{"logs-%%", "logs-%"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.index, func(t *testing.T) {
+			index, err := formatIndex(tc.index, ts)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, index)
+		})
+	}
+
+	t.Run("UnsupportedDirective", func(t *testing.T) {
+		_, err := formatIndex("logs-%Q", ts)
+		require.Error(t, err)
+	})
+
+	t.Run("IncompleteDirective", func(t *testing.T) {
+		_, err := formatIndex("logs-%", ts)
+		require.Error(t, err)
+	})
+}
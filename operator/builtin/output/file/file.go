@@ -15,10 +15,11 @@
 package file
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"html/template"
+	"io"
 	"os"
 	"sync"
 
@@ -35,6 +36,7 @@ func init() {
 func NewFileOutputConfig(operatorID string) *FileOutputConfig {
 	return &FileOutputConfig{
 		OutputConfig: helper.NewOutputConfig(operatorID, "file_output"),
+		Encoding:     helper.NewEncodingConfig("json"),
 	}
 }
 
@@ -42,8 +44,9 @@ func NewFileOutputConfig(operatorID string) *FileOutputConfig {
 type FileOutputConfig struct {
 	helper.OutputConfig `yaml:",inline"`
 
-	Path   string `json:"path" yaml:"path"`
-	Format string `json:"format,omitempty" path:"format,omitempty"`
+	Path     string                `json:"path"             yaml:"path"`
+	Format   string                `json:"format,omitempty" yaml:"format,omitempty"`
+	Encoding helper.EncodingConfig `json:"encoding"         yaml:"encoding"`
 }
 
 // Build will build a file output operator.
@@ -53,12 +56,14 @@ func (c FileOutputConfig) Build(context operator.BuildContext) ([]operator.Opera
 		return nil, err
 	}
 
-	var tmpl *template.Template
+	var encoder helper.Encoder
 	if c.Format != "" {
-		tmpl, err = template.New("file").Parse(c.Format)
-		if err != nil {
-			return nil, err
-		}
+		encoder, err = newFormatEncoder(c.Format)
+	} else {
+		encoder, err = c.Encoding.Build()
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	if c.Path == "" {
@@ -68,7 +73,7 @@ func (c FileOutputConfig) Build(context operator.BuildContext) ([]operator.Opera
 	fileOutput := &FileOutput{
 		OutputOperator: outputOperator,
 		path:           c.Path,
-		tmpl:           tmpl,
+		encoder:        encoder,
 	}
 
 	return []operator.Operator{fileOutput}, nil
@@ -79,8 +84,7 @@ type FileOutput struct {
 	helper.OutputOperator
 
 	path    string
-	tmpl    *template.Template
-	encoder *json.Encoder
+	encoder helper.Encoder
 	file    *os.File
 	mux     sync.Mutex
 }
@@ -89,14 +93,7 @@ type FileOutput struct {
 func (fo *FileOutput) Start() error {
 	var err error
 	fo.file, err = os.OpenFile(fo.path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0660)
-	if err != nil {
-		return err
-	}
-
-	fo.encoder = json.NewEncoder(fo.file)
-	fo.encoder.SetEscapeHTML(false)
-
-	return nil
+	return err
 }
 
 // Stop will close the output file.
@@ -109,20 +106,43 @@ func (fo *FileOutput) Stop() error {
 
 // Process will write an entry to the output file.
 func (fo *FileOutput) Process(ctx context.Context, entry *entry.Entry) error {
+	var buf bytes.Buffer
+	if err := fo.encoder.Encode(&buf, entry); err != nil {
+		return err
+	}
+
 	fo.mux.Lock()
 	defer fo.mux.Unlock()
 
-	if fo.tmpl != nil {
-		err := fo.tmpl.Execute(fo.file, entry)
-		if err != nil {
-			return err
-		}
-	} else {
-		err := fo.encoder.Encode(entry)
-		if err != nil {
+	_, err := fo.file.Write(buf.Bytes())
+	return err
+}
+
+// formatEncoder renders entries with the html template of the deprecated format
+// option. Unlike a template encoding, its output is HTML escaped, as it always has been.
+type formatEncoder struct {
+	tmpl *template.Template
+}
+
+func newFormatEncoder(format string) (helper.Encoder, error) {
+	tmpl, err := template.New("file").Parse(format)
+	if err != nil {
+		return nil, err
+	}
+	return formatEncoder{tmpl: tmpl}, nil
+}
+
+func (f formatEncoder) Encode(w io.Writer, e *entry.Entry) error {
+	return f.tmpl.Execute(w, e)
+}
+
+func (f formatEncoder) EncodeBatch(w io.Writer, entries []*entry.Entry) error {
+	for _, e := range entries {
+		if err := f.Encode(w, e); err != nil {
 			return err
 		}
 	}
-
 	return nil
 }
+
+func (formatEncoder) ContentType() string { return "text/html" }
@@ -0,0 +1,371 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fluentforward
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/errors"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/buffer"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/fluent"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/flusher"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"github.com/tinylib/msgp/msgp"
+	"go.uber.org/zap"
+)
+
+func init() {
+	operator.Register("fluentforward_output", func() operator.Builder { return NewFluentForwardOutputConfig("") })
+}
+
+const (
+	compressionGzip = "gzip"
+	compressionNone = "none"
+)
+
+// NewFluentForwardOutputConfig creates a new fluentforward output config with default values
+func NewFluentForwardOutputConfig(operatorID string) *FluentForwardOutputConfig {
+	return &FluentForwardOutputConfig{
+		OutputConfig:  helper.NewOutputConfig(operatorID, "fluentforward_output"),
+		BufferConfig:  buffer.NewConfig(),
+		FlusherConfig: flusher.NewConfig(),
+		TagField:      entry.NewLabelField("fluent.tag"),
+		DefaultTag:    "stanza",
+		Compression:   compressionNone,
+		Timeout:       helper.Duration{Duration: 10 * time.Second},
+	}
+}
+
+// FluentForwardOutputConfig is the configuration of a fluentforward output operator.
+type FluentForwardOutputConfig struct {
+	helper.OutputConfig `yaml:",inline"`
+	BufferConfig        buffer.Config           `json:"buffer"                  yaml:"buffer"`
+	FlusherConfig       flusher.Config          `json:"flusher"                 yaml:"flusher"`
+	Address             string                  `json:"address"                 yaml:"address"`
+	TLS                 *helper.TLSClientConfig `json:"tls,omitempty"           yaml:"tls,omitempty"`
+	SharedKey           string                  `json:"shared_key,omitempty"    yaml:"shared_key,omitempty"`
+	SelfHostname        string                  `json:"self_hostname,omitempty" yaml:"self_hostname,omitempty"`
+	TagField            entry.Field             `json:"tag_field"               yaml:"tag_field"`
+	DefaultTag          string                  `json:"default_tag,omitempty"   yaml:"default_tag,omitempty"`
+	RequireAck          bool                    `json:"require_ack,omitempty"   yaml:"require_ack,omitempty"`
+	Compression         string                  `json:"compression,omitempty"   yaml:"compression,omitempty"`
+	Timeout             helper.Duration         `json:"timeout,omitempty"       yaml:"timeout,omitempty"`
+}
+
+// Build will build a fluentforward output operator.
+func (c FluentForwardOutputConfig) Build(bc operator.BuildContext) ([]operator.Operator, error) {
+	outputOperator, err := c.OutputConfig.Build(bc)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Address == "" {
+		return nil, errors.NewError("missing required parameter 'address'", "")
+	}
+
+	switch c.Compression {
+	case compressionGzip, compressionNone, "":
+	default:
+		return nil, fmt.Errorf("invalid compression '%s'", c.Compression)
+	}
+
+	if c.DefaultTag == "" {
+		return nil, fmt.Errorf("`default_tag` must not be empty")
+	}
+
+	var tlsConfig *tls.Config
+	if c.TLS != nil {
+		tlsConfig, err = c.TLS.Build()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hostname := c.SelfHostname
+	if hostname == "" {
+		hostname, err = os.Hostname()
+		if err != nil {
+			return nil, errors.Wrap(err, "get hostname")
+		}
+	}
+
+	buffer, err := c.BufferConfig.Build(bc, c.ID())
+	if err != nil {
+		return nil, err
+	}
+
+	flusher := c.FlusherConfig.Build(bc.Logger.SugaredLogger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	fluentOutput := &FluentForwardOutput{
+		OutputOperator: outputOperator,
+		buffer:         buffer,
+		flusher:        flusher,
+		address:        c.Address,
+		tlsConfig:      tlsConfig,
+		sharedKey:      c.SharedKey,
+		hostname:       hostname,
+		tagField:       c.TagField,
+		defaultTag:     c.DefaultTag,
+		requireAck:     c.RequireAck,
+		compress:       c.Compression == compressionGzip,
+		timeout:        c.Timeout.Raw(),
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+
+	return []operator.Operator{fluentOutput}, nil
+}
+
+// FluentForwardOutput is an operator that sends entries using the Fluent Forward protocol
+type FluentForwardOutput struct {
+	helper.OutputOperator
+	buffer  buffer.Buffer
+	flusher *flusher.Flusher
+
+	address    string
+	tlsConfig  *tls.Config
+	sharedKey  string
+	hostname   string
+	tagField   entry.Field
+	defaultTag string
+	requireAck bool
+	compress   bool
+	timeout    time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Start signals to the FluentForwardOutput to begin flushing
+func (f *FluentForwardOutput) Start() error {
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		f.feedFlusher(f.ctx)
+	}()
+
+	return nil
+}
+
+// Stop tells the FluentForwardOutput to stop gracefully
+func (f *FluentForwardOutput) Stop() error {
+	f.cancel()
+	f.wg.Wait()
+	f.flusher.Stop()
+	return f.buffer.Close()
+}
+
+// Process adds an entry to the output's buffer
+func (f *FluentForwardOutput) Process(ctx context.Context, entry *entry.Entry) error {
+	return f.buffer.Add(ctx, entry)
+}
+
+func (f *FluentForwardOutput) feedFlusher(ctx context.Context) {
+	for {
+		entries, clearer, err := f.buffer.ReadChunk(ctx)
+		if err != nil && err == context.Canceled {
+			return
+		} else if err != nil {
+			f.Errorw("Failed to read chunk", zap.Error(err))
+			continue
+		}
+
+		messages := f.createMessages(entries)
+		f.flusher.Do(func(ctx context.Context) error {
+			if err := f.send(ctx, messages); err != nil {
+				return err
+			}
+
+			if err = clearer.MarkAllAsFlushed(); err != nil {
+				f.Errorw("Failed to mark entries as flushed", zap.Error(err))
+			}
+			return nil
+		})
+	}
+}
+
+// createMessages will encode entries as events grouped by tag, dropping any that fail
+func (f *FluentForwardOutput) createMessages(entries []*entry.Entry) []*message {
+	messages := make([]*message, 0, 1)
+	byTag := make(map[string]*message, 1)
+	for _, e := range entries {
+		tag := f.getTag(e)
+		msg, ok := byTag[tag]
+		if !ok {
+			msg = &message{tag: tag}
+			byTag[tag] = msg
+			messages = append(messages, msg)
+		}
+
+		events, err := appendEvent(msg.events, e.Timestamp, toRecord(e.Record))
+		if err != nil {
+			f.Errorw("Failed to encode entry. Dropping entry", zap.Error(err))
+			continue
+		}
+		msg.events = events
+		msg.count++
+	}
+	return messages
+}
+
+// getTag will return the tag of an entry, or the default tag if it is missing
+func (f *FluentForwardOutput) getTag(e *entry.Entry) string {
+	value, ok := e.Get(f.tagField)
+	if !ok {
+		return f.defaultTag
+	}
+
+	tag, ok := value.(string)
+	if !ok || tag == "" {
+		return f.defaultTag
+	}
+	return tag
+}
+
+// toRecord will convert an entry record to a map, wrapping other values in a message field
+func toRecord(record interface{}) map[string]interface{} {
+	if m, ok := record.(map[string]interface{}); ok {
+		return m
+	}
+	return map[string]interface{}{"message": record}
+}
+
+// send will write messages to a new connection, waiting for acks if required
+func (f *FluentForwardOutput) send(ctx context.Context, messages []*message) error {
+	conn, err := f.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(f.timeout)); err != nil {
+		return err
+	}
+
+	reader := msgp.NewReader(conn)
+	if f.sharedKey != "" {
+		if err := f.handshake(conn, reader); err != nil {
+			return errors.Wrap(err, "handshake")
+		}
+	}
+
+	for _, msg := range messages {
+		if msg.count == 0 {
+			continue
+		}
+
+		var chunk string
+		if f.requireAck {
+			if chunk, err = newChunkID(); err != nil {
+				return err
+			}
+		}
+
+		b, err := appendMessage(nil, msg, f.compress, chunk)
+		if err != nil {
+			return errors.Wrap(err, "encode message")
+		}
+
+		if _, err := conn.Write(b); err != nil {
+			return errors.Wrap(err, "write message")
+		}
+
+		if f.requireAck {
+			ack, err := readAck(reader)
+			if err != nil {
+				return errors.Wrap(err, "read ack")
+			}
+			if ack != chunk {
+				return fmt.Errorf("received ack '%s' for chunk '%s'", ack, chunk)
+			}
+		}
+	}
+
+	return nil
+}
+
+// dial will open a connection to the configured address
+func (f *FluentForwardOutput) dial(ctx context.Context) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: f.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", f.address)
+	if err != nil {
+		return nil, errors.Wrap(err, "dial")
+	}
+
+	if f.tlsConfig == nil {
+		return conn, nil
+	}
+
+	tlsConfig := f.tlsConfig
+	if tlsConfig.ServerName == "" {
+		tlsConfig = tlsConfig.Clone()
+		tlsConfig.ServerName, _, _ = net.SplitHostPort(f.address)
+	}
+	return tls.Client(conn, tlsConfig), nil
+}
+
+// handshake will authenticate with the server using the shared key
+func (f *FluentForwardOutput) handshake(conn net.Conn, r *msgp.Reader) error {
+	nonce, err := readHelo(r)
+	if err != nil {
+		return errors.Wrap(err, "read HELO")
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return errors.Wrap(err, "generate salt")
+	}
+
+	digest := fluent.SharedKeyDigest(salt, f.hostname, nonce, f.sharedKey)
+	if err := writePing(conn, f.hostname, salt, digest); err != nil {
+		return errors.Wrap(err, "write PING")
+	}
+
+	ok, reason, serverHostname, serverDigest, err := readPong(r)
+	if err != nil {
+		return errors.Wrap(err, "read PONG")
+	}
+	if !ok {
+		return fmt.Errorf("authentication failed: %s", reason)
+	}
+	expected := fluent.SharedKeyDigest(salt, serverHostname, nonce, f.sharedKey)
+	if subtle.ConstantTimeCompare([]byte(serverDigest), []byte(expected)) != 1 {
+		return fmt.Errorf("server digest does not match shared_key")
+	}
+	return nil
+}
+
+// newChunkID will generate a unique id used to acknowledge a message
+func newChunkID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", errors.Wrap(err, "generate chunk id")
+	}
+	return base64.StdEncoding.EncodeToString(id), nil
+}
@@ -0,0 +1,223 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fluentforward
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	input "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/input/fluentforward"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"github.com/opentelemetry/opentelemetry-log-collection/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func newTestFluentForwardOutput(t *testing.T, address string, configure func(cfg *FluentForwardOutputConfig)) *FluentForwardOutput {
+	cfg := NewFluentForwardOutputConfig("test_output")
+	cfg.Address = address
+	cfg.SelfHostname = "client"
+	cfg.Timeout.Duration = 5 * time.Second
+	if configure != nil {
+		configure(cfg)
+	}
+
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	return ops[0].(*FluentForwardOutput)
+}
+
+func newTestFluentForwardInput(t *testing.T, sharedKey string) (string, *testutil.FakeOutput) {
+	// Reserve a free port, since the input does not expose its listener
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	cfg := input.NewFluentForwardInputConfig("test_input")
+	cfg.ListenAddress = address
+	cfg.SelfHostname = "server"
+	cfg.SharedKey = sharedKey
+
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	fluentInput := ops[0].(*input.FluentForwardInput)
+
+	fakeOutput := testutil.NewFakeOutput(t)
+	fluentInput.InputOperator.OutputOperators = []operator.Operator{fakeOutput}
+
+	require.NoError(t, fluentInput.Start())
+	t.Cleanup(func() { require.NoError(t, fluentInput.Stop()) })
+	return address, fakeOutput
+}
+
+func newTestEntry(tag string, record interface{}) *entry.Entry {
+	e := entry.New()
+	e.Timestamp = time.Unix(1600000000, 123456789)
+	e.Record = record
+	if tag != "" {
+		e.Labels = map[string]string{"fluent.tag": tag}
+	}
+	return e
+}
+
+func expectEntry(t *testing.T, fakeOutput *testutil.FakeOutput, tag string, record interface{}) {
+	select {
+	case e := <-fakeOutput.Received:
+		require.True(t, time.Unix(1600000000, 123456789).Equal(e.Timestamp))
+		require.Equal(t, tag, e.Labels["fluent.tag"])
+		require.Equal(t, record, e.Record)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "Timed out waiting for entry")
+	}
+}
+
+func TestFluentForwardOutputCreateMessages(t *testing.T) {
+	f := newTestFluentForwardOutput(t, "127.0.0.1:24224", nil)
+
+	messages := f.createMessages([]*entry.Entry{
+		newTestEntry("a", map[string]interface{}{"message": "one"}),
+		newTestEntry("b", "two"),
+		newTestEntry("a", map[string]interface{}{"message": "three"}),
+		newTestEntry("", "four"),
+	})
+
+	require.Len(t, messages, 3)
+	require.Equal(t, "a", messages[0].tag)
+	require.Equal(t, 2, messages[0].count)
+	require.Equal(t, "b", messages[1].tag)
+	require.Equal(t, 1, messages[1].count)
+	require.Equal(t, "stanza", messages[2].tag)
+	require.Equal(t, 1, messages[2].count)
+}
+
+func TestFluentForwardOutput(t *testing.T) {
+	cases := []struct {
+		name      string
+		sharedKey string
+		configure func(cfg *FluentForwardOutputConfig)
+	}{
+		{"Forward", "", nil},
+		{"CompressedPackedForward", "", func(cfg *FluentForwardOutputConfig) { cfg.Compression = "gzip" }},
+		{"RequireAck", "", func(cfg *FluentForwardOutputConfig) { cfg.RequireAck = true }},
+		{"SharedKey", "secret", func(cfg *FluentForwardOutputConfig) { cfg.SharedKey = "secret" }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			address, fakeOutput := newTestFluentForwardInput(t, tc.sharedKey)
+
+			f := newTestFluentForwardOutput(t, address, tc.configure)
+			require.NoError(t, f.Start())
+			defer f.Stop()
+
+			require.NoError(t, f.Process(context.Background(), newTestEntry("app.logs", map[string]interface{}{"message": "test"})))
+			require.NoError(t, f.Process(context.Background(), newTestEntry("", "raw message")))
+
+			expectEntry(t, fakeOutput, "app.logs", map[string]interface{}{"message": "test"})
+			expectEntry(t, fakeOutput, "stanza", map[string]interface{}{"message": "raw message"})
+		})
+	}
+}
+
+func TestFluentForwardOutputSend(t *testing.T) {
+	serve := func(t *testing.T, handle func(conn net.Conn)) string {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		t.Cleanup(func() { ln.Close() })
+
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			handle(conn)
+		}()
+		return ln.Addr().String()
+	}
+
+	entries := []*entry.Entry{newTestEntry("app.logs", map[string]interface{}{"message": "test"})}
+
+	t.Run("AckMismatch", func(t *testing.T) {
+		address := serve(t, func(conn net.Conn) {
+			r := msgp.NewReader(conn)
+			_ = r.Skip()
+			b := msgp.AppendMapHeader(nil, 1)
+			b = msgp.AppendString(b, "ack")
+			b = msgp.AppendString(b, "wrong")
+			_, _ = conn.Write(b)
+		})
+
+		f := newTestFluentForwardOutput(t, address, func(cfg *FluentForwardOutputConfig) { cfg.RequireAck = true })
+		err := f.send(context.Background(), f.createMessages(entries))
+		require.Error(t, err)
+	})
+
+	t.Run("AckTimeout", func(t *testing.T) {
+		done := make(chan struct{})
+		address := serve(t, func(conn net.Conn) { <-done })
+		defer close(done)
+
+		f := newTestFluentForwardOutput(t, address, func(cfg *FluentForwardOutputConfig) {
+			cfg.RequireAck = true
+			cfg.Timeout.Duration = 100 * time.Millisecond
+		})
+		err := f.send(context.Background(), f.createMessages(entries))
+		require.Error(t, err)
+	})
+
+	t.Run("SharedKeyMismatch", func(t *testing.T) {
+		address, fakeOutput := newTestFluentForwardInput(t, "secret")
+
+		f := newTestFluentForwardOutput(t, address, func(cfg *FluentForwardOutputConfig) { cfg.SharedKey = "wrong" })
+		err := f.send(context.Background(), f.createMessages(entries))
+		require.Error(t, err)
+
+		select {
+		case e := <-fakeOutput.Received:
+			require.FailNow(t, "Unexpected entry: %v", e)
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+}
+
+func TestFluentForwardOutputBuild(t *testing.T) {
+	cases := []struct {
+		name      string
+		configure func(cfg *FluentForwardOutputConfig)
+	}{
+		{"MissingAddress", func(cfg *FluentForwardOutputConfig) { cfg.Address = "" }},
+		{"InvalidCompression", func(cfg *FluentForwardOutputConfig) { cfg.Compression = "zstd" }},
+		{"EmptyDefaultTag", func(cfg *FluentForwardOutputConfig) { cfg.DefaultTag = "" }},
+		{"InvalidTLS", func(cfg *FluentForwardOutputConfig) {
+			cfg.TLS = &helper.TLSClientConfig{CAFile: "/does/not/exist"}
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := NewFluentForwardOutputConfig("test_output")
+			cfg.Address = "127.0.0.1:24224"
+			tc.configure(cfg)
+			_, err := cfg.Build(testutil.NewBuildContext(t))
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fluentforward
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/fluent"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// message is a group of encoded events that share a tag
+type message struct {
+	tag    string
+	count  int
+	events []byte
+}
+
+// appendEvent encodes an event of the form [time, record]
+func appendEvent(b []byte, t time.Time, record map[string]interface{}) ([]byte, error) {
+	b = msgp.AppendArrayHeader(b, 2)
+	b, err := msgp.AppendExtension(b, &fluent.EventTime{Time: t})
+	if err != nil {
+		return nil, err
+	}
+	return msgp.AppendMapStrIntf(b, record)
+}
+
+// appendMessage encodes a message in Forward mode, or in CompressedPackedForward
+// mode when compressing. A chunk id is included when an ack is requested.
+func appendMessage(b []byte, msg *message, compress bool, chunk string) ([]byte, error) {
+	b = msgp.AppendArrayHeader(b, 3)
+	b = msgp.AppendString(b, msg.tag)
+
+	optionCount := uint32(1)
+	if compress {
+		optionCount++
+		compressed, err := gzipBytes(msg.events)
+		if err != nil {
+			return nil, err
+		}
+		b = msgp.AppendBytes(b, compressed)
+	} else {
+		b = msgp.AppendArrayHeader(b, uint32(msg.count))
+		b = append(b, msg.events...)
+	}
+
+	if chunk != "" {
+		optionCount++
+	}
+
+	b = msgp.AppendMapHeader(b, optionCount)
+	b = msgp.AppendString(b, "size")
+	b = msgp.AppendInt(b, msg.count)
+	if compress {
+		b = msgp.AppendString(b, "compressed")
+		b = msgp.AppendString(b, "gzip")
+	}
+	if chunk != "" {
+		b = msgp.AppendString(b, "chunk")
+		b = msgp.AppendString(b, chunk)
+	}
+	return b, nil
+}
+
+// readAck reads a response of the form {"ack": chunk}
+func readAck(r *msgp.Reader) (string, error) {
+	response := map[string]interface{}{}
+	if err := r.ReadMapStrIntf(response); err != nil {
+		return "", err
+	}
+
+	switch ack := response["ack"].(type) {
+	case string:
+		return ack, nil
+	case []byte:
+		return string(ack), nil
+	default:
+		return "", fmt.Errorf("response is missing 'ack'")
+	}
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// readHelo reads ["HELO", {"nonce": nonce, ...}] and returns the nonce
+func readHelo(r *msgp.Reader) ([]byte, error) {
+	size, err := r.ReadArrayHeader()
+	if err != nil {
+		return nil, err
+	}
+	if size != 2 {
+		return nil, fmt.Errorf("invalid HELO length %d", size)
+	}
+
+	msgType, err := r.ReadString()
+	if err != nil {
+		return nil, err
+	}
+	if msgType != "HELO" {
+		return nil, fmt.Errorf("expected HELO, got '%s'", msgType)
+	}
+
+	options := map[string]interface{}{}
+	if err := r.ReadMapStrIntf(options); err != nil {
+		return nil, err
+	}
+
+	if auth, ok := options["auth"]; ok && len(toBytes(auth)) > 0 {
+		return nil, fmt.Errorf("server requires user authentication, which is not supported")
+	}
+
+	nonce := toBytes(options["nonce"])
+	if len(nonce) == 0 {
+		return nil, fmt.Errorf("HELO is missing 'nonce'")
+	}
+	return nonce, nil
+}
+
+// writePing writes ["PING", hostname, salt, digest, "", ""]
+func writePing(w io.Writer, hostname string, salt []byte, digest string) error {
+	b := msgp.AppendArrayHeader(nil, 6)
+	b = msgp.AppendString(b, "PING")
+	b = msgp.AppendString(b, hostname)
+	b = msgp.AppendBytes(b, salt)
+	b = msgp.AppendString(b, digest)
+	b = msgp.AppendString(b, "")
+	b = msgp.AppendString(b, "")
+	_, err := w.Write(b)
+	return err
+}
+
+// readPong reads ["PONG", ok, reason, hostname, digest]
+func readPong(r *msgp.Reader) (ok bool, reason, hostname, digest string, err error) {
+	size, err := r.ReadArrayHeader()
+	if err != nil {
+		return
+	}
+	if size != 5 {
+		err = fmt.Errorf("invalid PONG length %d", size)
+		return
+	}
+
+	msgType, err := r.ReadString()
+	if err != nil {
+		return
+	}
+	if msgType != "PONG" {
+		err = fmt.Errorf("expected PONG, got '%s'", msgType)
+		return
+	}
+
+	if ok, err = r.ReadBool(); err != nil {
+		return
+	}
+	if reason, err = r.ReadString(); err != nil {
+		return
+	}
+	if hostname, err = r.ReadString(); err != nil {
+		return
+	}
+	digest, err = r.ReadString()
+	return
+}
+
+func toBytes(v interface{}) []byte {
+	switch value := v.(type) {
+	case []byte:
+		return value
+	case string:
+		return []byte(value)
+	default:
+		return nil
+	}
+}
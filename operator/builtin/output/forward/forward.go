@@ -17,7 +17,6 @@ package forward
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"sync"
@@ -41,15 +40,17 @@ func NewForwardOutputConfig(operatorID string) *ForwardOutputConfig {
 		OutputConfig:  helper.NewOutputConfig(operatorID, "forward_output"),
 		BufferConfig:  buffer.NewConfig(),
 		FlusherConfig: flusher.NewConfig(),
+		Encoding:      helper.NewEncodingConfig("json"),
 	}
 }
 
 // ForwardOutputConfig is the configuration of a forward output operator.
 type ForwardOutputConfig struct {
 	helper.OutputConfig `yaml:",inline"`
-	BufferConfig        buffer.Config  `json:"buffer"  yaml:"buffer"`
-	FlusherConfig       flusher.Config `json:"flusher" yaml:"flusher"`
-	Address             string         `json:"address" yaml:"address"`
+	BufferConfig        buffer.Config         `json:"buffer"   yaml:"buffer"`
+	FlusherConfig       flusher.Config        `json:"flusher"  yaml:"flusher"`
+	Address             string                `json:"address"  yaml:"address"`
+	Encoding            helper.EncodingConfig `json:"encoding" yaml:"encoding"`
 }
 
 // Build will build an forward output operator.
@@ -68,6 +69,11 @@ func (c ForwardOutputConfig) Build(bc operator.BuildContext) ([]operator.Operato
 		return nil, errors.NewError("missing required parameter 'address'", "")
 	}
 
+	encoder, err := c.Encoding.Build()
+	if err != nil {
+		return nil, err
+	}
+
 	flusher := c.FlusherConfig.Build(bc.Logger.SugaredLogger)
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -80,6 +86,7 @@ func (c ForwardOutputConfig) Build(bc operator.BuildContext) ([]operator.Operato
 		cancel:         cancel,
 		client:         &http.Client{},
 		address:        c.Address,
+		encoder:        encoder,
 	}
 
 	return []operator.Operator{forwardOutput}, nil
@@ -93,6 +100,7 @@ type ForwardOutput struct {
 
 	client  *http.Client
 	address string
+	encoder helper.Encoder
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -126,13 +134,16 @@ func (f *ForwardOutput) Process(ctx context.Context, entry *entry.Entry) error {
 // ProcessMulti will send entries to elasticsearch.
 func (f *ForwardOutput) createRequest(ctx context.Context, entries []*entry.Entry) (*http.Request, error) {
 	var b bytes.Buffer
-	enc := json.NewEncoder(&b)
-	err := enc.Encode(entries)
-	if err != nil {
+	if err := f.encoder.EncodeBatch(&b, entries); err != nil {
 		return nil, err
 	}
 
-	return http.NewRequestWithContext(ctx, "POST", f.address, &b)
+	req, err := http.NewRequestWithContext(ctx, "POST", f.address, &b)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", f.encoder.ContentType())
+	return req, nil
 }
 
 func (f *ForwardOutput) feedFlusher(ctx context.Context) {
@@ -72,3 +72,41 @@ func TestForwardOutput(t *testing.T) {
 		require.Equal(t, newEntry.Resource, e.Resource)
 	}
 }
+
+func TestForwardOutputEncoding(t *testing.T) {
+	type request struct {
+		contentType string
+		body        []byte
+	}
+	received := make(chan request, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		received <- request{req.Header.Get("Content-Type"), body}
+	}))
+	defer srv.Close()
+
+	cfg := NewForwardOutputConfig("test")
+	cfg.BufferConfig.Builder.(*buffer.MemoryBufferConfig).MaxChunkDelay = helper.NewDuration(50 * time.Millisecond)
+	cfg.Address = srv.URL
+	cfg.Encoding = helper.NewEncodingConfig("raw")
+
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	forwardOutput := ops[0].(*ForwardOutput)
+	require.NoError(t, forwardOutput.Start())
+	defer forwardOutput.Stop()
+
+	for _, record := range []string{"first", "second"} {
+		e := entry.New()
+		e.Record = record
+		require.NoError(t, forwardOutput.Process(context.Background(), e))
+	}
+
+	select {
+	case <-time.After(time.Second):
+		require.FailNow(t, "Timed out waiting for server to receive entries")
+	case req := <-received:
+		require.Equal(t, "text/plain", req.contentType)
+		require.Equal(t, "first\nsecond\n", string(req.body))
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gelf
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+const (
+	// chunkHeaderSize is the size of the magic bytes, message id, sequence number and sequence count
+	chunkHeaderSize = 12
+
+	// maxChunks is the maximum number of chunks a message may be split into
+	maxChunks = 128
+)
+
+// chunk will split a message into GELF chunks no larger than chunkSize.
+// Messages that fit in a single datagram are returned unchanged.
+func chunk(msg []byte, chunkSize int) ([][]byte, error) {
+	if len(msg) <= chunkSize {
+		return [][]byte{msg}, nil
+	}
+
+	dataSize := chunkSize - chunkHeaderSize
+	count := (len(msg) + dataSize - 1) / dataSize
+	if count > maxChunks {
+		return nil, fmt.Errorf("message of %d bytes requires %d chunks, which exceeds the maximum of %d", len(msg), count, maxChunks)
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("generate message id: %s", err)
+	}
+
+	chunks := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		start := i * dataSize
+		end := start + dataSize
+		if end > len(msg) {
+			end = len(msg)
+		}
+
+		c := make([]byte, 0, chunkHeaderSize+end-start)
+		c = append(c, 0x1e, 0x0f)
+		c = append(c, id...)
+		c = append(c, byte(i), byte(count))
+		c = append(c, msg[start:end]...)
+		chunks = append(chunks, c)
+	}
+
+	return chunks, nil
+}
@@ -0,0 +1,305 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/errors"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/buffer"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/flusher"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"go.uber.org/zap"
+)
+
+func init() {
+	operator.Register("gelf_output", func() operator.Builder { return NewGELFOutputConfig("") })
+}
+
+const (
+	protocolUDP = "udp"
+	protocolTCP = "tcp"
+
+	compressionGzip = "gzip"
+	compressionZlib = "zlib"
+	compressionNone = "none"
+
+	// defaultChunkSize is the chunk size recommended for sending over a WAN
+	defaultChunkSize = 1420
+)
+
+// NewGELFOutputConfig creates a new GELF output config with default values
+func NewGELFOutputConfig(operatorID string) *GELFOutputConfig {
+	return &GELFOutputConfig{
+		OutputConfig:  helper.NewOutputConfig(operatorID, "gelf_output"),
+		BufferConfig:  buffer.NewConfig(),
+		FlusherConfig: flusher.NewConfig(),
+		Protocol:      protocolUDP,
+		Compression:   compressionGzip,
+		ChunkSize:     defaultChunkSize,
+		MessageField:  entry.NewRecordField("message"),
+		Timeout:       helper.Duration{Duration: 10 * time.Second},
+	}
+}
+
+// GELFOutputConfig is the configuration of a GELF output operator.
+type GELFOutputConfig struct {
+	helper.OutputConfig `yaml:",inline"`
+	BufferConfig        buffer.Config   `json:"buffer"                yaml:"buffer"`
+	FlusherConfig       flusher.Config  `json:"flusher"               yaml:"flusher"`
+	Address             string          `json:"address"               yaml:"address"`
+	Protocol            string          `json:"protocol,omitempty"    yaml:"protocol,omitempty"`
+	Compression         string          `json:"compression,omitempty" yaml:"compression,omitempty"`
+	ChunkSize           helper.ByteSize `json:"chunk_size,omitempty"  yaml:"chunk_size,omitempty"`
+	Host                string          `json:"host,omitempty"        yaml:"host,omitempty"`
+	MessageField        entry.Field     `json:"message_field"         yaml:"message_field"`
+	Timeout             helper.Duration `json:"timeout,omitempty"     yaml:"timeout,omitempty"`
+}
+
+// Build will build a GELF output operator.
+func (c GELFOutputConfig) Build(bc operator.BuildContext) ([]operator.Operator, error) {
+	outputOperator, err := c.OutputConfig.Build(bc)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Address == "" {
+		return nil, errors.NewError("missing required parameter 'address'", "")
+	}
+
+	switch c.Protocol {
+	case protocolUDP:
+		if c.ChunkSize <= chunkHeaderSize {
+			return nil, fmt.Errorf("`chunk_size` must be greater than %d", chunkHeaderSize)
+		}
+	case protocolTCP:
+	default:
+		return nil, fmt.Errorf("invalid protocol '%s'", c.Protocol)
+	}
+
+	switch c.Compression {
+	case compressionGzip, compressionZlib, compressionNone, "":
+	default:
+		return nil, fmt.Errorf("invalid compression '%s'", c.Compression)
+	}
+
+	hostname := c.Host
+	if hostname == "" {
+		hostname, err = os.Hostname()
+		if err != nil {
+			return nil, errors.Wrap(err, "get hostname")
+		}
+	}
+
+	buffer, err := c.BufferConfig.Build(bc, c.ID())
+	if err != nil {
+		return nil, err
+	}
+
+	flusher := c.FlusherConfig.Build(bc.Logger.SugaredLogger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	gelfOutput := &GELFOutput{
+		OutputOperator: outputOperator,
+		buffer:         buffer,
+		flusher:        flusher,
+		address:        c.Address,
+		protocol:       c.Protocol,
+		compression:    c.Compression,
+		chunkSize:      int(c.ChunkSize),
+		hostname:       hostname,
+		messageField:   c.MessageField,
+		timeout:        c.Timeout.Raw(),
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+
+	return []operator.Operator{gelfOutput}, nil
+}
+
+// GELFOutput is an operator that sends entries to a GELF receiver, such as Graylog
+type GELFOutput struct {
+	helper.OutputOperator
+	buffer  buffer.Buffer
+	flusher *flusher.Flusher
+
+	address      string
+	protocol     string
+	compression  string
+	chunkSize    int
+	hostname     string
+	messageField entry.Field
+	timeout      time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Start signals to the GELFOutput to begin flushing
+func (g *GELFOutput) Start() error {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		g.feedFlusher(g.ctx)
+	}()
+
+	return nil
+}
+
+// Stop tells the GELFOutput to stop gracefully
+func (g *GELFOutput) Stop() error {
+	g.cancel()
+	g.wg.Wait()
+	g.flusher.Stop()
+	return g.buffer.Close()
+}
+
+// Process adds an entry to the output's buffer
+func (g *GELFOutput) Process(ctx context.Context, entry *entry.Entry) error {
+	return g.buffer.Add(ctx, entry)
+}
+
+func (g *GELFOutput) feedFlusher(ctx context.Context) {
+	for {
+		entries, clearer, err := g.buffer.ReadChunk(ctx)
+		if err != nil && err == context.Canceled {
+			return
+		} else if err != nil {
+			g.Errorw("Failed to read chunk", zap.Error(err))
+			continue
+		}
+
+		// Messages are created once, since creating them modifies the entries
+		messages := g.createMessages(entries)
+		g.flusher.Do(func(ctx context.Context) error {
+			if err := g.send(ctx, messages); err != nil {
+				return err
+			}
+
+			if err = clearer.MarkAllAsFlushed(); err != nil {
+				g.Errorw("Failed to mark entries as flushed", zap.Error(err))
+			}
+			return nil
+		})
+	}
+}
+
+// createMessages will encode entries as GELF messages, dropping any that fail
+func (g *GELFOutput) createMessages(entries []*entry.Entry) [][]byte {
+	messages := make([][]byte, 0, len(entries))
+	for _, e := range entries {
+		msg, err := g.createMessage(e)
+		if err != nil {
+			g.Errorw("Failed to create GELF message. Dropping entry", zap.Error(err))
+			continue
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+// send will send messages using the configured protocol
+func (g *GELFOutput) send(ctx context.Context, messages [][]byte) error {
+	dialer := net.Dialer{Timeout: g.timeout}
+	conn, err := dialer.DialContext(ctx, g.protocol, g.address)
+	if err != nil {
+		return errors.Wrap(err, "dial")
+	}
+	defer conn.Close()
+
+	if err := conn.SetWriteDeadline(time.Now().Add(g.timeout)); err != nil {
+		return err
+	}
+
+	if g.protocol == protocolTCP {
+		return g.sendTCP(conn, messages)
+	}
+	return g.sendUDP(conn, messages)
+}
+
+// sendTCP will write null byte delimited messages to a stream
+func (g *GELFOutput) sendTCP(w io.Writer, messages [][]byte) error {
+	var b bytes.Buffer
+	for _, msg := range messages {
+		b.Write(msg)
+		b.WriteByte(0)
+	}
+
+	if _, err := w.Write(b.Bytes()); err != nil {
+		return errors.Wrap(err, "write messages")
+	}
+	return nil
+}
+
+// sendUDP will write compressed and chunked messages as datagrams.
+// Compression is only supported by GELF receivers over udp.
+func (g *GELFOutput) sendUDP(w io.Writer, messages [][]byte) error {
+	for _, msg := range messages {
+		compressed, err := g.compress(msg)
+		if err != nil {
+			g.Errorw("Failed to compress GELF message. Dropping entry", zap.Error(err))
+			continue
+		}
+
+		datagrams, err := chunk(compressed, g.chunkSize)
+		if err != nil {
+			g.Errorw("Failed to chunk GELF message. Dropping entry", zap.Error(err))
+			continue
+		}
+
+		for _, datagram := range datagrams {
+			if _, err := w.Write(datagram); err != nil {
+				return errors.Wrap(err, "write datagram")
+			}
+		}
+	}
+	return nil
+}
+
+// compress will compress a message using the configured compression
+func (g *GELFOutput) compress(msg []byte) ([]byte, error) {
+	var b bytes.Buffer
+	var w io.WriteCloser
+
+	switch g.compression {
+	case compressionGzip:
+		w = gzip.NewWriter(&b)
+	case compressionZlib:
+		w = zlib.NewWriter(&b)
+	default:
+		return msg, nil
+	}
+
+	if _, err := w.Write(msg); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,251 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gelf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEntry() *entry.Entry {
+	e := entry.New()
+	e.Timestamp = time.Unix(1385053862, 307000000)
+	e.Severity = entry.Error
+	e.Resource = map[string]string{
+		"host.name": "example.org",
+	}
+	e.Labels = map[string]string{
+		"app": "web",
+	}
+	e.Record = map[string]interface{}{
+		"message":      "A short message",
+		"full_message": "Backtrace here",
+		"user_id":      9001,
+		"nested":       map[string]interface{}{"key": "value"},
+		"invalid key":  "value",
+	}
+	return e
+}
+
+func newTestGELFOutput(t *testing.T, configure func(cfg *GELFOutputConfig)) *GELFOutput {
+	cfg := NewGELFOutputConfig("test_output")
+	cfg.Address = "127.0.0.1:12201"
+	cfg.Host = "stanza-host"
+	if configure != nil {
+		configure(cfg)
+	}
+
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	return ops[0].(*GELFOutput)
+}
+
+func TestGELFOutputCreateMessage(t *testing.T) {
+	g := newTestGELFOutput(t, nil)
+
+	raw, err := g.createMessage(newTestEntry())
+	require.NoError(t, err)
+
+	var msg map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &msg))
+
+	expected := map[string]interface{}{
+		"version":       "1.1",
+		"host":          "example.org",
+		"short_message": "A short message",
+		"full_message":  "Backtrace here",
+		"timestamp":     1385053862.307,
+		"level":         float64(3),
+		"_app":          "web",
+		"_user_id":      float64(9001),
+		"_nested":       `{"key":"value"}`,
+		"_invalid_key":  "value",
+	}
+	require.Equal(t, expected, msg)
+}
+
+func TestGELFOutputCreateMessageStringRecord(t *testing.T) {
+	g := newTestGELFOutput(t, nil)
+
+	e := entry.New()
+	e.Record = "test message"
+
+	raw, err := g.createMessage(e)
+	require.NoError(t, err)
+
+	var msg map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &msg))
+	require.Equal(t, "test message", msg["short_message"])
+	require.Equal(t, "stanza-host", msg["host"])
+	require.Equal(t, float64(6), msg["level"])
+}
+
+func TestToLevel(t *testing.T) {
+	cases := []struct {
+		severity entry.Severity
+		level    int
+	}{
+		{entry.Default, 6},
+		{entry.Trace, 7},
+		{entry.Debug, 7},
+		{entry.Info, 6},
+		{entry.Info2, 6},
+		{entry.Notice, 5},
+		{entry.Warning, 4},
+		{entry.Error, 3},
+		{entry.Critical, 2},
+		{entry.Alert, 1},
+		{entry.Emergency, 0},
+		{entry.Catastrophe, 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.severity.String(), func(t *testing.T) {
+			require.Equal(t, tc.level, toLevel(tc.severity))
+		})
+	}
+}
+
+func TestChunk(t *testing.T) {
+	t.Run("Small", func(t *testing.T) {
+		chunks, err := chunk([]byte("message"), 100)
+		require.NoError(t, err)
+		require.Equal(t, [][]byte{[]byte("message")}, chunks)
+	})
+
+	t.Run("Chunked", func(t *testing.T) {
+		msg := []byte(strings.Repeat("a", 25))
+		chunks, err := chunk(msg, 22)
+		require.NoError(t, err)
+		require.Len(t, chunks, 3)
+
+		var joined []byte
+		for i, c := range chunks {
+			require.True(t, len(c) <= 22)
+			require.Equal(t, []byte{0x1e, 0x0f}, c[:2])
+			require.Equal(t, chunks[0][2:10], c[2:10])
+			require.Equal(t, byte(i), c[10])
+			require.Equal(t, byte(3), c[11])
+			joined = append(joined, c[12:]...)
+		}
+		require.Equal(t, msg, joined)
+	})
+
+	t.Run("TooLarge", func(t *testing.T) {
+		_, err := chunk(make([]byte, 129*10), 22)
+		require.Error(t, err)
+	})
+}
+
+func TestGELFOutputUDP(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	g := newTestGELFOutput(t, func(cfg *GELFOutputConfig) {
+		cfg.Address = conn.LocalAddr().String()
+		cfg.ChunkSize = 64
+	})
+	require.NoError(t, g.Start())
+	defer g.Stop()
+
+	require.NoError(t, g.Process(context.Background(), newTestEntry()))
+
+	// Reassemble the chunks in the order they are received
+	var payload []byte
+	buf := make([]byte, 1024)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		require.NoError(t, err)
+		require.True(t, n <= 64)
+		require.Equal(t, []byte{0x1e, 0x0f}, buf[:2])
+		payload = append(payload, buf[12:n]...)
+		if buf[10] == buf[11]-1 {
+			break
+		}
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(payload))
+	require.NoError(t, err)
+	decompressed, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+
+	var msg map[string]interface{}
+	require.NoError(t, json.Unmarshal(decompressed, &msg))
+	require.Equal(t, "A short message", msg["short_message"])
+	require.Equal(t, "example.org", msg["host"])
+}
+
+func TestGELFOutputTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	g := newTestGELFOutput(t, func(cfg *GELFOutputConfig) {
+		cfg.Address = ln.Addr().String()
+		cfg.Protocol = "tcp"
+	})
+	require.NoError(t, g.Start())
+	defer g.Stop()
+
+	require.NoError(t, g.Process(context.Background(), newTestEntry()))
+
+	conn, err := ln.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	raw, err := bufio.NewReader(conn).ReadBytes(0)
+	require.NoError(t, err)
+
+	var msg map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw[:len(raw)-1], &msg))
+	require.Equal(t, "A short message", msg["short_message"])
+}
+
+func TestGELFOutputBuild(t *testing.T) {
+	cases := []struct {
+		name      string
+		configure func(cfg *GELFOutputConfig)
+	}{
+		{"MissingAddress", func(cfg *GELFOutputConfig) { cfg.Address = "" }},
+		{"InvalidProtocol", func(cfg *GELFOutputConfig) { cfg.Protocol = "http" }},
+		{"InvalidCompression", func(cfg *GELFOutputConfig) { cfg.Compression = "lz4" }},
+		{"SmallChunkSize", func(cfg *GELFOutputConfig) { cfg.ChunkSize = 12 }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := NewGELFOutputConfig("test_output")
+			cfg.Address = "127.0.0.1:12201"
+			tc.configure(cfg)
+			_, err := cfg.Build(testutil.NewBuildContext(t))
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gelf
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+)
+
+const (
+	gelfVersion     = "1.1"
+	hostResourceKey = "host.name"
+)
+
+// invalidFieldChars matches characters that are not allowed in GELF field names
+var invalidFieldChars = regexp.MustCompile(`[^\w\.\-]`)
+
+// toLevel converts an entry severity to a syslog level
+func toLevel(severity entry.Severity) int {
+	switch {
+	case severity >= entry.Emergency:
+		return 0
+	case severity >= entry.Alert:
+		return 1
+	case severity >= entry.Critical:
+		return 2
+	case severity >= entry.Error:
+		return 3
+	case severity >= entry.Warning:
+		return 4
+	case severity >= entry.Notice:
+		return 5
+	case severity >= entry.Info:
+		return 6
+	case severity > entry.Default:
+		return 7
+	default:
+		// GELF has no unknown level, so default to informational
+		return 6
+	}
+}
+
+// createMessage will map an entry to a GELF message
+func (g *GELFOutput) createMessage(e *entry.Entry) ([]byte, error) {
+	msg := map[string]interface{}{
+		"version":   gelfVersion,
+		"host":      g.hostname,
+		"timestamp": float64(e.Timestamp.UnixNano()/int64(1e6)) / 1e3,
+		"level":     toLevel(e.Severity),
+	}
+
+	if host, ok := e.Resource[hostResourceKey]; ok && host != "" {
+		msg["host"] = host
+	}
+
+	for k, v := range e.Labels {
+		msg[fieldName(k)] = v
+	}
+
+	if value, ok := e.Delete(g.messageField); ok {
+		msg["short_message"] = stringValue(value)
+	}
+
+	switch record := e.Record.(type) {
+	case map[string]interface{}:
+		for k, v := range record {
+			if k == "full_message" {
+				msg["full_message"] = stringValue(v)
+				continue
+			}
+			msg[fieldName(k)] = fieldValue(v)
+		}
+	case nil:
+	default:
+		if _, ok := msg["short_message"]; !ok {
+			msg["short_message"] = stringValue(record)
+		}
+	}
+
+	// short_message is required, so fall back to the encoded record
+	if _, ok := msg["short_message"]; !ok {
+		raw, err := json.Marshal(e.Record)
+		if err != nil {
+			return nil, fmt.Errorf("encode record: %s", err)
+		}
+		msg["short_message"] = string(raw)
+	}
+
+	return json.Marshal(msg)
+}
+
+// fieldName converts a key to a GELF additional field name
+func fieldName(key string) string {
+	name := "_" + invalidFieldChars.ReplaceAllString(key, "_")
+	if name == "_id" {
+		// _id is reserved by the GELF specification
+		return "__id"
+	}
+	return name
+}
+
+// fieldValue converts a value to a string or number, as required for GELF additional fields
+func fieldValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return v
+	default:
+		return stringValue(v)
+	}
+}
+
+// stringValue converts a value to a string, encoding complex values as json
+func stringValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case nil:
+		return ""
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(raw)
+}
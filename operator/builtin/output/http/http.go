@@ -0,0 +1,403 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/errors"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/buffer"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/flusher"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"go.uber.org/zap"
+)
+
+func init() {
+	operator.Register("http_output", func() operator.Builder { return NewHTTPOutputConfig("") })
+}
+
+const (
+	compressionNone = "none"
+	compressionGzip = "gzip"
+)
+
+// NewHTTPOutputConfig creates a new http output config with default values
+func NewHTTPOutputConfig(operatorID string) *HTTPOutputConfig {
+	return &HTTPOutputConfig{
+		OutputConfig:     helper.NewOutputConfig(operatorID, "http_output"),
+		BufferConfig:     buffer.NewConfig(),
+		FlusherConfig:    flusher.NewConfig(),
+		Method:           http.MethodPost,
+		Encoding:         helper.NewEncodingConfig("json"),
+		Compression:      compressionNone,
+		Timeout:          helper.NewDuration(30 * time.Second),
+		RetryStatusCodes: []int{408, 429, 500, 502, 503, 504},
+	}
+}
+
+// HTTPOutputConfig is the configuration of an http output operator
+type HTTPOutputConfig struct {
+	helper.OutputConfig `yaml:",inline"`
+	BufferConfig        buffer.Config  `json:"buffer"  yaml:"buffer"`
+	FlusherConfig       flusher.Config `json:"flusher" yaml:"flusher"`
+
+	URL              string                             `json:"url"                          yaml:"url"`
+	Method           string                             `json:"method,omitempty"             yaml:"method,omitempty"`
+	Headers          map[string]helper.ExprStringConfig `json:"headers,omitempty"            yaml:"headers,omitempty"`
+	Encoding         helper.EncodingConfig              `json:"encoding,omitempty"           yaml:"encoding,omitempty"`
+	ContentType      string                             `json:"content_type,omitempty"       yaml:"content_type,omitempty"`
+	Compression      string                             `json:"compression,omitempty"        yaml:"compression,omitempty"`
+	Username         string                             `json:"username,omitempty"           yaml:"username,omitempty"`
+	Password         string                             `json:"password,omitempty"           yaml:"password,omitempty"`
+	BearerToken      string                             `json:"bearer_token,omitempty"       yaml:"bearer_token,omitempty"`
+	TLS              *helper.TLSClientConfig            `json:"tls,omitempty"                yaml:"tls,omitempty"`
+	Timeout          helper.Duration                    `json:"timeout,omitempty"            yaml:"timeout,omitempty"`
+	RetryStatusCodes []int                              `json:"retry_status_codes,omitempty" yaml:"retry_status_codes,omitempty,flow"`
+}
+
+// Build will build an http output operator
+func (c HTTPOutputConfig) Build(bc operator.BuildContext) ([]operator.Operator, error) {
+	outputOperator, err := c.OutputConfig.Build(bc)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.URL == "" {
+		return nil, errors.NewError("missing required parameter 'url'", "")
+	}
+
+	if c.Method == "" {
+		return nil, errors.NewError("missing required parameter 'method'", "")
+	}
+
+	encoder, err := c.Encoding.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := c.ContentType
+	if contentType == "" {
+		contentType = encoder.ContentType()
+	}
+
+	switch c.Compression {
+	case compressionNone, compressionGzip:
+	default:
+		return nil, fmt.Errorf("invalid compression '%s'", c.Compression)
+	}
+
+	if c.BearerToken != "" && (c.Username != "" || c.Password != "") {
+		return nil, fmt.Errorf("only one of 'bearer_token' and 'username' can be set")
+	}
+
+	headers := make(map[string]*helper.ExprString, len(c.Headers))
+	for k, v := range c.Headers {
+		header, err := v.Build()
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("build header '%s'", k))
+		}
+		headers[k] = header
+	}
+
+	retryStatusCodes := make(map[int]bool, len(c.RetryStatusCodes))
+	for _, code := range c.RetryStatusCodes {
+		retryStatusCodes[code] = true
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if c.TLS != nil {
+		tlsConfig, err := c.TLS.Build()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	buffer, err := c.BufferConfig.Build(bc, c.ID())
+	if err != nil {
+		return nil, err
+	}
+
+	flusher := c.FlusherConfig.Build(bc.Logger.SugaredLogger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	httpOutput := &HTTPOutput{
+		OutputOperator: outputOperator,
+		buffer:         buffer,
+		flusher:        flusher,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   c.Timeout.Raw(),
+		},
+		url:              c.URL,
+		method:           c.Method,
+		headers:          headers,
+		encoder:          encoder,
+		contentType:      contentType,
+		compression:      c.Compression,
+		username:         c.Username,
+		password:         c.Password,
+		bearerToken:      c.BearerToken,
+		retryStatusCodes: retryStatusCodes,
+		ctx:              ctx,
+		cancel:           cancel,
+	}
+
+	return []operator.Operator{httpOutput}, nil
+}
+
+// HTTPOutput is an operator that sends entries to an http endpoint
+type HTTPOutput struct {
+	helper.OutputOperator
+	buffer  buffer.Buffer
+	flusher *flusher.Flusher
+	client  *http.Client
+
+	url              string
+	method           string
+	headers          map[string]*helper.ExprString
+	encoder          helper.Encoder
+	contentType      string
+	compression      string
+	username         string
+	password         string
+	bearerToken      string
+	retryStatusCodes map[int]bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Start signals to the HTTPOutput to begin flushing
+func (h *HTTPOutput) Start() error {
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		h.feedFlusher(h.ctx)
+	}()
+
+	return nil
+}
+
+// Stop tells the HTTPOutput to stop gracefully
+func (h *HTTPOutput) Stop() error {
+	h.cancel()
+	h.wg.Wait()
+	h.flusher.Stop()
+	h.client.CloseIdleConnections()
+	return h.buffer.Close()
+}
+
+// Process adds an entry to the output's buffer
+func (h *HTTPOutput) Process(ctx context.Context, entry *entry.Entry) error {
+	return h.buffer.Add(ctx, entry)
+}
+
+// batch is a group of entries that are sent in a single request
+type batch struct {
+	headers map[string]string
+	body    []byte
+}
+
+func (h *HTTPOutput) feedFlusher(ctx context.Context) {
+	for {
+		entries, clearer, err := h.buffer.ReadChunk(ctx)
+		if err != nil && err == context.Canceled {
+			return
+		} else if err != nil {
+			h.Errorw("Failed to read chunk", zap.Error(err))
+			continue
+		}
+
+		batches := h.createBatches(entries)
+
+		// Batches that have been sent are skipped when the flush is retried
+		next := 0
+		h.flusher.Do(func(ctx context.Context) error {
+			for ; next < len(batches); next++ {
+				retry, err := h.send(ctx, batches[next])
+				if err != nil && retry {
+					return err
+				} else if err != nil {
+					h.Errorw("Dropping batch after a non-retryable error", zap.Error(err))
+				}
+			}
+
+			if err := clearer.MarkAllAsFlushed(); err != nil {
+				h.Errorw("Failed to mark entries as flushed", zap.Error(err))
+			}
+			return nil
+		})
+	}
+}
+
+// createBatches groups entries by the value of their headers and encodes the body of each group
+func (h *HTTPOutput) createBatches(entries []*entry.Entry) []*batch {
+	keys := make([]string, 0)
+	headersByKey := make(map[string]map[string]string)
+	entriesByKey := make(map[string][]*entry.Entry)
+
+	for _, e := range entries {
+		headers, err := h.renderHeaders(e)
+		if err != nil {
+			h.Errorw("Failed to render headers", zap.Error(err))
+			continue
+		}
+
+		key := headersKey(headers)
+		if _, ok := entriesByKey[key]; !ok {
+			keys = append(keys, key)
+			headersByKey[key] = headers
+		}
+		entriesByKey[key] = append(entriesByKey[key], e)
+	}
+
+	batches := make([]*batch, 0, len(keys))
+	for _, key := range keys {
+		body, err := h.encode(entriesByKey[key])
+		if err != nil {
+			h.Errorw("Failed to encode entries", zap.Error(err))
+			continue
+		}
+
+		if h.compression == compressionGzip {
+			body, err = gzipBytes(body)
+			if err != nil {
+				h.Errorw("Failed to compress entries", zap.Error(err))
+				continue
+			}
+		}
+
+		batches = append(batches, &batch{
+			headers: headersByKey[key],
+			body:    body,
+		})
+	}
+	return batches
+}
+
+func (h *HTTPOutput) renderHeaders(e *entry.Entry) (map[string]string, error) {
+	if len(h.headers) == 0 {
+		return nil, nil
+	}
+
+	env := helper.GetExprEnv(e)
+	defer helper.PutExprEnv(env)
+
+	headers := make(map[string]string, len(h.headers))
+	for k, v := range h.headers {
+		value, err := v.Render(env)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("render header '%s'", k))
+		}
+		headers[k] = value
+	}
+	return headers, nil
+}
+
+// headersKey returns a string that is the same for equal sets of headers
+func headersKey(headers map[string]string) string {
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(strconv.Quote(k))
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(headers[k]))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// encode will create a request body from entries
+func (h *HTTPOutput) encode(entries []*entry.Entry) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := h.encoder.EncodeBatch(&buf, entries); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gzipBytes(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// send will send a batch, and report whether the batch should be retried if it fails
+func (h *HTTPOutput) send(ctx context.Context, b *batch) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, h.method, h.url, bytes.NewReader(b.body))
+	if err != nil {
+		return false, errors.Wrap(err, "create request")
+	}
+
+	req.Header.Set("Content-Type", h.contentType)
+	if h.compression == compressionGzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for k, v := range b.headers {
+		req.Header.Set(k, v)
+	}
+
+	switch {
+	case h.bearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+h.bearerToken)
+	case h.username != "":
+		req.SetBasicAuth(h.username, h.password)
+	}
+
+	res, err := h.client.Do(req)
+	if err != nil {
+		return true, errors.Wrap(err, "send request")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		_, _ = io.Copy(ioutil.Discard, res.Body)
+		return false, nil
+	}
+
+	body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+	err = errors.NewError("non-success status code", "",
+		"status", strconv.Itoa(res.StatusCode),
+		"body", string(body),
+	)
+	return h.retryStatusCodes[res.StatusCode], err
+}
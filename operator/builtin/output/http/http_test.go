@@ -0,0 +1,315 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/buffer"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"github.com/opentelemetry/opentelemetry-log-collection/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type request struct {
+	method string
+	header http.Header
+	body   []byte
+}
+
+// newTestServer returns a server that responds with each status code in turn,
+// and then with 200 for any further requests
+func newTestServer(t *testing.T, tls bool, statusCodes ...int) (*httptest.Server, chan request) {
+	received := make(chan request, 10)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gr, err := gzip.NewReader(bytes.NewReader(body))
+			require.NoError(t, err)
+			body, err = ioutil.ReadAll(gr)
+			require.NoError(t, err)
+		}
+		received <- request{r.Method, r.Header, body}
+
+		status := http.StatusOK
+		if len(statusCodes) > 0 {
+			status, statusCodes = statusCodes[0], statusCodes[1:]
+		}
+		w.WriteHeader(status)
+	})
+
+	var ts *httptest.Server
+	if tls {
+		ts = httptest.NewTLSServer(handler)
+	} else {
+		ts = httptest.NewServer(handler)
+	}
+	t.Cleanup(ts.Close)
+	return ts, received
+}
+
+func newTestConfig(url string) *HTTPOutputConfig {
+	cfg := NewHTTPOutputConfig("test")
+	cfg.URL = url
+	cfg.BufferConfig.Builder.(*buffer.MemoryBufferConfig).MaxChunkDelay = helper.NewDuration(50 * time.Millisecond)
+	return cfg
+}
+
+func startOutput(t *testing.T, cfg *HTTPOutputConfig, records ...interface{}) {
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	op := ops[0]
+	require.NoError(t, op.Start())
+	t.Cleanup(func() { require.NoError(t, op.Stop()) })
+
+	for _, record := range records {
+		e := entry.New()
+		e.Timestamp = time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC)
+		e.Record = record
+		require.NoError(t, op.Process(context.Background(), e))
+	}
+}
+
+func expectRequest(t *testing.T, received chan request) request {
+	select {
+	case req := <-received:
+		return req
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "Timed out waiting for request")
+	}
+	return request{}
+}
+
+func TestHTTPOutputEncoding(t *testing.T) {
+	cases := []struct {
+		name        string
+		configure   func(cfg *HTTPOutputConfig)
+		contentType string
+		expected    string
+	}{
+		{
+			"JSON",
+			func(cfg *HTTPOutputConfig) {},
+			"application/json",
+			`[{"timestamp":"2021-02-03T04:05:06Z","severity":0,"record":"a"},{"timestamp":"2021-02-03T04:05:06Z","severity":0,"record":"<b>"}]` + "\n",
+		},
+		{
+			"NDJSON",
+			func(cfg *HTTPOutputConfig) { cfg.Encoding = helper.NewEncodingConfig("ndjson") },
+			"application/x-ndjson",
+			`{"timestamp":"2021-02-03T04:05:06Z","severity":0,"record":"a"}` + "\n" +
+				`{"timestamp":"2021-02-03T04:05:06Z","severity":0,"record":"<b>"}` + "\n",
+		},
+		{
+			"Template",
+			func(cfg *HTTPOutputConfig) {
+				cfg.Encoding = helper.NewEncodingConfig("template")
+				cfg.Encoding.Template = `{{ .Record }};`
+			},
+			"text/plain",
+			"a;<b>;",
+		},
+		{
+			"ContentTypeOverride",
+			func(cfg *HTTPOutputConfig) {
+				cfg.Encoding = helper.NewEncodingConfig("template")
+				cfg.Encoding.Template = `{"text": "{{ .Record }}"}`
+				cfg.ContentType = "application/json"
+			},
+			"application/json",
+			`{"text": "a"}{"text": "<b>"}`,
+		},
+		{
+			"Gzip",
+			func(cfg *HTTPOutputConfig) {
+				cfg.Encoding = helper.NewEncodingConfig("ndjson")
+				cfg.Compression = compressionGzip
+			},
+			"application/x-ndjson",
+			`{"timestamp":"2021-02-03T04:05:06Z","severity":0,"record":"a"}` + "\n" +
+				`{"timestamp":"2021-02-03T04:05:06Z","severity":0,"record":"<b>"}` + "\n",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ts, received := newTestServer(t, false)
+			cfg := newTestConfig(ts.URL)
+			tc.configure(cfg)
+			startOutput(t, cfg, "a", "<b>")
+
+			req := expectRequest(t, received)
+			require.Equal(t, http.MethodPost, req.method)
+			require.Equal(t, tc.contentType, req.header.Get("Content-Type"))
+			require.Equal(t, tc.expected, string(req.body))
+		})
+	}
+}
+
+func TestHTTPOutputHeaders(t *testing.T) {
+	ts, received := newTestServer(t, false)
+	cfg := newTestConfig(ts.URL)
+	cfg.Method = http.MethodPut
+	cfg.Encoding = helper.NewEncodingConfig("template")
+	cfg.Encoding.Template = `{{ .Record.message }};`
+	cfg.Headers = map[string]helper.ExprStringConfig{
+		"X-Static":  "static",
+		"X-Service": "EXPR($record.service)",
+	}
+	startOutput(t, cfg,
+		map[string]interface{}{"service": "web", "message": "a"},
+		map[string]interface{}{"service": "db", "message": "b"},
+		map[string]interface{}{"service": "web", "message": "c"},
+	)
+
+	// Entries with different header values are sent in separate requests
+	req := expectRequest(t, received)
+	require.Equal(t, http.MethodPut, req.method)
+	require.Equal(t, "static", req.header.Get("X-Static"))
+	require.Equal(t, "web", req.header.Get("X-Service"))
+	require.Equal(t, "a;c;", string(req.body))
+
+	req = expectRequest(t, received)
+	require.Equal(t, "db", req.header.Get("X-Service"))
+	require.Equal(t, "b;", string(req.body))
+}
+
+func TestHTTPOutputAuth(t *testing.T) {
+	t.Run("Basic", func(t *testing.T) {
+		ts, received := newTestServer(t, false)
+		cfg := newTestConfig(ts.URL)
+		cfg.Username = "user"
+		cfg.Password = "pass"
+		startOutput(t, cfg, "a")
+
+		req := expectRequest(t, received)
+		require.Equal(t, "Basic dXNlcjpwYXNz", req.header.Get("Authorization"))
+	})
+
+	t.Run("Bearer", func(t *testing.T) {
+		ts, received := newTestServer(t, false)
+		cfg := newTestConfig(ts.URL)
+		cfg.BearerToken = "token"
+		startOutput(t, cfg, "a")
+
+		req := expectRequest(t, received)
+		require.Equal(t, "Bearer token", req.header.Get("Authorization"))
+	})
+}
+
+func TestHTTPOutputTLS(t *testing.T) {
+	ts, received := newTestServer(t, true)
+
+	caFile, err := ioutil.TempFile("", "ca")
+	require.NoError(t, err)
+	defer os.Remove(caFile.Name())
+	require.NoError(t, pem.Encode(caFile, &pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}))
+	require.NoError(t, caFile.Close())
+
+	cfg := newTestConfig(ts.URL)
+	cfg.TLS = &helper.TLSClientConfig{CAFile: caFile.Name()}
+	startOutput(t, cfg, "a")
+
+	req := expectRequest(t, received)
+	require.Contains(t, string(req.body), `"record":"a"`)
+}
+
+func TestHTTPOutputStatusCodes(t *testing.T) {
+	t.Run("Retry", func(t *testing.T) {
+		ts, received := newTestServer(t, false, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+		cfg := newTestConfig(ts.URL)
+		startOutput(t, cfg, "a")
+
+		first := expectRequest(t, received)
+		require.Equal(t, first.body, expectRequest(t, received).body)
+		require.Equal(t, first.body, expectRequest(t, received).body)
+
+		select {
+		case <-received:
+			require.FailNow(t, "Unexpected request after success")
+		case <-time.After(200 * time.Millisecond):
+		}
+	})
+
+	t.Run("Drop", func(t *testing.T) {
+		ts, received := newTestServer(t, false, http.StatusBadRequest)
+		cfg := newTestConfig(ts.URL)
+		startOutput(t, cfg, "a")
+
+		expectRequest(t, received)
+		select {
+		case <-received:
+			require.FailNow(t, "Dropped batch was retried")
+		case <-time.After(200 * time.Millisecond):
+		}
+	})
+
+	t.Run("CustomRetryStatusCodes", func(t *testing.T) {
+		ts, received := newTestServer(t, false, http.StatusConflict)
+		cfg := newTestConfig(ts.URL)
+		cfg.RetryStatusCodes = []int{http.StatusConflict}
+		startOutput(t, cfg, "a")
+
+		first := expectRequest(t, received)
+		require.Equal(t, first.body, expectRequest(t, received).body)
+	})
+}
+
+func TestHTTPOutputBuild(t *testing.T) {
+	cases := []struct {
+		name      string
+		configure func(cfg *HTTPOutputConfig)
+	}{
+		{"MissingURL", func(cfg *HTTPOutputConfig) { cfg.URL = "" }},
+		{"MissingMethod", func(cfg *HTTPOutputConfig) { cfg.Method = "" }},
+		{"InvalidEncoding", func(cfg *HTTPOutputConfig) { cfg.Encoding = helper.NewEncodingConfig("xml") }},
+		{"MissingTemplate", func(cfg *HTTPOutputConfig) { cfg.Encoding = helper.NewEncodingConfig("template") }},
+		{"InvalidTemplate", func(cfg *HTTPOutputConfig) {
+			cfg.Encoding = helper.NewEncodingConfig("template")
+			cfg.Encoding.Template = "{{ .Record"
+		}},
+		{"InvalidCompression", func(cfg *HTTPOutputConfig) { cfg.Compression = "zstd" }},
+		{"BearerAndBasicAuth", func(cfg *HTTPOutputConfig) {
+			cfg.BearerToken = "token"
+			cfg.Username = "user"
+		}},
+		{"InvalidHeader", func(cfg *HTTPOutputConfig) {
+			cfg.Headers = map[string]helper.ExprStringConfig{"X-Invalid": "EXPR($record.)"}
+		}},
+		{"InvalidTLS", func(cfg *HTTPOutputConfig) {
+			cfg.TLS = &helper.TLSClientConfig{CertFile: "cert.pem"}
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := newTestConfig("http://localhost")
+			tc.configure(cfg)
+			_, err := cfg.Build(testutil.NewBuildContext(t))
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,13 @@
+module github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/loki
+
+go 1.14
+
+require (
+	github.com/golang/snappy v0.0.2
+	github.com/opentelemetry/opentelemetry-log-collection v0.13.12
+	github.com/stretchr/testify v1.6.1
+	go.uber.org/zap v1.15.0
+	google.golang.org/protobuf v1.25.0
+)
+
+replace github.com/opentelemetry/opentelemetry-log-collection => ../../../../
@@ -0,0 +1,301 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loki
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/errors"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/buffer"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/flusher"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"go.uber.org/zap"
+)
+
+func init() {
+	operator.Register("loki_output", func() operator.Builder { return NewLokiOutputConfig("") })
+}
+
+// streamIdleTimeout is how long the last timestamp of a stream is remembered
+// after the stream stops receiving entries
+const streamIdleTimeout = time.Hour
+
+// NewLokiOutputConfig creates a new loki output config with default values
+func NewLokiOutputConfig(operatorID string) *LokiOutputConfig {
+	return &LokiOutputConfig{
+		OutputConfig:  helper.NewOutputConfig(operatorID, "loki_output"),
+		BufferConfig:  buffer.NewConfig(),
+		FlusherConfig: flusher.NewConfig(),
+		PushFormat:    pushFormatProtobuf,
+		Timeout:       helper.NewDuration(30 * time.Second),
+	}
+}
+
+// LokiOutputConfig is the configuration of a loki output operator
+type LokiOutputConfig struct {
+	helper.OutputConfig `yaml:",inline"`
+	BufferConfig        buffer.Config  `json:"buffer"  yaml:"buffer"`
+	FlusherConfig       flusher.Config `json:"flusher" yaml:"flusher"`
+
+	URL          string                  `json:"url"                     yaml:"url"`
+	TenantID     string                  `json:"tenant_id,omitempty"     yaml:"tenant_id,omitempty"`
+	LabelKeys    []string                `json:"label_keys,omitempty"    yaml:"label_keys,omitempty"`
+	ResourceKeys []string                `json:"resource_keys,omitempty" yaml:"resource_keys,omitempty"`
+	StaticLabels map[string]string       `json:"static_labels,omitempty" yaml:"static_labels,omitempty"`
+	PushFormat   string                  `json:"push_format,omitempty"   yaml:"push_format,omitempty"`
+	Username     string                  `json:"username,omitempty"      yaml:"username,omitempty"`
+	Password     string                  `json:"password,omitempty"      yaml:"password,omitempty"`
+	TLS          *helper.TLSClientConfig `json:"tls,omitempty"           yaml:"tls,omitempty"`
+	Timeout      helper.Duration         `json:"timeout,omitempty"       yaml:"timeout,omitempty"`
+}
+
+// Build will build a loki output operator
+func (c LokiOutputConfig) Build(bc operator.BuildContext) ([]operator.Operator, error) {
+	outputOperator, err := c.OutputConfig.Build(bc)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.URL == "" {
+		return nil, errors.NewError("missing required parameter 'url'", "")
+	}
+
+	switch c.PushFormat {
+	case pushFormatProtobuf, pushFormatJSON:
+	default:
+		return nil, fmt.Errorf("invalid push_format '%s'", c.PushFormat)
+	}
+
+	if c.Password != "" && c.Username == "" {
+		return nil, fmt.Errorf("'password' requires 'username'")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if c.TLS != nil {
+		tlsConfig, err := c.TLS.Build()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	buffer, err := c.BufferConfig.Build(bc, c.ID())
+	if err != nil {
+		return nil, err
+	}
+
+	// Loki rejects entries that are older than the last entry of their stream, so
+	// chunks are sent one at a time and a retried chunk holds back the ones after it
+	c.FlusherConfig.MaxConcurrent = 1
+	flusher := c.FlusherConfig.Build(bc.Logger.SugaredLogger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	lokiOutput := &LokiOutput{
+		OutputOperator: outputOperator,
+		buffer:         buffer,
+		flusher:        flusher,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   c.Timeout.Raw(),
+		},
+		url:            c.URL,
+		tenantID:       c.TenantID,
+		labelKeys:      c.LabelKeys,
+		resourceKeys:   c.ResourceKeys,
+		staticLabels:   c.StaticLabels,
+		pushFormat:     c.PushFormat,
+		username:       c.Username,
+		password:       c.Password,
+		lastTimestamps: make(map[string]streamTimestamp),
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+
+	return []operator.Operator{lokiOutput}, nil
+}
+
+// LokiOutput is an operator that sends entries to loki
+type LokiOutput struct {
+	helper.OutputOperator
+	buffer  buffer.Buffer
+	flusher *flusher.Flusher
+	client  *http.Client
+
+	url          string
+	tenantID     string
+	labelKeys    []string
+	resourceKeys []string
+	staticLabels map[string]string
+	pushFormat   string
+	username     string
+	password     string
+
+	lastTimestamps map[string]streamTimestamp
+	orderMux       sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Start signals to the LokiOutput to begin flushing
+func (l *LokiOutput) Start() error {
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		l.feedFlusher(l.ctx)
+	}()
+
+	return nil
+}
+
+// Stop tells the LokiOutput to stop gracefully
+func (l *LokiOutput) Stop() error {
+	l.cancel()
+	l.wg.Wait()
+	l.flusher.Stop()
+	l.client.CloseIdleConnections()
+	return l.buffer.Close()
+}
+
+// Process adds an entry to the output's buffer
+func (l *LokiOutput) Process(ctx context.Context, entry *entry.Entry) error {
+	return l.buffer.Add(ctx, entry)
+}
+
+func (l *LokiOutput) feedFlusher(ctx context.Context) {
+	for {
+		entries, clearer, err := l.buffer.ReadChunk(ctx)
+		if err != nil && err == context.Canceled {
+			return
+		} else if err != nil {
+			l.Errorw("Failed to read chunk", zap.Error(err))
+			continue
+		}
+
+		l.flusher.Do(func(ctx context.Context) error {
+			if err := l.flush(ctx, entries); err != nil {
+				return err
+			}
+
+			if err := clearer.MarkAllAsFlushed(); err != nil {
+				l.Errorw("Failed to mark entries as flushed", zap.Error(err))
+			}
+			return nil
+		})
+	}
+}
+
+// flush will send entries to loki. An error is only returned if the entries should
+// be retried, and entries that cannot be sent are dropped.
+func (l *LokiOutput) flush(ctx context.Context, entries []*entry.Entry) error {
+	// Streams are created on every attempt, so that their entries are
+	// ordered after the last entries that loki accepted
+	streams := l.createStreams(entries)
+	if len(streams) == 0 {
+		return nil
+	}
+
+	body, err := l.encode(streams)
+	if err != nil {
+		// Drop the entries, since retrying would fail the same way
+		l.Errorw("Failed to create request from payload", zap.Error(err))
+		return nil
+	}
+
+	retry, err := l.send(ctx, body)
+	switch {
+	case err != nil && retry:
+		return err
+	case err != nil:
+		l.Errorw("Dropping entries after a non-retryable error", zap.Error(err))
+	default:
+		l.updateTimestamps(streams)
+	}
+	return nil
+}
+
+// encode will encode streams as the body of a push request
+func (l *LokiOutput) encode(streams []*stream) ([]byte, error) {
+	if l.pushFormat == pushFormatJSON {
+		return encodeJSON(streams)
+	}
+	return encodeProtobuf(streams), nil
+}
+
+// send will push a request body to loki, and report whether the request should be retried if it fails
+func (l *LokiOutput) send(ctx context.Context, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.url, bytes.NewReader(body))
+	if err != nil {
+		return false, errors.Wrap(err, "create request")
+	}
+
+	if l.pushFormat == pushFormatJSON {
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		req.Header.Set("Content-Type", "application/x-protobuf")
+	}
+	if l.tenantID != "" {
+		req.Header.Set("X-Scope-OrgID", l.tenantID)
+	}
+	if l.username != "" {
+		req.SetBasicAuth(l.username, l.password)
+	}
+
+	res, err := l.client.Do(req)
+	if err != nil {
+		return true, errors.Wrap(err, "send request")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		_, _ = io.Copy(ioutil.Discard, res.Body)
+		return false, nil
+	}
+
+	resBody, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+	message := strings.TrimSpace(string(resBody))
+
+	// Loki accepts the valid entries of a request before rejecting the rest, so
+	// a request rejected for ordering is not retried
+	if res.StatusCode == http.StatusBadRequest && isOrderingError(message) {
+		return false, errors.NewError("loki rejected entries that are out of order", "",
+			"body", message,
+		)
+	}
+
+	err = errors.NewError("non-success status code", "",
+		"status", strconv.Itoa(res.StatusCode),
+		"body", message,
+	)
+	return res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500, err
+}
+
+// isOrderingError returns true if loki rejected entries for being older than their stream
+func isOrderingError(message string) bool {
+	return strings.Contains(message, "out of order") || strings.Contains(message, "too far behind")
+}
@@ -0,0 +1,304 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loki
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/buffer"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"github.com/opentelemetry/opentelemetry-log-collection/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type request struct {
+	header http.Header
+	body   []byte
+}
+
+type response struct {
+	status int
+	body   string
+}
+
+// newTestServer returns a server that sends each response in turn,
+// and then responds with 204 to any further requests
+func newTestServer(t *testing.T, responses ...response) (*httptest.Server, chan request) {
+	received := make(chan request, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		received <- request{r.Header, body}
+
+		res := response{status: http.StatusNoContent}
+		if len(responses) > 0 {
+			res, responses = responses[0], responses[1:]
+		}
+		w.WriteHeader(res.status)
+		_, _ = w.Write([]byte(res.body))
+	}))
+	t.Cleanup(ts.Close)
+	return ts, received
+}
+
+func newTestConfig(url string) *LokiOutputConfig {
+	cfg := NewLokiOutputConfig("test")
+	cfg.URL = url
+	cfg.BufferConfig.Builder.(*buffer.MemoryBufferConfig).MaxChunkDelay = helper.NewDuration(50 * time.Millisecond)
+	return cfg
+}
+
+func startOutput(t *testing.T, cfg *LokiOutputConfig, entries ...*entry.Entry) operator.Operator {
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	op := ops[0]
+	require.NoError(t, op.Start())
+	t.Cleanup(func() { require.NoError(t, op.Stop()) })
+
+	for _, e := range entries {
+		require.NoError(t, op.Process(context.Background(), e))
+	}
+	return op
+}
+
+func expectRequest(t *testing.T, received chan request) request {
+	select {
+	case req := <-received:
+		return req
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "Timed out waiting for request")
+	}
+	return request{}
+}
+
+func expectNoRequest(t *testing.T, received chan request) {
+	select {
+	case <-received:
+		require.FailNow(t, "Unexpected request")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func newEntry(record interface{}, ts time.Time, labels map[string]string) *entry.Entry {
+	e := entry.New()
+	e.Timestamp = ts
+	e.Record = record
+	e.Labels = labels
+	return e
+}
+
+// decodedStream is a stream decoded from a protobuf push request
+type decodedStream struct {
+	labels  string
+	entries [][2]string
+}
+
+func decodePushRequest(t *testing.T, body []byte) []decodedStream {
+	raw, err := snappy.Decode(nil, body)
+	require.NoError(t, err)
+
+	streams := make([]decodedStream, 0)
+	walk(t, raw, func(num protowire.Number, value []byte) {
+		require.Equal(t, protowire.Number(1), num)
+		s := decodedStream{}
+		walk(t, value, func(num protowire.Number, value []byte) {
+			switch num {
+			case 1:
+				s.labels = string(value)
+			case 2:
+				var seconds, nanos uint64
+				var line string
+				walk(t, value, func(num protowire.Number, value []byte) {
+					switch num {
+					case 1:
+						walk(t, value, func(num protowire.Number, value []byte) {
+							v, _ := protowire.ConsumeVarint(value)
+							if num == 1 {
+								seconds = v
+							} else {
+								nanos = v
+							}
+						})
+					case 2:
+						line = string(value)
+					}
+				})
+				ts := time.Unix(int64(seconds), int64(nanos)).UTC().Format(time.RFC3339Nano)
+				s.entries = append(s.entries, [2]string{ts, line})
+			}
+		})
+		streams = append(streams, s)
+	})
+	return streams
+}
+
+// walk calls fn with the number and value of each field of a protobuf message
+func walk(t *testing.T, b []byte, fn func(num protowire.Number, value []byte)) {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.True(t, n > 0)
+		b = b[n:]
+
+		m := protowire.ConsumeFieldValue(num, typ, b)
+		require.True(t, m > 0)
+		value := b[:m]
+		if typ == protowire.BytesType {
+			value, _ = protowire.ConsumeBytes(value)
+		}
+		fn(num, value)
+		b = b[m:]
+	}
+}
+
+func TestLokiOutputBuild(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(*LokiOutputConfig)
+	}{
+		{"MissingURL", func(c *LokiOutputConfig) { c.URL = "" }},
+		{"InvalidPushFormat", func(c *LokiOutputConfig) { c.PushFormat = "xml" }},
+		{"PasswordWithoutUsername", func(c *LokiOutputConfig) { c.Password = "pass" }},
+		{"InvalidTLS", func(c *LokiOutputConfig) { c.TLS = &helper.TLSClientConfig{CertFile: "cert"} }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := newTestConfig("http://localhost:3100/loki/api/v1/push")
+			tc.modify(cfg)
+			_, err := cfg.Build(testutil.NewBuildContext(t))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestLokiOutputProtobuf(t *testing.T) {
+	ts, received := newTestServer(t)
+	cfg := newTestConfig(ts.URL)
+	cfg.TenantID = "tenant"
+	cfg.Username = "user"
+	cfg.Password = "pass"
+	cfg.LabelKeys = []string{"app", "file.name"}
+	cfg.StaticLabels = map[string]string{"env": "prod"}
+
+	t0 := time.Date(2021, 2, 3, 4, 5, 6, 7, time.UTC)
+	startOutput(t, cfg,
+		newEntry("second", t0.Add(time.Second), map[string]string{"app": "web", "file.name": "a.log", "other": "x"}),
+		newEntry(map[string]interface{}{"key": "value"}, t0, map[string]string{"app": "db"}),
+		newEntry("first", t0, map[string]string{"app": "web", "file.name": "a.log"}),
+	)
+
+	req := expectRequest(t, received)
+	require.Equal(t, "application/x-protobuf", req.header.Get("Content-Type"))
+	require.Equal(t, "tenant", req.header.Get("X-Scope-OrgID"))
+	require.Equal(t, "Basic dXNlcjpwYXNz", req.header.Get("Authorization"))
+
+	expected := []decodedStream{
+		{
+			labels: `{app="web", env="prod", file_name="a.log"}`,
+			entries: [][2]string{
+				{"2021-02-03T04:05:06.000000007Z", "first"},
+				{"2021-02-03T04:05:07.000000007Z", "second"},
+			},
+		},
+		{
+			labels: `{app="db", env="prod"}`,
+			entries: [][2]string{
+				{"2021-02-03T04:05:06.000000007Z", `{"key":"value"}`},
+			},
+		},
+	}
+	require.Equal(t, expected, decodePushRequest(t, req.body))
+}
+
+func TestLokiOutputJSON(t *testing.T) {
+	ts, received := newTestServer(t)
+	cfg := newTestConfig(ts.URL)
+	cfg.PushFormat = pushFormatJSON
+	cfg.ResourceKeys = []string{"host.name"}
+
+	e := newEntry("message", time.Unix(1, 2), nil)
+	e.Resource = map[string]string{"host.name": "server"}
+	startOutput(t, cfg, e, newEntry("unlabeled", time.Unix(3, 0), nil))
+
+	req := expectRequest(t, received)
+	require.Equal(t, "application/json", req.header.Get("Content-Type"))
+	require.Empty(t, req.header.Get("X-Scope-OrgID"))
+
+	expected := `{"streams":[` +
+		`{"stream":{"host_name":"server"},"values":[["1000000002","message"]]},` +
+		`{"stream":{"exporter":"stanza"},"values":[["3000000000","unlabeled"]]}` +
+		`]}`
+	require.JSONEq(t, expected, string(req.body))
+}
+
+func TestLokiOutputRetry(t *testing.T) {
+	cases := []struct {
+		name     string
+		response response
+		retried  bool
+	}{
+		{"TooManyRequests", response{status: http.StatusTooManyRequests}, true},
+		{"ServerError", response{status: http.StatusServiceUnavailable}, true},
+		{"OutOfOrder", response{http.StatusBadRequest, "entry out of order for stream"}, false},
+		{"BadRequest", response{http.StatusBadRequest, "invalid labels"}, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ts, received := newTestServer(t, tc.response)
+			cfg := newTestConfig(ts.URL)
+			startOutput(t, cfg, newEntry("message", time.Unix(1, 0), nil))
+
+			first := expectRequest(t, received)
+			if tc.retried {
+				require.Equal(t, first.body, expectRequest(t, received).body)
+			} else {
+				expectNoRequest(t, received)
+			}
+		})
+	}
+}
+
+func TestLokiOutputRetryOrdering(t *testing.T) {
+	ts, received := newTestServer(t, response{status: http.StatusTooManyRequests})
+	cfg := newTestConfig(ts.URL)
+	cfg.FlusherConfig.MaxConcurrent = 16
+
+	t0 := time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC)
+	op := startOutput(t, cfg, newEntry("first", t0.Add(time.Second), nil))
+	first := expectRequest(t, received)
+
+	// The next chunk is held back until the retried chunk is accepted,
+	// and is then moved up to the timestamp of the retried chunk
+	require.NoError(t, op.Process(context.Background(), newEntry("second", t0, nil)))
+	require.Equal(t, first.body, expectRequest(t, received).body)
+
+	expected := []decodedStream{
+		{
+			labels:  `{exporter="stanza"}`,
+			entries: [][2]string{{"2021-02-03T04:05:07Z", "second"}},
+		},
+	}
+	require.Equal(t, expected, decodePushRequest(t, expectRequest(t, received).body))
+}
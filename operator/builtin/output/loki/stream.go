@@ -0,0 +1,181 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loki
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"go.uber.org/zap"
+)
+
+// fallbackLabelName and fallbackLabelValue label entries that have none of the
+// configured labels, because loki requires every stream to have a label
+const (
+	fallbackLabelName  = "exporter"
+	fallbackLabelValue = "stanza"
+)
+
+// stream is a group of entries with the same set of loki labels
+type stream struct {
+	key     string
+	labels  map[string]string
+	entries []streamEntry
+}
+
+// streamEntry is a single line of a stream
+type streamEntry struct {
+	timestamp time.Time
+	line      string
+}
+
+// streamLabels will return the loki labels of an entry
+func (l *LokiOutput) streamLabels(e *entry.Entry) map[string]string {
+	labels := make(map[string]string, len(l.labelKeys)+len(l.resourceKeys)+len(l.staticLabels))
+	for k, v := range l.staticLabels {
+		labels[sanitizeLabelName(k)] = v
+	}
+	for _, k := range l.resourceKeys {
+		if v, ok := e.Resource[k]; ok {
+			labels[sanitizeLabelName(k)] = v
+		}
+	}
+	for _, k := range l.labelKeys {
+		if v, ok := e.Labels[k]; ok {
+			labels[sanitizeLabelName(k)] = v
+		}
+	}
+
+	if len(labels) == 0 {
+		labels[fallbackLabelName] = fallbackLabelValue
+	}
+	return labels
+}
+
+// sanitizeLabelName will replace characters that are not allowed in loki
+// label names with underscores
+func sanitizeLabelName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9' && i > 0:
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// formatLabels will format labels as a loki label selector, such as {app="web", env="prod"}.
+// Names are sorted so equal sets of labels always have the same format.
+func formatLabels(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, k := range names {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[k]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// createStreams will group entries into streams, in the order each stream is first seen.
+// The entries of each stream are sorted by timestamp, and entries older than the last
+// entry accepted on their stream are moved up to its timestamp, since loki rejects entries
+// that are out of order.
+func (l *LokiOutput) createStreams(entries []*entry.Entry) []*stream {
+	streams := make([]*stream, 0)
+	streamsByKey := make(map[string]*stream)
+
+	for _, e := range entries {
+		line, err := helper.FormatRecord(e.Record)
+		if err != nil {
+			l.Errorw("Failed to format line", zap.Error(err))
+			continue
+		}
+
+		labels := l.streamLabels(e)
+		key := formatLabels(labels)
+		s, ok := streamsByKey[key]
+		if !ok {
+			s = &stream{key: key, labels: labels}
+			streamsByKey[key] = s
+			streams = append(streams, s)
+		}
+		s.entries = append(s.entries, streamEntry{timestamp: e.Timestamp, line: line})
+	}
+
+	l.orderMux.Lock()
+	defer l.orderMux.Unlock()
+
+	for _, s := range streams {
+		sort.SliceStable(s.entries, func(i, j int) bool {
+			return s.entries[i].timestamp.Before(s.entries[j].timestamp)
+		})
+
+		last, ok := l.lastTimestamps[s.key]
+		for i := range s.entries {
+			if ok && s.entries[i].timestamp.Before(last.timestamp) {
+				s.entries[i].timestamp = last.timestamp
+			}
+		}
+	}
+
+	return streams
+}
+
+// updateTimestamps will record the last timestamp of each stream once loki has accepted it
+func (l *LokiOutput) updateTimestamps(streams []*stream) {
+	l.orderMux.Lock()
+	defer l.orderMux.Unlock()
+
+	now := time.Now()
+	for _, s := range streams {
+		l.lastTimestamps[s.key] = streamTimestamp{
+			timestamp: s.entries[len(s.entries)-1].timestamp,
+			updated:   now,
+		}
+	}
+
+	// Forget streams that have been idle, so the map does not grow without bound
+	for key, last := range l.lastTimestamps {
+		if now.Sub(last.updated) > streamIdleTimeout {
+			delete(l.lastTimestamps, key)
+		}
+	}
+}
+
+// streamTimestamp is the timestamp of the last entry of a stream
+type streamTimestamp struct {
+	timestamp time.Time
+	updated   time.Time
+}
@@ -0,0 +1,90 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loki
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitizeLabelName(t *testing.T) {
+	cases := map[string]string{
+		"app":          "app",
+		"k8s.pod.name": "k8s_pod_name",
+		"9lives":       "_lives",
+		"with-dash":    "with_dash",
+		"Upper_Case1":  "Upper_Case1",
+	}
+
+	for name, expected := range cases {
+		require.Equal(t, expected, sanitizeLabelName(name))
+	}
+}
+
+func TestFormatLabels(t *testing.T) {
+	labels := map[string]string{
+		"b": `say "hi"`,
+		"a": "1",
+	}
+	require.Equal(t, `{a="1", b="say \"hi\""}`, formatLabels(labels))
+}
+
+func TestCreateStreamsOrdering(t *testing.T) {
+	cfg := NewLokiOutputConfig("test")
+	cfg.URL = "http://localhost:3100/loki/api/v1/push"
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	output := ops[0].(*LokiOutput)
+
+	t0 := time.Unix(100, 0)
+	newEntry := func(record string, ts time.Time) *entry.Entry {
+		e := entry.New()
+		e.Record = record
+		e.Timestamp = ts
+		return e
+	}
+
+	streams := output.createStreams([]*entry.Entry{
+		newEntry("b", t0.Add(2*time.Second)),
+		newEntry("a", t0.Add(time.Second)),
+	})
+	require.Len(t, streams, 1)
+	require.Equal(t, []streamEntry{
+		{t0.Add(time.Second), "a"},
+		{t0.Add(2 * time.Second), "b"},
+	}, streams[0].entries)
+
+	// Streams that have not been accepted by loki do not move later entries
+	retried := output.createStreams([]*entry.Entry{
+		newEntry("c", t0),
+	})
+	require.Equal(t, []streamEntry{{t0, "c"}}, retried[0].entries)
+	output.updateTimestamps(streams)
+
+	// Entries older than the last entry accepted on the stream are moved up to its timestamp
+	streams = output.createStreams([]*entry.Entry{
+		newEntry("c", t0),
+		newEntry("d", t0.Add(3*time.Second)),
+	})
+	require.Len(t, streams, 1)
+	require.Equal(t, []streamEntry{
+		{t0.Add(2 * time.Second), "c"},
+		{t0.Add(3 * time.Second), "d"},
+	}, streams[0].entries)
+}
@@ -0,0 +1,97 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"go.opentelemetry.io/collector/consumer/pdata"
+)
+
+// resourceLogsOverhead is the most bytes a request adds for each resource logs message
+// on top of its own size (a field tag and a varint length)
+const resourceLogsOverhead = 6
+
+// requestSize returns an upper bound on the encoded size of an export request for logs
+func requestSize(logs pdata.Logs) int {
+	return logs.SizeBytes() + logs.ResourceLogs().Len()*resourceLogsOverhead
+}
+
+// splitLogs will split logs into batches with an encoded size no larger than maxBytes.
+// A single log record that is larger than maxBytes is returned as its own batch.
+func splitLogs(logs pdata.Logs, maxBytes int) []pdata.Logs {
+	count := logs.LogRecordCount()
+	if maxBytes <= 0 || count <= 1 || requestSize(logs) <= maxBytes {
+		return []pdata.Logs{logs}
+	}
+
+	half := count / 2
+	batches := splitLogs(sliceLogs(logs, 0, half), maxBytes)
+	return append(batches, splitLogs(sliceLogs(logs, half, count), maxBytes)...)
+}
+
+// sliceLogs copies the log records in the range [start, end) to new logs,
+// along with the resource and instrumentation library of each record
+func sliceLogs(logs pdata.Logs, start, end int) pdata.Logs {
+	sliced := pdata.NewLogs()
+	index := 0
+
+	rls := logs.ResourceLogs()
+	for i := 0; i < rls.Len(); i++ {
+		rl := rls.At(i)
+		if rl.IsNil() {
+			continue
+		}
+
+		var slicedRL pdata.ResourceLogs
+		ills := rl.InstrumentationLibraryLogs()
+		for j := 0; j < ills.Len(); j++ {
+			ill := ills.At(j)
+			if ill.IsNil() {
+				continue
+			}
+
+			var slicedILL pdata.InstrumentationLibraryLogs
+			lrs := ill.Logs()
+			for k := 0; k < lrs.Len(); k++ {
+				lr := lrs.At(k)
+				if lr.IsNil() {
+					continue
+				}
+
+				if index >= start && index < end {
+					if slicedRL == (pdata.ResourceLogs{}) {
+						slicedRL = pdata.NewResourceLogs()
+						slicedRL.InitEmpty()
+						rl.Resource().CopyTo(slicedRL.Resource())
+						sliced.ResourceLogs().Append(slicedRL)
+					}
+
+					if slicedILL == (pdata.InstrumentationLibraryLogs{}) {
+						slicedILL = pdata.NewInstrumentationLibraryLogs()
+						slicedILL.InitEmpty()
+						ill.InstrumentationLibrary().CopyTo(slicedILL.InstrumentationLibrary())
+						slicedRL.InstrumentationLibraryLogs().Append(slicedILL)
+					}
+
+					slicedLR := pdata.NewLogRecord()
+					lr.CopyTo(slicedLR)
+					slicedILL.Logs().Append(slicedLR)
+				}
+				index++
+			}
+		}
+	}
+
+	return sliced
+}
@@ -0,0 +1,95 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer/pdata"
+)
+
+func newBatchTestEntries() []*entry.Entry {
+	entries := make([]*entry.Entry, 0, 20)
+	for i := 0; i < 20; i++ {
+		e := entry.New()
+		e.AddResourceKey("host", fmt.Sprintf("host-%d", i%2))
+		e.Record = fmt.Sprintf("message %d", i)
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func bodies(logs []pdata.Logs) []string {
+	result := []string{}
+	for _, l := range logs {
+		rls := l.ResourceLogs()
+		for i := 0; i < rls.Len(); i++ {
+			ills := rls.At(i).InstrumentationLibraryLogs()
+			for j := 0; j < ills.Len(); j++ {
+				lrs := ills.At(j).Logs()
+				for k := 0; k < lrs.Len(); k++ {
+					result = append(result, lrs.At(k).Body().StringVal())
+				}
+			}
+		}
+	}
+	return result
+}
+
+func TestSplitLogs(t *testing.T) {
+	logs := Convert(newBatchTestEntries())
+	expected := bodies([]pdata.Logs{logs})
+
+	t.Run("Unlimited", func(t *testing.T) {
+		batches := splitLogs(logs, 0)
+		require.Len(t, batches, 1)
+	})
+
+	t.Run("LargerThanLogs", func(t *testing.T) {
+		batches := splitLogs(logs, requestSize(logs))
+		require.Len(t, batches, 1)
+	})
+
+	t.Run("Split", func(t *testing.T) {
+		maxBytes := requestSize(logs) / 3
+		batches := splitLogs(logs, maxBytes)
+		require.True(t, len(batches) > 1)
+		require.Equal(t, expected, bodies(batches))
+
+		for _, batch := range batches {
+			protoBytes, err := batch.ToOtlpProtoBytes()
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(protoBytes), maxBytes)
+			require.Equal(t, "host", firstResourceKey(batch))
+		}
+	})
+
+	t.Run("SingleRecordTooLarge", func(t *testing.T) {
+		batches := splitLogs(logs, 1)
+		require.Len(t, batches, 20)
+		require.Equal(t, expected, bodies(batches))
+	})
+}
+
+func firstResourceKey(logs pdata.Logs) string {
+	key := ""
+	logs.ResourceLogs().At(0).Resource().Attributes().ForEach(func(k string, _ pdata.AttributeValue) {
+		key = k
+	})
+	return key
+}
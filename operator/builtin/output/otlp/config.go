@@ -23,6 +23,11 @@ import (
 	"go.opentelemetry.io/collector/config/confighttp"
 )
 
+const (
+	defaultHTTPEndpoint = "https://localhost:55681/v1/logs"
+	defaultGRPCEndpoint = "localhost:4317"
+)
+
 // HTTPClientConfig makes confighttp.HTTPClientSettings marshallable with json and yaml
 type HTTPClientConfig struct {
 	confighttp.HTTPClientSettings
@@ -32,7 +37,7 @@ type HTTPClientConfig struct {
 func NewHTTPClientConfig() HTTPClientConfig {
 	return HTTPClientConfig{
 		confighttp.HTTPClientSettings{
-			Endpoint: "https://localhost:55681/v1/logs",
+			Endpoint: defaultHTTPEndpoint,
 		},
 	}
 }
@@ -44,27 +49,29 @@ func (c *HTTPClientConfig) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
-	settings := confighttp.HTTPClientSettings{}
-	if err := mapstructure.Decode(any, &settings); err != nil {
-		return err
-	}
-	c.HTTPClientSettings = settings
-	return nil
+	return c.decode(any)
 }
 
-// UnmarshalYAML will unmarshal json into a HTTPClientConfig struct
+// UnmarshalYAML will unmarshal yaml into a HTTPClientConfig struct
 func (c *HTTPClientConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	var any interface{}
 	if err := unmarshal(&any); err != nil {
 		return err
 	}
 
-	settings := confighttp.HTTPClientSettings{}
-	if err := mapstructure.Decode(any, &settings); err != nil {
+	return c.decode(any)
+}
+
+// decode will decode a raw config onto the current settings so that defaults are kept
+func (c *HTTPClientConfig) decode(any interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.StringToTimeDurationHookFunc(),
+		Result:     &c.HTTPClientSettings,
+	})
+	if err != nil {
 		return err
 	}
-	c.HTTPClientSettings = settings
-	return nil
+	return decoder.Decode(any)
 }
 
 func (c *HTTPClientConfig) cleanEndpoint() error {
@@ -86,3 +93,20 @@ func (c *HTTPClientConfig) cleanEndpoint() error {
 
 	return nil
 }
+
+// grpcTarget returns the endpoint as a gRPC dial target, without a scheme or path
+func (c *HTTPClientConfig) grpcTarget() (string, error) {
+	if c.Endpoint == "" {
+		return "", fmt.Errorf("'endpoint' is required")
+	}
+
+	if c.Endpoint == defaultHTTPEndpoint {
+		return defaultGRPCEndpoint, nil
+	}
+
+	target := strings.TrimPrefix(strings.TrimPrefix(c.Endpoint, "http://"), "https://")
+	if i := strings.Index(target, "/"); i >= 0 {
+		target = target[:i]
+	}
+	return target, nil
+}
@@ -17,7 +17,10 @@ package otlp
 import (
 	"encoding/json"
 	"testing"
+	"time"
 
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/collector/config/confighttp"
 	yaml "gopkg.in/yaml.v2"
@@ -99,3 +102,55 @@ endpoint: localhost:1234`)
 
 	require.Equal(t, expected, cfg)
 }
+
+func TestUnmarshalOperatorConfig(t *testing.T) {
+	expected := NewOTLPOutputConfig("my_otlp")
+	expected.Protocol = protocolGRPC
+	expected.Compression = compressionZstd
+	expected.MaxBatchBytes = helper.ByteSize(4 * 1024 * 1024)
+	expected.Endpoint = "collector:4317"
+	expected.Timeout = 5 * time.Second
+	expected.Headers = map[string]string{"X-Tenant": "test"}
+	expected.TLSSetting.CAFile = "ca.pem"
+
+	t.Run("YAML", func(t *testing.T) {
+		raw := `
+id: my_otlp
+type: otlp_output
+protocol: grpc
+compression: zstd
+max_batch_bytes: 4MiB
+endpoint: collector:4317
+timeout: 5s
+headers:
+  X-Tenant: test
+ca_file: ca.pem
+`
+		var cfg operator.Config
+		require.NoError(t, yaml.Unmarshal([]byte(raw), &cfg))
+		require.Equal(t, expected, cfg.Builder)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		raw := `{
+  "id": "my_otlp",
+  "type": "otlp_output",
+  "protocol": "grpc",
+  "compression": "zstd",
+  "max_batch_bytes": "4MiB",
+  "endpoint": "collector:4317",
+  "timeout": "5s",
+  "headers": {"X-Tenant": "test"},
+  "ca_file": "ca.pem"
+}`
+		var cfg operator.Config
+		require.NoError(t, json.Unmarshal([]byte(raw), &cfg))
+		require.Equal(t, expected, cfg.Builder)
+	})
+
+	t.Run("Defaults", func(t *testing.T) {
+		var cfg operator.Config
+		require.NoError(t, yaml.Unmarshal([]byte("type: otlp_output\n"), &cfg))
+		require.Equal(t, NewOTLPOutputConfig(""), cfg.Builder)
+	})
+}
@@ -78,7 +78,8 @@ func Convert(entries []*entry.Entry) pdata.Logs {
 }
 
 func groupByResource(entries []*entry.Entry) [][]*entry.Entry {
-	resourceMap := make(map[string][]*entry.Entry)
+	resourceIndexes := make(map[string]int)
+	entriesByResource := make([][]*entry.Entry, 0)
 
 	for _, ent := range entries {
 		resourceBytes, err := json.Marshal(ent.Resource)
@@ -87,19 +88,14 @@ func groupByResource(entries []*entry.Entry) [][]*entry.Entry {
 		}
 		resourceHash := string(resourceBytes)
 
-		if resourceEntries, ok := resourceMap[resourceHash]; ok {
-			resourceEntries = append(resourceEntries, ent)
+		if i, ok := resourceIndexes[resourceHash]; ok {
+			entriesByResource[i] = append(entriesByResource[i], ent)
 		} else {
-			resourceEntries = make([]*entry.Entry, 0, 8)
-			resourceEntries = append(resourceEntries, ent)
-			resourceMap[resourceHash] = resourceEntries
+			resourceIndexes[resourceHash] = len(entriesByResource)
+			entriesByResource = append(entriesByResource, []*entry.Entry{ent})
 		}
 	}
 
-	entriesByResource := make([][]*entry.Entry, 0, len(resourceMap))
-	for _, v := range resourceMap {
-		entriesByResource = append(entriesByResource, v)
-	}
 	return entriesByResource
 }
 
@@ -108,6 +108,30 @@ func TestConvertMetadata(t *testing.T) {
 	require.True(t, bod.BoolVal())
 }
 
+func TestConvertGroupsByResource(t *testing.T) {
+	entries := []*entry.Entry{}
+	for _, host := range []string{"a", "b", "a", "b", "a"} {
+		e := entry.New()
+		e.AddResourceKey("host", host)
+		entries = append(entries, e)
+	}
+
+	result := Convert(entries)
+	require.Equal(t, 5, result.LogRecordCount())
+
+	resourceLogs := result.ResourceLogs()
+	require.Equal(t, 2, resourceLogs.Len(), "expected 2 resources")
+	for i, expected := range []struct {
+		host  string
+		count int
+	}{{"a", 3}, {"b", 2}} {
+		host, ok := resourceLogs.At(i).Resource().Attributes().Get("host")
+		require.True(t, ok)
+		require.Equal(t, expected.host, host.StringVal())
+		require.Equal(t, expected.count, resourceLogs.At(i).InstrumentationLibraryLogs().At(0).Logs().Len())
+	}
+}
+
 func TestConvertSimpleBody(t *testing.T) {
 
 	require.True(t, recordToBody(true).BoolVal())
@@ -0,0 +1,46 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"io"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+)
+
+func init() {
+	helper.RegisterEncoding("otlp", func(helper.EncodingConfig) (helper.Encoder, error) { return encoder{}, nil })
+}
+
+// encoder encodes entries as OTLP ExportLogsServiceRequest protobuf messages.
+// A stream of messages can be read as a single message, since protobuf
+// concatenates the repeated fields of concatenated messages.
+type encoder struct{}
+
+func (e encoder) Encode(w io.Writer, ent *entry.Entry) error {
+	return e.EncodeBatch(w, []*entry.Entry{ent})
+}
+
+func (encoder) EncodeBatch(w io.Writer, entries []*entry.Entry) error {
+	b, err := Convert(entries).ToOtlpProtoBytes()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func (encoder) ContentType() string { return "application/x-protobuf" }
@@ -0,0 +1,53 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncoding(t *testing.T) {
+	encoder, err := helper.NewEncodingConfig("otlp").Build()
+	require.NoError(t, err)
+	require.Equal(t, "application/x-protobuf", encoder.ContentType())
+
+	// Records and labels with a single key keep the encoding deterministic
+	first := entry.New()
+	first.Severity = entry.Error
+	first.AddLabel("one", "two")
+	first.Record = map[string]interface{}{"message": "first"}
+	second := entry.New()
+	second.Record = "second"
+
+	var batch bytes.Buffer
+	require.NoError(t, encoder.EncodeBatch(&batch, []*entry.Entry{first, second}))
+	expected, err := Convert([]*entry.Entry{first, second}).ToOtlpProtoBytes()
+	require.NoError(t, err)
+	require.Equal(t, expected, batch.Bytes())
+
+	var stream bytes.Buffer
+	require.NoError(t, encoder.Encode(&stream, first))
+	require.NoError(t, encoder.Encode(&stream, second))
+	firstBytes, err := Convert([]*entry.Entry{first}).ToOtlpProtoBytes()
+	require.NoError(t, err)
+	secondBytes, err := Convert([]*entry.Entry{second}).ToOtlpProtoBytes()
+	require.NoError(t, err)
+	require.Equal(t, append(firstBytes, secondBytes...), stream.Bytes())
+}
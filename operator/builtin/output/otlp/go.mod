@@ -3,11 +3,14 @@ module github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/ou
 go 1.14
 
 require (
+	github.com/klauspost/compress v1.10.10
 	github.com/mitchellh/mapstructure v1.3.2
 	github.com/opentelemetry/opentelemetry-log-collection v0.13.12
 	github.com/stretchr/testify v1.6.1
 	go.opentelemetry.io/collector v0.13.0
 	go.uber.org/zap v1.16.0
+	google.golang.org/grpc v1.32.0
+	google.golang.org/protobuf v1.25.0
 	gopkg.in/yaml.v2 v2.3.0
 )
 
@@ -15,11 +15,9 @@
 package otlp
 
 import (
-	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"net/url"
 	"sync"
 
@@ -36,61 +34,108 @@ func init() {
 	operator.Register("otlp_output", func() operator.Builder { return NewOTLPOutputConfig("") })
 }
 
-// NewOTLPOutputConfig creates a new elastic output config with default values
+// NewOTLPOutputConfig creates a new otlp output config with default values
 func NewOTLPOutputConfig(operatorID string) *OTLPOutputConfig {
 	return &OTLPOutputConfig{
-		OutputConfig:     helper.NewOutputConfig(operatorID, "otlp_output"),
-		BufferConfig:     buffer.NewConfig(),
-		FlusherConfig:    flusher.NewConfig(),
+		OTLPOutputOptions: OTLPOutputOptions{
+			OutputConfig:  helper.NewOutputConfig(operatorID, "otlp_output"),
+			BufferConfig:  buffer.NewConfig(),
+			FlusherConfig: flusher.NewConfig(),
+			Protocol:      protocolHTTP,
+			Compression:   compressionNone,
+		},
 		HTTPClientConfig: NewHTTPClientConfig(),
 	}
 }
 
+const (
+	protocolHTTP = "http"
+	protocolGRPC = "grpc"
+
+	compressionNone = "none"
+	compressionGzip = "gzip"
+	compressionZstd = "zstd"
+)
+
 // OTLPOutputConfig is the configuration of a OTLPOutput operator
 type OTLPOutputConfig struct {
+	OTLPOutputOptions `yaml:",inline"`
+	HTTPClientConfig  `yaml:",inline"`
+}
+
+// OTLPOutputOptions are the options of a OTLPOutput operator that are not part of the client settings
+type OTLPOutputOptions struct {
 	helper.OutputConfig `yaml:",inline"`
-	BufferConfig        buffer.Config  `json:"buffer" yaml:"buffer"`
-	FlusherConfig       flusher.Config `json:"flusher" yaml:"flusher"`
-	HTTPClientConfig    `yaml:",inline"`
+	BufferConfig        buffer.Config   `json:"buffer"                    yaml:"buffer"`
+	FlusherConfig       flusher.Config  `json:"flusher"                   yaml:"flusher"`
+	Protocol            string          `json:"protocol,omitempty"        yaml:"protocol,omitempty"`
+	Compression         string          `json:"compression,omitempty"     yaml:"compression,omitempty"`
+	MaxBatchBytes       helper.ByteSize `json:"max_batch_bytes,omitempty" yaml:"max_batch_bytes,omitempty"`
+}
+
+// UnmarshalJSON will unmarshal json into a OTLPOutputConfig. The client settings
+// are unmarshalled separately, since they would otherwise replace the whole config.
+func (c *OTLPOutputConfig) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &c.OTLPOutputOptions); err != nil {
+		return err
+	}
+	return c.HTTPClientConfig.UnmarshalJSON(data)
+}
+
+// UnmarshalYAML will unmarshal yaml into a OTLPOutputConfig. The client settings
+// are unmarshalled separately, since they would otherwise replace the whole config.
+func (c *OTLPOutputConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&c.OTLPOutputOptions); err != nil {
+		return err
+	}
+	return c.HTTPClientConfig.UnmarshalYAML(unmarshal)
 }
 
 // Build will build a new OTLPOutput
 func (c OTLPOutputConfig) Build(bc operator.BuildContext) ([]operator.Operator, error) {
-
 	outputOperator, err := c.OutputConfig.Build(bc)
 	if err != nil {
 		return nil, err
 	}
 
-	buffer, err := c.BufferConfig.Build(bc, c.ID())
-	if err != nil {
-		return nil, err
+	switch c.Compression {
+	case compressionNone, compressionGzip, compressionZstd:
+	default:
+		return nil, fmt.Errorf("invalid compression '%s'", c.Compression)
 	}
 
-	flusher := c.FlusherConfig.Build(bc.Logger.SugaredLogger)
-
-	if err := c.cleanEndpoint(); err != nil {
-		return nil, err
+	if c.MaxBatchBytes < 0 {
+		return nil, fmt.Errorf("'max_batch_bytes' must not be negative")
 	}
 
-	client, err := c.HTTPClientConfig.ToClient()
+	var sender sender
+	switch c.Protocol {
+	case protocolHTTP:
+		sender, err = c.buildHTTPSender()
+	case protocolGRPC:
+		sender, err = newGRPCSender(&c.HTTPClientConfig, c.Compression)
+	default:
+		return nil, fmt.Errorf("invalid protocol '%s'", c.Protocol)
+	}
 	if err != nil {
-		return nil, errors.Wrap(err, "create client")
+		return nil, err
 	}
 
-	url, err := url.Parse(c.HTTPClientConfig.Endpoint)
+	buffer, err := c.BufferConfig.Build(bc, c.ID())
 	if err != nil {
-		return nil, errors.Wrap(err, "'endpoint' is not a valid URL")
+		return nil, err
 	}
 
+	flusher := c.FlusherConfig.Build(bc.Logger.SugaredLogger)
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	otlp := &OTLPOutput{
 		OutputOperator: outputOperator,
 		buffer:         buffer,
 		flusher:        flusher,
-		client:         client,
-		url:            url,
+		sender:         sender,
+		maxBatchBytes:  int(c.MaxBatchBytes),
 		ctx:            ctx,
 		cancel:         cancel,
 	}
@@ -98,13 +143,35 @@ func (c OTLPOutputConfig) Build(bc operator.BuildContext) ([]operator.Operator,
 	return []operator.Operator{otlp}, nil
 }
 
+func (c OTLPOutputConfig) buildHTTPSender() (*httpSender, error) {
+	if err := c.cleanEndpoint(); err != nil {
+		return nil, err
+	}
+
+	client, err := c.HTTPClientConfig.ToClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "create client")
+	}
+
+	url, err := url.Parse(c.HTTPClientConfig.Endpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "'endpoint' is not a valid URL")
+	}
+
+	return &httpSender{
+		client:      client,
+		url:         url.String(),
+		compression: c.Compression,
+	}, nil
+}
+
 // OTLPOutput is an operator that sends entries to the OTLP recevier
 type OTLPOutput struct {
 	helper.OutputOperator
-	buffer  buffer.Buffer
-	flusher *flusher.Flusher
-	client  *http.Client
-	url     *url.URL
+	buffer        buffer.Buffer
+	flusher       *flusher.Flusher
+	sender        sender
+	maxBatchBytes int
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -113,6 +180,10 @@ type OTLPOutput struct {
 
 // Start flushing entries
 func (o *OTLPOutput) Start() error {
+	if err := o.sender.start(); err != nil {
+		return err
+	}
+
 	o.wg.Add(1)
 	go func() {
 		defer o.wg.Done()
@@ -127,6 +198,9 @@ func (o *OTLPOutput) Stop() error {
 	o.cancel()
 	o.wg.Wait()
 	o.flusher.Stop()
+	if err := o.sender.stop(); err != nil {
+		o.Errorw("Failed to stop sender", zap.Error(err))
+	}
 	return o.buffer.Close()
 }
 
@@ -137,30 +211,44 @@ func (o *OTLPOutput) feedFlusher(ctx context.Context) {
 		if err != nil && err == context.Canceled {
 			return
 		} else if err != nil {
-			o.Errorf("Failed to read chunk", zap.Error(err))
+			o.Errorw("Failed to read chunk", zap.Error(err))
 			continue
 		}
 
+		requests, err := o.createRequests(entries)
+		if err != nil {
+			o.Errorw("Failed to create requests", zap.Error(err))
+			// drop these logs because we couldn't create a request and a retry won't help
+			if err := clearer.MarkAllAsFlushed(); err != nil {
+				o.Errorw("Failed to mark entries as flushed after failing to create a request", zap.Error(err))
+			}
+			continue
+		}
+
+		// Requests that have been sent are skipped when the flush is retried
+		next := 0
 		o.flusher.Do(func(ctx context.Context) error {
-			req, err := o.createRequest(ctx, entries)
-			if err != nil {
-				o.Errorf("Failed to create request", zap.Error(err))
-				// drop these logs because we couldn't creat a request and a retry won't help
-				if err := clearer.MarkAllAsFlushed(); err != nil {
-					o.Errorf("Failed to mark entries as flushed after failing to create a request", zap.Error(err))
+			for ; next < len(requests); next++ {
+				partial, err := o.sender.send(ctx, requests[next])
+				if perr, ok := err.(permanentError); ok {
+					o.Errorw("Dropping batch after a non-retryable error", zap.Error(perr.err))
+					continue
+				} else if rerr, ok := err.(responseError); ok {
+					o.Errorw("Failed to read the response to an exported batch", zap.Error(rerr.err))
+					continue
+				} else if err != nil {
+					return err
 				}
-				return nil
-			}
-			res, err := o.client.Do(req)
-			if err != nil {
-				return errors.Wrap(err, "send request")
-			}
 
-			if err := o.handleResponse(res); err != nil {
-				return err
+				if partial != nil {
+					o.Warnw("Receiver rejected part of a batch",
+						"rejected_log_records", partial.RejectedLogRecords,
+						"error_message", partial.ErrorMessage,
+					)
+				}
 			}
 
-			if err = clearer.MarkAllAsFlushed(); err != nil {
+			if err := clearer.MarkAllAsFlushed(); err != nil {
 				o.Errorw("Failed to mark entries as flushed", zap.Error(err))
 			}
 			return nil
@@ -173,32 +261,17 @@ func (o *OTLPOutput) Process(ctx context.Context, entry *entry.Entry) error {
 	return o.buffer.Add(ctx, entry)
 }
 
-// ProcessMulti will send a chunk of entries
-func (o *OTLPOutput) createRequest(ctx context.Context, entries []*entry.Entry) (*http.Request, error) {
-	logs := Convert(entries)
-	protoBytes, err := logs.ToOtlpProtoBytes()
-	if err != nil {
-		return nil, errors.Wrap(err, "convert logs to proto bytes")
-	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.url.String(), bytes.NewReader(protoBytes))
-	if err != nil {
-		return nil, errors.Wrap(err, "create request")
-	}
-	req.Header.Set("Content-Type", "application/x-protobuf")
-	return req, nil
-}
-
-func (o *OTLPOutput) handleResponse(res *http.Response) error {
-	if !(res.StatusCode >= 200 && res.StatusCode < 300) {
-		body, err := ioutil.ReadAll(res.Body)
+// createRequests will convert a chunk of entries to encoded export requests,
+// splitting them into batches no larger than max_batch_bytes
+func (o *OTLPOutput) createRequests(entries []*entry.Entry) ([][]byte, error) {
+	batches := splitLogs(Convert(entries), o.maxBatchBytes)
+	requests := make([][]byte, 0, len(batches))
+	for _, batch := range batches {
+		protoBytes, err := batch.ToOtlpProtoBytes()
 		if err != nil {
-			return errors.NewError("non-success status code", "", "status", fmt.Sprint(res.StatusCode))
-		} else {
-			res.Body.Close()
-			return errors.NewError("non-success status code", "", "status", fmt.Sprint(res.StatusCode), "body", string(body))
+			return nil, errors.Wrap(err, "convert logs to proto bytes")
 		}
+		requests = append(requests, protoBytes)
 	}
-	res.Body.Close()
-	return nil
+	return requests, nil
 }
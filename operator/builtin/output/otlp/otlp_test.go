@@ -15,14 +15,18 @@
 package otlp
 
 import (
+	"compress/gzip"
 	"context"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"testing"
 	"time"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/errors"
 	"github.com/opentelemetry/opentelemetry-log-collection/operator/buffer"
 	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
 	"github.com/opentelemetry/opentelemetry-log-collection/testutil"
@@ -46,6 +50,47 @@ func TestOTLPConfigBuild(t *testing.T) {
 		require.Equal(t, err.Error(), "'endpoint' is required")
 	})
 
+	t.Run("InvalidProtocol", func(t *testing.T) {
+		cfg := NewOTLPOutputConfig("test")
+		cfg.Protocol = "udp"
+		_, err := cfg.Build(testutil.NewBuildContext(t))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid protocol")
+	})
+
+	t.Run("InvalidCompression", func(t *testing.T) {
+		cfg := NewOTLPOutputConfig("test")
+		cfg.Compression = "lz4"
+		_, err := cfg.Build(testutil.NewBuildContext(t))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid compression")
+	})
+
+	t.Run("NegativeMaxBatchBytes", func(t *testing.T) {
+		cfg := NewOTLPOutputConfig("test")
+		cfg.MaxBatchBytes = -1
+		_, err := cfg.Build(testutil.NewBuildContext(t))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "max_batch_bytes")
+	})
+
+	t.Run("GRPCMissingEndpoint", func(t *testing.T) {
+		cfg := NewOTLPOutputConfig("test")
+		cfg.Protocol = protocolGRPC
+		cfg.Endpoint = ""
+		_, err := cfg.Build(testutil.NewBuildContext(t))
+		require.Error(t, err)
+		require.Equal(t, err.Error(), "'endpoint' is required")
+	})
+
+	t.Run("GRPCInvalidTLS", func(t *testing.T) {
+		cfg := NewOTLPOutputConfig("test")
+		cfg.Protocol = protocolGRPC
+		cfg.TLSSetting.CAFile = "/does/not/exist"
+		_, err := cfg.Build(testutil.NewBuildContext(t))
+		require.Error(t, err)
+	})
+
 	t.Run("InvalidEndpoint", func(t *testing.T) {
 		cfg := NewOTLPOutputConfig("test")
 		cfg.Endpoint = `%^&*($@)`
@@ -80,6 +125,22 @@ func TestOTLPOutput(t *testing.T) {
 				Record:    "test2",
 			}},
 		},
+		{
+			"Gzip",
+			func(cfg *OTLPOutputConfig) { cfg.Compression = compressionGzip },
+			[]*entry.Entry{{
+				Timestamp: time.Date(2016, 10, 10, 8, 58, 52, 0, time.UTC),
+				Record:    "test",
+			}},
+		},
+		{
+			"Zstd",
+			func(cfg *OTLPOutputConfig) { cfg.Compression = compressionZstd },
+			[]*entry.Entry{{
+				Timestamp: time.Date(2016, 10, 10, 8, 58, 52, 0, time.UTC),
+				Record:    "test",
+			}},
+		},
 	}
 
 	for _, tc := range cases {
@@ -115,6 +176,143 @@ func TestOTLPOutput(t *testing.T) {
 	}
 }
 
+func TestOTLPOutputHeaders(t *testing.T) {
+	ln := newListener()
+	addr, err := ln.start()
+	require.NoError(t, err)
+	defer ln.stop()
+
+	cfg := NewOTLPOutputConfig("test")
+	cfg.BufferConfig.Builder.(*buffer.MemoryBufferConfig).MaxChunkDelay = helper.NewDuration(50 * time.Millisecond)
+	cfg.Endpoint = addr
+	cfg.TLSSetting.Insecure = true
+	cfg.Compression = compressionGzip
+	cfg.Headers = map[string]string{"X-Tenant": "test"}
+
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	op := ops[0]
+	require.NoError(t, op.Start())
+	defer op.Stop()
+	require.NoError(t, op.Process(context.Background(), entry.New()))
+
+	select {
+	case header := <-ln.requestHeaders:
+		require.Equal(t, "test", header.Get("X-Tenant"))
+		require.Equal(t, "gzip", header.Get("Content-Encoding"))
+		require.Equal(t, "application/x-protobuf", header.Get("Content-Type"))
+	case <-time.After(time.Second):
+		require.FailNow(t, "Timed out waiting for request")
+	}
+}
+
+func TestOTLPOutputMaxBatchBytes(t *testing.T) {
+	ln := newListener()
+	addr, err := ln.start()
+	require.NoError(t, err)
+	defer ln.stop()
+
+	entries := newBatchTestEntries()
+	maxBatchBytes := requestSize(Convert(entries)) / 3
+
+	expected := [][]byte{}
+	for _, batch := range splitLogs(Convert(entries), maxBatchBytes) {
+		protoBytes, err := batch.ToOtlpProtoBytes()
+		require.NoError(t, err)
+		expected = append(expected, protoBytes)
+	}
+	require.True(t, len(expected) > 1)
+
+	cfg := NewOTLPOutputConfig("test")
+	cfg.BufferConfig.Builder.(*buffer.MemoryBufferConfig).MaxChunkDelay = helper.NewDuration(50 * time.Millisecond)
+	cfg.Endpoint = addr
+	cfg.TLSSetting.Insecure = true
+	cfg.MaxBatchBytes = helper.ByteSize(maxBatchBytes)
+
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	op := ops[0]
+	require.NoError(t, op.Start())
+	defer op.Stop()
+	for _, e := range entries {
+		require.NoError(t, op.Process(context.Background(), e))
+	}
+
+	for _, body := range expected {
+		expectRequestBody(t, ln, body)
+	}
+}
+
+func TestHTTPSender(t *testing.T) {
+	cases := []struct {
+		name        string
+		statusCode  int
+		contentType string
+		response    []byte
+		expected    *partialSuccess
+		retryable   bool
+		permanent   bool
+	}{
+		{"Success", 200, "", []byte(`{}`), nil, false, false},
+		{"PartialSuccessProtobuf", 200, "application/x-protobuf", encodePartialSuccess(2, "invalid"), &partialSuccess{2, "invalid"}, false, false},
+		{"PartialSuccessJSON", 200, "application/json; charset=utf-8", []byte(`{"partialSuccess": {"rejectedLogRecords": "1"}}`), &partialSuccess{RejectedLogRecords: 1}, false, false},
+		{"TooManyRequests", 429, "", nil, nil, true, false},
+		{"Unavailable", 503, "", nil, nil, true, false},
+		{"BadRequest", 400, "", nil, nil, false, true},
+		{"InternalServerError", 500, "", nil, nil, false, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ln := newListener()
+			ln.statusCode = tc.statusCode
+			ln.contentType = tc.contentType
+			ln.response = tc.response
+			addr, err := ln.start()
+			require.NoError(t, err)
+			defer ln.stop()
+
+			s := &httpSender{
+				client:      http.DefaultClient,
+				url:         "http://" + addr + "/v1/logs",
+				compression: compressionNone,
+			}
+
+			partial, err := s.send(context.Background(), []byte("request"))
+			switch {
+			case tc.retryable:
+				require.Error(t, err)
+				require.IsType(t, errors.AgentError{}, err)
+			case tc.permanent:
+				require.Error(t, err)
+				require.IsType(t, permanentError{}, err)
+			default:
+				require.NoError(t, err)
+				require.Equal(t, tc.expected, partial)
+			}
+		})
+	}
+}
+
+func TestHTTPSenderInvalidResponse(t *testing.T) {
+	ln := newListener()
+	ln.contentType = "application/x-protobuf"
+	ln.response = []byte{0xff}
+	addr, err := ln.start()
+	require.NoError(t, err)
+	defer ln.stop()
+
+	s := &httpSender{
+		client:      http.DefaultClient,
+		url:         "http://" + addr + "/v1/logs",
+		compression: compressionNone,
+	}
+
+	_, err = s.send(context.Background(), []byte("request"))
+	require.Error(t, err)
+	require.IsType(t, responseError{}, err)
+}
+
 func expectRequestBody(t *testing.T, ln *listener, expected []byte) {
 	select {
 	case body := <-ln.requestBodies:
@@ -125,22 +323,29 @@ func expectRequestBody(t *testing.T, ln *listener, expected []byte) {
 }
 
 type listener struct {
-	server        *http.Server
-	requestBodies chan []byte
+	server         *http.Server
+	requestBodies  chan []byte
+	requestHeaders chan http.Header
+
+	statusCode  int
+	contentType string
+	response    []byte
 }
 
 func newListener() *listener {
-	requests := make(chan []byte, 100)
+	l := &listener{
+		requestBodies:  make(chan []byte, 100),
+		requestHeaders: make(chan http.Header, 100),
+		statusCode:     200,
+		response:       []byte(`{}`),
+	}
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/", handle(requests))
-
-	return &listener{
-		server: &http.Server{
-			Handler: mux,
-		},
-		requestBodies: requests,
+	mux.HandleFunc("/", l.handle)
+	l.server = &http.Server{
+		Handler: mux,
 	}
+	return l
 }
 
 func (l *listener) start() (string, error) {
@@ -160,17 +365,36 @@ func (l *listener) stop() {
 	l.server.Shutdown(context.Background())
 }
 
-func handle(ch chan []byte) func(rw http.ResponseWriter, req *http.Request) {
-	return func(rw http.ResponseWriter, req *http.Request) {
-		rw.WriteHeader(200)
-		rw.Write([]byte(`{}`))
+func (l *listener) handle(rw http.ResponseWriter, req *http.Request) {
+	if l.contentType != "" {
+		rw.Header().Set("Content-Type", l.contentType)
+	}
+	rw.WriteHeader(l.statusCode)
+	rw.Write(l.response)
 
-		body, err := ioutil.ReadAll(req.Body)
+	var reader io.Reader = req.Body
+	switch req.Header.Get("Content-Encoding") {
+	case "gzip":
+		gr, err := gzip.NewReader(req.Body)
 		if err != nil {
 			panic(err)
 		}
-		req.Body.Close()
+		reader = gr
+	case "zstd":
+		zr, err := zstd.NewReader(req.Body)
+		if err != nil {
+			panic(err)
+		}
+		defer zr.Close()
+		reader = zr
+	}
 
-		ch <- body
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		panic(err)
 	}
+	req.Body.Close()
+
+	l.requestHeaders <- req.Header
+	l.requestBodies <- body
 }
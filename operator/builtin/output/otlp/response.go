@@ -0,0 +1,125 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// partialSuccess describes the log records that a receiver rejected from an
+// otherwise successful export request
+type partialSuccess struct {
+	RejectedLogRecords int64
+	ErrorMessage       string
+}
+
+// parsePartialSuccess reads the partial success field of an encoded ExportLogsServiceResponse.
+// It returns nil if the response does not report any rejected log records.
+func parsePartialSuccess(b []byte) (*partialSuccess, error) {
+	var result *partialSuccess
+	err := walkFields(b, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		if num != 1 || typ != protowire.BytesType {
+			return nil
+		}
+
+		ps := partialSuccess{}
+		err := walkFields(value, func(num protowire.Number, typ protowire.Type, value []byte) error {
+			switch {
+			case num == 1 && typ == protowire.VarintType:
+				v, _ := protowire.ConsumeVarint(value)
+				ps.RejectedLogRecords = int64(v)
+			case num == 2 && typ == protowire.BytesType:
+				ps.ErrorMessage = string(value)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		result = &ps
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result == nil || (result.RejectedLogRecords == 0 && result.ErrorMessage == "") {
+		return nil, nil
+	}
+	return result, nil
+}
+
+// walkFields calls fn with each field of an encoded protobuf message. Length
+// delimited values are passed without their length prefix.
+func walkFields(b []byte, fn func(num protowire.Number, typ protowire.Type, value []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return fmt.Errorf("invalid field tag: %s", protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		m := protowire.ConsumeFieldValue(num, typ, b)
+		if m < 0 {
+			return fmt.Errorf("invalid field %d: %s", num, protowire.ParseError(m))
+		}
+
+		value := b[:m]
+		if typ == protowire.BytesType {
+			value, _ = protowire.ConsumeBytes(value)
+		}
+
+		if err := fn(num, typ, value); err != nil {
+			return err
+		}
+		b = b[m:]
+	}
+	return nil
+}
+
+// parsePartialSuccessJSON reads the partial success field of a json encoded ExportLogsServiceResponse
+func parsePartialSuccessJSON(b []byte) (*partialSuccess, error) {
+	var response struct {
+		PartialSuccess *struct {
+			RejectedLogRecords json.Number `json:"rejectedLogRecords"`
+			ErrorMessage       string      `json:"errorMessage"`
+		} `json:"partialSuccess"`
+	}
+	if err := json.Unmarshal(b, &response); err != nil {
+		return nil, err
+	}
+
+	if response.PartialSuccess == nil {
+		return nil, nil
+	}
+
+	ps := partialSuccess{ErrorMessage: response.PartialSuccess.ErrorMessage}
+	if response.PartialSuccess.RejectedLogRecords != "" {
+		rejected, err := response.PartialSuccess.RejectedLogRecords.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid rejectedLogRecords: %s", err)
+		}
+		ps.RejectedLogRecords = rejected
+	}
+
+	if ps.RejectedLogRecords == 0 && ps.ErrorMessage == "" {
+		return nil, nil
+	}
+	return &ps, nil
+}
@@ -0,0 +1,89 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func encodePartialSuccess(rejected int64, message string) []byte {
+	var ps []byte
+	ps = protowire.AppendTag(ps, 1, protowire.VarintType)
+	ps = protowire.AppendVarint(ps, uint64(rejected))
+	ps = protowire.AppendTag(ps, 2, protowire.BytesType)
+	ps = protowire.AppendString(ps, message)
+
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	return protowire.AppendBytes(b, ps)
+}
+
+func TestParsePartialSuccess(t *testing.T) {
+	t.Run("Empty", func(t *testing.T) {
+		ps, err := parsePartialSuccess(nil)
+		require.NoError(t, err)
+		require.Nil(t, ps)
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		ps, err := parsePartialSuccess(encodePartialSuccess(3, "too old"))
+		require.NoError(t, err)
+		require.Equal(t, &partialSuccess{RejectedLogRecords: 3, ErrorMessage: "too old"}, ps)
+	})
+
+	t.Run("NothingRejected", func(t *testing.T) {
+		ps, err := parsePartialSuccess(encodePartialSuccess(0, ""))
+		require.NoError(t, err)
+		require.Nil(t, ps)
+	})
+
+	t.Run("UnknownFields", func(t *testing.T) {
+		b := protowire.AppendTag(nil, 5, protowire.VarintType)
+		b = protowire.AppendVarint(b, 10)
+		b = append(b, encodePartialSuccess(1, "")...)
+		ps, err := parsePartialSuccess(b)
+		require.NoError(t, err)
+		require.Equal(t, &partialSuccess{RejectedLogRecords: 1}, ps)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := parsePartialSuccess([]byte{0x0a, 0x05, 0x01})
+		require.Error(t, err)
+	})
+}
+
+func TestParsePartialSuccessJSON(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected *partialSuccess
+	}{
+		{"Empty", `{}`, nil},
+		{"NothingRejected", `{"partialSuccess": {}}`, nil},
+		{"StringCount", `{"partialSuccess": {"rejectedLogRecords": "2", "errorMessage": "invalid"}}`, &partialSuccess{2, "invalid"}},
+		{"NumberCount", `{"partialSuccess": {"rejectedLogRecords": 4}}`, &partialSuccess{RejectedLogRecords: 4}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ps, err := parsePartialSuccessJSON([]byte(tc.input))
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, ps)
+		})
+	}
+}
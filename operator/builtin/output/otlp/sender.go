@@ -0,0 +1,287 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/opentelemetry/opentelemetry-log-collection/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding"
+	_ "google.golang.org/grpc/encoding/gzip" // register the gzip compressor
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const exportMethod = "/opentelemetry.proto.collector.logs.v1.LogsService/Export"
+
+func init() {
+	encoding.RegisterCompressor(zstdCompressor{})
+}
+
+// sender sends encoded export requests to an OTLP receiver
+type sender interface {
+	start() error
+	send(ctx context.Context, request []byte) (*partialSuccess, error)
+	stop() error
+}
+
+// permanentError is returned by a sender when retrying the request will not help
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+// responseError is returned by a sender when a request succeeded but the
+// response could not be read. The request must not be sent again.
+type responseError struct {
+	err error
+}
+
+func (e responseError) Error() string {
+	return e.err.Error()
+}
+
+// httpSender sends export requests as protobuf over http
+type httpSender struct {
+	client      *http.Client
+	url         string
+	compression string
+}
+
+func (s *httpSender) start() error {
+	return nil
+}
+
+func (s *httpSender) stop() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+func (s *httpSender) send(ctx context.Context, request []byte) (*partialSuccess, error) {
+	body, err := compress(s.compression, request)
+	if err != nil {
+		return nil, permanentError{errors.Wrap(err, "compress request")}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, permanentError{errors.Wrap(err, "create request")}
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	if s.compression != compressionNone {
+		req.Header.Set("Content-Encoding", s.compression)
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "send request")
+	}
+	defer res.Body.Close()
+
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "read response")
+	}
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		err := errors.NewError("non-success status code", "", "status", fmt.Sprint(res.StatusCode), "body", string(resBody))
+		switch res.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return nil, err
+		default:
+			return nil, permanentError{err}
+		}
+	}
+
+	var partial *partialSuccess
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/x-protobuf":
+		partial, err = parsePartialSuccess(resBody)
+	case "application/json":
+		partial, err = parsePartialSuccessJSON(resBody)
+	}
+	if err != nil {
+		return nil, responseError{errors.Wrap(err, "parse response")}
+	}
+	return partial, nil
+}
+
+// compress will compress a request body for http
+func compress(compression string, b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch compression {
+	case compressionGzip:
+		w = gzip.NewWriter(&buf)
+	case compressionZstd:
+		zw, err := zstd.NewWriter(&buf)
+		if err != nil {
+			return nil, err
+		}
+		w = zw
+	default:
+		return b, nil
+	}
+
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// grpcSender sends export requests to the OTLP logs service over grpc
+type grpcSender struct {
+	target      string
+	dialOptions []grpc.DialOption
+	callOptions []grpc.CallOption
+	headers     metadata.MD
+	timeout     time.Duration
+	conn        *grpc.ClientConn
+}
+
+func newGRPCSender(c *HTTPClientConfig, compression string) (*grpcSender, error) {
+	target, err := c.grpcTarget()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &grpcSender{
+		target:      target,
+		callOptions: []grpc.CallOption{grpc.ForceCodec(rawCodec{})},
+		headers:     metadata.New(c.Headers),
+		timeout:     c.Timeout,
+	}
+
+	// The scheme of the endpoint is not part of the target, but an http:// endpoint still means no TLS
+	if c.TLSSetting.Insecure || strings.HasPrefix(c.Endpoint, "http://") {
+		s.dialOptions = append(s.dialOptions, grpc.WithInsecure())
+	} else {
+		tlsConfig, err := c.TLSSetting.LoadTLSConfig()
+		if err != nil {
+			return nil, errors.Wrap(err, "load tls config")
+		}
+		s.dialOptions = append(s.dialOptions, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	}
+
+	if compression != compressionNone {
+		s.callOptions = append(s.callOptions, grpc.UseCompressor(compression))
+	}
+
+	return s, nil
+}
+
+func (s *grpcSender) start() error {
+	conn, err := grpc.Dial(s.target, s.dialOptions...)
+	if err != nil {
+		return errors.Wrap(err, "dial")
+	}
+	s.conn = conn
+	return nil
+}
+
+func (s *grpcSender) stop() error {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+func (s *grpcSender) send(ctx context.Context, request []byte) (*partialSuccess, error) {
+	ctx = metadata.NewOutgoingContext(ctx, s.headers)
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	var response []byte
+	if err := s.conn.Invoke(ctx, exportMethod, &request, &response, s.callOptions...); err != nil {
+		switch status.Code(err) {
+		case codes.Canceled, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted,
+			codes.OutOfRange, codes.Unavailable, codes.DataLoss:
+			return nil, errors.Wrap(err, "export logs")
+		default:
+			return nil, permanentError{errors.Wrap(err, "export logs")}
+		}
+	}
+
+	partial, err := parsePartialSuccess(response)
+	if err != nil {
+		return nil, responseError{errors.Wrap(err, "parse response")}
+	}
+	return partial, nil
+}
+
+// rawCodec sends pre-encoded protobuf bytes, since the generated
+// OTLP service clients are internal to the collector
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	return *(v.(*[]byte)), nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	*(v.(*[]byte)) = append([]byte(nil), data...)
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
+
+// zstdCompressor is a grpc compressor using zstd
+type zstdCompressor struct{}
+
+func (zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w)
+}
+
+func (zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	decoder, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer decoder.Close()
+
+	b, err := ioutil.ReadAll(decoder)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(b), nil
+}
+
+func (zstdCompressor) Name() string {
+	return compressionZstd
+}
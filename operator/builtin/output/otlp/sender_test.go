@@ -0,0 +1,216 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/errors"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/buffer"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"github.com/opentelemetry/opentelemetry-log-collection/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// serverCodec adapts rawCodec to the codec interface used by grpc servers
+type serverCodec struct {
+	rawCodec
+}
+
+func (serverCodec) String() string {
+	return "proto"
+}
+
+type grpcRequest struct {
+	method string
+	md     metadata.MD
+	body   []byte
+}
+
+type grpcListener struct {
+	server   *grpc.Server
+	requests chan grpcRequest
+	err      error
+	response []byte
+}
+
+func newGRPCListener(t *testing.T) (*grpcListener, string) {
+	l := &grpcListener{
+		requests: make(chan grpcRequest, 100),
+	}
+
+	// The raw codec lets the handler receive the encoded request without the generated service
+	l.server = grpc.NewServer(grpc.CustomCodec(serverCodec{}), grpc.UnknownServiceHandler(l.handle))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		l.server.Serve(ln)
+	}()
+	t.Cleanup(l.server.Stop)
+
+	return l, ln.Addr().String()
+}
+
+func (l *grpcListener) handle(_ interface{}, stream grpc.ServerStream) error {
+	method, _ := grpc.MethodFromServerStream(stream)
+	md, _ := metadata.FromIncomingContext(stream.Context())
+
+	var body []byte
+	if err := stream.RecvMsg(&body); err != nil {
+		return err
+	}
+	l.requests <- grpcRequest{method, md, body}
+
+	if l.err != nil {
+		return l.err
+	}
+	response := l.response
+	return stream.SendMsg(&response)
+}
+
+func TestOTLPOutputGRPC(t *testing.T) {
+	for _, compression := range []string{compressionNone, compressionGzip, compressionZstd} {
+		t.Run(compression, func(t *testing.T) {
+			ln, addr := newGRPCListener(t)
+
+			cfg := NewOTLPOutputConfig("test")
+			cfg.BufferConfig.Builder.(*buffer.MemoryBufferConfig).MaxChunkDelay = helper.NewDuration(50 * time.Millisecond)
+			cfg.Protocol = protocolGRPC
+			cfg.Compression = compression
+			cfg.Endpoint = "http://" + addr
+			cfg.Headers = map[string]string{"x-tenant": "test"}
+
+			ops, err := cfg.Build(testutil.NewBuildContext(t))
+			require.NoError(t, err)
+			op := ops[0]
+			require.NoError(t, op.Start())
+			defer op.Stop()
+
+			e := entry.New()
+			e.Record = "test"
+			require.NoError(t, op.Process(context.Background(), e))
+
+			expected, err := Convert([]*entry.Entry{e}).ToOtlpProtoBytes()
+			require.NoError(t, err)
+
+			select {
+			case req := <-ln.requests:
+				require.Equal(t, exportMethod, req.method)
+				require.Equal(t, []string{"test"}, req.md.Get("x-tenant"))
+				require.Equal(t, expected, req.body)
+			case <-time.After(5 * time.Second):
+				require.FailNow(t, "Timed out waiting for request")
+			}
+		})
+	}
+}
+
+func TestGRPCSender(t *testing.T) {
+	cases := []struct {
+		name      string
+		err       error
+		response  []byte
+		expected  *partialSuccess
+		permanent bool
+	}{
+		{"Success", nil, nil, nil, false},
+		{"PartialSuccess", nil, encodePartialSuccess(5, "dropped"), &partialSuccess{5, "dropped"}, false},
+		{"Unavailable", status.Error(codes.Unavailable, "unavailable"), nil, nil, false},
+		{"ResourceExhausted", status.Error(codes.ResourceExhausted, "slow down"), nil, nil, false},
+		{"InvalidArgument", status.Error(codes.InvalidArgument, "invalid"), nil, nil, true},
+		{"Unauthenticated", status.Error(codes.Unauthenticated, "denied"), nil, nil, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ln, addr := newGRPCListener(t)
+			ln.err = tc.err
+			ln.response = tc.response
+
+			cfg := NewHTTPClientConfig()
+			cfg.Endpoint = addr
+			cfg.TLSSetting.Insecure = true
+			cfg.Timeout = 5 * time.Second
+
+			s, err := newGRPCSender(&cfg, compressionNone)
+			require.NoError(t, err)
+			require.NoError(t, s.start())
+			defer s.stop()
+
+			partial, err := s.send(context.Background(), []byte{})
+			switch {
+			case tc.err == nil:
+				require.NoError(t, err)
+				require.Equal(t, tc.expected, partial)
+			case tc.permanent:
+				require.Error(t, err)
+				require.IsType(t, permanentError{}, err)
+			default:
+				require.Error(t, err)
+				require.IsType(t, errors.AgentError{}, err)
+				require.Contains(t, err.Error(), status.Code(tc.err).String())
+			}
+		})
+	}
+}
+
+func TestGRPCSenderInvalidResponse(t *testing.T) {
+	ln, addr := newGRPCListener(t)
+	ln.response = []byte{0xff}
+
+	cfg := NewHTTPClientConfig()
+	cfg.Endpoint = "http://" + addr
+	cfg.Timeout = 5 * time.Second
+
+	s, err := newGRPCSender(&cfg, compressionNone)
+	require.NoError(t, err)
+	require.NoError(t, s.start())
+	defer s.stop()
+
+	_, err = s.send(context.Background(), []byte{})
+	require.Error(t, err)
+	require.IsType(t, responseError{}, err)
+}
+
+func TestGRPCTarget(t *testing.T) {
+	cases := []struct {
+		endpoint string
+		expected string
+	}{
+		{defaultHTTPEndpoint, defaultGRPCEndpoint},
+		{"collector:4317", "collector:4317"},
+		{"http://collector:4317", "collector:4317"},
+		{"https://collector:4317/v1/logs", "collector:4317"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.endpoint, func(t *testing.T) {
+			cfg := NewHTTPClientConfig()
+			cfg.Endpoint = tc.endpoint
+			target, err := cfg.grpcTarget()
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, target)
+		})
+	}
+}
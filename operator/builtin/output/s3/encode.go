@@ -0,0 +1,88 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	_ "github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/otlp" // register the otlp encoding
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+)
+
+const (
+	compressionNone = "none"
+	compressionGzip = "gzip"
+	compressionZstd = "zstd"
+)
+
+// encodeEntries will encode entries to be appended to an object. Since chunks
+// are appended to the same object, entries are encoded as a stream of records.
+//
+// OTLP objects are a series of encoded ExportLogsServiceRequest messages. Since
+// the messages only have a repeated field, the series is itself a valid message.
+func encodeEntries(encoder helper.Encoder, entries []*entry.Entry) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, e := range entries {
+		if err := encoder.Encode(&buf, e); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// contentType returns the content type of objects in an encoding
+func contentType(encodingType string, encoder helper.Encoder) string {
+	// Objects in the json encoding have an entry per line
+	if encodingType == "json" {
+		return "application/x-ndjson"
+	}
+	return encoder.ContentType()
+}
+
+// extension returns the file extension of objects in an encoding
+func extension(encodingType string) string {
+	switch encodingType {
+	case "json", "ndjson":
+		return ".ndjson"
+	case "otlp":
+		return ".pb"
+	case "msgpack":
+		return ".msgpack"
+	default:
+		return ".log"
+	}
+}
+
+// compressTo will return a writer that compresses data written to w
+func compressTo(compression string, w io.Writer) (io.WriteCloser, error) {
+	switch compression {
+	case compressionGzip:
+		return gzip.NewWriter(w), nil
+	case compressionZstd:
+		return zstd.NewWriter(w)
+	default:
+		return nopWriteCloser{w}, nil
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,481 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/errors"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/buffer"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/flusher"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"go.uber.org/zap"
+)
+
+func init() {
+	operator.Register("s3_output", func() operator.Builder { return NewS3OutputConfig("") })
+}
+
+const (
+	defaultMaxObjectSize = 64 * 1024 * 1024
+	defaultPartSize      = 5 * 1024 * 1024
+
+	// minPartSize is the smallest part that S3 accepts in a multipart upload
+	minPartSize = 5 * 1024 * 1024
+
+	// stagingRetryInterval is the time to wait before staging entries again after a failure
+	stagingRetryInterval = 5 * time.Second
+)
+
+// NewS3OutputConfig creates a new s3 output config with default values
+func NewS3OutputConfig(operatorID string) *S3OutputConfig {
+	return &S3OutputConfig{
+		OutputConfig:  helper.NewOutputConfig(operatorID, "s3_output"),
+		BufferConfig:  buffer.NewConfig(),
+		FlusherConfig: flusher.NewConfig(),
+		Region:        "us-east-1",
+		Encoding:      helper.NewEncodingConfig("ndjson"),
+		Compression:   compressionGzip,
+		MaxObjectSize: defaultMaxObjectSize,
+		MaxObjectAge:  helper.NewDuration(5 * time.Minute),
+		PartSize:      defaultPartSize,
+	}
+}
+
+// S3OutputConfig is the configuration of an s3 output operator
+type S3OutputConfig struct {
+	helper.OutputConfig `yaml:",inline"`
+	BufferConfig        buffer.Config  `json:"buffer"  yaml:"buffer"`
+	FlusherConfig       flusher.Config `json:"flusher" yaml:"flusher"`
+
+	Bucket           string                  `json:"bucket"                      yaml:"bucket"`
+	Region           string                  `json:"region,omitempty"            yaml:"region,omitempty"`
+	Endpoint         string                  `json:"endpoint,omitempty"          yaml:"endpoint,omitempty"`
+	ForcePathStyle   bool                    `json:"force_path_style,omitempty"  yaml:"force_path_style,omitempty"`
+	AccessKeyID      string                  `json:"access_key_id,omitempty"     yaml:"access_key_id,omitempty"`
+	SecretAccessKey  string                  `json:"secret_access_key,omitempty" yaml:"secret_access_key,omitempty"`
+	KeyPrefix        helper.ExprStringConfig `json:"key_prefix,omitempty"        yaml:"key_prefix,omitempty"`
+	Encoding         helper.EncodingConfig   `json:"encoding,omitempty"          yaml:"encoding,omitempty"`
+	Compression      string                  `json:"compression,omitempty"       yaml:"compression,omitempty"`
+	MaxObjectSize    helper.ByteSize         `json:"max_object_size,omitempty"   yaml:"max_object_size,omitempty"`
+	MaxObjectAge     helper.Duration         `json:"max_object_age,omitempty"    yaml:"max_object_age,omitempty"`
+	PartSize         helper.ByteSize         `json:"part_size,omitempty"         yaml:"part_size,omitempty"`
+	StagingDirectory string                  `json:"staging_directory"           yaml:"staging_directory"`
+}
+
+// Build will build an s3 output operator
+func (c S3OutputConfig) Build(bc operator.BuildContext) ([]operator.Operator, error) {
+	outputOperator, err := c.OutputConfig.Build(bc)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Bucket == "" {
+		return nil, errors.NewError("missing required parameter 'bucket'", "")
+	}
+
+	if c.StagingDirectory == "" {
+		return nil, errors.NewError(
+			"missing required parameter 'staging_directory'",
+			"Set 'staging_directory' to a directory where objects can be stored until they are uploaded",
+		)
+	}
+
+	encoder, err := c.Encoding.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	switch c.Compression {
+	case compressionNone, compressionGzip, compressionZstd:
+	default:
+		return nil, fmt.Errorf("invalid compression '%s'", c.Compression)
+	}
+
+	if c.MaxObjectSize <= 0 {
+		return nil, fmt.Errorf("`max_object_size` must be positive")
+	}
+
+	if c.MaxObjectAge.Raw() <= 0 {
+		return nil, fmt.Errorf("`max_object_age` must be positive")
+	}
+
+	if c.PartSize < minPartSize {
+		return nil, fmt.Errorf("`part_size` must be at least 5MiB")
+	}
+
+	if (c.AccessKeyID == "") != (c.SecretAccessKey == "") {
+		return nil, fmt.Errorf("'access_key_id' and 'secret_access_key' must be set together")
+	}
+
+	keyPrefix, err := c.KeyPrefix.Build()
+	if err != nil {
+		return nil, errors.Wrap(err, "build key_prefix")
+	}
+
+	awsConfig := aws.NewConfig().
+		WithRegion(c.Region).
+		WithS3ForcePathStyle(c.ForcePathStyle)
+	if c.Endpoint != "" {
+		awsConfig = awsConfig.WithEndpoint(c.Endpoint)
+	}
+	if c.AccessKeyID != "" {
+		awsConfig = awsConfig.WithCredentials(credentials.NewStaticCredentials(c.AccessKeyID, c.SecretAccessKey, ""))
+	}
+
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "create aws session")
+	}
+
+	uploader := s3manager.NewUploader(sess, func(u *s3manager.Uploader) {
+		u.PartSize = int64(c.PartSize)
+	})
+
+	buffer, err := c.BufferConfig.Build(bc, c.ID())
+	if err != nil {
+		return nil, err
+	}
+
+	flusher := c.FlusherConfig.Build(bc.Logger.SugaredLogger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s3Output := &S3Output{
+		OutputOperator:   outputOperator,
+		buffer:           buffer,
+		flusher:          flusher,
+		uploader:         uploader,
+		bucket:           c.Bucket,
+		keyPrefix:        keyPrefix,
+		encoder:          encoder,
+		encodingType:     c.Encoding.Type,
+		compression:      c.Compression,
+		maxObjectSize:    int64(c.MaxObjectSize),
+		maxObjectAge:     c.MaxObjectAge.Raw(),
+		stagingDirectory: c.StagingDirectory,
+		objects:          make(map[string]*stagedObject),
+		ctx:              ctx,
+		cancel:           cancel,
+	}
+
+	return []operator.Operator{s3Output}, nil
+}
+
+// S3Output is an operator that archives entries in objects in s3
+type S3Output struct {
+	helper.OutputOperator
+	buffer   buffer.Buffer
+	flusher  *flusher.Flusher
+	uploader *s3manager.Uploader
+
+	bucket           string
+	keyPrefix        *helper.ExprString
+	encoder          helper.Encoder
+	encodingType     string
+	compression      string
+	maxObjectSize    int64
+	maxObjectAge     time.Duration
+	stagingDirectory string
+
+	// objects are the objects accepting entries, by key prefix
+	objects    map[string]*stagedObject
+	objectsMux sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Start will upload objects left by a previous run, and begin accepting entries
+func (s *S3Output) Start() error {
+	if err := os.MkdirAll(s.stagingDirectory, 0700); err != nil {
+		return errors.Wrap(err, "create staging directory")
+	}
+
+	recovered, err := recoverStagedObjects(s.stagingDirectory)
+	if err != nil {
+		return errors.Wrap(err, "recover staged objects")
+	}
+
+	s.wg.Add(3)
+	go func() {
+		defer s.wg.Done()
+		for _, o := range recovered {
+			s.upload(o)
+		}
+	}()
+	go func() {
+		defer s.wg.Done()
+		s.feedObjects(s.ctx)
+	}()
+	go func() {
+		defer s.wg.Done()
+		s.sealExpiredObjects(s.ctx)
+	}()
+
+	return nil
+}
+
+// Stop will stop accepting entries. Objects that have not been uploaded
+// remain in the staging directory, and are uploaded on the next start.
+func (s *S3Output) Stop() error {
+	s.cancel()
+	s.wg.Wait()
+	s.flusher.Stop()
+
+	s.objectsMux.Lock()
+	for _, o := range s.objects {
+		if err := o.close(); err != nil {
+			s.Errorw("Failed to close staged object", zap.Error(err))
+		}
+	}
+	s.objects = make(map[string]*stagedObject)
+	s.objectsMux.Unlock()
+
+	return s.buffer.Close()
+}
+
+// Process adds an entry to the output's buffer
+func (s *S3Output) Process(ctx context.Context, entry *entry.Entry) error {
+	return s.buffer.Add(ctx, entry)
+}
+
+// feedObjects will append the entries of each chunk to the staged objects
+func (s *S3Output) feedObjects(ctx context.Context) {
+	for {
+		entries, clearer, err := s.buffer.ReadChunk(ctx)
+		if err != nil && err == context.Canceled {
+			return
+		} else if err != nil {
+			s.Errorw("Failed to read chunk", zap.Error(err))
+			continue
+		}
+
+		// Groups that have been staged are skipped when staging is retried
+		groups := s.groupEntries(entries)
+		next := 0
+		for {
+			staged, err := s.appendEntries(groups[next:])
+			next += staged
+			if err == nil {
+				break
+			}
+
+			s.Errorw("Failed to stage entries. Waiting before retry", zap.Error(err), "wait_time", stagingRetryInterval)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(stagingRetryInterval):
+			}
+		}
+
+		if err := clearer.MarkAllAsFlushed(); err != nil {
+			s.Errorw("Failed to mark entries as flushed", zap.Error(err))
+		}
+	}
+}
+
+// entryGroup is a group of entries that share a key prefix
+type entryGroup struct {
+	prefix  string
+	entries []*entry.Entry
+}
+
+// groupEntries will group entries by key prefix, in the order each prefix is first seen
+func (s *S3Output) groupEntries(entries []*entry.Entry) []*entryGroup {
+	groups := make([]*entryGroup, 0)
+	groupsByPrefix := make(map[string]*entryGroup)
+	for _, e := range entries {
+		prefix, err := s.renderKeyPrefix(e)
+		if err != nil {
+			s.Errorw("Failed to render key prefix", zap.Error(err))
+			continue
+		}
+
+		g, ok := groupsByPrefix[prefix]
+		if !ok {
+			g = &entryGroup{prefix: prefix}
+			groupsByPrefix[prefix] = g
+			groups = append(groups, g)
+		}
+		g.entries = append(g.entries, e)
+	}
+	return groups
+}
+
+// appendEntries will append each group to the object of its key prefix. It returns
+// the number of groups that were staged, so a retry can continue with the next one.
+func (s *S3Output) appendEntries(groups []*entryGroup) (int, error) {
+	sealed := make([]*stagedObject, 0)
+	defer func() {
+		for _, o := range sealed {
+			s.upload(o)
+		}
+	}()
+
+	s.objectsMux.Lock()
+	defer s.objectsMux.Unlock()
+
+	for i, g := range groups {
+		data, err := encodeEntries(s.encoder, g.entries)
+		if err != nil {
+			s.Errorw("Failed to encode entries", zap.Error(err))
+			continue
+		}
+
+		o, ok := s.objects[g.prefix]
+		if !ok {
+			o, err = newStagedObject(s.stagingDirectory, g.prefix, objectMeta{
+				Encoding:    s.encodingType,
+				ContentType: contentType(s.encodingType, s.encoder),
+				Compression: s.compression,
+				Created:     time.Now(),
+			})
+			if err != nil {
+				return i, err
+			}
+			s.objects[g.prefix] = o
+		}
+
+		if err := o.append(data); err != nil {
+			return i, errors.Wrap(err, "append to staged object")
+		}
+
+		if o.size >= s.maxObjectSize {
+			delete(s.objects, g.prefix)
+			sealed = append(sealed, o)
+		}
+	}
+
+	return len(groups), nil
+}
+
+func (s *S3Output) renderKeyPrefix(e *entry.Entry) (string, error) {
+	env := helper.GetExprEnv(e)
+	defer helper.PutExprEnv(env)
+	return s.keyPrefix.Render(env)
+}
+
+// sealExpiredObjects will upload objects once they reach the max object age
+func (s *S3Output) sealExpiredObjects(ctx context.Context) {
+	interval := time.Second
+	if s.maxObjectAge < 2*interval {
+		interval = s.maxObjectAge / 2
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			sealed := make([]*stagedObject, 0)
+			s.objectsMux.Lock()
+			for prefix, o := range s.objects {
+				if now.Sub(o.Created) >= s.maxObjectAge {
+					delete(s.objects, prefix)
+					sealed = append(sealed, o)
+				}
+			}
+			s.objectsMux.Unlock()
+
+			for _, o := range sealed {
+				s.upload(o)
+			}
+		}
+	}
+}
+
+// upload will upload an object, retrying until it succeeds or the flusher gives up.
+// Objects that are not uploaded remain in the staging directory.
+func (s *S3Output) upload(o *stagedObject) {
+	if err := o.close(); err != nil {
+		s.Errorw("Failed to close staged object", zap.Error(err))
+	}
+
+	s.flusher.Do(func(ctx context.Context) error {
+		if err := s.uploadObject(ctx, o); err != nil {
+			s.Warnw("Failed to upload object", zap.String("key", o.Key), zap.Error(err))
+			return err
+		}
+
+		if err := o.remove(); err != nil {
+			s.Errorw("Failed to remove staged object", zap.Error(err))
+		}
+		return nil
+	})
+}
+
+// uploadObject will compress the data of an object as it is uploaded. Objects larger
+// than the part size are sent in a multipart upload.
+func (s *S3Output) uploadObject(ctx context.Context, o *stagedObject) error {
+	f, err := os.Open(o.dataPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pr, pw := io.Pipe()
+	go func() {
+		w, err := compressTo(o.Compression, pw)
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		if _, err := io.Copy(w, f); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		pw.CloseWithError(w.Close())
+	}()
+
+	input := &s3manager.UploadInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(o.Key),
+		Body:        pr,
+		ContentType: aws.String(objectContentType(o.objectMeta)),
+	}
+
+	_, err = s.uploader.UploadWithContext(ctx, input)
+	// Unblock the compressing goroutine if the upload stopped reading
+	pr.CloseWithError(io.ErrClosedPipe)
+	return err
+}
+
+// objectContentType returns the content type of an uploaded object
+func objectContentType(meta objectMeta) string {
+	switch meta.Compression {
+	case compressionGzip:
+		return "application/gzip"
+	case compressionZstd:
+		return "application/zstd"
+	default:
+		return meta.ContentType
+	}
+}
@@ -0,0 +1,397 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/buffer"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/builtin/output/otlp"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"github.com/opentelemetry/opentelemetry-log-collection/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type storedObject struct {
+	body        []byte
+	contentType string
+	multipart   bool
+}
+
+// testStore is a minimal S3 compatible server that supports path style
+// object uploads and multipart uploads
+type testStore struct {
+	sync.Mutex
+	t       *testing.T
+	objects map[string]storedObject
+	uploads map[string]map[int][]byte
+	created chan string
+}
+
+func newTestStore(t *testing.T) (*testStore, string) {
+	s := &testStore{
+		t:       t,
+		objects: make(map[string]storedObject),
+		uploads: make(map[string]map[int][]byte),
+		created: make(chan string, 10),
+	}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return s, ts.URL
+}
+
+func (s *testStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	key := r.URL.Path
+	query := r.URL.Query()
+	_, initiate := query["uploads"]
+	body, err := ioutil.ReadAll(r.Body)
+	require.NoError(s.t, err)
+
+	switch {
+	case r.Method == http.MethodPost && initiate:
+		uploadID := fmt.Sprintf("upload-%d", len(s.uploads))
+		s.uploads[uploadID] = make(map[int][]byte)
+		fmt.Fprintf(w, `<InitiateMultipartUploadResult><Bucket>bucket</Bucket><Key>%s</Key><UploadId>%s</UploadId></InitiateMultipartUploadResult>`, key, uploadID)
+	case r.Method == http.MethodPut && query.Get("uploadId") != "":
+		var partNumber int
+		fmt.Sscanf(query.Get("partNumber"), "%d", &partNumber)
+		s.uploads[query.Get("uploadId")][partNumber] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"%d"`, partNumber))
+	case r.Method == http.MethodPost && query.Get("uploadId") != "":
+		parts := s.uploads[query.Get("uploadId")]
+		numbers := make([]int, 0, len(parts))
+		for n := range parts {
+			numbers = append(numbers, n)
+		}
+		sort.Ints(numbers)
+
+		var buf bytes.Buffer
+		for _, n := range numbers {
+			buf.Write(parts[n])
+		}
+		s.store(key, storedObject{body: buf.Bytes(), multipart: true})
+		fmt.Fprintf(w, `<CompleteMultipartUploadResult><Key>%s</Key><ETag>"etag"</ETag></CompleteMultipartUploadResult>`, key)
+	case r.Method == http.MethodPut:
+		s.store(key, storedObject{body: body, contentType: r.Header.Get("Content-Type")})
+		w.Header().Set("ETag", `"etag"`)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func (s *testStore) store(key string, o storedObject) {
+	s.objects[key] = o
+	s.created <- key
+}
+
+func (s *testStore) expectObject(t *testing.T) (string, storedObject) {
+	select {
+	case key := <-s.created:
+		s.Lock()
+		defer s.Unlock()
+		return key, s.objects[key]
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "Timed out waiting for object")
+	}
+	return "", storedObject{}
+}
+
+func (s *testStore) expectNoObject(t *testing.T) {
+	select {
+	case key := <-s.created:
+		require.FailNow(t, "Unexpected object", key)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func newTestConfig(t *testing.T, endpoint string) *S3OutputConfig {
+	cfg := NewS3OutputConfig("test")
+	cfg.Bucket = "bucket"
+	cfg.Endpoint = endpoint
+	cfg.ForcePathStyle = true
+	cfg.AccessKeyID = "key"
+	cfg.SecretAccessKey = "secret"
+	cfg.StagingDirectory = testutil.NewTempDir(t)
+	cfg.BufferConfig.Builder.(*buffer.MemoryBufferConfig).MaxChunkDelay = helper.NewDuration(50 * time.Millisecond)
+	return cfg
+}
+
+func startOutput(t *testing.T, cfg *S3OutputConfig, entries ...*entry.Entry) *S3Output {
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	op := ops[0].(*S3Output)
+	require.NoError(t, op.Start())
+	t.Cleanup(func() { require.NoError(t, op.Stop()) })
+
+	for _, e := range entries {
+		require.NoError(t, op.Process(context.Background(), e))
+	}
+	return op
+}
+
+func newEntry(record interface{}, labels map[string]string) *entry.Entry {
+	e := entry.New()
+	e.Timestamp = time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC)
+	e.Record = record
+	e.Labels = labels
+	return e
+}
+
+func gunzip(t *testing.T, b []byte) string {
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	require.NoError(t, err)
+	out, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	return string(out)
+}
+
+func TestS3OutputBuild(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(*S3OutputConfig)
+	}{
+		{"MissingBucket", func(c *S3OutputConfig) { c.Bucket = "" }},
+		{"MissingStagingDirectory", func(c *S3OutputConfig) { c.StagingDirectory = "" }},
+		{"InvalidEncoding", func(c *S3OutputConfig) { c.Encoding = helper.NewEncodingConfig("csv") }},
+		{"InvalidCompression", func(c *S3OutputConfig) { c.Compression = "bzip2" }},
+		{"ZeroMaxObjectSize", func(c *S3OutputConfig) { c.MaxObjectSize = 0 }},
+		{"ZeroMaxObjectAge", func(c *S3OutputConfig) { c.MaxObjectAge = helper.NewDuration(0) }},
+		{"SmallPartSize", func(c *S3OutputConfig) { c.PartSize = 1024 }},
+		{"AccessKeyWithoutSecret", func(c *S3OutputConfig) { c.SecretAccessKey = "" }},
+		{"InvalidKeyPrefix", func(c *S3OutputConfig) { c.KeyPrefix = "EXPR($labels.)" }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := newTestConfig(t, "http://localhost:9000")
+			tc.modify(cfg)
+			_, err := cfg.Build(testutil.NewBuildContext(t))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestS3OutputMaxObjectAge(t *testing.T) {
+	store, endpoint := newTestStore(t)
+	cfg := newTestConfig(t, endpoint)
+	cfg.KeyPrefix = `logs/EXPR($labels.app)/EXPR($timestamp.Format("2006/01/02"))/`
+	cfg.MaxObjectAge = helper.NewDuration(200 * time.Millisecond)
+
+	startOutput(t, cfg,
+		newEntry("first", map[string]string{"app": "web"}),
+		newEntry("second", map[string]string{"app": "db"}),
+		newEntry(map[string]interface{}{"key": "<value>"}, map[string]string{"app": "web"}),
+	)
+
+	objects := make(map[string]storedObject)
+	for i := 0; i < 2; i++ {
+		key, object := store.expectObject(t)
+		objects[key] = object
+	}
+	store.expectNoObject(t)
+
+	for key, object := range objects {
+		require.Regexp(t, `^/bucket/logs/(web|db)/2021/02/03/\d{8}T\d{6}Z_[0-9a-f]{16}\.ndjson\.gz$`, key)
+		require.Equal(t, "application/gzip", object.contentType)
+		require.False(t, object.multipart)
+
+		if strings.Contains(key, "/web/") {
+			expected := `{"timestamp":"2021-02-03T04:05:06Z","severity":0,"labels":{"app":"web"},"record":"first"}` + "\n" +
+				`{"timestamp":"2021-02-03T04:05:06Z","severity":0,"labels":{"app":"web"},"record":{"key":"<value>"}}` + "\n"
+			require.Equal(t, expected, gunzip(t, object.body))
+		} else {
+			expected := `{"timestamp":"2021-02-03T04:05:06Z","severity":0,"labels":{"app":"db"},"record":"second"}` + "\n"
+			require.Equal(t, expected, gunzip(t, object.body))
+		}
+	}
+}
+
+func TestS3OutputMaxObjectSize(t *testing.T) {
+	store, endpoint := newTestStore(t)
+	cfg := newTestConfig(t, endpoint)
+	cfg.Compression = compressionNone
+	cfg.MaxObjectSize = 1
+	cfg.MaxObjectAge = helper.NewDuration(time.Hour)
+
+	op := startOutput(t, cfg, newEntry("first", nil))
+	key, object := store.expectObject(t)
+	require.True(t, strings.HasSuffix(key, ".ndjson"))
+	require.Equal(t, "application/x-ndjson", object.contentType)
+	require.Contains(t, string(object.body), `"record":"first"`)
+
+	require.NoError(t, op.Process(context.Background(), newEntry("second", nil)))
+	_, object = store.expectObject(t)
+	require.Contains(t, string(object.body), `"record":"second"`)
+}
+
+func TestS3OutputMultipart(t *testing.T) {
+	store, endpoint := newTestStore(t)
+	cfg := newTestConfig(t, endpoint)
+	cfg.Compression = compressionNone
+	cfg.MaxObjectSize = 1
+
+	record := strings.Repeat("a", 6*1024*1024)
+	startOutput(t, cfg, newEntry(record, nil))
+
+	_, object := store.expectObject(t)
+	require.True(t, object.multipart)
+	require.Contains(t, string(object.body), record)
+}
+
+func TestS3OutputOTLP(t *testing.T) {
+	store, endpoint := newTestStore(t)
+	cfg := newTestConfig(t, endpoint)
+	cfg.Encoding = helper.NewEncodingConfig("otlp")
+	cfg.Compression = compressionZstd
+	cfg.MaxObjectSize = 1
+
+	e := newEntry("message", map[string]string{"app": "web"})
+	startOutput(t, cfg, e)
+
+	key, object := store.expectObject(t)
+	require.True(t, strings.HasSuffix(key, ".pb.zst"))
+	require.Equal(t, "application/zstd", object.contentType)
+
+	decoder, err := zstd.NewReader(nil)
+	require.NoError(t, err)
+	defer decoder.Close()
+	body, err := decoder.DecodeAll(object.body, nil)
+	require.NoError(t, err)
+
+	expected, err := otlp.Convert([]*entry.Entry{e}).ToOtlpProtoBytes()
+	require.NoError(t, err)
+	require.Equal(t, expected, body)
+}
+
+func TestS3OutputRecoversStagedObjects(t *testing.T) {
+	store, endpoint := newTestStore(t)
+	cfg := newTestConfig(t, endpoint)
+	cfg.MaxObjectAge = helper.NewDuration(time.Hour)
+
+	// An object left by a previous run
+	staged, err := newStagedObject(cfg.StagingDirectory, "previous/", objectMeta{
+		Encoding:    "ndjson",
+		ContentType: "application/x-ndjson",
+		Compression: compressionNone,
+		Created:     time.Now(),
+	})
+	require.NoError(t, err)
+	require.NoError(t, staged.append([]byte("staged\n")))
+	require.NoError(t, staged.close())
+
+	// A data file without metadata has no entries
+	orphan := filepath.Join(cfg.StagingDirectory, "orphan"+dataExtension)
+	require.NoError(t, ioutil.WriteFile(orphan, nil, 0600))
+
+	startOutput(t, cfg)
+
+	key, object := store.expectObject(t)
+	require.True(t, strings.HasPrefix(key, "/bucket/previous/"))
+	require.True(t, strings.HasSuffix(key, ".ndjson"))
+	require.Equal(t, "staged\n", string(object.body))
+
+	require.Eventually(t, func() bool {
+		files, err := ioutil.ReadDir(cfg.StagingDirectory)
+		require.NoError(t, err)
+		return len(files) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestS3OutputKeepsObjectsOnStop(t *testing.T) {
+	_, endpoint := newTestStore(t)
+	cfg := newTestConfig(t, endpoint)
+	cfg.MaxObjectAge = helper.NewDuration(time.Hour)
+
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	op := ops[0]
+	require.NoError(t, op.Start())
+	require.NoError(t, op.Process(context.Background(), newEntry("partial", nil)))
+
+	require.Eventually(t, func() bool {
+		matches, err := filepath.Glob(filepath.Join(cfg.StagingDirectory, "*"+dataExtension))
+		require.NoError(t, err)
+		if len(matches) != 1 {
+			return false
+		}
+		info, err := os.Stat(matches[0])
+		require.NoError(t, err)
+		return info.Size() > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, op.Stop())
+
+	recovered, err := recoverStagedObjects(cfg.StagingDirectory)
+	require.NoError(t, err)
+	require.Len(t, recovered, 1)
+	data, err := ioutil.ReadFile(recovered[0].dataPath)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"record":"partial"`)
+}
+
+func TestS3OutputAppendEntriesProgress(t *testing.T) {
+	_, endpoint := newTestStore(t)
+	cfg := newTestConfig(t, endpoint)
+
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	op := ops[0].(*S3Output)
+
+	groups := []*entryGroup{
+		{prefix: "first/", entries: []*entry.Entry{newEntry("first", nil)}},
+		{prefix: "second/", entries: []*entry.Entry{newEntry("second", nil)}},
+	}
+
+	staged, err := op.appendEntries(groups[:1])
+	require.NoError(t, err)
+	require.Equal(t, 1, staged)
+	size := op.objects["first/"].size
+
+	// The second object cannot be created without the staging directory
+	require.NoError(t, os.RemoveAll(cfg.StagingDirectory))
+	staged, err = op.appendEntries(groups)
+	require.Error(t, err)
+	require.Equal(t, 1, staged)
+	require.Equal(t, 2*size, op.objects["first/"].size)
+
+	// Only the group that failed is staged again
+	require.NoError(t, os.MkdirAll(cfg.StagingDirectory, 0700))
+	staged, err = op.appendEntries(groups[staged:])
+	require.NoError(t, err)
+	require.Equal(t, 1, staged)
+	require.Equal(t, 2*size, op.objects["first/"].size)
+	require.Contains(t, op.objects, "second/")
+
+	for _, o := range op.objects {
+		require.NoError(t, o.remove())
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	dataExtension = ".data"
+	metaExtension = ".json"
+)
+
+// objectMeta describes a staged object, so it can be uploaded after a restart
+type objectMeta struct {
+	Key         string    `json:"key"`
+	Encoding    string    `json:"encoding"`
+	ContentType string    `json:"content_type"`
+	Compression string    `json:"compression"`
+	Created     time.Time `json:"created"`
+}
+
+// stagedObject is an object whose data is accumulated in the staging directory
+// until it is uploaded
+type stagedObject struct {
+	objectMeta
+	dataPath string
+	metaPath string
+	file     *os.File
+	size     int64
+}
+
+// newStagedObject will create the files of a new object in the staging directory.
+// The key of the object is the key prefix followed by a unique name.
+func newStagedObject(dir, keyPrefix string, meta objectMeta) (*stagedObject, error) {
+	id, err := newObjectID()
+	if err != nil {
+		return nil, fmt.Errorf("create object id: %s", err)
+	}
+	meta.Key = objectKey(keyPrefix, meta, id)
+
+	o := &stagedObject{
+		objectMeta: meta,
+		dataPath:   filepath.Join(dir, id+dataExtension),
+		metaPath:   filepath.Join(dir, id+metaExtension),
+	}
+
+	o.file, err = os.OpenFile(o.dataPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("create staging file: %s", err)
+	}
+
+	metaBytes, err := json.Marshal(meta)
+	if err != nil {
+		o.remove()
+		return nil, fmt.Errorf("encode object metadata: %s", err)
+	}
+
+	if err := ioutil.WriteFile(o.metaPath, metaBytes, 0600); err != nil {
+		o.remove()
+		return nil, fmt.Errorf("write object metadata: %s", err)
+	}
+
+	return o, nil
+}
+
+// append will write data to the object and sync it to disk. If the data
+// is not written completely, the object is truncated to its previous size,
+// so that the data can be appended again.
+func (o *stagedObject) append(data []byte) error {
+	_, err := o.file.Write(data)
+	if err == nil {
+		err = o.file.Sync()
+	}
+	if err != nil {
+		if truncateErr := o.file.Truncate(o.size); truncateErr != nil {
+			return fmt.Errorf("%s, and failed to truncate: %s", err, truncateErr)
+		}
+		return err
+	}
+
+	o.size += int64(len(data))
+	return nil
+}
+
+// close will close the data file of the object, so it is ready for upload
+func (o *stagedObject) close() error {
+	if o.file == nil {
+		return nil
+	}
+	err := o.file.Close()
+	o.file = nil
+	return err
+}
+
+// remove will delete the files of the object
+func (o *stagedObject) remove() error {
+	_ = o.close()
+	if err := os.Remove(o.dataPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(o.metaPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// recoverStagedObjects will find the objects left in the staging directory by a previous run.
+// Files that do not describe a complete object are removed.
+func recoverStagedObjects(dir string) ([]*stagedObject, error) {
+	metaPaths, err := filepath.Glob(filepath.Join(dir, "*"+metaExtension))
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]*stagedObject, 0, len(metaPaths))
+	for _, metaPath := range metaPaths {
+		o := &stagedObject{
+			metaPath: metaPath,
+			dataPath: strings.TrimSuffix(metaPath, metaExtension) + dataExtension,
+		}
+
+		metaBytes, err := ioutil.ReadFile(metaPath)
+		if err != nil {
+			return nil, err
+		}
+
+		info, err := os.Stat(o.dataPath)
+		if err != nil || json.Unmarshal(metaBytes, &o.objectMeta) != nil {
+			_ = o.remove()
+			continue
+		}
+		o.size = info.Size()
+		objects = append(objects, o)
+	}
+
+	// A data file without metadata was created just before a crash, so it has no entries
+	dataPaths, err := filepath.Glob(filepath.Join(dir, "*"+dataExtension))
+	if err != nil {
+		return nil, err
+	}
+	for _, dataPath := range dataPaths {
+		metaPath := strings.TrimSuffix(dataPath, dataExtension) + metaExtension
+		if _, err := os.Stat(metaPath); os.IsNotExist(err) {
+			_ = os.Remove(dataPath)
+		}
+	}
+
+	return objects, nil
+}
+
+// objectKey will create the key of an object from its prefix, creation time and id
+func objectKey(keyPrefix string, meta objectMeta, id string) string {
+	var b strings.Builder
+	b.WriteString(keyPrefix)
+	b.WriteString(meta.Created.UTC().Format("20060102T150405Z"))
+	b.WriteByte('_')
+	b.WriteString(id)
+
+	b.WriteString(extension(meta.Encoding))
+
+	switch meta.Compression {
+	case compressionGzip:
+		b.WriteString(".gz")
+	case compressionZstd:
+		b.WriteString(".zst")
+	}
+	return b.String()
+}
+
+// newObjectID will create a random id for an object
+func newObjectID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,95 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package splunkhec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/errors"
+	"go.uber.org/zap"
+)
+
+// ackRequest is the body of a request for the status of acknowledgements
+type ackRequest struct {
+	Acks []int64 `json:"acks"`
+}
+
+// ackResponse reports whether each requested acknowledgement has been indexed
+type ackResponse struct {
+	Acks map[string]bool `json:"acks"`
+}
+
+// waitForAck will poll the http event collector until a batch is indexed, or the ack timeout expires
+func (s *SplunkHECOutput) waitForAck(ctx context.Context, ackID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, s.ackTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(s.ackPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return errors.NewError("timed out waiting for acknowledgement", "",
+				"ack_id", strconv.FormatInt(ackID, 10),
+			)
+		case <-ticker.C:
+			acked, err := s.queryAck(ctx, ackID)
+			if err != nil {
+				s.Debugw("Failed to query acknowledgement", zap.Error(err))
+				continue
+			}
+			if acked {
+				return nil
+			}
+		}
+	}
+}
+
+// queryAck will return whether a batch has been indexed
+func (s *SplunkHECOutput) queryAck(ctx context.Context, ackID int64) (bool, error) {
+	body, err := json.Marshal(ackRequest{Acks: []int64{ackID}})
+	if err != nil {
+		return false, err
+	}
+
+	req, err := s.newRequest(ctx, s.ackURL, body)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return false, errors.Wrap(err, "send request")
+	}
+	defer res.Body.Close()
+
+	resBody, _ := ioutil.ReadAll(io.LimitReader(res.Body, 64*1024))
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return false, fmt.Errorf("non-success status code %d: %s", res.StatusCode, resBody)
+	}
+
+	var ackRes ackResponse
+	if err := json.Unmarshal(resBody, &ackRes); err != nil {
+		return false, errors.Wrap(err, "decode response")
+	}
+	return ackRes.Acks[strconv.FormatInt(ackID, 10)], nil
+}
@@ -0,0 +1,89 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package splunkhec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"go.uber.org/zap"
+)
+
+// hecEvent is the json format of an event sent to the http event collector
+type hecEvent struct {
+	Time       json.Number       `json:"time"`
+	Host       string            `json:"host,omitempty"`
+	Source     string            `json:"source,omitempty"`
+	SourceType string            `json:"sourcetype,omitempty"`
+	Index      string            `json:"index,omitempty"`
+	Event      interface{}       `json:"event"`
+	Fields     map[string]string `json:"fields,omitempty"`
+}
+
+// metadataConfig is a metadata value of an event, read from a field of the
+// entry or set to a static value
+type metadataConfig struct {
+	field *entry.Field
+	value string
+}
+
+// find will return the metadata value of an entry
+func (m metadataConfig) find(e *entry.Entry) string {
+	if m.field == nil {
+		return m.value
+	}
+
+	var value string
+	if err := e.Read(*m.field, &value); err != nil {
+		return m.value
+	}
+	return value
+}
+
+// formatTime will format the timestamp of an entry as epoch seconds with millisecond precision
+func formatTime(e *entry.Entry) json.Number {
+	ms := e.Timestamp.UnixNano() / 1e6
+	return json.Number(fmt.Sprintf("%d.%03d", ms/1000, ms%1000))
+}
+
+// newEvent will map an entry to an event
+func (s *SplunkHECOutput) newEvent(e *entry.Entry) *hecEvent {
+	return &hecEvent{
+		Time:       formatTime(e),
+		Host:       s.host.find(e),
+		Source:     s.source.find(e),
+		SourceType: s.sourceType.find(e),
+		Index:      s.index.find(e),
+		Event:      e.Record,
+		Fields:     e.Labels,
+	}
+}
+
+// encodeEvents will encode entries as a body of concatenated events.
+// Entries that can not be encoded are dropped.
+func (s *SplunkHECOutput) encodeEvents(entries []*entry.Entry) []byte {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	for _, e := range entries {
+		// The encoder writes nothing if an event fails to encode
+		if err := enc.Encode(s.newEvent(e)); err != nil {
+			s.Errorw("Failed to encode entry", zap.Error(err))
+		}
+	}
+	return buf.Bytes()
+}
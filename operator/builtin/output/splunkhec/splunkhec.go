@@ -0,0 +1,386 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package splunkhec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/errors"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/buffer"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/flusher"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"go.uber.org/zap"
+)
+
+func init() {
+	operator.Register("splunk_hec_output", func() operator.Builder { return NewSplunkHECOutputConfig("") })
+}
+
+const (
+	eventPath = "/services/collector/event"
+	ackPath   = "/services/collector/ack"
+
+	compressionNone = "none"
+	compressionGzip = "gzip"
+)
+
+// NewSplunkHECOutputConfig creates a new splunk hec output config with default values
+func NewSplunkHECOutputConfig(operatorID string) *SplunkHECOutputConfig {
+	return &SplunkHECOutputConfig{
+		OutputConfig:    helper.NewOutputConfig(operatorID, "splunk_hec_output"),
+		BufferConfig:    buffer.NewConfig(),
+		FlusherConfig:   flusher.NewConfig(),
+		Compression:     compressionNone,
+		AckPollInterval: helper.NewDuration(time.Second),
+		AckTimeout:      helper.NewDuration(time.Minute),
+		Timeout:         helper.NewDuration(30 * time.Second),
+	}
+}
+
+// SplunkHECOutputConfig is the configuration of a splunk hec output operator
+type SplunkHECOutputConfig struct {
+	helper.OutputConfig `yaml:",inline"`
+	BufferConfig        buffer.Config  `json:"buffer"  yaml:"buffer"`
+	FlusherConfig       flusher.Config `json:"flusher" yaml:"flusher"`
+
+	Endpoint        string                  `json:"endpoint"                    yaml:"endpoint"`
+	Token           string                  `json:"token"                       yaml:"token"`
+	Host            string                  `json:"host,omitempty"              yaml:"host,omitempty"`
+	HostField       *entry.Field            `json:"host_field,omitempty"        yaml:"host_field,omitempty"`
+	Source          string                  `json:"source,omitempty"            yaml:"source,omitempty"`
+	SourceField     *entry.Field            `json:"source_field,omitempty"      yaml:"source_field,omitempty"`
+	SourceType      string                  `json:"sourcetype,omitempty"        yaml:"sourcetype,omitempty"`
+	SourceTypeField *entry.Field            `json:"sourcetype_field,omitempty"  yaml:"sourcetype_field,omitempty"`
+	Index           string                  `json:"index,omitempty"             yaml:"index,omitempty"`
+	IndexField      *entry.Field            `json:"index_field,omitempty"       yaml:"index_field,omitempty"`
+	Compression     string                  `json:"compression,omitempty"       yaml:"compression,omitempty"`
+	UseAck          bool                    `json:"use_ack,omitempty"           yaml:"use_ack,omitempty"`
+	Channel         string                  `json:"channel,omitempty"           yaml:"channel,omitempty"`
+	AckPollInterval helper.Duration         `json:"ack_poll_interval,omitempty" yaml:"ack_poll_interval,omitempty"`
+	AckTimeout      helper.Duration         `json:"ack_timeout,omitempty"       yaml:"ack_timeout,omitempty"`
+	TLS             *helper.TLSClientConfig `json:"tls,omitempty"               yaml:"tls,omitempty"`
+	Timeout         helper.Duration         `json:"timeout,omitempty"           yaml:"timeout,omitempty"`
+}
+
+// Build will build a splunk hec output operator
+func (c SplunkHECOutputConfig) Build(bc operator.BuildContext) ([]operator.Operator, error) {
+	outputOperator, err := c.OutputConfig.Build(bc)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Endpoint == "" {
+		return nil, errors.NewError("missing required parameter 'endpoint'", "")
+	}
+
+	if c.Token == "" {
+		return nil, errors.NewError("missing required parameter 'token'", "")
+	}
+
+	switch c.Compression {
+	case compressionNone, compressionGzip:
+	default:
+		return nil, fmt.Errorf("invalid compression '%s'", c.Compression)
+	}
+
+	channel := c.Channel
+	if c.UseAck {
+		if c.AckPollInterval.Raw() <= 0 {
+			return nil, fmt.Errorf("`ack_poll_interval` must be positive")
+		}
+		if c.AckTimeout.Raw() <= 0 {
+			return nil, fmt.Errorf("`ack_timeout` must be positive")
+		}
+
+		// Acknowledgements are tracked per channel, so one is required
+		if channel == "" {
+			channel, err = newChannel()
+			if err != nil {
+				return nil, errors.Wrap(err, "create channel")
+			}
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if c.TLS != nil {
+		tlsConfig, err := c.TLS.Build()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	buffer, err := c.BufferConfig.Build(bc, c.ID())
+	if err != nil {
+		return nil, err
+	}
+
+	flusher := c.FlusherConfig.Build(bc.Logger.SugaredLogger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	endpoint := strings.TrimSuffix(c.Endpoint, "/")
+	splunkOutput := &SplunkHECOutput{
+		OutputOperator: outputOperator,
+		buffer:         buffer,
+		flusher:        flusher,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   c.Timeout.Raw(),
+		},
+		eventURL:        endpoint + eventPath,
+		ackURL:          endpoint + ackPath,
+		token:           c.Token,
+		host:            metadataConfig{c.HostField, c.Host},
+		source:          metadataConfig{c.SourceField, c.Source},
+		sourceType:      metadataConfig{c.SourceTypeField, c.SourceType},
+		index:           metadataConfig{c.IndexField, c.Index},
+		compression:     c.Compression,
+		useAck:          c.UseAck,
+		channel:         channel,
+		ackPollInterval: c.AckPollInterval.Raw(),
+		ackTimeout:      c.AckTimeout.Raw(),
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+
+	return []operator.Operator{splunkOutput}, nil
+}
+
+// newChannel will create a random uuid to identify a channel
+func newChannel() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// SplunkHECOutput is an operator that sends entries to a splunk http event collector
+type SplunkHECOutput struct {
+	helper.OutputOperator
+	buffer  buffer.Buffer
+	flusher *flusher.Flusher
+	client  *http.Client
+
+	eventURL        string
+	ackURL          string
+	token           string
+	host            metadataConfig
+	source          metadataConfig
+	sourceType      metadataConfig
+	index           metadataConfig
+	compression     string
+	useAck          bool
+	channel         string
+	ackPollInterval time.Duration
+	ackTimeout      time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Start signals to the SplunkHECOutput to begin flushing
+func (s *SplunkHECOutput) Start() error {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.feedFlusher(s.ctx)
+	}()
+
+	return nil
+}
+
+// Stop tells the SplunkHECOutput to stop gracefully
+func (s *SplunkHECOutput) Stop() error {
+	s.cancel()
+	s.wg.Wait()
+	s.flusher.Stop()
+	s.client.CloseIdleConnections()
+	return s.buffer.Close()
+}
+
+// Process adds an entry to the output's buffer
+func (s *SplunkHECOutput) Process(ctx context.Context, entry *entry.Entry) error {
+	return s.buffer.Add(ctx, entry)
+}
+
+func (s *SplunkHECOutput) feedFlusher(ctx context.Context) {
+	for {
+		entries, clearer, err := s.buffer.ReadChunk(ctx)
+		if err != nil && err == context.Canceled {
+			return
+		} else if err != nil {
+			s.Errorw("Failed to read chunk", zap.Error(err))
+			continue
+		}
+
+		events := s.encodeEvents(entries)
+		if len(events) == 0 {
+			if err := clearer.MarkAllAsFlushed(); err != nil {
+				s.Errorw("Failed to mark entries as flushed", zap.Error(err))
+			}
+			continue
+		}
+
+		body, err := s.compress(events)
+		if err != nil {
+			s.Errorw("Failed to compress events", zap.Error(err))
+			// Drop the entries, since retrying would fail the same way
+			if err := clearer.MarkAllAsFlushed(); err != nil {
+				s.Errorw("Failed to mark entries as flushed", zap.Error(err))
+			}
+			continue
+		}
+
+		// A batch that has been sent is not sent again while its acknowledgement is pending
+		var ackID *int64
+		s.flusher.Do(func(ctx context.Context) error {
+			if ackID == nil {
+				id, retry, err := s.send(ctx, body)
+				if err != nil && retry {
+					return err
+				} else if err != nil {
+					s.Errorw("Failed to send entries, and will not retry", zap.Error(err))
+				} else if s.useAck {
+					ackID = &id
+				}
+			}
+
+			if ackID != nil {
+				if err := s.waitForAck(ctx, *ackID); err != nil {
+					// The batch is sent again when the flush is retried
+					ackID = nil
+					return err
+				}
+			}
+
+			if err := clearer.MarkAllAsFlushed(); err != nil {
+				s.Errorw("Failed to mark entries as flushed", zap.Error(err))
+			}
+			return nil
+		})
+	}
+}
+
+// compress will compress a request body if compression is configured
+func (s *SplunkHECOutput) compress(body []byte) ([]byte, error) {
+	if s.compression != compressionGzip {
+		return body, nil
+	}
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(body); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// hecResponse is the response of the http event collector
+type hecResponse struct {
+	Text  string `json:"text"`
+	Code  int    `json:"code"`
+	AckID *int64 `json:"ackId"`
+}
+
+// newRequest will create a request with the headers required by the http event collector
+func (s *SplunkHECOutput) newRequest(ctx context.Context, url string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "create request")
+	}
+
+	req.Header.Set("Authorization", "Splunk "+s.token)
+	req.Header.Set("Content-Type", "application/json")
+	if s.channel != "" {
+		req.Header.Set("X-Splunk-Request-Channel", s.channel)
+	}
+	return req, nil
+}
+
+// send will send a batch of events, and return the id used to acknowledge them.
+// If it fails, it reports whether the batch should be retried.
+func (s *SplunkHECOutput) send(ctx context.Context, body []byte) (ackID int64, retry bool, err error) {
+	req, err := s.newRequest(ctx, s.eventURL, body)
+	if err != nil {
+		return 0, false, err
+	}
+	if s.compression == compressionGzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return 0, true, errors.Wrap(err, "send request")
+	}
+	defer res.Body.Close()
+
+	resBody, _ := ioutil.ReadAll(io.LimitReader(res.Body, 64*1024))
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		err = errors.NewError("non-success status code", "",
+			"status", strconv.Itoa(res.StatusCode),
+			"body", strings.TrimSpace(string(resBody)),
+		)
+		return 0, isRetryable(res.StatusCode), err
+	}
+
+	if !s.useAck {
+		return 0, false, nil
+	}
+
+	var hecRes hecResponse
+	if err := json.Unmarshal(resBody, &hecRes); err != nil {
+		return 0, true, errors.Wrap(err, "decode response")
+	}
+	if hecRes.AckID == nil {
+		return 0, false, errors.NewError(
+			"response did not include an ack id",
+			"Ensure indexer acknowledgement is enabled for the token",
+		)
+	}
+	return *hecRes.AckID, false, nil
+}
+
+// isRetryable returns true if a request that failed with a status code should be retried
+func isRetryable(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,286 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package splunkhec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/buffer"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"github.com/opentelemetry/opentelemetry-log-collection/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type request struct {
+	header http.Header
+	body   []byte
+}
+
+// testCollector is a fake http event collector
+type testCollector struct {
+	sync.Mutex
+	t        *testing.T
+	events   chan request
+	statuses []int
+	nextAck  int64
+
+	// ackAfter is the number of queries before a batch is acknowledged.
+	// A negative value means batches are never acknowledged.
+	ackAfter int
+	queries  map[int64]int
+}
+
+func newTestCollector(t *testing.T, statuses ...int) (*testCollector, string) {
+	c := &testCollector{
+		t:        t,
+		events:   make(chan request, 10),
+		statuses: statuses,
+		queries:  make(map[int64]int),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(eventPath, c.handleEvent)
+	mux.HandleFunc(ackPath, c.handleAck)
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return c, ts.URL
+}
+
+func (c *testCollector) handleEvent(w http.ResponseWriter, r *http.Request) {
+	c.Lock()
+	defer c.Unlock()
+
+	body, err := ioutil.ReadAll(r.Body)
+	require.NoError(c.t, err)
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gr, err := gzip.NewReader(bytes.NewReader(body))
+		require.NoError(c.t, err)
+		body, err = ioutil.ReadAll(gr)
+		require.NoError(c.t, err)
+	}
+	c.events <- request{r.Header, body}
+
+	status := http.StatusOK
+	if len(c.statuses) > 0 {
+		status, c.statuses = c.statuses[0], c.statuses[1:]
+	}
+	w.WriteHeader(status)
+	if status != http.StatusOK {
+		fmt.Fprint(w, `{"text":"Error","code":8}`)
+		return
+	}
+
+	fmt.Fprintf(w, `{"text":"Success","code":0,"ackId":%d}`, c.nextAck)
+	c.nextAck++
+}
+
+func (c *testCollector) handleAck(w http.ResponseWriter, r *http.Request) {
+	c.Lock()
+	defer c.Unlock()
+
+	var req ackRequest
+	require.NoError(c.t, json.NewDecoder(r.Body).Decode(&req))
+	require.NotEmpty(c.t, r.Header.Get("X-Splunk-Request-Channel"))
+
+	acks := make(map[string]bool)
+	for _, id := range req.Acks {
+		c.queries[id]++
+		acks[fmt.Sprint(id)] = c.ackAfter >= 0 && c.queries[id] > c.ackAfter
+	}
+	require.NoError(c.t, json.NewEncoder(w).Encode(ackResponse{Acks: acks}))
+}
+
+func (c *testCollector) expectEvents(t *testing.T) request {
+	select {
+	case req := <-c.events:
+		return req
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "Timed out waiting for request")
+	}
+	return request{}
+}
+
+func (c *testCollector) expectNoEvents(t *testing.T) {
+	select {
+	case <-c.events:
+		require.FailNow(t, "Unexpected request")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func newTestConfig(endpoint string) *SplunkHECOutputConfig {
+	cfg := NewSplunkHECOutputConfig("test")
+	cfg.Endpoint = endpoint
+	cfg.Token = "token"
+	cfg.BufferConfig.Builder.(*buffer.MemoryBufferConfig).MaxChunkDelay = helper.NewDuration(50 * time.Millisecond)
+	return cfg
+}
+
+func startOutput(t *testing.T, cfg *SplunkHECOutputConfig, entries ...*entry.Entry) {
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	op := ops[0]
+	require.NoError(t, op.Start())
+	t.Cleanup(func() { require.NoError(t, op.Stop()) })
+
+	for _, e := range entries {
+		require.NoError(t, op.Process(context.Background(), e))
+	}
+}
+
+func newEntry(record interface{}) *entry.Entry {
+	e := entry.New()
+	e.Timestamp = time.Date(2021, 2, 3, 4, 5, 6, 789000000, time.UTC)
+	e.Record = record
+	return e
+}
+
+func TestSplunkHECOutputBuild(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(*SplunkHECOutputConfig)
+	}{
+		{"MissingEndpoint", func(c *SplunkHECOutputConfig) { c.Endpoint = "" }},
+		{"MissingToken", func(c *SplunkHECOutputConfig) { c.Token = "" }},
+		{"InvalidCompression", func(c *SplunkHECOutputConfig) { c.Compression = "zstd" }},
+		{"ZeroAckPollInterval", func(c *SplunkHECOutputConfig) {
+			c.UseAck = true
+			c.AckPollInterval = helper.NewDuration(0)
+		}},
+		{"ZeroAckTimeout", func(c *SplunkHECOutputConfig) {
+			c.UseAck = true
+			c.AckTimeout = helper.NewDuration(0)
+		}},
+		{"InvalidTLS", func(c *SplunkHECOutputConfig) { c.TLS = &helper.TLSClientConfig{CertFile: "cert"} }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := newTestConfig("https://localhost:8088")
+			tc.modify(cfg)
+			_, err := cfg.Build(testutil.NewBuildContext(t))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestSplunkHECOutputChannel(t *testing.T) {
+	cfg := newTestConfig("https://localhost:8088")
+	cfg.UseAck = true
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	require.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, ops[0].(*SplunkHECOutput).channel)
+
+	cfg.Channel = "configured"
+	ops, err = cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	require.Equal(t, "configured", ops[0].(*SplunkHECOutput).channel)
+}
+
+func TestSplunkHECOutputEvents(t *testing.T) {
+	collector, endpoint := newTestCollector(t)
+	cfg := newTestConfig(endpoint + "/")
+	hostField := entry.NewResourceField("host.name")
+	sourceTypeField := entry.NewRecordField("type")
+	cfg.HostField = &hostField
+	cfg.Host = "default-host"
+	cfg.SourceTypeField = &sourceTypeField
+	cfg.SourceType = "stanza"
+	cfg.Index = "main"
+	cfg.Compression = compressionGzip
+
+	first := newEntry(map[string]interface{}{"type": "access", "message": "<hello>"})
+	first.Resource = map[string]string{"host.name": "server"}
+	first.Labels = map[string]string{"env": "prod"}
+	startOutput(t, cfg, first, newEntry("plain"))
+
+	req := collector.expectEvents(t)
+	require.Equal(t, "Splunk token", req.header.Get("Authorization"))
+	require.Equal(t, "gzip", req.header.Get("Content-Encoding"))
+	require.Equal(t, "application/json", req.header.Get("Content-Type"))
+
+	expected := `{"time":1612325106.789,"host":"server","sourcetype":"access","index":"main","event":{"message":"<hello>","type":"access"},"fields":{"env":"prod"}}` + "\n" +
+		`{"time":1612325106.789,"host":"default-host","sourcetype":"stanza","index":"main","event":"plain"}` + "\n"
+	require.Equal(t, expected, string(req.body))
+}
+
+func TestSplunkHECOutputRetry(t *testing.T) {
+	t.Run("ServiceUnavailable", func(t *testing.T) {
+		collector, endpoint := newTestCollector(t, http.StatusServiceUnavailable)
+		startOutput(t, newTestConfig(endpoint), newEntry("message"))
+
+		first := collector.expectEvents(t)
+		require.Equal(t, first.body, collector.expectEvents(t).body)
+	})
+
+	t.Run("BadRequest", func(t *testing.T) {
+		collector, endpoint := newTestCollector(t, http.StatusBadRequest)
+		startOutput(t, newTestConfig(endpoint), newEntry("message"))
+
+		collector.expectEvents(t)
+		collector.expectNoEvents(t)
+	})
+}
+
+func TestSplunkHECOutputAck(t *testing.T) {
+	t.Run("Acknowledged", func(t *testing.T) {
+		collector, endpoint := newTestCollector(t)
+		collector.ackAfter = 2
+		cfg := newTestConfig(endpoint)
+		cfg.UseAck = true
+		cfg.Channel = "channel"
+		cfg.AckPollInterval = helper.NewDuration(10 * time.Millisecond)
+		startOutput(t, cfg, newEntry("message"))
+
+		req := collector.expectEvents(t)
+		require.Equal(t, "channel", req.header.Get("X-Splunk-Request-Channel"))
+		collector.expectNoEvents(t)
+
+		collector.Lock()
+		defer collector.Unlock()
+		require.Equal(t, 3, collector.queries[0])
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		collector, endpoint := newTestCollector(t)
+		collector.ackAfter = -1
+		cfg := newTestConfig(endpoint)
+		cfg.UseAck = true
+		cfg.AckPollInterval = helper.NewDuration(10 * time.Millisecond)
+		cfg.AckTimeout = helper.NewDuration(50 * time.Millisecond)
+		startOutput(t, cfg, newEntry("message"))
+
+		// A batch that is not acknowledged is sent again
+		first := collector.expectEvents(t)
+		require.Equal(t, first.body, collector.expectEvents(t).body)
+	})
+}
+
+func TestFormatTime(t *testing.T) {
+	e := entry.New()
+	e.Timestamp = time.Unix(1612325106, 7000000)
+	require.Equal(t, "1612325106.007", formatTime(e).String())
+}
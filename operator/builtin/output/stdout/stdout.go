@@ -15,8 +15,8 @@
 package stdout
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
 	"io"
 	"os"
 	"sync"
@@ -37,12 +37,14 @@ func init() {
 func NewStdoutConfig(operatorID string) *StdoutConfig {
 	return &StdoutConfig{
 		OutputConfig: helper.NewOutputConfig(operatorID, "stdout"),
+		Encoding:     helper.NewEncodingConfig("json"),
 	}
 }
 
 // StdoutConfig is the configuration of the Stdout operator
 type StdoutConfig struct {
 	helper.OutputConfig `yaml:",inline"`
+	Encoding            helper.EncodingConfig `json:"encoding" yaml:"encoding"`
 }
 
 // Build will build a stdout operator.
@@ -52,9 +54,15 @@ func (c StdoutConfig) Build(context operator.BuildContext) ([]operator.Operator,
 		return nil, err
 	}
 
+	encoder, err := c.Encoding.Build()
+	if err != nil {
+		return nil, err
+	}
+
 	op := &StdoutOperator{
 		OutputOperator: outputOperator,
-		encoder:        json.NewEncoder(Stdout),
+		encoder:        encoder,
+		writer:         Stdout,
 	}
 	return []operator.Operator{op}, nil
 }
@@ -62,19 +70,21 @@ func (c StdoutConfig) Build(context operator.BuildContext) ([]operator.Operator,
 // StdoutOperator is an operator that logs entries using stdout.
 type StdoutOperator struct {
 	helper.OutputOperator
-	encoder *json.Encoder
+	encoder helper.Encoder
+	writer  io.Writer
 	mux     sync.Mutex
 }
 
 // Process will log entries received.
 func (o *StdoutOperator) Process(ctx context.Context, entry *entry.Entry) error {
-	o.mux.Lock()
-	err := o.encoder.Encode(entry)
-	if err != nil {
-		o.mux.Unlock()
-		o.Errorf("Failed to process entry: %s, $s", err, entry.Record)
+	var buf bytes.Buffer
+	if err := o.encoder.Encode(&buf, entry); err != nil {
+		o.Errorf("Failed to process entry: %s, %s", err, entry.Record)
 		return err
 	}
-	o.mux.Unlock()
-	return nil
+
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	_, err := o.writer.Write(buf.Bytes())
+	return err
 }
@@ -28,21 +28,14 @@ import (
 )
 
 func TestStdoutOperator(t *testing.T) {
-	cfg := StdoutConfig{
-		OutputConfig: helper.OutputConfig{
-			BasicConfig: helper.BasicConfig{
-				OperatorID:   "test_operator_id",
-				OperatorType: "stdout",
-			},
-		},
-	}
+	cfg := NewStdoutConfig("test_operator_id")
 
 	ops, err := cfg.Build(testutil.NewBuildContext(t))
 	require.NoError(t, err)
 	op := ops[0]
 
 	var buf bytes.Buffer
-	op.(*StdoutOperator).encoder = json.NewEncoder(&buf)
+	op.(*StdoutOperator).writer = &buf
 
 	ts := time.Unix(1591042864, 0)
 	e := &entry.Entry{
@@ -58,3 +51,31 @@ func TestStdoutOperator(t *testing.T) {
 	expected := `{"timestamp":` + string(marshalledTimestamp) + `,"severity":0,"record":"test record"}` + "\n"
 	require.Equal(t, expected, buf.String())
 }
+
+func TestStdoutOperatorEncoding(t *testing.T) {
+	cfg := NewStdoutConfig("test_operator_id")
+	cfg.Encoding = helper.NewEncodingConfig("logfmt")
+
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	op := ops[0]
+
+	var buf bytes.Buffer
+	op.(*StdoutOperator).writer = &buf
+
+	e := &entry.Entry{
+		Timestamp: time.Date(2020, 6, 1, 20, 21, 4, 0, time.UTC),
+		Severity:  entry.Info,
+		Record:    "test record",
+	}
+	require.NoError(t, op.Process(context.Background(), e))
+	require.Equal(t, `timestamp=2020-06-01T20:21:04Z severity=info record="test record"`+"\n", buf.String())
+}
+
+func TestStdoutOperatorInvalidEncoding(t *testing.T) {
+	cfg := NewStdoutConfig("test_operator_id")
+	cfg.Encoding = helper.NewEncodingConfig("xml")
+
+	_, err := cfg.Build(testutil.NewBuildContext(t))
+	require.Error(t, err)
+}
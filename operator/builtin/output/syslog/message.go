@@ -0,0 +1,290 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syslog
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+)
+
+const (
+	nilValue        = "-"
+	hostResourceKey = "host.name"
+
+	// Maximum lengths of header fields defined by RFC5424
+	maxHostnameLength = 255
+	maxAppNameLength  = 48
+	maxProcIDLength   = 128
+	maxMsgIDLength    = 32
+	maxSDNameLength   = 32
+
+	// maxTagLength is the maximum length of a RFC3164 tag
+	maxTagLength = 32
+
+	rfc5424Timestamp = "2006-01-02T15:04:05.000000Z07:00"
+	rfc3164Timestamp = "Jan _2 15:04:05"
+)
+
+var facilities = map[string]int{
+	"kern":         0,
+	"user":         1,
+	"mail":         2,
+	"daemon":       3,
+	"auth":         4,
+	"syslog":       5,
+	"lpr":          6,
+	"news":         7,
+	"uucp":         8,
+	"cron":         9,
+	"authpriv":     10,
+	"ftp":          11,
+	"ntp":          12,
+	"security":     13,
+	"console":      14,
+	"solaris-cron": 15,
+	"local0":       16,
+	"local1":       17,
+	"local2":       18,
+	"local3":       19,
+	"local4":       20,
+	"local5":       21,
+	"local6":       22,
+	"local7":       23,
+}
+
+// parseFacility converts a facility name or number to a facility code
+func parseFacility(facility string) (int, error) {
+	if code, ok := facilities[facility]; ok {
+		return code, nil
+	}
+
+	code, err := strconv.Atoi(facility)
+	if err != nil || code < 0 || code > 23 {
+		return 0, fmt.Errorf("invalid facility '%s'", facility)
+	}
+	return code, nil
+}
+
+// toSeverity converts an entry severity to a syslog severity
+func toSeverity(severity entry.Severity) int {
+	switch {
+	case severity >= entry.Emergency:
+		return 0
+	case severity >= entry.Alert:
+		return 1
+	case severity >= entry.Critical:
+		return 2
+	case severity >= entry.Error:
+		return 3
+	case severity >= entry.Warning:
+		return 4
+	case severity >= entry.Notice:
+		return 5
+	case severity >= entry.Info:
+		return 6
+	case severity > entry.Default:
+		return 7
+	default:
+		// Entries without a severity are sent as informational
+		return 6
+	}
+}
+
+// header is the part of a syslog message read from an entry
+type header struct {
+	priority int
+	hostname string
+	appName  string
+	procID   string
+	msgID    string
+}
+
+// createHeader will read the header fields of a message from an entry
+func (s *SyslogOutput) createHeader(e *entry.Entry) header {
+	h := header{
+		priority: s.facility*8 + toSeverity(e.Severity),
+		hostname: s.hostname,
+		appName:  s.appName,
+	}
+
+	if host, ok := e.Resource[hostResourceKey]; ok && host != "" {
+		h.hostname = host
+	}
+
+	readField(e, s.hostnameField, &h.hostname)
+	readField(e, s.appNameField, &h.appName)
+	readField(e, s.procIDField, &h.procID)
+	readField(e, s.msgIDField, &h.msgID)
+	return h
+}
+
+// readField will read a field of an entry as a string, leaving the
+// destination unchanged if the entry does not have the field
+func readField(e *entry.Entry, field *entry.Field, dest *string) {
+	if field == nil {
+		return
+	}
+
+	value, ok := e.Get(*field)
+	if !ok {
+		return
+	}
+
+	if s := stringValue(value); s != "" {
+		*dest = s
+	}
+}
+
+// createMessage will format an entry as a syslog message
+func (s *SyslogOutput) createMessage(e *entry.Entry) ([]byte, error) {
+	msg, err := helper.FormatRecord(e.Record)
+	if err != nil {
+		return nil, err
+	}
+
+	h := s.createHeader(e)
+	if s.format == formatRFC3164 {
+		return formatRFC3164Message(e, h, msg), nil
+	}
+	return formatRFC5424Message(e, h, s.sdID, msg), nil
+}
+
+// formatRFC5424Message will create a RFC5424 message, with labels as structured data
+func formatRFC5424Message(e *entry.Entry, h header, sdID, msg string) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %s %s ",
+		h.priority,
+		e.Timestamp.Format(rfc5424Timestamp),
+		headerValue(h.hostname, maxHostnameLength),
+		headerValue(h.appName, maxAppNameLength),
+		headerValue(h.procID, maxProcIDLength),
+		headerValue(h.msgID, maxMsgIDLength),
+	)
+	writeStructuredData(&b, sdID, e.Labels)
+
+	if msg != "" {
+		b.WriteByte(' ')
+		b.WriteString(msg)
+	}
+	return b.Bytes()
+}
+
+// formatRFC3164Message will create a RFC3164 message. The procid is included in the tag,
+// and the msgid and labels are not sent since the format has no place for them.
+func formatRFC3164Message(e *entry.Entry, h header, msg string) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<%d>%s %s ",
+		h.priority,
+		e.Timestamp.Format(rfc3164Timestamp),
+		headerValue(h.hostname, maxHostnameLength),
+	)
+
+	tag := truncate(printable(h.appName), maxTagLength)
+	if tag == "" {
+		tag = nilValue
+	}
+	b.WriteString(tag)
+	if procID := printable(h.procID); procID != "" {
+		fmt.Fprintf(&b, "[%s]", truncate(procID, maxProcIDLength))
+	}
+	b.WriteString(": ")
+	b.WriteString(msg)
+	return b.Bytes()
+}
+
+// writeStructuredData will write labels as a single structured data element
+func writeStructuredData(b *bytes.Buffer, sdID string, labels map[string]string) {
+	if len(labels) == 0 {
+		b.WriteString(nilValue)
+		return
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	b.WriteByte('[')
+	b.WriteString(sdID)
+	for _, k := range keys {
+		name := sdName(k)
+		if name == "" {
+			continue
+		}
+		fmt.Fprintf(b, ` %s="%s"`, name, sdValueEscaper.Replace(labels[k]))
+	}
+	b.WriteByte(']')
+}
+
+// sdValueEscaper escapes the characters that are not allowed in a param value
+var sdValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// sdName converts a label key to a valid structured data name
+func sdName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, printable(key))
+	return truncate(name, maxSDNameLength)
+}
+
+// headerValue converts a value to a valid header field, or the nil value if it is empty
+func headerValue(value string, maxLength int) string {
+	value = truncate(printable(value), maxLength)
+	if value == "" {
+		return nilValue
+	}
+	return value
+}
+
+// printable removes the characters that are not printable US-ASCII
+func printable(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, value)
+}
+
+func truncate(value string, maxLength int) string {
+	if len(value) > maxLength {
+		return value[:maxLength]
+	}
+	return value
+}
+
+// stringValue converts a field value to a string
+func stringValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
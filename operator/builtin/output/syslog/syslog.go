@@ -0,0 +1,398 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syslog
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/errors"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/buffer"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/flusher"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"go.uber.org/zap"
+)
+
+func init() {
+	operator.Register("syslog_output", func() operator.Builder { return NewSyslogOutputConfig("") })
+}
+
+const (
+	protocolUDP = "udp"
+	protocolTCP = "tcp"
+
+	formatRFC5424 = "rfc5424"
+	formatRFC3164 = "rfc3164"
+
+	framingNewline       = "newline"
+	framingOctetCounting = "octet_counting"
+
+	// maxDatagramSize is the largest message that can be sent over udp
+	maxDatagramSize = 65507
+)
+
+// NewSyslogOutputConfig creates a new syslog output config with default values
+func NewSyslogOutputConfig(operatorID string) *SyslogOutputConfig {
+	return &SyslogOutputConfig{
+		OutputConfig:     helper.NewOutputConfig(operatorID, "syslog_output"),
+		BufferConfig:     buffer.NewConfig(),
+		FlusherConfig:    flusher.NewConfig(),
+		Protocol:         protocolUDP,
+		Format:           formatRFC5424,
+		Facility:         "user",
+		AppName:          "stanza",
+		StructuredDataID: "labels@32473",
+		Timeout:          helper.Duration{Duration: 10 * time.Second},
+	}
+}
+
+// SyslogOutputConfig is the configuration of a syslog output operator.
+type SyslogOutputConfig struct {
+	helper.OutputConfig `yaml:",inline"`
+	BufferConfig        buffer.Config           `json:"buffer"                       yaml:"buffer"`
+	FlusherConfig       flusher.Config          `json:"flusher"                      yaml:"flusher"`
+	Address             string                  `json:"address"                      yaml:"address"`
+	Protocol            string                  `json:"protocol,omitempty"           yaml:"protocol,omitempty"`
+	TLS                 *helper.TLSClientConfig `json:"tls,omitempty"                yaml:"tls,omitempty"`
+	Framing             string                  `json:"framing,omitempty"            yaml:"framing,omitempty"`
+	Format              string                  `json:"format,omitempty"             yaml:"format,omitempty"`
+	Facility            string                  `json:"facility,omitempty"           yaml:"facility,omitempty"`
+	Hostname            string                  `json:"hostname,omitempty"           yaml:"hostname,omitempty"`
+	HostnameField       *entry.Field            `json:"hostname_field,omitempty"     yaml:"hostname_field,omitempty"`
+	AppName             string                  `json:"app_name,omitempty"           yaml:"app_name,omitempty"`
+	AppNameField        *entry.Field            `json:"app_name_field,omitempty"     yaml:"app_name_field,omitempty"`
+	ProcIDField         *entry.Field            `json:"proc_id_field,omitempty"      yaml:"proc_id_field,omitempty"`
+	MsgIDField          *entry.Field            `json:"msg_id_field,omitempty"       yaml:"msg_id_field,omitempty"`
+	StructuredDataID    string                  `json:"structured_data_id,omitempty" yaml:"structured_data_id,omitempty"`
+	Timeout             helper.Duration         `json:"timeout,omitempty"            yaml:"timeout,omitempty"`
+}
+
+// Build will build a syslog output operator.
+func (c SyslogOutputConfig) Build(bc operator.BuildContext) ([]operator.Operator, error) {
+	outputOperator, err := c.OutputConfig.Build(bc)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Address == "" {
+		return nil, errors.NewError("missing required parameter 'address'", "")
+	}
+
+	switch c.Protocol {
+	case protocolUDP:
+		if c.TLS != nil {
+			return nil, fmt.Errorf("`tls` is only supported with protocol '%s'", protocolTCP)
+		}
+	case protocolTCP:
+	default:
+		return nil, fmt.Errorf("invalid protocol '%s'", c.Protocol)
+	}
+
+	switch c.Format {
+	case formatRFC5424, formatRFC3164:
+	default:
+		return nil, fmt.Errorf("invalid format '%s'", c.Format)
+	}
+
+	// RFC5425 and RFC6587 use octet counting for RFC5424 messages,
+	// while receivers of RFC3164 messages usually expect a newline
+	framing := c.Framing
+	if framing == "" {
+		framing = framingOctetCounting
+		if c.Format == formatRFC3164 {
+			framing = framingNewline
+		}
+	}
+
+	switch framing {
+	case framingNewline, framingOctetCounting:
+	default:
+		return nil, fmt.Errorf("invalid framing '%s'", c.Framing)
+	}
+
+	facility, err := parseFacility(c.Facility)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.StructuredDataID == "" || sdName(c.StructuredDataID) != c.StructuredDataID {
+		return nil, fmt.Errorf("invalid structured_data_id '%s'", c.StructuredDataID)
+	}
+
+	var tlsConfig *tls.Config
+	if c.TLS != nil {
+		tlsConfig, err = c.TLS.Build()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hostname := c.Hostname
+	if hostname == "" {
+		hostname, err = os.Hostname()
+		if err != nil {
+			return nil, errors.Wrap(err, "get hostname")
+		}
+	}
+
+	buffer, err := c.BufferConfig.Build(bc, c.ID())
+	if err != nil {
+		return nil, err
+	}
+
+	flusher := c.FlusherConfig.Build(bc.Logger.SugaredLogger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	syslogOutput := &SyslogOutput{
+		OutputOperator: outputOperator,
+		buffer:         buffer,
+		flusher:        flusher,
+		address:        c.Address,
+		protocol:       c.Protocol,
+		tlsConfig:      tlsConfig,
+		framing:        framing,
+		format:         c.Format,
+		facility:       facility,
+		hostname:       hostname,
+		hostnameField:  c.HostnameField,
+		appName:        c.AppName,
+		appNameField:   c.AppNameField,
+		procIDField:    c.ProcIDField,
+		msgIDField:     c.MsgIDField,
+		sdID:           c.StructuredDataID,
+		timeout:        c.Timeout.Raw(),
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+
+	return []operator.Operator{syslogOutput}, nil
+}
+
+// SyslogOutput is an operator that sends entries to a syslog receiver
+type SyslogOutput struct {
+	helper.OutputOperator
+	buffer  buffer.Buffer
+	flusher *flusher.Flusher
+
+	address       string
+	protocol      string
+	tlsConfig     *tls.Config
+	framing       string
+	format        string
+	facility      int
+	hostname      string
+	hostnameField *entry.Field
+	appName       string
+	appNameField  *entry.Field
+	procIDField   *entry.Field
+	msgIDField    *entry.Field
+	sdID          string
+	timeout       time.Duration
+
+	// conn is kept open between chunks, and is replaced after any failure
+	conn    net.Conn
+	connMux sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Start signals to the SyslogOutput to begin flushing
+func (s *SyslogOutput) Start() error {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.feedFlusher(s.ctx)
+	}()
+
+	return nil
+}
+
+// Stop tells the SyslogOutput to stop gracefully
+func (s *SyslogOutput) Stop() error {
+	s.cancel()
+	s.wg.Wait()
+	s.flusher.Stop()
+
+	s.connMux.Lock()
+	s.closeConn()
+	s.connMux.Unlock()
+
+	return s.buffer.Close()
+}
+
+// Process adds an entry to the output's buffer
+func (s *SyslogOutput) Process(ctx context.Context, entry *entry.Entry) error {
+	return s.buffer.Add(ctx, entry)
+}
+
+func (s *SyslogOutput) feedFlusher(ctx context.Context) {
+	for {
+		entries, clearer, err := s.buffer.ReadChunk(ctx)
+		if err != nil && err == context.Canceled {
+			return
+		} else if err != nil {
+			s.Errorw("Failed to read chunk", zap.Error(err))
+			continue
+		}
+
+		messages := s.createMessages(entries)
+		s.flusher.Do(func(ctx context.Context) error {
+			if err := s.send(ctx, messages); err != nil {
+				return err
+			}
+
+			if err = clearer.MarkAllAsFlushed(); err != nil {
+				s.Errorw("Failed to mark entries as flushed", zap.Error(err))
+			}
+			return nil
+		})
+	}
+}
+
+// createMessages will format entries as syslog messages, dropping any that fail
+func (s *SyslogOutput) createMessages(entries []*entry.Entry) [][]byte {
+	messages := make([][]byte, 0, len(entries))
+	for _, e := range entries {
+		msg, err := s.createMessage(e)
+		if err != nil {
+			s.Errorw("Failed to create syslog message. Dropping entry", zap.Error(err))
+			continue
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+// send will send messages using the configured protocol
+func (s *SyslogOutput) send(ctx context.Context, messages [][]byte) error {
+	s.connMux.Lock()
+	defer s.connMux.Unlock()
+
+	if s.conn == nil {
+		conn, err := s.dial(ctx)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+
+	if err := s.write(s.conn, messages); err != nil {
+		s.closeConn()
+		return err
+	}
+	return nil
+}
+
+// write will write messages to a connection using the configured protocol
+func (s *SyslogOutput) write(conn net.Conn, messages [][]byte) error {
+	if err := conn.SetDeadline(time.Now().Add(s.timeout)); err != nil {
+		return err
+	}
+
+	if s.protocol == protocolTCP {
+		return s.sendTCP(conn, messages)
+	}
+	return s.sendUDP(conn, messages)
+}
+
+// closeConn will close the current connection, if any. The caller must hold connMux.
+func (s *SyslogOutput) closeConn() {
+	if s.conn == nil {
+		return
+	}
+	if err := s.conn.Close(); err != nil {
+		s.Debugw("Failed to close connection", zap.Error(err))
+	}
+	s.conn = nil
+}
+
+// dial will open a connection to the configured address, completing the TLS handshake if enabled
+func (s *SyslogOutput) dial(ctx context.Context) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, s.protocol, s.address)
+	if err != nil {
+		return nil, errors.Wrap(err, "dial")
+	}
+
+	if s.tlsConfig == nil {
+		return conn, nil
+	}
+
+	tlsConfig := s.tlsConfig
+	if tlsConfig.ServerName == "" {
+		tlsConfig = tlsConfig.Clone()
+		tlsConfig.ServerName, _, _ = net.SplitHostPort(s.address)
+	}
+
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, errors.Wrap(err, "tls handshake")
+	}
+	return tlsConn, nil
+}
+
+// sendTCP will write framed messages to a stream
+func (s *SyslogOutput) sendTCP(w io.Writer, messages [][]byte) error {
+	var b bytes.Buffer
+	for _, msg := range messages {
+		if s.framing == framingOctetCounting {
+			b.WriteString(strconv.Itoa(len(msg)))
+			b.WriteByte(' ')
+			b.Write(msg)
+			continue
+		}
+
+		// A newline in a message would be read as the end of the message
+		b.Write(bytes.ReplaceAll(msg, []byte{'\n'}, []byte{' '}))
+		b.WriteByte('\n')
+	}
+
+	if _, err := w.Write(b.Bytes()); err != nil {
+		return errors.Wrap(err, "write messages")
+	}
+	return nil
+}
+
+// sendUDP will write each message as a datagram
+func (s *SyslogOutput) sendUDP(w io.Writer, messages [][]byte) error {
+	for _, msg := range messages {
+		if len(msg) > maxDatagramSize {
+			s.Errorw("Syslog message is too large for a datagram. Dropping entry", zap.Int("size", len(msg)))
+			continue
+		}
+
+		if _, err := w.Write(msg); err != nil {
+			return errors.Wrap(err, "write datagram")
+		}
+	}
+	return nil
+}
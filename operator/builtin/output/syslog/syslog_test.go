@@ -0,0 +1,429 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"github.com/opentelemetry/opentelemetry-log-collection/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEntry() *entry.Entry {
+	e := entry.New()
+	e.Timestamp = time.Date(2021, 2, 3, 4, 5, 6, 123456789, time.UTC)
+	e.Severity = entry.Error
+	e.Labels = map[string]string{
+		"env":          "prod",
+		"quote\"key":   `a "quoted" \value]`,
+		"request path": "/index",
+	}
+	e.Record = map[string]interface{}{
+		"message": "A message",
+		"pid":     1234,
+		"msgid":   "ID47",
+	}
+	return e
+}
+
+func newTestSyslogOutput(t *testing.T, configure func(cfg *SyslogOutputConfig)) *SyslogOutput {
+	cfg := NewSyslogOutputConfig("test_output")
+	cfg.Address = "127.0.0.1:514"
+	cfg.Hostname = "stanza-host"
+	procID := entry.NewRecordField("pid")
+	cfg.ProcIDField = &procID
+	msgID := entry.NewRecordField("msgid")
+	cfg.MsgIDField = &msgID
+	if configure != nil {
+		configure(cfg)
+	}
+
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	return ops[0].(*SyslogOutput)
+}
+
+func TestSyslogOutputCreateMessage(t *testing.T) {
+	cases := []struct {
+		name      string
+		configure func(cfg *SyslogOutputConfig)
+		modify    func(e *entry.Entry)
+		expected  string
+	}{
+		{
+			"RFC5424",
+			nil,
+			nil,
+			`<11>1 2021-02-03T04:05:06.123456Z stanza-host stanza 1234 ID47 [labels@32473 env="prod" quote_key="a \"quoted\" \\value\]" requestpath="/index"] {"message":"A message","msgid":"ID47","pid":1234}`,
+		},
+		{
+			"RFC5424NoLabels",
+			func(cfg *SyslogOutputConfig) { cfg.Facility = "local4" },
+			func(e *entry.Entry) {
+				e.Labels = nil
+				e.Record = "A message"
+				e.Severity = entry.Default
+			},
+			`<166>1 2021-02-03T04:05:06.123456Z stanza-host stanza - - - A message`,
+		},
+		{
+			"RFC5424Fields",
+			func(cfg *SyslogOutputConfig) {
+				hostname := entry.NewLabelField("host")
+				cfg.HostnameField = &hostname
+				appName := entry.NewRecordField("app")
+				cfg.AppNameField = &appName
+				cfg.StructuredDataID = "meta"
+			},
+			func(e *entry.Entry) {
+				e.Labels = map[string]string{"host": "web-1"}
+				e.Resource = map[string]string{"host.name": "resource-host"}
+				e.Record = map[string]interface{}{"app": "my app"}
+			},
+			`<11>1 2021-02-03T04:05:06.123456Z web-1 myapp - - [meta host="web-1"] {"app":"my app"}`,
+		},
+		{
+			"RFC5424ResourceHost",
+			nil,
+			func(e *entry.Entry) {
+				e.Labels = nil
+				e.Resource = map[string]string{"host.name": "resource-host"}
+				e.Record = ""
+			},
+			`<11>1 2021-02-03T04:05:06.123456Z resource-host stanza - - -`,
+		},
+		{
+			"RFC3164",
+			func(cfg *SyslogOutputConfig) {
+				cfg.Format = formatRFC3164
+				cfg.Facility = "4"
+			},
+			func(e *entry.Entry) { e.Severity = entry.Warning },
+			`<36>Feb  3 04:05:06 stanza-host stanza[1234]: {"message":"A message","msgid":"ID47","pid":1234}`,
+		},
+		{
+			"RFC3164NoProcID",
+			func(cfg *SyslogOutputConfig) {
+				cfg.Format = formatRFC3164
+				cfg.ProcIDField = nil
+			},
+			func(e *entry.Entry) { e.Record = "A message" },
+			`<11>Feb  3 04:05:06 stanza-host stanza: A message`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestSyslogOutput(t, tc.configure)
+			e := newTestEntry()
+			if tc.modify != nil {
+				tc.modify(e)
+			}
+
+			msg, err := s.createMessage(e)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, string(msg))
+		})
+	}
+}
+
+func TestToSeverity(t *testing.T) {
+	cases := []struct {
+		severity entry.Severity
+		level    int
+	}{
+		{entry.Default, 6},
+		{entry.Trace, 7},
+		{entry.Debug, 7},
+		{entry.Info, 6},
+		{entry.Info2, 6},
+		{entry.Notice, 5},
+		{entry.Warning, 4},
+		{entry.Error, 3},
+		{entry.Critical, 2},
+		{entry.Alert, 1},
+		{entry.Emergency, 0},
+		{entry.Catastrophe, 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.severity.String(), func(t *testing.T) {
+			require.Equal(t, tc.level, toSeverity(tc.severity))
+		})
+	}
+}
+
+func TestParseFacility(t *testing.T) {
+	cases := []struct {
+		facility string
+		code     int
+		valid    bool
+	}{
+		{"kern", 0, true},
+		{"user", 1, true},
+		{"local7", 23, true},
+		{"0", 0, true},
+		{"23", 23, true},
+		{"24", 0, false},
+		{"-1", 0, false},
+		{"invalid", 0, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.facility, func(t *testing.T) {
+			code, err := parseFacility(tc.facility)
+			if !tc.valid {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.code, code)
+		})
+	}
+}
+
+func TestSyslogOutputUDP(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	s := newTestSyslogOutput(t, func(cfg *SyslogOutputConfig) {
+		cfg.Address = conn.LocalAddr().String()
+	})
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	e := newTestEntry()
+	e.Record = "first"
+	require.NoError(t, s.Process(context.Background(), e))
+
+	buf := make([]byte, 1024)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := conn.ReadFromUDP(buf)
+	require.NoError(t, err)
+	require.Regexp(t, `^<11>1 .* first$`, string(buf[:n]))
+}
+
+func TestSyslogOutputTCP(t *testing.T) {
+	cases := []struct {
+		name     string
+		format   string
+		framing  string
+		expected []string
+	}{
+		{
+			"OctetCounting",
+			formatRFC5424,
+			"",
+			[]string{"first", "second\nline"},
+		},
+		{
+			"Newline",
+			formatRFC5424,
+			framingNewline,
+			[]string{"first", "second line"},
+		},
+		{
+			"RFC3164DefaultsToNewline",
+			formatRFC3164,
+			"",
+			[]string{"first", "second line"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			defer ln.Close()
+
+			s := newTestSyslogOutput(t, func(cfg *SyslogOutputConfig) {
+				cfg.Address = ln.Addr().String()
+				cfg.Protocol = protocolTCP
+				cfg.Format = tc.format
+				cfg.Framing = tc.framing
+			})
+			require.NoError(t, s.Start())
+			defer s.Stop()
+
+			for _, record := range []string{"first", "second\nline"} {
+				e := newTestEntry()
+				e.Record = record
+				require.NoError(t, s.Process(context.Background(), e))
+			}
+
+			conn, err := ln.Accept()
+			require.NoError(t, err)
+			defer conn.Close()
+			require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+			reader := bufio.NewReader(conn)
+			for _, expected := range tc.expected {
+				var msg string
+				if tc.framing == "" && tc.format == formatRFC5424 {
+					msg = readOctetCounted(t, reader)
+				} else {
+					msg, err = reader.ReadString('\n')
+					require.NoError(t, err)
+					msg = msg[:len(msg)-1]
+				}
+				require.Regexp(t, ` `+expected+`$`, msg)
+			}
+		})
+	}
+}
+
+func TestSyslogOutputReuseConnection(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	s := newTestSyslogOutput(t, func(cfg *SyslogOutputConfig) {
+		cfg.Address = ln.Addr().String()
+		cfg.Protocol = protocolTCP
+	})
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	e := newTestEntry()
+	e.Record = "first"
+	require.NoError(t, s.Process(context.Background(), e))
+
+	conn, err := ln.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	reader := bufio.NewReader(conn)
+	require.Regexp(t, ` first$`, readOctetCounted(t, reader))
+
+	// The next chunk is sent on the same connection
+	e = newTestEntry()
+	e.Record = "second"
+	require.NoError(t, s.Process(context.Background(), e))
+	require.Regexp(t, ` second$`, readOctetCounted(t, reader))
+}
+
+func readOctetCounted(t *testing.T, r *bufio.Reader) string {
+	length, err := r.ReadString(' ')
+	require.NoError(t, err)
+	n, err := strconv.Atoi(length[:len(length)-1])
+	require.NoError(t, err)
+
+	msg := make([]byte, n)
+	_, err = io.ReadFull(r, msg)
+	require.NoError(t, err)
+	return string(msg)
+}
+
+func TestSyslogOutputTLS(t *testing.T) {
+	cert, key := createCertificate(t)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{cert.Raw}, PrivateKey: key}},
+	})
+	require.NoError(t, err)
+	defer ln.Close()
+
+	caFile := filepath.Join(testutil.NewTempDir(t), "ca.crt")
+	require.NoError(t, ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0600))
+
+	s := newTestSyslogOutput(t, func(cfg *SyslogOutputConfig) {
+		cfg.Address = ln.Addr().String()
+		cfg.Protocol = protocolTCP
+		cfg.TLS = &helper.TLSClientConfig{CAFile: caFile}
+	})
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	e := newTestEntry()
+	e.Record = "secure"
+	require.NoError(t, s.Process(context.Background(), e))
+
+	conn, err := ln.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	msg := readOctetCounted(t, bufio.NewReader(conn))
+	require.Regexp(t, `^<11>1 .* secure$`, msg)
+}
+
+// createCertificate will create a self signed certificate for 127.0.0.1
+func createCertificate(t *testing.T) (*x509.Certificate, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(raw)
+	require.NoError(t, err)
+	return cert, key
+}
+
+func TestSyslogOutputBuild(t *testing.T) {
+	cases := []struct {
+		name      string
+		configure func(cfg *SyslogOutputConfig)
+	}{
+		{"MissingAddress", func(cfg *SyslogOutputConfig) { cfg.Address = "" }},
+		{"InvalidProtocol", func(cfg *SyslogOutputConfig) { cfg.Protocol = "http" }},
+		{"InvalidFormat", func(cfg *SyslogOutputConfig) { cfg.Format = "rfc1234" }},
+		{"InvalidFraming", func(cfg *SyslogOutputConfig) { cfg.Framing = "null" }},
+		{"InvalidFacility", func(cfg *SyslogOutputConfig) { cfg.Facility = "local8" }},
+		{"InvalidStructuredDataID", func(cfg *SyslogOutputConfig) { cfg.StructuredDataID = "my labels" }},
+		{"EmptyStructuredDataID", func(cfg *SyslogOutputConfig) { cfg.StructuredDataID = "" }},
+		{"TLSWithUDP", func(cfg *SyslogOutputConfig) { cfg.TLS = &helper.TLSClientConfig{} }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := NewSyslogOutputConfig("test_output")
+			cfg.Address = "127.0.0.1:514"
+			tc.configure(cfg)
+			_, err := cfg.Build(testutil.NewBuildContext(t))
+			require.Error(t, err)
+		})
+	}
+}
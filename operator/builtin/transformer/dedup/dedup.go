@@ -0,0 +1,344 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dedup
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/errors"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"go.uber.org/zap"
+)
+
+func init() {
+	operator.Register("dedup", func() operator.Builder { return NewDedupOperatorConfig("") })
+}
+
+// windowKey is the key the unexpired window is persisted under
+const windowKey = "window"
+
+// NewDedupOperatorConfig creates a new dedup config with default values
+func NewDedupOperatorConfig(operatorID string) *DedupOperatorConfig {
+	return &DedupOperatorConfig{
+		TransformerConfig: helper.NewTransformerConfig(operatorID, "dedup"),
+		Window:            helper.Duration{Duration: 10 * time.Second},
+		MaxEntries:        10000,
+		CountLabel:        "dedup_count",
+	}
+}
+
+// DedupOperatorConfig is the configuration of a dedup operator
+type DedupOperatorConfig struct {
+	helper.TransformerConfig `yaml:",inline"`
+
+	Fields     []entry.Field   `json:"fields,omitempty"      yaml:"fields,omitempty"`
+	Window     helper.Duration `json:"window,omitempty"      yaml:"window,omitempty"`
+	MaxEntries int             `json:"max_entries,omitempty" yaml:"max_entries,omitempty"`
+	CountLabel string          `json:"count_label,omitempty" yaml:"count_label,omitempty"`
+	Persist    bool            `json:"persist,omitempty"     yaml:"persist,omitempty"`
+}
+
+// Build will build a dedup operator from the supplied configuration
+func (c DedupOperatorConfig) Build(context operator.BuildContext) ([]operator.Operator, error) {
+	transformerOperator, err := c.TransformerConfig.Build(context)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Window.Raw() <= 0 {
+		return nil, fmt.Errorf("invalid value for window '%s'", c.Window.Raw())
+	}
+
+	if c.MaxEntries <= 0 {
+		return nil, fmt.Errorf("`max_entries` must be positive")
+	}
+
+	dedupOperator := &DedupOperator{
+		TransformerOperator: transformerOperator,
+		fields:              c.Fields,
+		window:              c.Window.Raw(),
+		maxEntries:          c.MaxEntries,
+		countLabel:          c.CountLabel,
+		order:               list.New(),
+		seen:                make(map[string]*list.Element),
+	}
+
+	if c.Persist {
+		dedupOperator.persist = helper.NewScopedDBPersister(context.Database, c.ID())
+	}
+
+	return []operator.Operator{dedupOperator}, nil
+}
+
+// DedupOperator is an operator that drops entries that are identical to an
+// entry seen within the window
+type DedupOperator struct {
+	helper.TransformerOperator
+
+	fields     []entry.Field
+	window     time.Duration
+	maxEntries int
+	countLabel string
+	persist    helper.Persister
+
+	// order holds the seen entries from oldest to newest. Since every window
+	// has the same length, this is also the order in which they expire.
+	order   *list.List
+	seen    map[string]*list.Element
+	seenMux sync.Mutex
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// seenEntry is an entry that has been seen within the window. The entry is
+// held until the window expires so that it can be labeled with the number of
+// duplicates, and is nil if it was already written.
+type seenEntry struct {
+	hash    string
+	expires time.Time
+	count   int
+	entry   *entry.Entry
+}
+
+// Start will load the persisted window and start expiring entries
+func (d *DedupOperator) Start() error {
+	if d.persist != nil {
+		if err := d.persist.Load(); err != nil {
+			return errors.Wrap(err, "load window")
+		}
+		d.loadWindow()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+
+	interval := d.window
+	if interval > time.Second {
+		interval = time.Second
+	}
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				d.expire(ctx, now)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop will write the held entries and persist the window
+func (d *DedupOperator) Stop() error {
+	d.cancel()
+	d.wg.Wait()
+
+	d.seenMux.Lock()
+	held := make([]*entry.Entry, 0, d.order.Len())
+	for e := d.order.Front(); e != nil; e = e.Next() {
+		held = appendHeld(held, e.Value.(*seenEntry), d.countLabel)
+	}
+	if d.persist != nil {
+		d.saveWindow()
+	}
+	d.seenMux.Unlock()
+
+	d.write(context.Background(), held)
+
+	if d.persist != nil {
+		if err := d.persist.Sync(); err != nil {
+			d.Errorw("Failed to persist window", zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// Process will hold the first entry with a given hash and drop the rest
+func (d *DedupOperator) Process(ctx context.Context, entry *entry.Entry) error {
+	hash, err := d.hash(entry)
+	if err != nil {
+		return d.HandleEntryError(ctx, entry, err)
+	}
+
+	d.seenMux.Lock()
+	held := d.add(hash, entry, time.Now())
+	d.seenMux.Unlock()
+
+	d.write(ctx, held)
+	return nil
+}
+
+// add will hold an entry, or count it if an identical entry is held. It returns
+// the held entries that were released to make room for it.
+func (d *DedupOperator) add(hash string, e *entry.Entry, now time.Time) []*entry.Entry {
+	var held []*entry.Entry
+	if elem, ok := d.seen[hash]; ok {
+		seen := elem.Value.(*seenEntry)
+		if now.Before(seen.expires) {
+			seen.count++
+			return nil
+		}
+		held = appendHeld(held, seen, d.countLabel)
+		d.remove(elem)
+	}
+
+	if d.order.Len() >= d.maxEntries {
+		oldest := d.order.Front()
+		held = appendHeld(held, oldest.Value.(*seenEntry), d.countLabel)
+		d.remove(oldest)
+	}
+
+	seen := &seenEntry{hash: hash, expires: now.Add(d.window), count: 1, entry: e}
+	d.seen[hash] = d.order.PushBack(seen)
+	return held
+}
+
+// hash returns the hash of the configured fields, or of the whole record
+func (d *DedupOperator) hash(entry *entry.Entry) (string, error) {
+	var value interface{} = entry.Record
+	if len(d.fields) > 0 {
+		values := make([]interface{}, len(d.fields))
+		for i, field := range d.fields {
+			values[i], _ = entry.Get(field)
+		}
+		value = values
+	}
+
+	// Maps are encoded with sorted keys, so equal values have equal encodings
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", errors.Wrap(err, "encode fields")
+	}
+
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:16]), nil
+}
+
+// expire writes and forgets the entries whose window has expired
+func (d *DedupOperator) expire(ctx context.Context, now time.Time) {
+	var held []*entry.Entry
+
+	d.seenMux.Lock()
+	for e := d.order.Front(); e != nil; e = d.order.Front() {
+		seen := e.Value.(*seenEntry)
+		if now.Before(seen.expires) {
+			break
+		}
+		held = appendHeld(held, seen, d.countLabel)
+		d.remove(e)
+	}
+	d.seenMux.Unlock()
+
+	d.write(ctx, held)
+}
+
+// appendHeld releases a held entry, labeled with the number of times it was seen
+func appendHeld(held []*entry.Entry, seen *seenEntry, countLabel string) []*entry.Entry {
+	if seen.entry == nil {
+		return held
+	}
+
+	if countLabel != "" {
+		seen.entry.AddLabel(countLabel, strconv.Itoa(seen.count))
+	}
+	held = append(held, seen.entry)
+	seen.entry = nil
+	return held
+}
+
+// write sends released entries to the outputs. It is called without holding
+// seenMux, so that a slow output does not block other entries from being counted.
+func (d *DedupOperator) write(ctx context.Context, entries []*entry.Entry) {
+	for _, e := range entries {
+		d.Write(ctx, e)
+	}
+}
+
+func (d *DedupOperator) remove(e *list.Element) {
+	delete(d.seen, e.Value.(*seenEntry).hash)
+	d.order.Remove(e)
+}
+
+// loadWindow restores the hashes of entries written before a restart, so
+// that duplicates of them are still dropped until their window expires
+func (d *DedupOperator) loadWindow() {
+	saved := d.persist.Get(windowKey)
+	if saved == nil {
+		return
+	}
+
+	var window []persistedEntry
+	if err := json.Unmarshal(saved, &window); err != nil {
+		d.Errorw("Failed to decode persisted window. Starting with an empty window", zap.Error(err))
+		return
+	}
+
+	d.seenMux.Lock()
+	defer d.seenMux.Unlock()
+
+	now := time.Now()
+	for _, p := range window {
+		expires := time.Unix(0, p.Expires)
+		if !now.Before(expires) || d.order.Len() >= d.maxEntries {
+			continue
+		}
+		if _, ok := d.seen[p.Hash]; ok {
+			continue
+		}
+		seen := &seenEntry{hash: p.Hash, expires: expires, count: 1}
+		d.seen[p.Hash] = d.order.PushBack(seen)
+	}
+}
+
+// persistedEntry is the persisted form of a seen entry
+type persistedEntry struct {
+	Hash    string `json:"hash"`
+	Expires int64  `json:"expires"`
+}
+
+func (d *DedupOperator) saveWindow() {
+	window := make([]persistedEntry, 0, d.order.Len())
+	for e := d.order.Front(); e != nil; e = e.Next() {
+		seen := e.Value.(*seenEntry)
+		window = append(window, persistedEntry{Hash: seen.hash, Expires: seen.expires.UnixNano()})
+	}
+
+	saved, err := json.Marshal(window)
+	if err != nil {
+		d.Errorw("Failed to encode window", zap.Error(err))
+		return
+	}
+	d.persist.Set(windowKey, saved)
+}
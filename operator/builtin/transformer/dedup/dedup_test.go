@@ -0,0 +1,301 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dedup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"github.com/opentelemetry/opentelemetry-log-collection/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestOperator(t *testing.T, buildContext operator.BuildContext, configure func(*DedupOperatorConfig)) (operator.Operator, *testutil.FakeOutput) {
+	cfg := NewDedupOperatorConfig("test_id")
+	cfg.OutputIDs = []string{"$.fake"}
+	cfg.Window = helper.Duration{Duration: 50 * time.Millisecond}
+	if configure != nil {
+		configure(cfg)
+	}
+
+	ops, err := cfg.Build(buildContext)
+	require.NoError(t, err)
+	op := ops[0]
+
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, op.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, op.Start())
+	return op, fake
+}
+
+func newEntry(record interface{}) *entry.Entry {
+	e := entry.New()
+	e.Record = record
+	return e
+}
+
+func receive(t *testing.T, fake *testutil.FakeOutput) *entry.Entry {
+	select {
+	case e := <-fake.Received:
+		return e
+	case <-time.After(time.Second):
+		require.FailNow(t, "Timed out waiting for entry")
+	}
+	return nil
+}
+
+func expectNoEntry(t *testing.T, fake *testutil.FakeOutput, wait time.Duration) {
+	select {
+	case e := <-fake.Received:
+		require.FailNow(t, "Received unexpected entry", e.Record)
+	case <-time.After(wait):
+	}
+}
+
+func TestDedupWholeRecord(t *testing.T) {
+	op, fake := newTestOperator(t, testutil.NewBuildContext(t), nil)
+	defer op.Stop()
+
+	ctx := context.Background()
+	require.NoError(t, op.Process(ctx, newEntry(map[string]interface{}{"message": "a", "code": 1})))
+	require.NoError(t, op.Process(ctx, newEntry(map[string]interface{}{"code": 1, "message": "a"})))
+	require.NoError(t, op.Process(ctx, newEntry(map[string]interface{}{"message": "b", "code": 1})))
+	require.NoError(t, op.Process(ctx, newEntry(map[string]interface{}{"message": "a", "code": 1})))
+
+	first := receive(t, fake)
+	require.Equal(t, "a", first.Record.(map[string]interface{})["message"])
+	require.Equal(t, "3", first.Labels["dedup_count"])
+
+	second := receive(t, fake)
+	require.Equal(t, "b", second.Record.(map[string]interface{})["message"])
+	require.Equal(t, "1", second.Labels["dedup_count"])
+
+	expectNoEntry(t, fake, 100*time.Millisecond)
+}
+
+func TestDedupFields(t *testing.T) {
+	op, fake := newTestOperator(t, testutil.NewBuildContext(t), func(cfg *DedupOperatorConfig) {
+		cfg.Fields = []entry.Field{entry.NewRecordField("message"), entry.NewLabelField("host")}
+		cfg.CountLabel = "duplicates"
+	})
+	defer op.Stop()
+
+	first := newEntry(map[string]interface{}{"message": "a", "attempt": 1})
+	first.AddLabel("host", "web-1")
+	retry := newEntry(map[string]interface{}{"message": "a", "attempt": 2})
+	retry.AddLabel("host", "web-1")
+	other := newEntry(map[string]interface{}{"message": "a", "attempt": 1})
+	other.AddLabel("host", "web-2")
+
+	ctx := context.Background()
+	require.NoError(t, op.Process(ctx, first))
+	require.NoError(t, op.Process(ctx, retry))
+	require.NoError(t, op.Process(ctx, other))
+
+	e := receive(t, fake)
+	require.Equal(t, 1, e.Record.(map[string]interface{})["attempt"])
+	require.Equal(t, "web-1", e.Labels["host"])
+	require.Equal(t, "2", e.Labels["duplicates"])
+
+	e = receive(t, fake)
+	require.Equal(t, "web-2", e.Labels["host"])
+	require.Equal(t, "1", e.Labels["duplicates"])
+}
+
+func TestDedupAfterWindow(t *testing.T) {
+	op, fake := newTestOperator(t, testutil.NewBuildContext(t), nil)
+	defer op.Stop()
+
+	ctx := context.Background()
+	require.NoError(t, op.Process(ctx, newEntry("message")))
+	require.Equal(t, "1", receive(t, fake).Labels["dedup_count"])
+
+	// Once the window has expired, the same entry starts a new window
+	require.NoError(t, op.Process(ctx, newEntry("message")))
+	require.Equal(t, "1", receive(t, fake).Labels["dedup_count"])
+}
+
+func TestDedupMaxEntries(t *testing.T) {
+	op, fake := newTestOperator(t, testutil.NewBuildContext(t), func(cfg *DedupOperatorConfig) {
+		cfg.Window = helper.Duration{Duration: time.Hour}
+		cfg.MaxEntries = 2
+	})
+
+	ctx := context.Background()
+	require.NoError(t, op.Process(ctx, newEntry("a")))
+	require.NoError(t, op.Process(ctx, newEntry("a")))
+	require.NoError(t, op.Process(ctx, newEntry("b")))
+	expectNoEntry(t, fake, 50*time.Millisecond)
+
+	// The oldest entry is written when it is evicted
+	require.NoError(t, op.Process(ctx, newEntry("c")))
+	e := receive(t, fake)
+	require.Equal(t, "a", e.Record)
+	require.Equal(t, "2", e.Labels["dedup_count"])
+
+	// Its duplicates are no longer recognized once it is evicted
+	require.NoError(t, op.Process(ctx, newEntry("a")))
+	e = receive(t, fake)
+	require.Equal(t, "b", e.Record)
+
+	require.NoError(t, op.Stop())
+	require.Equal(t, "c", receive(t, fake).Record)
+	require.Equal(t, "a", receive(t, fake).Record)
+}
+
+func TestDedupSlowOutput(t *testing.T) {
+	op, fake := newTestOperator(t, testutil.NewBuildContext(t), func(cfg *DedupOperatorConfig) {
+		cfg.Window = helper.Duration{Duration: time.Hour}
+		cfg.MaxEntries = 1
+	})
+	dedup := op.(*DedupOperator)
+
+	// Writes block until the entry is received
+	fake.Received = make(chan *entry.Entry)
+
+	ctx := context.Background()
+	require.NoError(t, op.Process(ctx, newEntry("a")))
+
+	// The first entry is evicted, and its write blocks
+	go func() { _ = op.Process(ctx, newEntry("b")) }()
+	require.Eventually(t, func() bool {
+		dedup.seenMux.Lock()
+		defer dedup.seenMux.Unlock()
+		return dedup.order.Front().Value.(*seenEntry).entry.Record == "b"
+	}, time.Second, 10*time.Millisecond)
+
+	// Entries are still counted while the output is blocked
+	processed := make(chan struct{})
+	go func() {
+		defer close(processed)
+		for i := 0; i < 3; i++ {
+			_ = op.Process(ctx, newEntry("b"))
+		}
+	}()
+	select {
+	case <-processed:
+	case <-time.After(time.Second):
+		require.FailNow(t, "Timed out waiting for entries to be processed")
+	}
+
+	require.Equal(t, "a", receive(t, fake).Record)
+
+	go func() { _ = op.Stop() }()
+	e := receive(t, fake)
+	require.Equal(t, "b", e.Record)
+	require.Equal(t, "4", e.Labels["dedup_count"])
+}
+
+func TestDedupStopFlushes(t *testing.T) {
+	op, fake := newTestOperator(t, testutil.NewBuildContext(t), func(cfg *DedupOperatorConfig) {
+		cfg.Window = helper.Duration{Duration: time.Hour}
+		cfg.CountLabel = ""
+	})
+
+	ctx := context.Background()
+	require.NoError(t, op.Process(ctx, newEntry("a")))
+	require.NoError(t, op.Process(ctx, newEntry("a")))
+	expectNoEntry(t, fake, 50*time.Millisecond)
+
+	require.NoError(t, op.Stop())
+	e := receive(t, fake)
+	require.Equal(t, "a", e.Record)
+	require.Empty(t, e.Labels)
+}
+
+func TestDedupPersist(t *testing.T) {
+	buildContext := testutil.NewBuildContext(t)
+	configure := func(cfg *DedupOperatorConfig) {
+		cfg.Window = helper.Duration{Duration: time.Hour}
+		cfg.Persist = true
+	}
+
+	op, fake := newTestOperator(t, buildContext, configure)
+	require.NoError(t, op.Process(context.Background(), newEntry("a")))
+	require.NoError(t, op.Stop())
+	require.Equal(t, "a", receive(t, fake).Record)
+
+	// Duplicates of entries written before the restart are still dropped
+	op, fake = newTestOperator(t, buildContext, configure)
+	require.NoError(t, op.Process(context.Background(), newEntry("a")))
+	require.NoError(t, op.Process(context.Background(), newEntry("b")))
+	require.NoError(t, op.Stop())
+	require.Equal(t, "b", receive(t, fake).Record)
+	expectNoEntry(t, fake, 50*time.Millisecond)
+}
+
+func TestDedupWithoutPersist(t *testing.T) {
+	buildContext := testutil.NewBuildContext(t)
+	configure := func(cfg *DedupOperatorConfig) {
+		cfg.Window = helper.Duration{Duration: time.Hour}
+	}
+
+	op, fake := newTestOperator(t, buildContext, configure)
+	require.NoError(t, op.Process(context.Background(), newEntry("a")))
+	require.NoError(t, op.Stop())
+	require.Equal(t, "a", receive(t, fake).Record)
+
+	op, fake = newTestOperator(t, buildContext, configure)
+	require.NoError(t, op.Process(context.Background(), newEntry("a")))
+	require.NoError(t, op.Stop())
+	require.Equal(t, "a", receive(t, fake).Record)
+}
+
+func TestDedupPersistKeepsExpiry(t *testing.T) {
+	buildContext := testutil.NewBuildContext(t)
+	configure := func(cfg *DedupOperatorConfig) {
+		cfg.Window = helper.Duration{Duration: time.Hour}
+		cfg.Persist = true
+	}
+
+	op, fake := newTestOperator(t, buildContext, configure)
+	require.NoError(t, op.Process(context.Background(), newEntry("a")))
+	require.NoError(t, op.Stop())
+	require.Equal(t, "a", receive(t, fake).Record)
+
+	// A restart with a shorter window keeps the persisted expiry times
+	op, fake = newTestOperator(t, buildContext, func(cfg *DedupOperatorConfig) {
+		cfg.Window = helper.Duration{Duration: 10 * time.Millisecond}
+		cfg.Persist = true
+	})
+	defer op.Stop()
+	require.NoError(t, op.Process(context.Background(), newEntry("a")))
+	expectNoEntry(t, fake, 50*time.Millisecond)
+}
+
+func TestBuildDedupErrors(t *testing.T) {
+	cases := []struct {
+		name      string
+		configure func(*DedupOperatorConfig)
+	}{
+		{"ZeroWindow", func(c *DedupOperatorConfig) { c.Window = helper.Duration{} }},
+		{"ZeroMaxEntries", func(c *DedupOperatorConfig) { c.MaxEntries = 0 }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := NewDedupOperatorConfig("test_id")
+			cfg.OutputIDs = []string{"$.fake"}
+			tc.configure(cfg)
+
+			_, err := cfg.Build(testutil.NewBuildContext(t))
+			require.Error(t, err)
+		})
+	}
+}
@@ -18,6 +18,9 @@ import (
 	"context"
 	"net/http"
 	"net/url"
+	"os"
+	"path"
+	"strings"
 	"sync"
 	"time"
 
@@ -25,38 +28,58 @@ import (
 	"github.com/opentelemetry/opentelemetry-log-collection/errors"
 	"github.com/opentelemetry/opentelemetry-log-collection/operator"
 	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
-	appsv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
-	batchv1 "k8s.io/client-go/kubernetes/typed/batch/v1"
-	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	listersv1 "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 )
 
 func init() {
 	operator.Register("k8s_metadata_decorator", func() operator.Builder { return NewK8sMetadataDecoratorConfig("") })
 }
 
+const (
+	// nodeNameEnv is the environment variable used for the node name when node_name is not set
+	nodeNameEnv = "KUBE_NODE_NAME"
+
+	// failedLookupTTL is how long a pod that was not found, or a job that could not be
+	// requested, is remembered before it is requested from the API again
+	failedLookupTTL = 10 * time.Second
+)
+
 // NewK8sMetadataDecoratorConfig creates a new k8s metadata decorator config with default values
 func NewK8sMetadataDecoratorConfig(operatorID string) *K8sMetadataDecoratorConfig {
 	return &K8sMetadataDecoratorConfig{
-		TransformerConfig: helper.NewTransformerConfig(operatorID, "k8s_metadata_decorator"),
-		PodNameField:      entry.NewResourceField("k8s.pod.name"),
-		NamespaceField:    entry.NewResourceField("k8s.namespace.name"),
-		CacheTTL:          helper.Duration{Duration: 10 * time.Minute},
-		Timeout:           helper.Duration{Duration: 10 * time.Second},
-		AllowProxy:        false,
+		TransformerConfig:  helper.NewTransformerConfig(operatorID, "k8s_metadata_decorator"),
+		PodNameField:       entry.NewResourceField("k8s.pod.name"),
+		NamespaceField:     entry.NewResourceField("k8s.namespace.name"),
+		ContainerNameField: entry.NewResourceField("k8s.container.name"),
+		Timeout:            helper.Duration{Duration: 10 * time.Second},
+		AllowProxy:         false,
 	}
 }
 
 // K8sMetadataDecoratorConfig is the configuration of k8s_metadata_decorator operator
 type K8sMetadataDecoratorConfig struct {
 	helper.TransformerConfig `yaml:",inline"`
-	PodNameField             entry.Field     `json:"pod_name_field,omitempty"  yaml:"pod_name_field,omitempty"`
-	NamespaceField           entry.Field     `json:"namespace_field,omitempty" yaml:"namespace_field,omitempty"`
-	CacheTTL                 helper.Duration `json:"cache_ttl,omitempty"       yaml:"cache_ttl,omitempty"`
-	Timeout                  helper.Duration `json:"timeout,omitempty"         yaml:"timeout,omitempty"`
-	AllowProxy               bool            `json:"allow_proxy,omitempty"     yaml:"allow_proxy,omitempty"`
+	PodNameField             entry.Field     `json:"pod_name_field,omitempty"       yaml:"pod_name_field,omitempty"`
+	NamespaceField           entry.Field     `json:"namespace_field,omitempty"      yaml:"namespace_field,omitempty"`
+	ContainerNameField       entry.Field     `json:"container_name_field,omitempty" yaml:"container_name_field,omitempty"`
+	NodeName                 string          `json:"node_name,omitempty"            yaml:"node_name,omitempty"`
+	IncludeLabels            []string        `json:"include_labels,omitempty"       yaml:"include_labels,omitempty"`
+	ExcludeLabels            []string        `json:"exclude_labels,omitempty"       yaml:"exclude_labels,omitempty"`
+	IncludeAnnotations       []string        `json:"include_annotations,omitempty"  yaml:"include_annotations,omitempty"`
+	ExcludeAnnotations       []string        `json:"exclude_annotations,omitempty"  yaml:"exclude_annotations,omitempty"`
+	Timeout                  helper.Duration `json:"timeout,omitempty"              yaml:"timeout,omitempty"`
+	AllowProxy               bool            `json:"allow_proxy,omitempty"          yaml:"allow_proxy,omitempty"`
+
+	// CacheTTL is no longer used, since metadata is kept up to date by watching the API
+	CacheTTL helper.Duration `json:"cache_ttl,omitempty" yaml:"cache_ttl,omitempty"`
 }
 
 // Build will build a k8s_metadata_decorator operator from the supplied configuration
@@ -66,13 +89,34 @@ func (c K8sMetadataDecoratorConfig) Build(context operator.BuildContext) ([]oper
 		return nil, errors.Wrap(err, "build transformer")
 	}
 
+	labelFilter, err := newKeyFilter(c.IncludeLabels, c.ExcludeLabels)
+	if err != nil {
+		return nil, errors.Wrap(err, "build label filter")
+	}
+
+	annotationFilter, err := newKeyFilter(c.IncludeAnnotations, c.ExcludeAnnotations)
+	if err != nil {
+		return nil, errors.Wrap(err, "build annotation filter")
+	}
+
+	nodeName := c.NodeName
+	if nodeName == "" {
+		nodeName = os.Getenv(nodeNameEnv)
+	}
+
 	op := &K8sMetadataDecorator{
 		TransformerOperator: transformer,
 		podNameField:        c.PodNameField,
 		namespaceField:      c.NamespaceField,
-		cacheTTL:            c.CacheTTL.Raw(),
+		containerNameField:  c.ContainerNameField,
+		nodeName:            nodeName,
+		labelFilter:         labelFilter,
+		annotationFilter:    annotationFilter,
 		timeout:             c.Timeout.Raw(),
 		allowProxy:          c.AllowProxy,
+		pods:                make(map[string]*podMetadata),
+		missingPods:         make(map[string]time.Time),
+		cronJobs:            make(map[string]cronJobLookup),
 	}
 
 	return []operator.Operator{op}, nil
@@ -81,55 +125,109 @@ func (c K8sMetadataDecoratorConfig) Build(context operator.BuildContext) ([]oper
 // K8sMetadataDecorator is an operator for decorating entries with kubernetes metadata
 type K8sMetadataDecorator struct {
 	helper.TransformerOperator
-	podNameField   entry.Field
-	namespaceField entry.Field
-
-	client      *corev1.CoreV1Client
-	appsClient  *appsv1.AppsV1Client
-	batchClient *batchv1.BatchV1Client
-
-	namespaceCache MetadataCache
-	podCache       MetadataCache
-	cacheTTL       time.Duration
-	timeout        time.Duration
-	allowProxy     bool
+	podNameField       entry.Field
+	namespaceField     entry.Field
+	containerNameField entry.Field
+	nodeName           string
+	labelFilter        keyFilter
+	annotationFilter   keyFilter
+	timeout            time.Duration
+	allowProxy         bool
+
+	client     kubernetes.Interface
+	namespaces listersv1.NamespaceLister
+	stop       chan struct{}
+
+	pods    map[string]*podMetadata
+	podsMux sync.RWMutex
+
+	// missingPods are the pods that were not found, and when they may be requested again.
+	// podDeletions counts the deletions reported by the watch. Both are guarded by podsMux.
+	missingPods  map[string]time.Time
+	podDeletions uint64
+
+	// cronJobs are the cron jobs that own jobs, by namespace and job name
+	cronJobs    map[string]cronJobLookup
+	cronJobsMux sync.Mutex
 }
 
-// MetadataCacheEntry is an entry in the metadata cache
-type MetadataCacheEntry struct {
-	ClusterName    string
-	UID            string
-	ExpirationTime time.Time
-	Labels         map[string]string
-	Annotations    map[string]string
-
-	AdditionalResourceValues map[string]string
+// cronJobLookup is the result of requesting the owner of a job. A failed request
+// expires, so that it is retried, while a successful one is kept.
+type cronJobLookup struct {
+	name    string
+	expires time.Time
 }
 
-// MetadataCache is a cache of kubernetes metadata
-type MetadataCache struct {
-	m sync.Map
+// podMetadata is the metadata of a pod, computed whenever the pod changes
+type podMetadata struct {
+	uid         string
+	clusterName string
+	nodeName    string
+	ip          string
+	labels      map[string]string
+	annotations map[string]string
+	images      map[string]string
+	workloads   map[string]string
 }
 
-// Load will return an entry stored in the metadata cache
-func (m *MetadataCache) Load(key string) (MetadataCacheEntry, bool) {
-	entry, ok := m.m.Load(key)
-	if ok {
-		return entry.(MetadataCacheEntry), ok
+// Start will start the k8s_metadata_decorator operator
+func (k *K8sMetadataDecorator) Start() error {
+	if k.client == nil {
+		client, err := k.newClient()
+		if err != nil {
+			return err
+		}
+		k.client = client
+	}
+
+	if k.nodeName == "" {
+		k.Warnw("No node name is set, so metadata for all pods in the cluster will be watched", "env", nodeNameEnv)
 	}
-	return MetadataCacheEntry{}, ok
+
+	namespaceFactory := informers.NewSharedInformerFactory(k.client, 0)
+	podFactory := informers.NewSharedInformerFactoryWithOptions(k.client, 0, informers.WithTweakListOptions(k.podListOptions))
+
+	namespaceInformer := namespaceFactory.Core().V1().Namespaces()
+	k.namespaces = namespaceInformer.Lister()
+
+	podInformer := podFactory.Core().V1().Pods().Informer()
+	podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    k.storePod,
+		UpdateFunc: func(_, obj interface{}) { k.storePod(obj) },
+		DeleteFunc: k.deletePod,
+	})
+
+	k.stop = make(chan struct{})
+	namespaceFactory.Start(k.stop)
+	podFactory.Start(k.stop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), k.timeout)
+	defer cancel()
+	if !cache.WaitForCacheSync(ctx.Done(), namespaceInformer.Informer().HasSynced, podInformer.HasSynced) {
+		close(k.stop)
+		return errors.NewError(
+			"timed out waiting for kubernetes metadata",
+			"ensure the agent's service account is allowed to list and watch pods and namespaces",
+			"timeout", k.timeout.String(),
+		)
+	}
+
+	return nil
 }
 
-// Store will store an entry in the metadata cache
-func (m *MetadataCache) Store(key string, entry MetadataCacheEntry) {
-	m.m.Store(key, entry)
+// Stop will stop watching kubernetes metadata
+func (k *K8sMetadataDecorator) Stop() error {
+	if k.stop != nil {
+		close(k.stop)
+		k.stop = nil
+	}
+	return nil
 }
 
-// Start will start the k8s_metadata_decorator operator
-func (k *K8sMetadataDecorator) Start() error {
+func (k *K8sMetadataDecorator) newClient() (kubernetes.Interface, error) {
 	config, err := rest.InClusterConfig()
 	if err != nil {
-		return errors.NewError(
+		return nil, errors.NewError(
 			"agent not in kubernetes cluster",
 			"the k8s_metadata_decorator operator only supports running in a pod inside a kubernetes cluster",
 		)
@@ -143,33 +241,17 @@ func (k *K8sMetadataDecorator) Start() error {
 
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
-		return errors.Wrap(err, "build client set")
-	}
-
-	k.client = clientset.CoreV1().(*corev1.CoreV1Client)
-	k.appsClient = clientset.AppsV1().(*appsv1.AppsV1Client)
-	k.batchClient = clientset.BatchV1().(*batchv1.BatchV1Client)
-
-	// Test connection
-	ctx, cancel := context.WithTimeout(context.Background(), k.timeout)
-	defer cancel()
-	namespaceList, err := k.client.Namespaces().List(ctx, metav1.ListOptions{})
-	if err != nil {
-		return errors.Wrap(err, "test connection list namespaces")
+		return nil, errors.Wrap(err, "build client set")
 	}
 
-	if len(namespaceList.Items) == 0 {
-		k.Warn("During test connection, namespace list came back empty")
-		return nil
-	}
+	return clientset, nil
+}
 
-	namespaceName := namespaceList.Items[0].ObjectMeta.Name
-	_, err = k.client.Pods(namespaceName).List(ctx, metav1.ListOptions{})
-	if err != nil {
-		return errors.Wrap(err, "test connection list pods")
+// podListOptions limits the pods watched to those scheduled on the local node
+func (k *K8sMetadataDecorator) podListOptions(options *metav1.ListOptions) {
+	if k.nodeName != "" {
+		options.FieldSelector = fields.OneTermEqualSelector("spec.nodeName", k.nodeName).String()
 	}
-
-	return nil
 }
 
 // Process will process an entry received by the k8s_metadata_decorator operator
@@ -183,184 +265,348 @@ func (k *K8sMetadataDecorator) Process(ctx context.Context, entry *entry.Entry)
 	var namespace string
 	err = entry.Read(k.namespaceField, &namespace)
 	if err != nil {
-		return k.HandleEntryError(ctx, entry, errors.Wrap(err, "find namespace").WithDetails("search_field", k.podNameField.String()))
+		return k.HandleEntryError(ctx, entry, errors.Wrap(err, "find namespace").WithDetails("search_field", k.namespaceField.String()))
 	}
 
-	nsMeta, err := k.getNamespaceMetadata(ctx, namespace)
+	ns, err := k.namespaces.Get(namespace)
 	if err != nil {
-		return k.HandleEntryError(ctx, entry, err)
+		return k.HandleEntryError(ctx, entry, errors.Wrap(err, "get namespace metadata").WithDetails("namespace", namespace))
 	}
-	k.decorateEntryWithNamespaceMetadata(nsMeta, entry)
+	k.decorateEntryWithNamespaceMetadata(ns, entry)
 
-	podMeta, err := k.getPodMetadata(ctx, namespace, podName)
+	podMeta, err := k.getPodMetadata(namespace, podName)
 	if err != nil {
 		return k.HandleEntryError(ctx, entry, err)
 	}
 	k.decorateEntryWithPodMetadata(podMeta, entry)
 
+	if jobName, ok := podMeta.workloads["k8s.job.name"]; ok {
+		if cronJob := k.findCronJob(namespace, jobName); cronJob != "" {
+			entry.Resource["k8s.cronjob.name"] = cronJob
+		}
+	}
+
+	var containerName string
+	if err := entry.Read(k.containerNameField, &containerName); err == nil {
+		k.decorateEntryWithContainerMetadata(podMeta, containerName, entry)
+	}
+
 	k.Write(ctx, entry)
 	return nil
 }
 
-func (k *K8sMetadataDecorator) getNamespaceMetadata(ctx context.Context, namespace string) (MetadataCacheEntry, error) {
-	cacheEntry, ok := k.namespaceCache.Load(namespace)
+// getPodMetadata returns the metadata of a pod. Entries can arrive before the watch
+// reports a new pod, so pods that are not cached are requested from the API.
+func (k *K8sMetadataDecorator) getPodMetadata(namespace, podName string) (*podMetadata, error) {
+	key := namespace + "/" + podName
+
+	k.podsMux.RLock()
+	podMeta, ok := k.pods[key]
+	missingUntil, missing := k.missingPods[key]
+	deletions := k.podDeletions
+	k.podsMux.RUnlock()
+	if ok {
+		return podMeta, nil
+	}
+
+	notFound := errors.NewError(
+		"pod not found",
+		"ensure the pod exists",
+		"namespace", namespace,
+		"pod_name", podName,
+	)
+
+	// Entries keep arriving from pods that have been deleted, so a pod that was not
+	// found is not requested again for a while
+	if missing && time.Now().Before(missingUntil) {
+		return nil, notFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), k.timeout)
+	defer cancel()
+
+	pod, err := k.client.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		k.podsMux.Lock()
+		k.addMissingPod(key)
+		k.podsMux.Unlock()
+		return nil, notFound
+	} else if err != nil {
+		return nil, errors.Wrap(err, "get pod metadata").WithDetails("namespace", namespace, "pod_name", podName)
+	}
+
+	podMeta = k.newPodMetadata(pod, nil)
+
+	// Pods that are watched are cached until the watch reports their deletion. Pods that
+	// are being deleted are not cached, since the watch may already have reported it, and
+	// neither are pods requested while a deletion was reported, since it may have been this one.
+	if pod.DeletionTimestamp == nil && (k.nodeName == "" || pod.Spec.NodeName == k.nodeName) {
+		k.podsMux.Lock()
+		if _, ok := k.pods[key]; !ok && k.podDeletions == deletions {
+			k.pods[key] = podMeta
+		}
+		k.podsMux.Unlock()
+	}
+
+	return podMeta, nil
+}
+
+// addMissingPod remembers that a pod was not found, and forgets other pods that
+// have expired. The caller must hold podsMux.
+func (k *K8sMetadataDecorator) addMissingPod(key string) {
+	now := time.Now()
+	for missingKey, until := range k.missingPods {
+		if now.After(until) {
+			delete(k.missingPods, missingKey)
+		}
+	}
+	k.missingPods[key] = now.Add(failedLookupTTL)
+}
 
-	var err error
-	if !ok || cacheEntry.ExpirationTime.Before(time.Now()) {
-		cacheEntry, err = k.refreshNamespaceMetadata(ctx, namespace)
+func (k *K8sMetadataDecorator) storePod(obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return
 	}
+	key := pod.Namespace + "/" + pod.Name
+
+	k.podsMux.RLock()
+	previous := k.pods[key]
+	k.podsMux.RUnlock()
 
-	return cacheEntry, err
+	podMeta := k.newPodMetadata(pod, previous)
+
+	k.podsMux.Lock()
+	k.pods[key] = podMeta
+	delete(k.missingPods, key)
+	k.podsMux.Unlock()
 }
 
-func (k *K8sMetadataDecorator) getPodMetadata(ctx context.Context, namespace, podName string) (MetadataCacheEntry, error) {
-	key := namespace + ":" + podName
-	cacheEntry, ok := k.podCache.Load(key)
+// newPodMetadata computes the metadata of a pod, reusing the workloads of the previous
+// metadata of the same pod
+func (k *K8sMetadataDecorator) newPodMetadata(pod *corev1.Pod, previous *podMetadata) *podMetadata {
+	podMeta := &podMetadata{
+		uid:         string(pod.UID),
+		clusterName: pod.ClusterName,
+		nodeName:    pod.Spec.NodeName,
+		ip:          pod.Status.PodIP,
+		labels:      k.labelFilter.apply(pod.Labels),
+		annotations: k.annotationFilter.apply(pod.Annotations),
+		images:      make(map[string]string),
+	}
 
-	var err error
-	if !ok || cacheEntry.ExpirationTime.Before(time.Now()) {
-		cacheEntry, err = k.refreshPodMetadata(ctx, namespace, podName)
+	// The owners of a pod do not change, so they are only looked up once
+	if previous != nil && previous.uid == podMeta.uid {
+		podMeta.workloads = previous.workloads
+	} else {
+		podMeta.workloads = findWorkloads(pod)
 	}
 
-	return cacheEntry, err
+	for _, container := range pod.Spec.InitContainers {
+		podMeta.images[container.Name] = container.Image
+	}
+	for _, container := range pod.Spec.Containers {
+		podMeta.images[container.Name] = container.Image
+	}
+	return podMeta
 }
 
-func (k *K8sMetadataDecorator) refreshNamespaceMetadata(ctx context.Context, namespace string) (MetadataCacheEntry, error) {
-	ctx, cancel := context.WithTimeout(ctx, k.timeout)
-	defer cancel()
+func (k *K8sMetadataDecorator) deletePod(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
 
-	// Query the API
-	namespaceResponse, err := k.client.Namespaces().Get(ctx, namespace, metav1.GetOptions{})
-	if err != nil {
-		// Add an empty entry to the cache so we don't continuously retry
-		cacheEntry := MetadataCacheEntry{ExpirationTime: time.Now().Add(10 * time.Second)}
-		k.namespaceCache.Store(namespace, cacheEntry)
-		return cacheEntry, errors.Wrap(err, "get namespace metadata").WithDetails("namespace", namespace).WithDetails("retry_after", "10s")
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return
 	}
 
-	// Cache the results
-	cacheEntry := MetadataCacheEntry{
-		ClusterName:    namespaceResponse.ClusterName,
-		ExpirationTime: time.Now().Add(k.cacheTTL),
-		UID:            string(namespaceResponse.UID),
-		Labels:         namespaceResponse.Labels,
-		Annotations:    namespaceResponse.Annotations,
+	k.podsMux.Lock()
+	delete(k.pods, pod.Namespace+"/"+pod.Name)
+	k.podDeletions++
+	k.podsMux.Unlock()
+
+	if jobName := findNameOfKind(pod.OwnerReferences, "Job"); jobName != "" {
+		k.cronJobsMux.Lock()
+		delete(k.cronJobs, pod.Namespace+"/"+jobName)
+		k.cronJobsMux.Unlock()
 	}
-	k.namespaceCache.Store(namespace, cacheEntry)
+}
 
-	return cacheEntry, nil
+// findWorkloads returns the names of the controllers that own a pod. The cron job
+// that owns a job is looked up when entries are processed, since it requires an API request.
+func findWorkloads(pod *corev1.Pod) map[string]string {
+	workloads := make(map[string]string)
+	for _, ref := range pod.OwnerReferences {
+		switch ref.Kind {
+		case "ReplicaSet":
+			workloads["k8s.replicaset.name"] = ref.Name
+			// Deployments name their replica sets after the hash in the pod-template-hash label
+			if hash, ok := pod.Labels["pod-template-hash"]; ok && strings.HasSuffix(ref.Name, "-"+hash) {
+				workloads["k8s.deployment.name"] = strings.TrimSuffix(ref.Name, "-"+hash)
+			}
+		case "StatefulSet":
+			workloads["k8s.statefulset.name"] = ref.Name
+		case "DaemonSet":
+			workloads["k8s.daemonset.name"] = ref.Name
+		case "ReplicationController":
+			workloads["k8s.replicationcontroller.name"] = ref.Name
+		case "Job":
+			workloads["k8s.job.name"] = ref.Name
+		}
+	}
+	return workloads
 }
 
-func (k *K8sMetadataDecorator) refreshPodMetadata(ctx context.Context, namespace, podName string) (MetadataCacheEntry, error) {
-	key := namespace + ":" + podName
+// findCronJob returns the name of the cron job that owns a job. The owner of a job
+// does not change, so it is requested once and kept until the job's pods are deleted.
+// A failed request is retried once failedLookupTTL has passed.
+func (k *K8sMetadataDecorator) findCronJob(namespace, jobName string) string {
+	key := namespace + "/" + jobName
+
+	k.cronJobsMux.Lock()
+	lookup, ok := k.cronJobs[key]
+	k.cronJobsMux.Unlock()
+	if ok && (lookup.expires.IsZero() || time.Now().Before(lookup.expires)) {
+		return lookup.name
+	}
 
-	subCtx, cancel := context.WithTimeout(ctx, k.timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), k.timeout)
 	defer cancel()
 
-	// Query the API
-	podResponse, err := k.client.Pods(namespace).Get(subCtx, podName, metav1.GetOptions{})
+	job, err := k.client.BatchV1().Jobs(namespace).Get(ctx, jobName, metav1.GetOptions{})
 	if err != nil {
-		// Add an empty entry to the cache so we don't continuously retry
-		cacheEntry := MetadataCacheEntry{ExpirationTime: time.Now().Add(10 * time.Second)}
-		k.podCache.Store(key, cacheEntry)
-
-		return cacheEntry, errors.Wrap(err, "get pod metadata").WithDetails(
-			"namespace", namespace,
-			"pod_name", podName,
-			"retry_after", "10s",
-		)
+		k.Errorw("Failed to get job metadata", "namespace", namespace, "job_name", jobName, "error", err)
+		lookup = cronJobLookup{expires: time.Now().Add(failedLookupTTL)}
+	} else {
+		lookup = cronJobLookup{name: findNameOfKind(job.OwnerReferences, "CronJob")}
 	}
 
-	// Create the cache entry
-	cacheEntry := MetadataCacheEntry{
-		ClusterName:    podResponse.ClusterName,
-		UID:            string(podResponse.UID),
-		ExpirationTime: time.Now().Add(k.cacheTTL),
-		Labels:         podResponse.Labels,
-		Annotations:    podResponse.Annotations,
-		AdditionalResourceValues: map[string]string{
-			"k8s.replicaset.name":            findNameOfKind(podResponse.OwnerReferences, "ReplicaSet"),
-			"k8s.daemonset.name":             findNameOfKind(podResponse.OwnerReferences, "DaemonSet"),
-			"k8s.statefulset.name":           findNameOfKind(podResponse.OwnerReferences, "StatefulSet"),
-			"k8s.job.name":                   findNameOfKind(podResponse.OwnerReferences, "Job"),
-			"k8s.replicationcontroller.name": findNameOfKind(podResponse.OwnerReferences, "ReplicationController"),
-			"k8s.service.name":               findNameOfKind(podResponse.OwnerReferences, "ServiceName"),
-		},
-	}
-
-	// Add deployment if it exists
-	rsName := cacheEntry.AdditionalResourceValues["k8s.replicaset.name"]
-	if rsName != "" {
-		subCtx, cancel := context.WithTimeout(ctx, k.timeout)
-		defer cancel()
-		replicaSetResponse, err := k.appsClient.ReplicaSets(namespace).Get(subCtx, rsName, metav1.GetOptions{})
-		if err != nil {
-			k.Errorw("Failed to get replica set metadata", "error", err)
-		} else {
-			cacheEntry.AdditionalResourceValues["k8s.deployment.name"] = findNameOfKind(replicaSetResponse.OwnerReferences, "Deployment")
-		}
+	k.cronJobsMux.Lock()
+	k.cronJobs[key] = lookup
+	k.cronJobsMux.Unlock()
+	return lookup.name
+}
+
+func (k *K8sMetadataDecorator) decorateEntryWithNamespaceMetadata(ns *corev1.Namespace, entry *entry.Entry) {
+	if entry.Labels == nil {
+		entry.Labels = make(map[string]string)
+	}
+	if entry.Resource == nil {
+		entry.Resource = make(map[string]string)
 	}
 
-	// Add cron job name if it exists
-	jobName := cacheEntry.AdditionalResourceValues["k8s.job.name"]
-	if jobName != "" {
-		subCtx, cancel := context.WithTimeout(ctx, k.timeout)
-		defer cancel()
-		replicaSetResponse, err := k.batchClient.Jobs(namespace).Get(subCtx, jobName, metav1.GetOptions{})
-		if err != nil {
-			k.Errorw("Failed to get replica set metadata", "error", err)
-		} else {
-			cacheEntry.AdditionalResourceValues["k8s.cronjob.name"] = findNameOfKind(replicaSetResponse.OwnerReferences, "CronJob")
+	for key, v := range ns.Annotations {
+		if k.annotationFilter.match(key) {
+			entry.Labels["k8s-ns-annotation/"+key] = v
 		}
 	}
 
-	k.podCache.Store(key, cacheEntry)
+	for key, v := range ns.Labels {
+		if k.labelFilter.match(key) {
+			entry.Labels["k8s-ns/"+key] = v
+		}
+	}
 
-	return cacheEntry, nil
+	entry.Resource["k8s.namespace.uid"] = string(ns.UID)
+	if ns.ClusterName != "" {
+		entry.Resource["k8s.cluster.name"] = ns.ClusterName
+	}
 }
 
-func (k *K8sMetadataDecorator) decorateEntryWithNamespaceMetadata(nsMeta MetadataCacheEntry, entry *entry.Entry) {
-	if entry.Labels == nil {
-		entry.Labels = make(map[string]string)
+func (k *K8sMetadataDecorator) decorateEntryWithPodMetadata(podMeta *podMetadata, entry *entry.Entry) {
+	for k, v := range podMeta.annotations {
+		entry.Labels["k8s-pod-annotation/"+k] = v
 	}
 
-	for k, v := range nsMeta.Annotations {
-		entry.Labels["k8s-ns-annotation/"+k] = v
+	for k, v := range podMeta.labels {
+		entry.Labels["k8s-pod/"+k] = v
+	}
+
+	entry.Resource["k8s.pod.uid"] = podMeta.uid
+	if podMeta.clusterName != "" {
+		entry.Resource["k8s.cluster.name"] = podMeta.clusterName
 	}
 
-	for k, v := range nsMeta.Labels {
-		entry.Labels["k8s-ns/"+k] = v
+	if podMeta.nodeName != "" {
+		entry.Resource["k8s.node.name"] = podMeta.nodeName
+	}
+	if podMeta.ip != "" {
+		entry.Resource["k8s.pod.ip"] = podMeta.ip
 	}
 
-	entry.Resource["k8s.namespace.uid"] = nsMeta.UID
-	if nsMeta.ClusterName != "" {
-		entry.Resource["k8s.cluster.name"] = nsMeta.ClusterName
+	for key, value := range podMeta.workloads {
+		entry.Resource[key] = value
 	}
 }
 
-func (k *K8sMetadataDecorator) decorateEntryWithPodMetadata(podMeta MetadataCacheEntry, entry *entry.Entry) {
-	if entry.Labels == nil {
-		entry.Labels = make(map[string]string)
+func (k *K8sMetadataDecorator) decorateEntryWithContainerMetadata(podMeta *podMetadata, containerName string, entry *entry.Entry) {
+	image, ok := podMeta.images[containerName]
+	if !ok {
+		return
 	}
 
-	for k, v := range podMeta.Annotations {
-		entry.Labels["k8s-pod-annotation/"+k] = v
+	name, tag := splitImage(image)
+	entry.Resource["container.image.name"] = name
+	if tag != "" {
+		entry.Resource["container.image.tag"] = tag
 	}
+}
 
-	for k, v := range podMeta.Labels {
-		entry.Labels["k8s-pod/"+k] = v
+// splitImage splits a container image into its name and tag
+func splitImage(image string) (name, tag string) {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+
+	// A colon before the last slash separates a registry host from its port
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i], image[i+1:]
 	}
+	return image, ""
+}
 
-	entry.Resource["k8s.pod.uid"] = podMeta.UID
-	if podMeta.ClusterName != "" {
-		entry.Resource["k8s.cluster.name"] = podMeta.ClusterName
+// keyFilter selects the label and annotation keys added to entries
+type keyFilter struct {
+	include []string
+	exclude []string
+}
+
+func newKeyFilter(include, exclude []string) (keyFilter, error) {
+	for _, pattern := range append(append([]string{}, include...), exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return keyFilter{}, errors.Wrap(err, "parse pattern").WithDetails("pattern", pattern)
+		}
 	}
+	return keyFilter{include: include, exclude: exclude}, nil
+}
+
+// match returns true if a key matches an include pattern, or there are none, and no exclude pattern
+func (f keyFilter) match(key string) bool {
+	if len(f.include) > 0 && !matchAny(f.include, key) {
+		return false
+	}
+	return !matchAny(f.exclude, key)
+}
+
+// apply returns the values whose keys match the filter
+func (f keyFilter) apply(values map[string]string) map[string]string {
+	filtered := make(map[string]string, len(values))
+	for key, value := range values {
+		if f.match(key) {
+			filtered[key] = value
+		}
+	}
+	return filtered
+}
 
-	for key, value := range podMeta.AdditionalResourceValues {
-		if value != "" {
-			entry.Resource[key] = value
+func matchAny(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
 		}
 	}
+	return false
 }
 
 func findNameOfKind(ownerRefs []metav1.OwnerReference, kind string) string {
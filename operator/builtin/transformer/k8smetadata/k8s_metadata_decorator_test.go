@@ -16,6 +16,7 @@ package k8smetadata
 
 import (
 	"context"
+	"os"
 	"testing"
 	"time"
 
@@ -25,33 +26,27 @@ import (
 	"github.com/opentelemetry/opentelemetry-log-collection/testutil"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
-func TestMetadataCache(t *testing.T) {
-	m := MetadataCache{}
-	entry := MetadataCacheEntry{
-		Labels: map[string]string{
-			"label1": "value1",
-		},
-		Annotations: map[string]string{
-			"annotation2": "value2",
-		},
-	}
-
-	m.Store("testkey", entry)
-	loaded, ok := m.Load("testkey")
-	require.True(t, ok)
-	require.Equal(t, entry, loaded)
-}
-
 func basicConfig() *K8sMetadataDecoratorConfig {
 	cfg := NewK8sMetadataDecoratorConfig("testoperator")
 	cfg.OutputIDs = []string{"mock"}
+	cfg.NodeName = "testnode"
 	return cfg
 }
 
 func TestK8sMetadataDecoratorBuildDefault(t *testing.T) {
-	cfg := basicConfig()
+	os.Setenv(nodeNameEnv, "envnode")
+	defer os.Unsetenv(nodeNameEnv)
+
+	cfg := NewK8sMetadataDecoratorConfig("testoperator")
+	cfg.OutputIDs = []string{"mock"}
 
 	expected := &K8sMetadataDecorator{
 		TransformerOperator: helper.TransformerOperator{
@@ -64,11 +59,15 @@ func TestK8sMetadataDecoratorBuildDefault(t *testing.T) {
 			},
 			OnError: "send",
 		},
-		podNameField:   entry.NewResourceField("k8s.pod.name"),
-		namespaceField: entry.NewResourceField("k8s.namespace.name"),
-		cacheTTL:       10 * time.Minute,
-		timeout:        10 * time.Second,
-		allowProxy:     false,
+		podNameField:       entry.NewResourceField("k8s.pod.name"),
+		namespaceField:     entry.NewResourceField("k8s.namespace.name"),
+		containerNameField: entry.NewResourceField("k8s.container.name"),
+		nodeName:           "envnode",
+		timeout:            10 * time.Second,
+		allowProxy:         false,
+		pods:               map[string]*podMetadata{},
+		missingPods:        map[string]time.Time{},
+		cronJobs:           map[string]cronJobLookup{},
 	}
 
 	ops, err := cfg.Build(testutil.NewBuildContext(t))
@@ -76,72 +75,374 @@ func TestK8sMetadataDecoratorBuildDefault(t *testing.T) {
 	op := ops[0]
 	op.(*K8sMetadataDecorator).SugaredLogger = nil
 	require.Equal(t, expected, op)
-
 }
 
-func TestK8sMetadataDecoratorCachedMetadata(t *testing.T) {
-
+func TestK8sMetadataDecoratorBuildInvalidPattern(t *testing.T) {
 	cfg := basicConfig()
-	ops, err := cfg.Build(testutil.NewBuildContext(t))
-	require.NoError(t, err)
-	op := ops[0]
-
-	mockOutput := testutil.NewMockOperator("mock")
-	op.SetOutputs([]operator.Operator{mockOutput})
+	cfg.ExcludeAnnotations = []string{"["}
+	_, err := cfg.Build(testutil.NewBuildContext(t))
+	require.Error(t, err)
+}
 
-	// Preload cache so we don't hit the network
-	k8s := op.(*K8sMetadataDecorator)
-	k8s.namespaceCache.Store("testnamespace", MetadataCacheEntry{
-		ExpirationTime: time.Now().Add(time.Hour),
-		Labels: map[string]string{
-			"label1": "lab1",
-		},
-		ClusterName: "testcluster",
-		Annotations: map[string]string{
-			"annotation1": "ann1",
+func testNamespace() *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "testnamespace",
+			UID:         "nsuid",
+			ClusterName: "testcluster",
+			Labels: map[string]string{
+				"label1": "lab1",
+			},
+			Annotations: map[string]string{
+				"annotation1": "ann1",
+			},
 		},
-	})
+	}
+}
 
-	k8s.podCache.Store("testnamespace:testpodname", MetadataCacheEntry{
-		ExpirationTime: time.Now().Add(time.Hour),
-		Labels: map[string]string{
-			"podlabel1": "podlab1",
-		},
-		Annotations: map[string]string{
-			"podannotation1": "podann1",
+func testPod(name string, owner metav1.OwnerReference) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "testnamespace",
+			UID:       "poduid",
+			Labels: map[string]string{
+				"app.kubernetes.io/name": "web",
+				"pod-template-hash":      "6cdcf6bf9d",
+			},
+			Annotations: map[string]string{
+				"podannotation1":                  "podann1",
+				"kubectl.kubernetes.io/restarted": "now",
+			},
+			OwnerReferences: []metav1.OwnerReference{owner},
 		},
-		AdditionalResourceValues: map[string]string{
-			"k8s.service.name": "testservice",
+		Spec: corev1.PodSpec{
+			NodeName: "testnode",
+			InitContainers: []corev1.Container{
+				{Name: "init", Image: "busybox"},
+			},
+			Containers: []corev1.Container{
+				{Name: "nginx", Image: "registry.example.com:5000/library/nginx:1.19"},
+			},
 		},
-	})
-
-	expected := entry.Entry{
-		Labels: map[string]string{
-			"k8s-pod/podlabel1":                 "podlab1",
-			"k8s-ns/label1":                     "lab1",
-			"k8s-pod-annotation/podannotation1": "podann1",
-			"k8s-ns-annotation/annotation1":     "ann1",
-		},
-		Resource: map[string]string{
-			"k8s.pod.name":       "testpodname",
-			"k8s.namespace.name": "testnamespace",
-			"k8s.service.name":   "testservice",
-			"k8s.cluster.name":   "testcluster",
+		Status: corev1.PodStatus{
+			PodIP: "10.0.0.12",
 		},
 	}
+}
+
+func newTestDecorator(t *testing.T, cfg *K8sMetadataDecoratorConfig, objects ...runtime.Object) (*K8sMetadataDecorator, *fake.Clientset, chan *entry.Entry) {
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	op := ops[0].(*K8sMetadataDecorator)
 
+	client := fake.NewSimpleClientset(objects...)
+	op.client = client
+
+	received := make(chan *entry.Entry, 10)
+	mockOutput := testutil.NewMockOperator("$.mock")
 	mockOutput.On("Process", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
-		entry := args.Get(1).(*entry.Entry)
-		require.Equal(t, expected.Labels, entry.Labels)
-		require.Equal(t, expected.Resource, entry.Resource)
+		received <- args.Get(1).(*entry.Entry)
 	}).Return(nil)
+	require.NoError(t, op.SetOutputs([]operator.Operator{mockOutput}))
 
-	e := &entry.Entry{
+	require.NoError(t, op.Start())
+	t.Cleanup(func() { op.Stop() })
+	return op, client, received
+}
+
+func newTestEntry(podName string) *entry.Entry {
+	return &entry.Entry{
 		Resource: map[string]string{
-			"k8s.pod.name":       "testpodname",
+			"k8s.pod.name":       podName,
 			"k8s.namespace.name": "testnamespace",
+			"k8s.container.name": "nginx",
+		},
+	}
+}
+
+func processEntry(t *testing.T, op *K8sMetadataDecorator, received chan *entry.Entry, e *entry.Entry) *entry.Entry {
+	require.NoError(t, op.Process(context.Background(), e))
+	select {
+	case e := <-received:
+		return e
+	case <-time.After(time.Second):
+		require.FailNow(t, "Timed out waiting for entry")
+		return nil
+	}
+}
+
+func TestK8sMetadataDecoratorProcess(t *testing.T) {
+	pod := testPod("web-6cdcf6bf9d-f4f9n", metav1.OwnerReference{Kind: "ReplicaSet", Name: "web-6cdcf6bf9d"})
+	op, _, received := newTestDecorator(t, basicConfig(), testNamespace(), pod)
+
+	e := processEntry(t, op, received, newTestEntry(pod.Name))
+
+	expectedLabels := map[string]string{
+		"k8s-ns/label1":                                      "lab1",
+		"k8s-ns-annotation/annotation1":                      "ann1",
+		"k8s-pod/app.kubernetes.io/name":                     "web",
+		"k8s-pod/pod-template-hash":                          "6cdcf6bf9d",
+		"k8s-pod-annotation/podannotation1":                  "podann1",
+		"k8s-pod-annotation/kubectl.kubernetes.io/restarted": "now",
+	}
+	require.Equal(t, expectedLabels, e.Labels)
+
+	expectedResource := map[string]string{
+		"k8s.pod.name":         "web-6cdcf6bf9d-f4f9n",
+		"k8s.namespace.name":   "testnamespace",
+		"k8s.container.name":   "nginx",
+		"k8s.namespace.uid":    "nsuid",
+		"k8s.cluster.name":     "testcluster",
+		"k8s.pod.uid":          "poduid",
+		"k8s.pod.ip":           "10.0.0.12",
+		"k8s.node.name":        "testnode",
+		"k8s.replicaset.name":  "web-6cdcf6bf9d",
+		"k8s.deployment.name":  "web",
+		"container.image.name": "registry.example.com:5000/library/nginx",
+		"container.image.tag":  "1.19",
+	}
+	require.Equal(t, expectedResource, e.Resource)
+}
+
+func TestK8sMetadataDecoratorWatchesLocalNode(t *testing.T) {
+	_, client, _ := newTestDecorator(t, basicConfig(), testNamespace())
+
+	var selectors []string
+	for _, action := range client.Actions() {
+		if list, ok := action.(k8stesting.ListAction); ok && action.GetResource().Resource == "pods" {
+			selectors = append(selectors, list.GetListRestrictions().Fields.String())
+		}
+	}
+	require.Equal(t, []string{"spec.nodeName=testnode"}, selectors)
+}
+
+func TestK8sMetadataDecoratorCronJob(t *testing.T) {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "backup-1602836100",
+			Namespace:       "testnamespace",
+			OwnerReferences: []metav1.OwnerReference{{Kind: "CronJob", Name: "backup"}},
 		},
 	}
-	err = op.Process(context.Background(), e)
+	pod := testPod("backup-1602836100-x7k2p", metav1.OwnerReference{Kind: "Job", Name: job.Name})
+	op, client, received := newTestDecorator(t, basicConfig(), testNamespace(), job, pod)
+
+	// The job is not requested by the watch
+	require.Equal(t, 0, countActions(client, "get", "jobs"))
+
+	for i := 0; i < 2; i++ {
+		e := processEntry(t, op, received, newTestEntry(pod.Name))
+		require.Equal(t, "backup-1602836100", e.Resource["k8s.job.name"])
+		require.Equal(t, "backup", e.Resource["k8s.cronjob.name"])
+		require.NotContains(t, e.Resource, "k8s.deployment.name")
+	}
+	require.Equal(t, 1, countActions(client, "get", "jobs"))
+}
+
+func TestK8sMetadataDecoratorCronJobLookupFailed(t *testing.T) {
+	pod := testPod("backup-1602836100-x7k2p", metav1.OwnerReference{Kind: "Job", Name: "backup-1602836100"})
+	op, client, received := newTestDecorator(t, basicConfig(), testNamespace(), pod)
+
+	for i := 0; i < 2; i++ {
+		e := processEntry(t, op, received, newTestEntry(pod.Name))
+		require.Equal(t, "backup-1602836100", e.Resource["k8s.job.name"])
+		require.NotContains(t, e.Resource, "k8s.cronjob.name")
+	}
+	require.Equal(t, 1, countActions(client, "get", "jobs"))
+
+	// The job is requested again once the failure expires
+	op.cronJobsMux.Lock()
+	op.cronJobs[pod.Namespace+"/backup-1602836100"] = cronJobLookup{expires: time.Now().Add(-time.Second)}
+	op.cronJobsMux.Unlock()
+
+	processEntry(t, op, received, newTestEntry(pod.Name))
+	require.Equal(t, 2, countActions(client, "get", "jobs"))
+}
+
+func countActions(client *fake.Clientset, verb, resource string) int {
+	count := 0
+	for _, action := range client.Actions() {
+		if action.GetVerb() == verb && action.GetResource().Resource == resource {
+			count++
+		}
+	}
+	return count
+}
+
+func TestK8sMetadataDecoratorStatefulSet(t *testing.T) {
+	pod := testPod("db-0", metav1.OwnerReference{Kind: "StatefulSet", Name: "db"})
+	op, _, received := newTestDecorator(t, basicConfig(), testNamespace(), pod)
+
+	e := processEntry(t, op, received, newTestEntry(pod.Name))
+	require.Equal(t, "db", e.Resource["k8s.statefulset.name"])
+	require.NotContains(t, e.Resource, "k8s.replicaset.name")
+}
+
+func TestK8sMetadataDecoratorFilters(t *testing.T) {
+	cfg := basicConfig()
+	cfg.IncludeLabels = []string{"app.kubernetes.io/*"}
+	cfg.ExcludeAnnotations = []string{"kubectl.kubernetes.io/*", "annotation1"}
+
+	pod := testPod("web-6cdcf6bf9d-f4f9n", metav1.OwnerReference{Kind: "ReplicaSet", Name: "web-6cdcf6bf9d"})
+	op, _, received := newTestDecorator(t, cfg, testNamespace(), pod)
+
+	e := processEntry(t, op, received, newTestEntry(pod.Name))
+	expectedLabels := map[string]string{
+		"k8s-pod/app.kubernetes.io/name":    "web",
+		"k8s-pod-annotation/podannotation1": "podann1",
+	}
+	require.Equal(t, expectedLabels, e.Labels)
+}
+
+func TestK8sMetadataDecoratorPodChanges(t *testing.T) {
+	op, client, received := newTestDecorator(t, basicConfig(), testNamespace())
+
+	pod := testPod("web-6cdcf6bf9d-f4f9n", metav1.OwnerReference{Kind: "ReplicaSet", Name: "web-6cdcf6bf9d"})
+	pod.Status.PodIP = ""
+
+	e := processEntry(t, op, received, newTestEntry(pod.Name))
+	require.NotContains(t, e.Resource, "k8s.pod.uid")
+
+	_, err := client.CoreV1().Pods(pod.Namespace).Create(context.Background(), pod, metav1.CreateOptions{})
 	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		op.podsMux.RLock()
+		defer op.podsMux.RUnlock()
+		return op.pods[pod.Namespace+"/"+pod.Name] != nil
+	}, time.Second, 10*time.Millisecond)
+
+	e = processEntry(t, op, received, newTestEntry(pod.Name))
+	require.NotContains(t, e.Resource, "k8s.pod.ip")
+
+	pod.Status.PodIP = "10.0.0.12"
+	_, err = client.CoreV1().Pods(pod.Namespace).Update(context.Background(), pod, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		op.podsMux.RLock()
+		defer op.podsMux.RUnlock()
+		return op.pods[pod.Namespace+"/"+pod.Name].ip != ""
+	}, time.Second, 10*time.Millisecond)
+
+	e = processEntry(t, op, received, newTestEntry(pod.Name))
+	require.Equal(t, "10.0.0.12", e.Resource["k8s.pod.ip"])
+	require.Equal(t, "web", e.Resource["k8s.deployment.name"])
+
+	err = client.CoreV1().Pods(pod.Namespace).Delete(context.Background(), pod.Name, metav1.DeleteOptions{})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		op.podsMux.RLock()
+		defer op.podsMux.RUnlock()
+		return op.pods[pod.Namespace+"/"+pod.Name] == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestK8sMetadataDecoratorPodNotCached(t *testing.T) {
+	pod := testPod("web-6cdcf6bf9d-f4f9n", metav1.OwnerReference{Kind: "ReplicaSet", Name: "web-6cdcf6bf9d"})
+	op, client, received := newTestDecorator(t, basicConfig(), testNamespace(), pod)
+
+	// Simulate an entry that arrives before the watch reports its pod
+	op.podsMux.Lock()
+	delete(op.pods, pod.Namespace+"/"+pod.Name)
+	op.podsMux.Unlock()
+
+	e := processEntry(t, op, received, newTestEntry(pod.Name))
+	require.Equal(t, "poduid", e.Resource["k8s.pod.uid"])
+	require.Equal(t, "web", e.Resource["k8s.deployment.name"])
+	require.Equal(t, 1, countActions(client, "get", "pods"))
+
+	// The pod is cached until the watch reports its deletion
+	e = processEntry(t, op, received, newTestEntry(pod.Name))
+	require.Equal(t, "poduid", e.Resource["k8s.pod.uid"])
+	require.Equal(t, 1, countActions(client, "get", "pods"))
+}
+
+func TestK8sMetadataDecoratorPodNotFound(t *testing.T) {
+	op, client, received := newTestDecorator(t, basicConfig(), testNamespace())
+
+	for i := 0; i < 2; i++ {
+		e := processEntry(t, op, received, newTestEntry("deletedpod"))
+		require.NotContains(t, e.Resource, "k8s.pod.uid")
+	}
+	require.Equal(t, 1, countActions(client, "get", "pods"))
+
+	// The pod is requested again once the lookup expires
+	op.podsMux.Lock()
+	op.missingPods["testnamespace/deletedpod"] = time.Now().Add(-time.Second)
+	op.podsMux.Unlock()
+
+	processEntry(t, op, received, newTestEntry("deletedpod"))
+	require.Equal(t, 2, countActions(client, "get", "pods"))
+}
+
+func TestK8sMetadataDecoratorPodDeletedDuringRequest(t *testing.T) {
+	pod := testPod("web-6cdcf6bf9d-f4f9n", metav1.OwnerReference{Kind: "ReplicaSet", Name: "web-6cdcf6bf9d"})
+	op, client, received := newTestDecorator(t, basicConfig(), testNamespace(), pod)
+
+	op.podsMux.Lock()
+	delete(op.pods, pod.Namespace+"/"+pod.Name)
+	op.podsMux.Unlock()
+
+	// Simulate the watch reporting the deletion while the pod is being requested
+	client.PrependReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		op.deletePod(pod)
+		return false, nil, nil
+	})
+
+	e := processEntry(t, op, received, newTestEntry(pod.Name))
+	require.Equal(t, "poduid", e.Resource["k8s.pod.uid"])
+
+	op.podsMux.RLock()
+	defer op.podsMux.RUnlock()
+	require.NotContains(t, op.pods, pod.Namespace+"/"+pod.Name)
+}
+
+func TestK8sMetadataDecoratorPodOnOtherNode(t *testing.T) {
+	pod := testPod("web-6cdcf6bf9d-f4f9n", metav1.OwnerReference{Kind: "ReplicaSet", Name: "web-6cdcf6bf9d"})
+	pod.Spec.NodeName = "othernode"
+	op, _, received := newTestDecorator(t, basicConfig(), testNamespace(), pod)
+
+	// The fake client does not filter the watch by node
+	op.podsMux.Lock()
+	delete(op.pods, pod.Namespace+"/"+pod.Name)
+	op.podsMux.Unlock()
+
+	e := processEntry(t, op, received, newTestEntry(pod.Name))
+	require.Equal(t, "poduid", e.Resource["k8s.pod.uid"])
+	require.Equal(t, "othernode", e.Resource["k8s.node.name"])
+
+	// Pods that are not watched are not cached, since their deletion would not be seen
+	op.podsMux.RLock()
+	defer op.podsMux.RUnlock()
+	require.NotContains(t, op.pods, pod.Namespace+"/"+pod.Name)
+}
+
+func TestK8sMetadataDecoratorUnknownNamespace(t *testing.T) {
+	op, _, received := newTestDecorator(t, basicConfig())
+
+	e := processEntry(t, op, received, newTestEntry("testpodname"))
+	require.NotContains(t, e.Resource, "k8s.pod.uid")
+}
+
+func TestSplitImage(t *testing.T) {
+	cases := []struct {
+		image string
+		name  string
+		tag   string
+	}{
+		{"nginx", "nginx", ""},
+		{"nginx:1.19", "nginx", "1.19"},
+		{"library/nginx:1.19", "library/nginx", "1.19"},
+		{"registry.example.com:5000/nginx", "registry.example.com:5000/nginx", ""},
+		{"registry.example.com:5000/nginx:1.19", "registry.example.com:5000/nginx", "1.19"},
+		{"nginx:1.19@sha256:abc123", "nginx", "1.19"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.image, func(t *testing.T) {
+			name, tag := splitImage(tc.image)
+			require.Equal(t, tc.name, name)
+			require.Equal(t, tc.tag, tag)
+		})
+	}
 }
@@ -0,0 +1,463 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reduce
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/errors"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+)
+
+func init() {
+	operator.Register("reduce", func() operator.Builder { return NewReduceOperatorConfig("") })
+}
+
+const (
+	concatStrategy = "concat"
+	sumStrategy    = "sum"
+	maxStrategy    = "max"
+	firstStrategy  = "first"
+	lastStrategy   = "last"
+
+	defaultSeparator = "\n"
+
+	// maxExpireDelay is the longest a group may stay open after its window has ended
+	maxExpireDelay = time.Second
+)
+
+// NewReduceOperatorConfig creates a new reduce config with default values
+func NewReduceOperatorConfig(operatorID string) *ReduceOperatorConfig {
+	return &ReduceOperatorConfig{
+		TransformerConfig:  helper.NewTransformerConfig(operatorID, "reduce"),
+		Window:             helper.Duration{Duration: 30 * time.Second},
+		MaxGroupSize:       1000,
+		MaxGroups:          1000,
+		CountField:         entry.NewLabelField("reduce_count"),
+		LastTimestampField: entry.NewLabelField("reduce_last_timestamp"),
+	}
+}
+
+// ReduceOperatorConfig is the configuration of a reduce operator
+type ReduceOperatorConfig struct {
+	helper.TransformerConfig `yaml:",inline"`
+
+	GroupBy            []entry.Field   `json:"group_by,omitempty"             yaml:"group_by,omitempty"`
+	Window             helper.Duration `json:"window,omitempty"               yaml:"window,omitempty"`
+	MaxGroupSize       int             `json:"max_group_size,omitempty"       yaml:"max_group_size,omitempty"`
+	MaxGroups          int             `json:"max_groups,omitempty"           yaml:"max_groups,omitempty"`
+	Merge              []MergeConfig   `json:"merge,omitempty"                yaml:"merge,omitempty"`
+	CountField         entry.Field     `json:"count_field,omitempty"          yaml:"count_field,omitempty"`
+	LastTimestampField entry.Field     `json:"last_timestamp_field,omitempty" yaml:"last_timestamp_field,omitempty"`
+}
+
+// MergeConfig is the configuration of how a field is merged
+type MergeConfig struct {
+	Field     entry.Field `json:"field"               yaml:"field"`
+	Strategy  string      `json:"strategy"            yaml:"strategy"`
+	Separator *string     `json:"separator,omitempty" yaml:"separator,omitempty"`
+}
+
+// Build will build a reduce operator from the supplied configuration
+func (c ReduceOperatorConfig) Build(context operator.BuildContext) ([]operator.Operator, error) {
+	transformerOperator, err := c.TransformerConfig.Build(context)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Window.Raw() <= 0 {
+		return nil, fmt.Errorf("invalid value for window '%s'", c.Window.Raw())
+	}
+
+	if c.MaxGroupSize <= 0 {
+		return nil, fmt.Errorf("`max_group_size` must be positive")
+	}
+
+	if c.MaxGroups <= 0 {
+		return nil, fmt.Errorf("`max_groups` must be positive")
+	}
+
+	for _, merge := range c.Merge {
+		if merge.Field.FieldInterface == nil {
+			return nil, fmt.Errorf("missing required argument 'field' in merge")
+		}
+
+		switch merge.Strategy {
+		case concatStrategy, sumStrategy, maxStrategy, firstStrategy, lastStrategy:
+		default:
+			return nil, fmt.Errorf("invalid strategy '%s' for field '%s'", merge.Strategy, merge.Field.String())
+		}
+
+		if merge.Separator != nil && merge.Strategy != concatStrategy {
+			return nil, fmt.Errorf("`separator` can only be used with `strategy: %s`", concatStrategy)
+		}
+	}
+
+	reduceOperator := &ReduceOperator{
+		TransformerOperator: transformerOperator,
+		groupBy:             c.GroupBy,
+		window:              c.Window.Raw(),
+		maxGroupSize:        c.MaxGroupSize,
+		maxGroups:           c.MaxGroups,
+		merge:               c.Merge,
+		countField:          c.CountField,
+		lastTimestampField:  c.LastTimestampField,
+		order:               list.New(),
+		groups:              make(map[string]*list.Element),
+	}
+	return []operator.Operator{reduceOperator}, nil
+}
+
+// ReduceOperator is an operator that merges the entries of a group that
+// arrive within a window into a single entry
+type ReduceOperator struct {
+	helper.TransformerOperator
+
+	groupBy            []entry.Field
+	window             time.Duration
+	maxGroupSize       int
+	maxGroups          int
+	merge              []MergeConfig
+	countField         entry.Field
+	lastTimestampField entry.Field
+
+	// order lists the open groups by when they were opened. A window starts when
+	// its group is opened, so the group at the front is always the next to expire.
+	order     *list.List
+	groups    map[string]*list.Element
+	groupsMux sync.Mutex
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// group is the state of the entries of a group that have been merged so far.
+// The first entry is the base of the merged entry.
+type group struct {
+	key            string
+	expires        time.Time
+	base           *entry.Entry
+	count          int
+	firstTimestamp time.Time
+	lastTimestamp  time.Time
+	fields         []*fieldState
+}
+
+// Start will start flushing groups when their window expires
+func (r *ReduceOperator) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	r.wg.Add(1)
+	go r.expireGroups(ctx)
+	return nil
+}
+
+// expireGroups checks for expired groups until the context is cancelled. Short
+// windows are checked as often as they end, and longer windows every maxExpireDelay.
+func (r *ReduceOperator) expireGroups(ctx context.Context) {
+	defer r.wg.Done()
+
+	checkInterval := maxExpireDelay
+	if r.window < checkInterval {
+		checkInterval = r.window
+	}
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			r.expire(ctx, now)
+		}
+	}
+}
+
+// Stop will flush all open groups
+func (r *ReduceOperator) Stop() error {
+	r.cancel()
+	r.wg.Wait()
+
+	r.groupsMux.Lock()
+	merged := make([]*entry.Entry, 0, r.order.Len())
+	for e := r.order.Front(); e != nil; e = r.order.Front() {
+		merged = append(merged, r.close(e))
+	}
+	r.groupsMux.Unlock()
+
+	r.write(context.Background(), merged)
+	return nil
+}
+
+// Process will add an entry to its group
+func (r *ReduceOperator) Process(ctx context.Context, e *entry.Entry) error {
+	key, err := r.groupKey(e)
+	if err != nil {
+		return r.HandleEntryError(ctx, e, err)
+	}
+
+	// Merged entries are written after the lock is released, so that a slow
+	// output does not hold up the other groups
+	var merged []*entry.Entry
+
+	r.groupsMux.Lock()
+	elem, ok := r.groups[key]
+	if !ok {
+		if r.order.Len() >= r.maxGroups {
+			merged = append(merged, r.close(r.order.Front()))
+		}
+		elem = r.newGroup(key, e)
+	}
+
+	g := elem.Value.(*group)
+	g.add(e, r.merge)
+	if g.count >= r.maxGroupSize {
+		merged = append(merged, r.close(elem))
+	}
+	r.groupsMux.Unlock()
+
+	r.write(ctx, merged)
+	return nil
+}
+
+// groupKey returns the values of the group_by fields encoded as a string
+func (r *ReduceOperator) groupKey(entry *entry.Entry) (string, error) {
+	values := make([]interface{}, len(r.groupBy))
+	for i, field := range r.groupBy {
+		values[i], _ = entry.Get(field)
+	}
+
+	key, err := json.Marshal(values)
+	if err != nil {
+		return "", errors.Wrap(err, "encode group_by fields")
+	}
+	return string(key), nil
+}
+
+func (r *ReduceOperator) newGroup(key string, base *entry.Entry) *list.Element {
+	g := &group{
+		key:            key,
+		expires:        time.Now().Add(r.window),
+		base:           base,
+		firstTimestamp: base.Timestamp,
+		lastTimestamp:  base.Timestamp,
+		fields:         make([]*fieldState, len(r.merge)),
+	}
+	for i := range g.fields {
+		g.fields[i] = &fieldState{}
+	}
+
+	e := r.order.PushBack(g)
+	r.groups[key] = e
+	return e
+}
+
+// add merges an entry into the group
+func (g *group) add(entry *entry.Entry, merge []MergeConfig) {
+	g.count++
+	if entry.Timestamp.Before(g.firstTimestamp) {
+		g.firstTimestamp = entry.Timestamp
+	}
+	if entry.Timestamp.After(g.lastTimestamp) {
+		g.lastTimestamp = entry.Timestamp
+	}
+
+	for i, m := range merge {
+		if value, ok := entry.Get(m.Field); ok {
+			g.fields[i].add(m.Strategy, value)
+		}
+	}
+}
+
+// expire flushes the groups whose window has expired
+func (r *ReduceOperator) expire(ctx context.Context, now time.Time) {
+	var merged []*entry.Entry
+
+	r.groupsMux.Lock()
+	for e := r.order.Front(); e != nil; e = r.order.Front() {
+		if now.Before(e.Value.(*group).expires) {
+			break
+		}
+		merged = append(merged, r.close(e))
+	}
+	r.groupsMux.Unlock()
+
+	r.write(ctx, merged)
+}
+
+// write sends merged entries to the outputs. It must not be called with groupsMux held.
+func (r *ReduceOperator) write(ctx context.Context, merged []*entry.Entry) {
+	for _, e := range merged {
+		r.Write(ctx, e)
+	}
+}
+
+// close removes a group and returns its merged entry. The caller must hold groupsMux.
+func (r *ReduceOperator) close(e *list.Element) *entry.Entry {
+	g := e.Value.(*group)
+	delete(r.groups, g.key)
+	r.order.Remove(e)
+
+	merged := g.base
+	merged.Timestamp = g.firstTimestamp
+
+	for i, m := range r.merge {
+		value, ok := g.fields[i].result(m)
+		if !ok {
+			continue
+		}
+		if err := merged.Set(m.Field, value); err != nil {
+			r.Warnw("Failed to set merged field", "field", m.Field.String(), "error", err)
+		}
+	}
+
+	r.setMetadata(merged, r.countField, g.count, strconv.Itoa(g.count))
+	r.setMetadata(merged, r.lastTimestampField, g.lastTimestamp, g.lastTimestamp.Format(time.RFC3339Nano))
+
+	return merged
+}
+
+// setMetadata sets a field added by the operator. Labels and resource keys
+// can only hold strings, so they are set to the formatted value.
+func (r *ReduceOperator) setMetadata(e *entry.Entry, field entry.Field, value interface{}, formatted string) {
+	if field.FieldInterface == nil {
+		return
+	}
+
+	if _, ok := field.FieldInterface.(entry.RecordField); !ok {
+		value = formatted
+	}
+	if err := e.Set(field, value); err != nil {
+		r.Warnw("Failed to set field", "field", field.String(), "error", err)
+	}
+}
+
+// fieldState is the merged value of a field so far
+type fieldState struct {
+	set     bool
+	value   interface{}
+	strings []string
+
+	isFloat    bool
+	intValue   int64
+	floatValue float64
+}
+
+func (f *fieldState) add(strategy string, value interface{}) {
+	switch strategy {
+	case firstStrategy:
+		if !f.set {
+			f.value = value
+		}
+	case lastStrategy:
+		f.value = value
+	case concatStrategy:
+		if s, ok := value.(string); ok {
+			f.strings = append(f.strings, s)
+		} else {
+			f.strings = append(f.strings, fmt.Sprint(value))
+		}
+	case sumStrategy, maxStrategy:
+		i, fl, isFloat, ok := toNumber(value)
+		if !ok {
+			return
+		}
+		f.addNumber(strategy, i, fl, isFloat)
+	}
+	f.set = true
+}
+
+// addNumber tracks the result both as an integer and as a float, so that
+// integers keep their precision unless a float is seen
+func (f *fieldState) addNumber(strategy string, i int64, fl float64, isFloat bool) {
+	if !isFloat {
+		fl = float64(i)
+	}
+
+	switch {
+	case !f.set:
+		f.intValue, f.floatValue = i, fl
+	case strategy == sumStrategy:
+		f.intValue += i
+		f.floatValue += fl
+	case fl > f.floatValue:
+		f.intValue, f.floatValue = i, fl
+	}
+	f.isFloat = f.isFloat || isFloat
+}
+
+func (f *fieldState) result(m MergeConfig) (interface{}, bool) {
+	if !f.set {
+		return nil, false
+	}
+
+	switch m.Strategy {
+	case concatStrategy:
+		separator := defaultSeparator
+		if m.Separator != nil {
+			separator = *m.Separator
+		}
+		return strings.Join(f.strings, separator), true
+	case sumStrategy, maxStrategy:
+		if f.isFloat {
+			return f.floatValue, true
+		}
+		return f.intValue, true
+	default:
+		return f.value, true
+	}
+}
+
+// toNumber converts a numeric value, or a string holding a number, to an
+// integer or a float
+func toNumber(value interface{}) (int64, float64, bool, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), 0, false, true
+	case int32:
+		return int64(v), 0, false, true
+	case int64:
+		return v, 0, false, true
+	case uint:
+		return int64(v), 0, false, true
+	case uint32:
+		return int64(v), 0, false, true
+	case uint64:
+		return int64(v), 0, false, true
+	case float32:
+		return 0, float64(v), true, true
+	case float64:
+		return 0, v, true, true
+	case string:
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return i, 0, false, true
+		}
+		if fl, err := strconv.ParseFloat(v, 64); err == nil {
+			return 0, fl, true, true
+		}
+	}
+	return 0, 0, false, false
+}
@@ -0,0 +1,341 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reduce
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator"
+	"github.com/opentelemetry/opentelemetry-log-collection/operator/helper"
+	"github.com/opentelemetry/opentelemetry-log-collection/testutil"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func newTestOperator(t *testing.T, configure func(*ReduceOperatorConfig)) (operator.Operator, *testutil.FakeOutput) {
+	cfg := NewReduceOperatorConfig("test_id")
+	cfg.OutputIDs = []string{"$.fake"}
+	cfg.Window = helper.Duration{Duration: time.Hour}
+	if configure != nil {
+		configure(cfg)
+	}
+
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	op := ops[0]
+
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, op.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, op.Start())
+	return op, fake
+}
+
+var baseTime = time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func newEntry(offset time.Duration, record map[string]interface{}) *entry.Entry {
+	e := entry.New()
+	e.Timestamp = baseTime.Add(offset)
+	e.Record = record
+	return e
+}
+
+func receive(t *testing.T, fake *testutil.FakeOutput) *entry.Entry {
+	select {
+	case e := <-fake.Received:
+		return e
+	case <-time.After(time.Second):
+		require.FailNow(t, "Timed out waiting for entry")
+	}
+	return nil
+}
+
+func expectNoEntry(t *testing.T, fake *testutil.FakeOutput) {
+	select {
+	case e := <-fake.Received:
+		require.FailNow(t, "Received unexpected entry", e.Record)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func separator(s string) *string {
+	return &s
+}
+
+func TestReduceMerge(t *testing.T) {
+	op, fake := newTestOperator(t, func(cfg *ReduceOperatorConfig) {
+		cfg.Window = helper.Duration{Duration: 50 * time.Millisecond}
+		cfg.GroupBy = []entry.Field{entry.NewRecordField("service")}
+		cfg.Merge = []MergeConfig{
+			{Field: entry.NewRecordField("message"), Strategy: concatStrategy, Separator: separator(" | ")},
+			{Field: entry.NewRecordField("bytes"), Strategy: sumStrategy},
+			{Field: entry.NewRecordField("latency"), Strategy: maxStrategy},
+			{Field: entry.NewRecordField("status"), Strategy: lastStrategy},
+			{Field: entry.NewRecordField("user"), Strategy: firstStrategy},
+		}
+	})
+	defer op.Stop()
+
+	ctx := context.Background()
+	require.NoError(t, op.Process(ctx, newEntry(time.Second, map[string]interface{}{
+		"service": "api", "message": "a", "bytes": 10, "latency": 0.5, "status": "200",
+	})))
+	require.NoError(t, op.Process(ctx, newEntry(0, map[string]interface{}{
+		"service": "web", "message": "x", "bytes": 1,
+	})))
+	require.NoError(t, op.Process(ctx, newEntry(3*time.Second, map[string]interface{}{
+		"service": "api", "message": "b", "bytes": "20", "latency": 2, "status": "500", "user": "alice",
+	})))
+	require.NoError(t, op.Process(ctx, newEntry(2*time.Second, map[string]interface{}{
+		"service": "api", "message": "c", "bytes": 5, "latency": "1.5", "status": "502", "user": "bob",
+	})))
+
+	api := receive(t, fake)
+	require.Equal(t, baseTime.Add(time.Second), api.Timestamp)
+	require.Equal(t, map[string]string{
+		"reduce_count":          "3",
+		"reduce_last_timestamp": "2020-10-01T12:00:03Z",
+	}, api.Labels)
+	require.Equal(t, map[string]interface{}{
+		"service": "api",
+		"message": "a | b | c",
+		"bytes":   int64(35),
+		"latency": 2.0,
+		"status":  "502",
+		"user":    "alice",
+	}, api.Record)
+
+	web := receive(t, fake)
+	require.Equal(t, "1", web.Labels["reduce_count"])
+	require.Equal(t, map[string]interface{}{
+		"service": "web",
+		"message": "x",
+		"bytes":   int64(1),
+	}, web.Record)
+
+	expectNoEntry(t, fake)
+}
+
+func TestReduceWithoutGroupBy(t *testing.T) {
+	op, fake := newTestOperator(t, func(cfg *ReduceOperatorConfig) {
+		cfg.Merge = []MergeConfig{{Field: entry.NewRecordField("message"), Strategy: concatStrategy}}
+	})
+
+	ctx := context.Background()
+	require.NoError(t, op.Process(ctx, newEntry(0, map[string]interface{}{"service": "api", "message": "a"})))
+	require.NoError(t, op.Process(ctx, newEntry(0, map[string]interface{}{"service": "web", "message": "b"})))
+	expectNoEntry(t, fake)
+
+	require.NoError(t, op.Stop())
+	e := receive(t, fake)
+	require.Equal(t, "2", e.Labels["reduce_count"])
+	require.Equal(t, "a\nb", e.Record.(map[string]interface{})["message"])
+}
+
+func TestReduceMaxGroupSize(t *testing.T) {
+	op, fake := newTestOperator(t, func(cfg *ReduceOperatorConfig) {
+		cfg.MaxGroupSize = 2
+	})
+	defer op.Stop()
+
+	ctx := context.Background()
+	require.NoError(t, op.Process(ctx, newEntry(0, map[string]interface{}{"message": "a"})))
+	expectNoEntry(t, fake)
+	require.NoError(t, op.Process(ctx, newEntry(time.Second, map[string]interface{}{"message": "a"})))
+	require.Equal(t, "2", receive(t, fake).Labels["reduce_count"])
+
+	// The next entry starts a new group
+	require.NoError(t, op.Process(ctx, newEntry(2*time.Second, map[string]interface{}{"message": "a"})))
+	expectNoEntry(t, fake)
+}
+
+func TestReduceMaxGroups(t *testing.T) {
+	op, fake := newTestOperator(t, func(cfg *ReduceOperatorConfig) {
+		cfg.GroupBy = []entry.Field{entry.NewRecordField("message")}
+		cfg.MaxGroups = 2
+	})
+
+	ctx := context.Background()
+	require.NoError(t, op.Process(ctx, newEntry(0, map[string]interface{}{"message": "a"})))
+	require.NoError(t, op.Process(ctx, newEntry(0, map[string]interface{}{"message": "b"})))
+	require.NoError(t, op.Process(ctx, newEntry(0, map[string]interface{}{"message": "a"})))
+	expectNoEntry(t, fake)
+
+	// The oldest group is flushed to make room for a new one
+	require.NoError(t, op.Process(ctx, newEntry(0, map[string]interface{}{"message": "c"})))
+	e := receive(t, fake)
+	require.Equal(t, "a", e.Record.(map[string]interface{})["message"])
+	require.Equal(t, "2", e.Labels["reduce_count"])
+
+	require.NoError(t, op.Stop())
+	require.Equal(t, "b", receive(t, fake).Record.(map[string]interface{})["message"])
+	require.Equal(t, "c", receive(t, fake).Record.(map[string]interface{})["message"])
+}
+
+func TestReduceSlowOutput(t *testing.T) {
+	op, fake := newTestOperator(t, func(cfg *ReduceOperatorConfig) {
+		cfg.GroupBy = []entry.Field{entry.NewRecordField("message")}
+		cfg.MaxGroupSize = 2
+	})
+	reduce := op.(*ReduceOperator)
+
+	// Writes block until the entry is received
+	fake.Received = make(chan *entry.Entry)
+
+	ctx := context.Background()
+	require.NoError(t, op.Process(ctx, newEntry(0, map[string]interface{}{"message": "a"})))
+
+	// The group is full, and the write of its merged entry blocks
+	go func() { _ = op.Process(ctx, newEntry(0, map[string]interface{}{"message": "a"})) }()
+	require.Eventually(t, func() bool {
+		reduce.groupsMux.Lock()
+		defer reduce.groupsMux.Unlock()
+		return reduce.order.Len() == 0
+	}, time.Second, 10*time.Millisecond)
+
+	// Other groups are still merged while the output is blocked
+	processed := make(chan struct{})
+	go func() {
+		defer close(processed)
+		_ = op.Process(ctx, newEntry(0, map[string]interface{}{"message": "b"}))
+	}()
+	select {
+	case <-processed:
+	case <-time.After(time.Second):
+		require.FailNow(t, "Timed out waiting for entry to be processed")
+	}
+
+	require.Equal(t, "a", receive(t, fake).Record.(map[string]interface{})["message"])
+
+	go func() { _ = op.Stop() }()
+	require.Equal(t, "b", receive(t, fake).Record.(map[string]interface{})["message"])
+}
+
+func TestReduceRecordFields(t *testing.T) {
+	op, fake := newTestOperator(t, func(cfg *ReduceOperatorConfig) {
+		cfg.CountField = entry.NewRecordField("count")
+		cfg.LastTimestampField = entry.NewRecordField("last_seen")
+	})
+
+	ctx := context.Background()
+	require.NoError(t, op.Process(ctx, newEntry(0, map[string]interface{}{"message": "a"})))
+	require.NoError(t, op.Process(ctx, newEntry(time.Minute, map[string]interface{}{"message": "a"})))
+	require.NoError(t, op.Stop())
+
+	e := receive(t, fake)
+	require.Empty(t, e.Labels)
+	require.Equal(t, map[string]interface{}{
+		"message":   "a",
+		"count":     2,
+		"last_seen": baseTime.Add(time.Minute),
+	}, e.Record)
+}
+
+func TestReduceNumbers(t *testing.T) {
+	op, fake := newTestOperator(t, func(cfg *ReduceOperatorConfig) {
+		cfg.Merge = []MergeConfig{
+			{Field: entry.NewRecordField("sum_int"), Strategy: sumStrategy},
+			{Field: entry.NewRecordField("sum_float"), Strategy: sumStrategy},
+			{Field: entry.NewRecordField("max_int"), Strategy: maxStrategy},
+			{Field: entry.NewRecordField("max_float"), Strategy: maxStrategy},
+		}
+	})
+
+	ctx := context.Background()
+	require.NoError(t, op.Process(ctx, newEntry(0, map[string]interface{}{
+		"sum_int": 1, "sum_float": 1, "max_int": int64(-5), "max_float": "2.5",
+	})))
+	require.NoError(t, op.Process(ctx, newEntry(0, map[string]interface{}{
+		"sum_int": "not a number", "sum_float": 0.5, "max_int": uint32(3), "max_float": 1,
+	})))
+	require.NoError(t, op.Process(ctx, newEntry(0, map[string]interface{}{
+		"sum_int": "2", "sum_float": "1.25", "max_int": -1, "max_float": 2,
+	})))
+	require.NoError(t, op.Stop())
+
+	e := receive(t, fake)
+	require.Equal(t, map[string]interface{}{
+		"sum_int":   int64(3),
+		"sum_float": 2.75,
+		"max_int":   int64(3),
+		"max_float": 2.5,
+	}, e.Record)
+}
+
+func TestReduceConfigYAML(t *testing.T) {
+	configYAML := `
+type: reduce
+id: my_reduce
+output: test_output
+group_by:
+  - $record.service
+  - $labels.host
+window: 1m
+merge:
+  - field: message
+    strategy: concat
+    separator: ", "
+  - field: $record.bytes
+    strategy: sum
+count_field: $record.count
+`
+
+	expected := NewReduceOperatorConfig("my_reduce")
+	expected.OutputIDs = []string{"test_output"}
+	expected.GroupBy = []entry.Field{entry.NewRecordField("service"), entry.NewLabelField("host")}
+	expected.Window = helper.Duration{Duration: time.Minute}
+	expected.Merge = []MergeConfig{
+		{Field: entry.NewRecordField("message"), Strategy: concatStrategy, Separator: separator(", ")},
+		{Field: entry.NewRecordField("bytes"), Strategy: sumStrategy},
+	}
+	expected.CountField = entry.NewRecordField("count")
+
+	var config operator.Config
+	require.NoError(t, yaml.UnmarshalStrict([]byte(configYAML), &config))
+	require.Equal(t, expected, config.Builder)
+}
+
+func TestBuildReduceErrors(t *testing.T) {
+	cases := []struct {
+		name      string
+		configure func(*ReduceOperatorConfig)
+	}{
+		{"ZeroWindow", func(c *ReduceOperatorConfig) { c.Window = helper.Duration{} }},
+		{"ZeroMaxGroupSize", func(c *ReduceOperatorConfig) { c.MaxGroupSize = 0 }},
+		{"ZeroMaxGroups", func(c *ReduceOperatorConfig) { c.MaxGroups = 0 }},
+		{"MissingMergeField", func(c *ReduceOperatorConfig) {
+			c.Merge = []MergeConfig{{Strategy: sumStrategy}}
+		}},
+		{"UnknownStrategy", func(c *ReduceOperatorConfig) {
+			c.Merge = []MergeConfig{{Field: entry.NewRecordField("bytes"), Strategy: "avg"}}
+		}},
+		{"SeparatorWithoutConcat", func(c *ReduceOperatorConfig) {
+			c.Merge = []MergeConfig{{Field: entry.NewRecordField("bytes"), Strategy: sumStrategy, Separator: separator(",")}}
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := NewReduceOperatorConfig("test_id")
+			cfg.OutputIDs = []string{"$.fake"}
+			tc.configure(cfg)
+
+			_, err := cfg.Build(testutil.NewBuildContext(t))
+			require.Error(t, err)
+		})
+	}
+}
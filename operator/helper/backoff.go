@@ -0,0 +1,88 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// Bounds of the delay between retries of a failed operation
+	minRetryDelay = 5 * time.Millisecond
+	maxRetryDelay = time.Second
+)
+
+// Backoff is an exponentially increasing delay between retries of an
+// operation that keeps failing, such as accepting connections. It keeps a
+// persistent error, like running out of file descriptors, from spinning a loop.
+type Backoff struct {
+	min   time.Duration
+	max   time.Duration
+	delay time.Duration
+}
+
+// NewBackoff creates a new backoff for operations that are cheap to retry
+func NewBackoff() *Backoff {
+	return NewBackoffWithLimits(minRetryDelay, maxRetryDelay)
+}
+
+// NewBackoffWithLimits creates a new backoff whose delay starts at min and is
+// capped at max
+func NewBackoffWithLimits(min, max time.Duration) *Backoff {
+	return &Backoff{
+		min: min,
+		max: max,
+	}
+}
+
+// Next doubles the delay, starting from the minimum and capped at the maximum
+func (b *Backoff) Next() time.Duration {
+	b.delay *= 2
+	if b.delay == 0 {
+		b.delay = b.min
+	}
+	if b.delay > b.max {
+		b.delay = b.max
+	}
+	return b.delay
+}
+
+// Reset will start the delay from the minimum again after a success
+func (b *Backoff) Reset() {
+	b.delay = 0
+}
+
+// Wait will log err with the next delay and wait for it before a retry.
+// It returns false if the context is done first.
+func (b *Backoff) Wait(ctx context.Context, log func(string, ...interface{}), msg string, err error) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	default:
+	}
+
+	delay := b.Next()
+	log(msg, zap.Error(err), "retry_delay", delay)
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(delay):
+		return true
+	}
+}
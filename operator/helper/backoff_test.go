@@ -0,0 +1,72 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackoffNext(t *testing.T) {
+	b := NewBackoff()
+	require.Equal(t, 5*time.Millisecond, b.Next())
+	require.Equal(t, 10*time.Millisecond, b.Next())
+	require.Equal(t, 20*time.Millisecond, b.Next())
+
+	for i := 0; i < 10; i++ {
+		b.Next()
+	}
+	require.Equal(t, time.Second, b.Next())
+
+	b.Reset()
+	require.Equal(t, 5*time.Millisecond, b.Next())
+}
+
+func TestBackoffWait(t *testing.T) {
+	var logged []interface{}
+	log := func(msg string, keysAndValues ...interface{}) {
+		logged = append(logged, msg)
+	}
+
+	b := NewBackoff()
+	require.True(t, b.Wait(context.Background(), log, "Failed", errors.New("test")))
+	require.Equal(t, []interface{}{"Failed"}, logged)
+}
+
+func TestBackoffWaitCancelled(t *testing.T) {
+	b := NewBackoffWithLimits(time.Hour, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	log := func(string, ...interface{}) {}
+
+	done := make(chan bool)
+	go func() {
+		done <- b.Wait(ctx, log, "Failed", errors.New("test"))
+	}()
+	cancel()
+
+	select {
+	case ok := <-done:
+		require.False(t, ok)
+	case <-time.After(time.Second):
+		require.FailNow(t, "Timed out waiting for the backoff to stop")
+	}
+
+	require.False(t, b.Wait(ctx, log, "Failed", errors.New("test")))
+}
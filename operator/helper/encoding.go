@@ -0,0 +1,377 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/opentelemetry/opentelemetry-log-collection/errors"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// Encoder encodes entries for an output
+type Encoder interface {
+	// Encode writes an entry as a self delimiting record in a stream, such as a file
+	Encode(w io.Writer, e *entry.Entry) error
+	// EncodeBatch writes entries as a single payload, such as the body of a request
+	EncodeBatch(w io.Writer, entries []*entry.Entry) error
+	// ContentType returns the media type of encoded entries
+	ContentType() string
+}
+
+// EncoderBuilder builds an encoder from an encoding config
+type EncoderBuilder func(EncodingConfig) (Encoder, error)
+
+var encoders = map[string]EncoderBuilder{
+	"json":     func(EncodingConfig) (Encoder, error) { return jsonEncoder{}, nil },
+	"ndjson":   func(EncodingConfig) (Encoder, error) { return ndjsonEncoder{}, nil },
+	"logfmt":   func(EncodingConfig) (Encoder, error) { return logfmtEncoder{}, nil },
+	"msgpack":  func(EncodingConfig) (Encoder, error) { return msgpackEncoder{}, nil },
+	"raw":      newRawEncoder,
+	"template": newTemplateEncoder,
+}
+
+// RegisterEncoding makes an encoding available to outputs. It is intended to be called
+// from the init function of packages that provide encodings with large dependencies.
+func RegisterEncoding(encodingType string, builder EncoderBuilder) {
+	encoders[encodingType] = builder
+}
+
+// NewEncodingConfig creates a new encoding config with default values
+func NewEncodingConfig(encodingType string) EncodingConfig {
+	return EncodingConfig{
+		Type:  encodingType,
+		Field: entry.NewRecordField(),
+	}
+}
+
+// EncodingConfig is the configuration of an encoding
+type EncodingConfig struct {
+	Type     string      `json:"type"               yaml:"type"`
+	Field    entry.Field `json:"field,omitempty"    yaml:"field,omitempty"`
+	Template string      `json:"template,omitempty" yaml:"template,omitempty"`
+}
+
+// Build will build an encoder
+func (c EncodingConfig) Build() (Encoder, error) {
+	builder, ok := encoders[c.Type]
+	if !ok {
+		return nil, errors.NewError(
+			fmt.Sprintf("unknown encoding type '%s'", c.Type),
+			"Use one of 'json', 'ndjson', 'logfmt', 'msgpack', 'raw', 'template' or 'otlp'",
+		)
+	}
+	return builder(c)
+}
+
+// jsonEncoder encodes each entry as a json object, and batches as a json array
+type jsonEncoder struct{}
+
+func (jsonEncoder) Encode(w io.Writer, e *entry.Entry) error {
+	return newJSONEncoder(w).Encode(e)
+}
+
+func (jsonEncoder) EncodeBatch(w io.Writer, entries []*entry.Entry) error {
+	return newJSONEncoder(w).Encode(entries)
+}
+
+func (jsonEncoder) ContentType() string { return "application/json" }
+
+// ndjsonEncoder encodes each entry as a line of json
+type ndjsonEncoder struct{}
+
+func (ndjsonEncoder) Encode(w io.Writer, e *entry.Entry) error {
+	return newJSONEncoder(w).Encode(e)
+}
+
+func (n ndjsonEncoder) EncodeBatch(w io.Writer, entries []*entry.Entry) error {
+	return encodeEach(n, w, entries)
+}
+
+func (ndjsonEncoder) ContentType() string { return "application/x-ndjson" }
+
+func newJSONEncoder(w io.Writer) *json.Encoder {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return enc
+}
+
+// logfmtEncoder encodes each entry as a line of logfmt key value pairs
+type logfmtEncoder struct{}
+
+func (logfmtEncoder) Encode(w io.Writer, e *entry.Entry) error {
+	var b bytes.Buffer
+	writeLogfmtPair(&b, "timestamp", e.Timestamp.Format(time.RFC3339Nano))
+	writeLogfmtPair(&b, "severity", e.Severity.String())
+	if e.SeverityText != "" {
+		writeLogfmtPair(&b, "severity_text", e.SeverityText)
+	}
+	writeLogfmtMap(&b, "labels.", e.Labels)
+	writeLogfmtMap(&b, "resource.", e.Resource)
+
+	switch record := e.Record.(type) {
+	case map[string]interface{}:
+		writeLogfmtRecord(&b, "", record)
+	case nil:
+	default:
+		writeLogfmtPair(&b, "record", logfmtValue(record))
+	}
+
+	b.WriteByte('\n')
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func (l logfmtEncoder) EncodeBatch(w io.Writer, entries []*entry.Entry) error {
+	return encodeEach(l, w, entries)
+}
+
+func (logfmtEncoder) ContentType() string { return "text/plain" }
+
+func writeLogfmtMap(b *bytes.Buffer, prefix string, m map[string]string) {
+	for _, k := range sortedKeys(m) {
+		writeLogfmtPair(b, prefix+k, m[k])
+	}
+}
+
+// writeLogfmtRecord will write the fields of a record, joining the keys of nested maps with dots
+func writeLogfmtRecord(b *bytes.Buffer, prefix string, record map[string]interface{}) {
+	keys := make([]string, 0, len(record))
+	for k := range record {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if nested, ok := record[k].(map[string]interface{}); ok {
+			writeLogfmtRecord(b, prefix+k+".", nested)
+			continue
+		}
+		writeLogfmtPair(b, prefix+k, logfmtValue(record[k]))
+	}
+}
+
+func writeLogfmtPair(b *bytes.Buffer, key, value string) {
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+
+	b.WriteString(strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' {
+			return '_'
+		}
+		return r
+	}, key))
+	b.WriteByte('=')
+
+	if value == "" || strings.ContainsAny(value, " =\"\\") || strings.IndexFunc(value, isControl) >= 0 {
+		b.WriteString(strconv.Quote(value))
+		return
+	}
+	b.WriteString(value)
+}
+
+func isControl(r rune) bool {
+	return r < ' ' || r == 0x7f
+}
+
+// logfmtValue converts a value to a string, encoding complex values as json
+func logfmtValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case nil:
+		return ""
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprintf("%v", v)
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(raw)
+}
+
+// msgpackEncoder encodes each entry as a msgpack map, and batches as a msgpack array
+type msgpackEncoder struct{}
+
+func (msgpackEncoder) Encode(w io.Writer, e *entry.Entry) error {
+	b, err := appendMsgpackEntry(nil, e)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func (msgpackEncoder) EncodeBatch(w io.Writer, entries []*entry.Entry) error {
+	b := msgp.AppendArrayHeader(nil, uint32(len(entries)))
+	for _, e := range entries {
+		var err error
+		if b, err = appendMsgpackEntry(b, e); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+func (msgpackEncoder) ContentType() string { return "application/msgpack" }
+
+// appendMsgpackEntry will append an entry with the same keys as its json encoding
+func appendMsgpackEntry(b []byte, e *entry.Entry) ([]byte, error) {
+	size := uint32(3)
+	if e.SeverityText != "" {
+		size++
+	}
+	if len(e.Labels) > 0 {
+		size++
+	}
+	if len(e.Resource) > 0 {
+		size++
+	}
+
+	b = msgp.AppendMapHeader(b, size)
+	b = msgp.AppendString(b, "timestamp")
+	b = msgp.AppendString(b, e.Timestamp.Format(time.RFC3339Nano))
+	b = msgp.AppendString(b, "severity")
+	b = msgp.AppendInt(b, int(e.Severity))
+	if e.SeverityText != "" {
+		b = msgp.AppendString(b, "severity_text")
+		b = msgp.AppendString(b, e.SeverityText)
+	}
+	if len(e.Labels) > 0 {
+		b = msgp.AppendString(b, "labels")
+		b = msgp.AppendMapStrStr(b, e.Labels)
+	}
+	if len(e.Resource) > 0 {
+		b = msgp.AppendString(b, "resource")
+		b = msgp.AppendMapStrStr(b, e.Resource)
+	}
+	b = msgp.AppendString(b, "record")
+	b, err := msgp.AppendIntf(b, e.Record)
+	if err != nil {
+		return nil, errors.Wrap(err, "encode record")
+	}
+	return b, nil
+}
+
+// rawEncoder encodes the value of a field of each entry as a line
+type rawEncoder struct {
+	field entry.Field
+}
+
+func newRawEncoder(c EncodingConfig) (Encoder, error) {
+	if c.Field.FieldInterface == nil {
+		return nil, errors.NewError("missing required parameter 'field'", "")
+	}
+	return rawEncoder{field: c.Field}, nil
+}
+
+func (r rawEncoder) Encode(w io.Writer, e *entry.Entry) error {
+	value, _ := e.Get(r.field)
+	s, err := FormatRecord(value)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, s+"\n")
+	return err
+}
+
+// FormatRecord returns a record, or the value of a field, as a single string for
+// outputs that send text. Strings and bytes are used as is, nil is empty, and other
+// values are encoded as json.
+func FormatRecord(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case nil:
+		return "", nil
+	}
+
+	var b bytes.Buffer
+	if err := newJSONEncoder(&b).Encode(value); err != nil {
+		return "", err
+	}
+	// The json encoder ends the value with a newline
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+func (r rawEncoder) EncodeBatch(w io.Writer, entries []*entry.Entry) error {
+	return encodeEach(r, w, entries)
+}
+
+func (rawEncoder) ContentType() string { return "text/plain" }
+
+// templateEncoder encodes each entry with a go template
+type templateEncoder struct {
+	tmpl *template.Template
+}
+
+func newTemplateEncoder(c EncodingConfig) (Encoder, error) {
+	if c.Template == "" {
+		return nil, errors.NewError("missing required parameter 'template'", "")
+	}
+
+	tmpl, err := template.New("encoding").Parse(c.Template)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse template")
+	}
+	return templateEncoder{tmpl: tmpl}, nil
+}
+
+func (t templateEncoder) Encode(w io.Writer, e *entry.Entry) error {
+	return t.tmpl.Execute(w, e)
+}
+
+func (t templateEncoder) EncodeBatch(w io.Writer, entries []*entry.Entry) error {
+	return encodeEach(t, w, entries)
+}
+
+func (templateEncoder) ContentType() string { return "text/plain" }
+
+// encodeEach will encode a batch as a stream of entries
+func encodeEach(enc Encoder, w io.Writer, entries []*entry.Entry) error {
+	for _, e := range entries {
+		if err := enc.Encode(w, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,270 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/opentelemetry/opentelemetry-log-collection/entry"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func newEncodingTestEntries() []*entry.Entry {
+	first := entry.New()
+	first.Timestamp = time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC)
+	first.Severity = entry.Error
+	first.Labels = map[string]string{"app": "web"}
+	first.Record = map[string]interface{}{
+		"message": "a <b> message",
+		"status":  500,
+		"request": map[string]interface{}{"path": "/index", "method": "GET"},
+	}
+
+	second := entry.New()
+	second.Timestamp = time.Date(2021, 2, 3, 4, 5, 7, 0, time.UTC)
+	second.SeverityText = "INFO"
+	second.Resource = map[string]string{"host": "example"}
+	second.Record = "second message"
+
+	return []*entry.Entry{first, second}
+}
+
+func encodeTestEntries(t *testing.T, cfg EncodingConfig) (string, string) {
+	encoder, err := cfg.Build()
+	require.NoError(t, err)
+
+	var stream bytes.Buffer
+	for _, e := range newEncodingTestEntries() {
+		require.NoError(t, encoder.Encode(&stream, e))
+	}
+
+	var batch bytes.Buffer
+	require.NoError(t, encoder.EncodeBatch(&batch, newEncodingTestEntries()))
+	return stream.String(), batch.String()
+}
+
+func TestEncoding(t *testing.T) {
+	firstJSON := `{"timestamp":"2021-02-03T04:05:06Z","severity":60,"labels":{"app":"web"},"record":{"message":"a <b> message","request":{"method":"GET","path":"/index"},"status":500}}`
+	secondJSON := `{"timestamp":"2021-02-03T04:05:07Z","severity":0,"severity_text":"INFO","resource":{"host":"example"},"record":"second message"}`
+
+	cases := []struct {
+		name        string
+		config      func() EncodingConfig
+		stream      string
+		batch       string
+		contentType string
+	}{
+		{
+			"JSON",
+			func() EncodingConfig { return NewEncodingConfig("json") },
+			firstJSON + "\n" + secondJSON + "\n",
+			"[" + firstJSON + "," + secondJSON + "]\n",
+			"application/json",
+		},
+		{
+			"NDJSON",
+			func() EncodingConfig { return NewEncodingConfig("ndjson") },
+			firstJSON + "\n" + secondJSON + "\n",
+			firstJSON + "\n" + secondJSON + "\n",
+			"application/x-ndjson",
+		},
+		{
+			"Logfmt",
+			func() EncodingConfig { return NewEncodingConfig("logfmt") },
+			`timestamp=2021-02-03T04:05:06Z severity=error labels.app=web message="a <b> message" request.method=GET request.path=/index status=500` + "\n" +
+				`timestamp=2021-02-03T04:05:07Z severity=default severity_text=INFO resource.host=example record="second message"` + "\n",
+			`timestamp=2021-02-03T04:05:06Z severity=error labels.app=web message="a <b> message" request.method=GET request.path=/index status=500` + "\n" +
+				`timestamp=2021-02-03T04:05:07Z severity=default severity_text=INFO resource.host=example record="second message"` + "\n",
+			"text/plain",
+		},
+		{
+			"RawRecord",
+			func() EncodingConfig { return NewEncodingConfig("raw") },
+			`{"message":"a <b> message","request":{"method":"GET","path":"/index"},"status":500}` + "\n" + "second message\n",
+			`{"message":"a <b> message","request":{"method":"GET","path":"/index"},"status":500}` + "\n" + "second message\n",
+			"text/plain",
+		},
+		{
+			"RawField",
+			func() EncodingConfig {
+				cfg := NewEncodingConfig("raw")
+				cfg.Field = entry.NewRecordField("message")
+				return cfg
+			},
+			"a <b> message\n\n",
+			"a <b> message\n\n",
+			"text/plain",
+		},
+		{
+			"Template",
+			func() EncodingConfig {
+				cfg := NewEncodingConfig("template")
+				cfg.Template = "{{ .Timestamp.Unix }} {{ .Record }}\n"
+				return cfg
+			},
+			"1612325106 map[message:a <b> message request:map[method:GET path:/index] status:500]\n1612325107 second message\n",
+			"1612325106 map[message:a <b> message request:map[method:GET path:/index] status:500]\n1612325107 second message\n",
+			"text/plain",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stream, batch := encodeTestEntries(t, tc.config())
+			require.Equal(t, tc.stream, stream)
+			require.Equal(t, tc.batch, batch)
+
+			encoder, err := tc.config().Build()
+			require.NoError(t, err)
+			require.Equal(t, tc.contentType, encoder.ContentType())
+		})
+	}
+}
+
+func TestEncodingMsgpack(t *testing.T) {
+	stream, batch := encodeTestEntries(t, NewEncodingConfig("msgpack"))
+
+	expected := []map[string]interface{}{
+		{
+			"timestamp": "2021-02-03T04:05:06Z",
+			"severity":  int64(60),
+			"labels":    map[string]interface{}{"app": "web"},
+			"record": map[string]interface{}{
+				"message": "a <b> message",
+				"status":  int64(500),
+				"request": map[string]interface{}{"path": "/index", "method": "GET"},
+			},
+		},
+		{
+			"timestamp":     "2021-02-03T04:05:07Z",
+			"severity":      int64(0),
+			"severity_text": "INFO",
+			"resource":      map[string]interface{}{"host": "example"},
+			"record":        "second message",
+		},
+	}
+
+	remaining := []byte(stream)
+	for _, e := range expected {
+		var decoded interface{}
+		var err error
+		decoded, remaining, err = msgp.ReadIntfBytes(remaining)
+		require.NoError(t, err)
+		require.Equal(t, e, decoded)
+	}
+	require.Empty(t, remaining)
+
+	decoded, remaining, err := msgp.ReadIntfBytes([]byte(batch))
+	require.NoError(t, err)
+	require.Empty(t, remaining)
+	require.Equal(t, []interface{}{expected[0], expected[1]}, decoded)
+}
+
+func TestEncodingLogfmtQuoting(t *testing.T) {
+	e := entry.New()
+	e.Timestamp = time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC)
+	e.Record = map[string]interface{}{
+		"empty":     "",
+		"equals":    "a=b",
+		"quote":     `say "hi"`,
+		"newline":   "a\nb",
+		"bad key":   true,
+		"list":      []interface{}{"a", 1},
+		"plain":     "value",
+		"nil_value": nil,
+	}
+
+	encoder, err := NewEncodingConfig("logfmt").Build()
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, encoder.Encode(&buf, e))
+
+	expected := `timestamp=2021-02-03T04:05:06Z severity=default bad_key=true empty="" equals="a=b" list="[\"a\",1]" newline="a\nb" nil_value="" plain=value quote="say \"hi\""` + "\n"
+	require.Equal(t, expected, buf.String())
+}
+
+func TestFormatRecord(t *testing.T) {
+	cases := []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{"String", "a <b> message", "a <b> message"},
+		{"Bytes", []byte("raw"), "raw"},
+		{"Nil", nil, ""},
+		{"Map", map[string]interface{}{"message": "a <b> message", "status": 500}, `{"message":"a <b> message","status":500}`},
+		{"Number", 5, "5"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			formatted, err := FormatRecord(tc.value)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, formatted)
+		})
+	}
+}
+
+func TestEncodingBuildErrors(t *testing.T) {
+	cases := []struct {
+		name   string
+		config EncodingConfig
+	}{
+		{"UnknownType", NewEncodingConfig("xml")},
+		{"EmptyType", EncodingConfig{}},
+		{"RawWithoutField", EncodingConfig{Type: "raw"}},
+		{"TemplateWithoutTemplate", NewEncodingConfig("template")},
+		{"InvalidTemplate", EncodingConfig{Type: "template", Template: "{{ .Record "}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.config.Build()
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestEncodingConfigUnmarshal(t *testing.T) {
+	t.Run("YAML", func(t *testing.T) {
+		cfg := NewEncodingConfig("json")
+		require.NoError(t, yaml.Unmarshal([]byte("type: raw\nfield: $labels.app\n"), &cfg))
+		require.Equal(t, "raw", cfg.Type)
+		require.Equal(t, entry.NewLabelField("app"), cfg.Field)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		cfg := NewEncodingConfig("json")
+		require.NoError(t, json.Unmarshal([]byte(`{"type":"template","template":"{{ .Record }}"}`), &cfg))
+		require.Equal(t, "template", cfg.Type)
+		require.Equal(t, "{{ .Record }}", cfg.Template)
+		require.Equal(t, entry.NewRecordField(), cfg.Field)
+	})
+}
+
+func TestRegisterEncoding(t *testing.T) {
+	RegisterEncoding("test_encoding", func(EncodingConfig) (Encoder, error) { return ndjsonEncoder{}, nil })
+	defer delete(encoders, "test_encoding")
+
+	encoder, err := NewEncodingConfig("test_encoding").Build()
+	require.NoError(t, err)
+	require.Equal(t, "application/x-ndjson", encoder.ContentType())
+}
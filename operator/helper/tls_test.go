@@ -0,0 +1,73 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLSClientConfigBuild(t *testing.T) {
+	t.Run("Default", func(t *testing.T) {
+		tlsConfig, err := TLSClientConfig{}.Build()
+		require.NoError(t, err)
+		require.Nil(t, tlsConfig.RootCAs)
+		require.Empty(t, tlsConfig.Certificates)
+	})
+
+	t.Run("ServerName", func(t *testing.T) {
+		tlsConfig, err := TLSClientConfig{ServerName: "example.com", InsecureSkipVerify: true}.Build()
+		require.NoError(t, err)
+		require.Equal(t, "example.com", tlsConfig.ServerName)
+		require.True(t, tlsConfig.InsecureSkipVerify)
+	})
+
+	t.Run("MissingCAFile", func(t *testing.T) {
+		_, err := TLSClientConfig{CAFile: "/does/not/exist"}.Build()
+		require.Error(t, err)
+	})
+
+	t.Run("InvalidCAFile", func(t *testing.T) {
+		caFile, err := ioutil.TempFile("", "")
+		require.NoError(t, err)
+		defer os.Remove(caFile.Name())
+		_, err = caFile.WriteString("not a certificate")
+		require.NoError(t, err)
+		require.NoError(t, caFile.Close())
+
+		_, err = TLSClientConfig{CAFile: caFile.Name()}.Build()
+		require.Error(t, err)
+	})
+
+	t.Run("CertWithoutKey", func(t *testing.T) {
+		_, err := TLSClientConfig{CertFile: "cert.pem"}.Build()
+		require.Error(t, err)
+	})
+}
+
+func TestTLSServerConfigBuild(t *testing.T) {
+	t.Run("MissingKey", func(t *testing.T) {
+		_, err := TLSServerConfig{CertFile: "cert.pem"}.Build()
+		require.Error(t, err)
+	})
+
+	t.Run("MissingCertFile", func(t *testing.T) {
+		_, err := TLSServerConfig{CertFile: "/does/not/exist", KeyFile: "/does/not/exist"}.Build()
+		require.Error(t, err)
+	})
+}